@@ -0,0 +1,24 @@
+// Package db embeds the SQL migrations into the gateway binary, so they are
+// found whatever directory it runs from.
+package db
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql
+var files embed.FS
+
+// Migrations holds the NNNNNN_name.up.sql and .down.sql files of every
+// migration at its root.
+var Migrations fs.FS
+
+func init() {
+	sub, err := fs.Sub(files, "migrations")
+	if err != nil {
+		panic(fmt.Sprintf("embedded migrations: %v", err))
+	}
+	Migrations = sub
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS gateway_policy_versions (
+    version INTEGER PRIMARY KEY,
+    document TEXT NOT NULL,
+    change VARCHAR(128) NOT NULL,
+    created_by VARCHAR(32),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    rolled_back_from INTEGER REFERENCES gateway_policy_versions (version)
+);
@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS idx_users_role ON users (role);
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_users_username ON users (username, id);
+CREATE INDEX IF NOT EXISTS idx_users_email ON users (email, id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS email_changes;
+DROP TABLE IF EXISTS password_histories;
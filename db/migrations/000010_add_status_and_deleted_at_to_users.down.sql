@@ -0,0 +1,14 @@
+-- Without these columns deleted, disabled and locked users would turn back into
+-- active accounts, so refuse to revert until every user is active again or has
+-- been removed deliberately.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE deleted_at IS NOT NULL OR status <> 'active') THEN
+        RAISE EXCEPTION 'users has deleted, disabled or locked rows; restore or remove them before reverting this migration';
+    END IF;
+END
+$$;
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS idx_users_status;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS status;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS superadmin_transfers;
+ALTER TABLE users DROP COLUMN IF EXISTS token_version;
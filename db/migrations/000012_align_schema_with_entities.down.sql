@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_user_uuid;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,16 @@
+-- Refresh tokens were only ever created by GORM's AutoMigrate.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    token VARCHAR(128) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_token ON refresh_tokens (token);
+
+-- 'user' is not a role; every user is created with an explicit one.
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_user_uuid ON users (user_uuid);
@@ -0,0 +1,48 @@
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Options configures how the first superadmin may be created over HTTP.
+type Options struct {
+	// HTTP serves /superadmin/check and /superadmin/create. They are never
+	// served in production mode, whatever HTTP says.
+	HTTP bool `yaml:"http" toml:"http"`
+	// TokenFile receives the setup token instead of stdout.
+	TokenFile string `yaml:"token_file" toml:"token_file"`
+}
+
+// DefaultOptions serves the bootstrap routes and prints the setup token.
+func DefaultOptions() Options {
+	return Options{HTTP: true}
+}
+
+// LoadEnv overrides o with BOOTSTRAP_HTTP and BOOTSTRAP_TOKEN_FILE as found by
+// lookup, usually os.LookupEnv.
+func (o *Options) LoadEnv(lookup func(string) (string, bool)) error {
+	if v, _ := lookup("BOOTSTRAP_HTTP"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("BOOTSTRAP_HTTP: %w", err)
+		}
+		o.HTTP = b
+	}
+	if v, _ := lookup("BOOTSTRAP_TOKEN_FILE"); v != "" {
+		o.TokenFile = v
+	}
+	return nil
+}
+
+// Validate rejects a token file that is a directory.
+func (o Options) Validate() error {
+	if o.TokenFile == "" {
+		return nil
+	}
+	if info, err := os.Stat(o.TokenFile); err == nil && info.IsDir() {
+		return fmt.Errorf("token file %q is a directory", o.TokenFile)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Package bootstrap guards the creation of the first superadmin over HTTP with
+// a one-time setup token that only the operator can read.
+package bootstrap
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"zeneye-gateway/internal/domain/domainerr"
+	"zeneye-gateway/pkg/logger"
+)
+
+// SetupToken is a one-time secret that authorizes creating the first
+// superadmin. Only its hash is kept in memory.
+type SetupToken struct {
+	mu   sync.Mutex
+	hash [sha256.Size]byte
+	used bool
+	// file is removed once the token is redeemed.
+	file string
+}
+
+// NewSetupToken returns a fresh token and its value.
+func NewSetupToken() (*SetupToken, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(raw)
+	return &SetupToken{hash: sha256.Sum256([]byte(value))}, value, nil
+}
+
+// Issue creates a token and hands it to the operator: written to
+// opts.TokenFile, readable by the owner only, or else printed to out. The
+// token never goes to the log.
+func Issue(opts Options, out io.Writer) (*SetupToken, error) {
+	token, value, err := NewSetupToken()
+	if err != nil {
+		return nil, err
+	}
+	if opts.TokenFile != "" {
+		if err := writeTokenFile(opts.TokenFile, value); err != nil {
+			return nil, fmt.Errorf("write setup token: %w", err)
+		}
+		token.file = opts.TokenFile
+		logger.LogInfo("Bootstrap", "Issue", "No superadmin yet; setup token written to "+opts.TokenFile, "")
+		return token, nil
+	}
+	fmt.Fprintf(out, "No superadmin exists yet. Create one with POST /superadmin/create and the header\n  X-Setup-Token: %s\nThe token works once and is forgotten on restart.\n", value)
+	logger.LogInfo("Bootstrap", "Issue", "No superadmin yet; setup token printed to stdout", "")
+	return token, nil
+}
+
+// writeTokenFile writes value to a new file at path that only the owner can
+// read. A file left from an earlier run is replaced rather than reused, since
+// its mode may be looser.
+func writeTokenFile(path, value string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(value + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Redeem runs create if value is the token and it was not used yet, and uses
+// the token up if create succeeds. Concurrent calls run one at a time, so the
+// token creates at most one superadmin.
+func (t *SetupToken) Redeem(value string, create func() error) error {
+	if t == nil {
+		return domainerr.ErrInvalidSetupToken
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hash := sha256.Sum256([]byte(value))
+	if t.used || subtle.ConstantTimeCompare(hash[:], t.hash[:]) != 1 {
+		return domainerr.ErrInvalidSetupToken
+	}
+	if err := create(); err != nil {
+		return err
+	}
+	t.used = true
+	if t.file != "" {
+		if err := os.Remove(t.file); err != nil && !os.IsNotExist(err) {
+			logger.LogError("Bootstrap", "Redeem", t.file, err)
+		}
+	}
+	return nil
+}
+
+// Outstanding reports whether the token was issued and not used yet.
+func (t *SetupToken) Outstanding() bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.used
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"zeneye-gateway/internal/adapter/http/middlewares"
+	"zeneye-gateway/internal/application/user"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	errorResponse "zeneye-gateway/pkg/error"
+	"zeneye-gateway/pkg/jwt"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler serves /me, every authenticated user's own profile, password
+// and email whatever their role, and the public password reset.
+type AccountHandler struct {
+	users      port.UserService
+	controller *user.AccountController
+	audit      port.AuditService
+}
+
+// NewAccountHandler returns an AccountHandler over the given services.
+func NewAccountHandler(users port.UserService, accounts port.AccountService, audit port.AuditService) *AccountHandler {
+	return &AccountHandler{users: users, controller: user.NewAccountController(users, accounts), audit: audit}
+}
+
+func (h *AccountHandler) GetProfile(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("GetProfile").Info("Starting GetProfile handler", logger.String("Activity", "Handler Start"))
+
+	claims := callerClaims(c)
+	profile, err := h.controller.GetProfile(claims.UserID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("GetProfile").Error("GetProfile Error", logger.Any("Object", claims.UserID), logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).WithSource("GetProfile").Info("Profile retrieved successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", profile))
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *AccountHandler) UpdateProfile(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("UpdateProfile").Info("Starting UpdateProfile handler", logger.String("Activity", "Handler Start"))
+
+	var req user.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("UpdateProfile").Error("Binding JSON", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	claims := callerClaims(c)
+	id := strconv.FormatUint(uint64(claims.UserID), 10)
+	event := newAuditEvent(c, AuditActionUserEdit, "user", id)
+	before := userAuditSnapshot(h.users, id)
+	profile, err := h.controller.UpdateProfile(claims.UserID, req)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("UpdateProfile").Error("UpdateProfile Error", logger.Any("Object", req), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		errorResponse.FromError(c, err)
+		return
+	}
+	recordAudit(c, h.audit, event, before, userAuditSnapshot(h.users, id), nil)
+
+	logger.FromContext(c.Request.Context()).WithSource("UpdateProfile").Info("Profile updated successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", profile))
+	c.JSON(http.StatusOK, profile)
+}
+
+// ChangePassword replaces the caller's password and signs out every session:
+// refresh tokens are deleted and the token version is bumped, so access tokens
+// issued before the change are refused with token_revoked on their next
+// request. The caller gets a fresh pair of tokens in the same headers as /login.
+func (h *AccountHandler) ChangePassword(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("ChangePassword").Info("Starting ChangePassword handler", logger.String("Activity", "Handler Start"))
+
+	var req user.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ChangePassword").Error("Binding JSON", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	claims := callerClaims(c)
+	event := newAuditEvent(c, AuditActionPasswordChange, "user", strconv.FormatUint(uint64(claims.UserID), 10))
+	if err := h.controller.ChangePassword(claims.UserID, req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ChangePassword").Error("ChangePassword Error", logger.Any("Object", claims.UserID), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		errorResponse.FromError(c, err)
+		return
+	}
+	recordAudit(c, h.audit, event, nil, nil, nil)
+
+	current, err := h.users.GetUser(claims.UserID)
+	if err == nil {
+		err = issueTokens(c, h.users, current)
+	}
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ChangePassword").Error("Could not issue tokens", logger.Any("Object", claims.UserID), logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).WithSource("ChangePassword").Info("Password changed successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", claims.UserID))
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
+
+// RequestEmailChange sends a confirmation token to the new address. The
+// email only changes once the token comes back through ConfirmEmailChange.
+func (h *AccountHandler) RequestEmailChange(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("RequestEmailChange").Info("Starting RequestEmailChange handler", logger.String("Activity", "Handler Start"))
+
+	var req user.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("RequestEmailChange").Error("Binding JSON", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	claims := callerClaims(c)
+	event := newAuditEvent(c, AuditActionEmailChangeRequest, "user", strconv.FormatUint(uint64(claims.UserID), 10))
+	if err := h.controller.RequestEmailChange(claims.UserID, req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("RequestEmailChange").Error("RequestEmailChange Error", logger.Any("Object", req), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		errorResponse.FromError(c, err)
+		return
+	}
+	recordAudit(c, h.audit, event, nil, map[string]interface{}{"email": req.Email}, nil)
+
+	logger.FromContext(c.Request.Context()).WithSource("RequestEmailChange").Info("Email change requested successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", req))
+	c.JSON(http.StatusAccepted, gin.H{"message": "Confirmation sent to the new email address"})
+}
+
+func (h *AccountHandler) ConfirmEmailChange(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("ConfirmEmailChange").Info("Starting ConfirmEmailChange handler", logger.String("Activity", "Handler Start"))
+
+	var req user.ConfirmEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ConfirmEmailChange").Error("Binding JSON", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	claims := callerClaims(c)
+	id := strconv.FormatUint(uint64(claims.UserID), 10)
+	event := newAuditEvent(c, AuditActionEmailChange, "user", id)
+	before := userAuditSnapshot(h.users, id)
+	profile, err := h.controller.ConfirmEmailChange(claims.UserID, req)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ConfirmEmailChange").Error("ConfirmEmailChange Error", logger.Any("Object", claims.UserID), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		errorResponse.FromError(c, err)
+		return
+	}
+	recordAudit(c, h.audit, event, before, userAuditSnapshot(h.users, id), nil)
+
+	logger.FromContext(c.Request.Context()).WithSource("ConfirmEmailChange").Info("Email changed successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", profile))
+	c.JSON(http.StatusOK, profile)
+}
+
+// ForgotPassword answers 202 with the same body whether or not an account has
+// the email, so it cannot be used to find out who has an account.
+func (h *AccountHandler) ForgotPassword(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("ForgotPassword").Info("Starting ForgotPassword handler", logger.String("Activity", "Handler Start"))
+
+	var req user.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ForgotPassword").Error("Binding JSON", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	event := newAuditEvent(c, AuditActionPasswordResetRequest, "user", "")
+	if err := h.controller.RequestPasswordReset(req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ForgotPassword").Error("RequestPasswordReset Error", logger.Any("Object", req), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		errorResponse.FromError(c, err)
+		return
+	}
+	recordAudit(c, h.audit, event, nil, map[string]interface{}{"email": req.Email}, nil)
+
+	logger.FromContext(c.Request.Context()).WithSource("ForgotPassword").Info("Password reset request handled", logger.String("Activity", "Handler Success"), logger.Any("Object", req))
+	c.JSON(http.StatusAccepted, gin.H{"message": "If an account has this email, a reset token was sent to it"})
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword and, like
+// ChangePassword, signs out every session of the user.
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("ResetPassword").Info("Starting ResetPassword handler", logger.String("Activity", "Handler Start"))
+
+	var req user.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ResetPassword").Error("Binding JSON", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	event := newAuditEvent(c, AuditActionPasswordReset, "user", "")
+	reset, err := h.controller.ResetPassword(req)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ResetPassword").Error("ResetPassword Error", logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		errorResponse.FromError(c, err)
+		return
+	}
+	// There is no authenticated caller, so the account is its own actor
+	actorID := reset.ID
+	event.ActorID, event.ActorUsername, event.ActorRole = &actorID, reset.Username, reset.Role
+	event.TargetID = strconv.FormatUint(uint64(reset.ID), 10)
+	recordAudit(c, h.audit, event, nil, nil, nil)
+
+	logger.FromContext(c.Request.Context()).WithSource("ResetPassword").Info("Password reset successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", reset.ID))
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// callerClaims returns the claims AuthMiddleware stored for the request.
+func callerClaims(c *gin.Context) *jwt.Claims {
+	value, _ := c.Get(middlewares.ClaimsKey)
+	claims, _ := value.(*jwt.Claims)
+	if claims == nil {
+		return &jwt.Claims{}
+	}
+	return claims
+}
+
+// issueTokens sets a new access token and refresh token for user in the
+// response headers.
+func issueTokens(c *gin.Context, users port.UserService, user *entity.User) error {
+	token, err := jwt.GenerateVersionedToken(user.ID, user.Username, user.Role, user.UserUUID, user.TokenVersion)
+	if err != nil {
+		return err
+	}
+	refreshToken, err := users.GenerateRefreshToken(user.ID)
+	if err != nil {
+		return err
+	}
+
+	c.Header("Authorization", "Bearer "+token)
+	c.Header("X-Refresh-Token", refreshToken)
+	c.Header("X-Token-Expires-In", seconds(jwt.AccessTokenTTL()))
+	c.Header("X-Refresh-Token-Expires-In", seconds(jwt.RefreshTokenTTL()))
+	return nil
+}
@@ -2,107 +2,103 @@ package handlers
 
 import (
 	"net/http"
-	"os"
 	"strconv"
-	"zeneye-gateway/internal/adapter/repository/postgres"
-	"zeneye-gateway/internal/adapter/service"
+	"time"
 	"zeneye-gateway/internal/application/user"
+	"zeneye-gateway/internal/domain/port"
 	error "zeneye-gateway/pkg/error"
 	"zeneye-gateway/pkg/jwt"
 	"zeneye-gateway/pkg/logger"
-	"zeneye-gateway/pkg/utils"
+	"zeneye-gateway/pkg/metrics"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
-func Login(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		logger.LogInfo("auth_handler", "Login", "Login handler called", "")
-
-		var req user.LoginRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			logger.LogError("auth_handler", "Login", "Error binding JSON", err)
-			error.NewErrorResponse(c, http.StatusBadRequest, "Invalid request format", err.Error())
-			return
-		}
-
-		logger.LogInfo("auth_handler", "Login", "Login request received", req)
-
-		repo := postgres.NewUserRepository(db)
-		userService := service.NewUserService(repo)
-		userController := user.NewUserController(userService)
-
-		user, err := userController.Login(req)
-		if err != nil {
-			logger.LogError("auth_handler", "Login", "Invalid username or password", err)
-			error.NewErrorResponse(c, http.StatusUnauthorized, "Invalid username or password", err.Error())
-			return
-		}
-
-		token, err := jwt.GenerateToken(user.ID, user.Username, user.Role, user.UserUUID)
-		if err != nil {
-			logger.LogError("auth_handler", "Login", "Could not generate token", err)
-			error.NewErrorResponse(c, http.StatusInternalServerError, "Could not generate token", err.Error())
-			return
-		}
-
-		refreshToken, err := userService.GenerateRefreshToken(user.ID)
-		if err != nil {
-			logger.LogError("auth_handler", "Login", "Could not generate refresh token", err)
-			error.NewErrorResponse(c, http.StatusInternalServerError, "Could not generate refresh token", err.Error())
-			return
-		}
-
-		jwtExpiration, _ := strconv.Atoi(utils.GetEnv("JWT_EXPIRATION"))
-		refreshTokenExpiration, _ := strconv.Atoi(os.Getenv("REFRESH_TOKEN_EXPIRATION"))
-
-		c.Header("Authorization", "Bearer "+token)
-		c.Header("X-Refresh-Token", refreshToken)
-		c.Header("X-Token-Expires-In", strconv.Itoa(jwtExpiration*3600))                  // convert hours to seconds
-		c.Header("X-Refresh-Token-Expires-In", strconv.Itoa(refreshTokenExpiration*3600)) // convert hours to seconds
-
-		logger.LogInfo("auth_handler", "Login", "Login successful", user)
-
-		c.JSON(http.StatusOK, gin.H{
-			"message": "Login successful",
-		})
-	}
+// AuthHandler issues and refreshes access tokens.
+type AuthHandler struct {
+	users      port.UserService
+	controller *user.UserController
+	audit      port.AuditService
+}
+
+// NewAuthHandler returns an AuthHandler over the given services.
+func NewAuthHandler(users port.UserService, audit port.AuditService) *AuthHandler {
+	return &AuthHandler{users: users, controller: user.NewUserController(users), audit: audit}
 }
 
-func RefreshToken(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		logger.LogInfo("auth_handler", "RefreshToken", "Refresh Token Called", "")
+func (h *AuthHandler) Login(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("auth_handler").Info("Login handler called", logger.String("Activity", "Login"))
+
+	var req user.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("auth_handler").Error("Login", logger.Any("Object", "Error binding JSON"), logger.Err(err))
+		error.FromError(c, err)
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).WithSource("auth_handler").Info("Login request received", logger.String("Activity", "Login"), logger.Any("Object", req.Username))
+
+	event := newAuditEvent(c, AuditActionLogin, "user", "")
+	event.ActorUsername = req.Username
+	user, err := h.controller.Login(req)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("auth_handler").Error("Login", logger.Any("Object", "Invalid username or password"), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		metrics.AuthFailure("invalid_credentials")
+		error.FromError(c, err)
+		return
+	}
 
-		var req struct {
-			RefreshToken string `json:"refresh_token" binding:"required"`
-		}
-		if err := c.ShouldBindJSON(&req); err != nil {
-			logger.LogError("auth_handler", "RefreshToken", "Error binding JSON", err)
-			error.NewErrorResponse(c, http.StatusBadRequest, "Invalid request format", err.Error())
-			return
-		}
+	if err := issueTokens(c, h.users, user); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("auth_handler").Error("Login", logger.Any("Object", "Could not issue tokens"), logger.Err(err))
+		error.FromError(c, err)
+		return
+	}
 
-		repo := postgres.NewUserRepository(db)
-		userService := service.NewUserService(repo)
+	actorID := user.ID
+	event.ActorID, event.ActorRole = &actorID, user.Role
+	event.TargetID = strconv.FormatUint(uint64(user.ID), 10)
+	recordAudit(c, h.audit, event, nil, nil, nil)
 
-		token, err := userService.RefreshAccessToken(req.RefreshToken)
-		if err != nil {
-			logger.LogError("auth_handler", "RefreshToken", "Invalid refresh token", err)
-			error.NewErrorResponse(c, http.StatusUnauthorized, "Invalid refresh token", err.Error())
-			return
-		}
+	logger.FromContext(c.Request.Context()).WithSource("auth_handler").Info("Login successful", logger.String("Activity", "Login"), logger.Any("Object", user))
 
-		// Set the tokens and expiration times in the headers
-		jwtExpiration, _ := strconv.Atoi(utils.GetEnv("JWT_EXPIRATION"))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+	})
+}
 
-		c.Header("Authorization", "Bearer "+token)
-		c.Header("X-Token-Expires-In", strconv.Itoa(jwtExpiration*3600)) // convert hours to seconds
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("auth_handler").Info("Refresh Token Called", logger.String("Activity", "RefreshToken"))
 
-		logger.LogInfo("auth_handler", "RefreshToken", "Token refreshed successfully", "")
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("auth_handler").Error("RefreshToken", logger.Any("Object", "Error binding JSON"), logger.Err(err))
+		error.FromError(c, err)
+		return
+	}
 
-		c.JSON(http.StatusOK, gin.H{
-			"message": "Token refreshed successfully",
-		})
+	token, err := h.users.RefreshAccessToken(req.RefreshToken)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("auth_handler").Error("RefreshToken", logger.Any("Object", "Invalid refresh token"), logger.Err(err))
+		metrics.AuthFailure("invalid_refresh_token")
+		error.FromError(c, err)
+		return
 	}
+
+	// Set the tokens and expiration times in the headers
+	c.Header("Authorization", "Bearer "+token)
+	c.Header("X-Token-Expires-In", seconds(jwt.AccessTokenTTL()))
+
+	logger.FromContext(c.Request.Context()).WithSource("auth_handler").Info("Token refreshed successfully", logger.String("Activity", "RefreshToken"))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Token refreshed successfully",
+	})
+}
+
+// seconds formats d as whole seconds for the X-*-Expires-In headers.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Second), 10)
 }
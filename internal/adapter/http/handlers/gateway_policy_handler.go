@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"zeneye-gateway/internal/adapter/service"
+	"zeneye-gateway/internal/config"
+	"zeneye-gateway/internal/domain/domainerr"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/cors"
+	errorResponse "zeneye-gateway/pkg/error"
+	"zeneye-gateway/pkg/loadbalancer"
+	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/rate_limiter"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PolicyApplier validates and swaps in the gateway policies, the same way a
+// configuration reload does.
+type PolicyApplier interface {
+	// Policies returns the policies in force and their stored version, zero
+	// while they come from the configuration.
+	Policies() (config.Policies, uint)
+	ValidatePolicies(policies config.Policies) error
+	ApplyPolicies(policies config.Policies, version uint) error
+}
+
+// GatewayPolicyHandler serves the gateway admin API for routes, upstream
+// pools, rate limits and CORS rules. Every change is validated, stored as a new
+// policy version, applied live and audited; ?dry_run=true stops after
+// validation.
+type GatewayPolicyHandler struct {
+	policies port.GatewayPolicyService
+	audit    port.AuditService
+	gateway  PolicyApplier
+	// mu serializes changes so each one builds on the version before it.
+	mu sync.Mutex
+}
+
+// NewGatewayPolicyHandler returns a GatewayPolicyHandler that stores versions
+// in policies and applies them to gateway.
+func NewGatewayPolicyHandler(policies port.GatewayPolicyService, audit port.AuditService, gateway PolicyApplier) *GatewayPolicyHandler {
+	return &GatewayPolicyHandler{policies: policies, audit: audit, gateway: gateway}
+}
+
+// PolicyEndpoints are the list, create, update and delete handlers of one
+// policy collection. Update and delete take the item's key as the *key path
+// parameter.
+type PolicyEndpoints struct {
+	List, Create, Update, Delete gin.HandlerFunc
+}
+
+// policyCollection describes one keyed list inside config.Policies.
+type policyCollection[T any] struct {
+	// name is the collection's JSON key; targetType is its audit target type.
+	name, targetType string
+	// slashed keys are path prefixes matched against the full request path and
+	// keep the leading slash of the *key parameter.
+	slashed bool
+	items   func(*config.Policies) *[]T
+	key     func(*T) *string
+}
+
+// Routes serves the route table.
+func (h *GatewayPolicyHandler) Routes() PolicyEndpoints {
+	return endpoints(h, policyCollection[loadbalancer.Route]{
+		name: "routes", targetType: "route",
+		items: func(p *config.Policies) *[]loadbalancer.Route { return &p.Routes },
+		key:   func(r *loadbalancer.Route) *string { return &r.Prefix },
+	})
+}
+
+// Pools serves the upstream pools.
+func (h *GatewayPolicyHandler) Pools() PolicyEndpoints {
+	return endpoints(h, policyCollection[loadbalancer.Pool]{
+		name: "pools", targetType: "pool",
+		items: func(p *config.Policies) *[]loadbalancer.Pool { return &p.Pools },
+		key:   func(pool *loadbalancer.Pool) *string { return &pool.Name },
+	})
+}
+
+// RateLimits serves the per-route rate limit policies.
+func (h *GatewayPolicyHandler) RateLimits() PolicyEndpoints {
+	return endpoints(h, policyCollection[rate_limiter.RoutePolicy]{
+		name: "rate_limits", targetType: "rate_limit", slashed: true,
+		items: func(p *config.Policies) *[]rate_limiter.RoutePolicy { return &p.RateLimit.Routes },
+		key:   func(r *rate_limiter.RoutePolicy) *string { return &r.Prefix },
+	})
+}
+
+// CORSRules serves the per-route CORS overrides.
+func (h *GatewayPolicyHandler) CORSRules() PolicyEndpoints {
+	return endpoints(h, policyCollection[cors.RouteOptions]{
+		name: "cors_rules", targetType: "cors_rule", slashed: true,
+		items: func(p *config.Policies) *[]cors.RouteOptions { return &p.CORS.Routes },
+		key:   func(r *cors.RouteOptions) *string { return &r.Prefix },
+	})
+}
+
+func endpoints[T any](h *GatewayPolicyHandler, col policyCollection[T]) PolicyEndpoints {
+	return PolicyEndpoints{
+		List: func(c *gin.Context) {
+			policies, version := h.gateway.Policies()
+			items := *col.items(&policies)
+			if items == nil {
+				items = []T{}
+			}
+			c.JSON(http.StatusOK, gin.H{"version": version, col.name: items})
+		},
+		Create: func(c *gin.Context) {
+			var item T
+			if err := c.ShouldBindJSON(&item); err != nil {
+				logger.FromContext(c.Request.Context()).WithSource("CreatePolicy").Error("Binding JSON", logger.Any("Object", col.name), logger.Err(err))
+				errorResponse.FromError(c, err)
+				return
+			}
+			key := *col.key(&item)
+			h.change(c, http.StatusCreated, AuditActionPolicyCreate, col.targetType, key, func(p *config.Policies) (before, after interface{}, err error) {
+				items := col.items(p)
+				if indexOf(*items, col.key, key) >= 0 {
+					return nil, nil, domainerr.Conflict(domainerr.CodePolicyExists, fmt.Sprintf("%s %q already exists", col.targetType, key))
+				}
+				*items = append(*items, item)
+				return nil, item, nil
+			})
+		},
+		Update: func(c *gin.Context) {
+			var item T
+			if err := c.ShouldBindJSON(&item); err != nil {
+				logger.FromContext(c.Request.Context()).WithSource("UpdatePolicy").Error("Binding JSON", logger.Any("Object", col.name), logger.Err(err))
+				errorResponse.FromError(c, err)
+				return
+			}
+			key := col.keyParam(c)
+			*col.key(&item) = key
+			h.change(c, http.StatusOK, AuditActionPolicyUpdate, col.targetType, key, func(p *config.Policies) (before, after interface{}, err error) {
+				items := col.items(p)
+				i := indexOf(*items, col.key, key)
+				if i < 0 {
+					return nil, nil, col.notFound(key)
+				}
+				before, (*items)[i] = (*items)[i], item
+				return before, item, nil
+			})
+		},
+		Delete: func(c *gin.Context) {
+			key := col.keyParam(c)
+			h.change(c, http.StatusOK, AuditActionPolicyDelete, col.targetType, key, func(p *config.Policies) (before, after interface{}, err error) {
+				items := col.items(p)
+				i := indexOf(*items, col.key, key)
+				if i < 0 {
+					return nil, nil, col.notFound(key)
+				}
+				before = (*items)[i]
+				*items = append((*items)[:i], (*items)[i+1:]...)
+				return before, nil, nil
+			})
+		},
+	}
+}
+
+// keyParam returns the item key from the *key path parameter.
+func (col policyCollection[T]) keyParam(c *gin.Context) string {
+	key := c.Param("key")
+	if !col.slashed {
+		key = strings.TrimPrefix(key, "/")
+	}
+	return key
+}
+
+func (col policyCollection[T]) notFound(key string) error {
+	return domainerr.NotFound(domainerr.CodePolicyNotFound, fmt.Sprintf("%s %q not found", col.targetType, key))
+}
+
+func indexOf[T any](items []T, key func(*T) *string, want string) int {
+	for i := range items {
+		if *key(&items[i]) == want {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetPolicies returns every runtime-editable policy and its version.
+func (h *GatewayPolicyHandler) GetPolicies(c *gin.Context) {
+	policies, version := h.gateway.Policies()
+	c.JSON(http.StatusOK, gin.H{"version": version, "policies": policies})
+}
+
+// ReplacePolicies replaces every runtime-editable policy at once, including
+// the gateway-wide rate limit and CORS policy.
+func (h *GatewayPolicyHandler) ReplacePolicies(c *gin.Context) {
+	var next config.Policies
+	if err := c.ShouldBindJSON(&next); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ReplacePolicies").Error("Binding JSON", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+	h.change(c, http.StatusOK, AuditActionPolicyReplace, "policies", "", func(p *config.Policies) (before, after interface{}, err error) {
+		before, *p = *p, next
+		return before, next, nil
+	})
+}
+
+func (h *GatewayPolicyHandler) ListVersions(c *gin.Context) {
+	limit, offset := service.DefaultPolicyVersionLimit, 0
+	for key, target := range map[string]*int{"limit": &limit, "offset": &offset} {
+		if v := c.Query(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				errorResponse.NewProblem(c, http.StatusBadRequest, errorResponse.CodeInvalidQuery, key+" must be a non-negative integer")
+				return
+			}
+			*target = n
+		}
+	}
+	limit = min(max(limit, 1), service.MaxPolicyVersionLimit)
+
+	versions, err := h.policies.ListVersions(limit, offset)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ListVersions").Error("ListVersions Error", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+	if versions == nil {
+		versions = []*entity.GatewayPolicyVersion{}
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": versions, "limit": limit, "offset": offset})
+}
+
+// policyVersionResponse is a stored version together with its document.
+type policyVersionResponse struct {
+	*entity.GatewayPolicyVersion
+	Policies config.Policies `json:"policies"`
+}
+
+func (h *GatewayPolicyHandler) GetVersion(c *gin.Context) {
+	version, policies, err := h.loadVersion(c)
+	if err != nil {
+		errorResponse.FromError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policyVersionResponse{GatewayPolicyVersion: version, Policies: policies})
+}
+
+// RollbackVersion stores the document of an earlier version as a new version
+// and applies it.
+func (h *GatewayPolicyHandler) RollbackVersion(c *gin.Context) {
+	version, restored, err := h.loadVersion(c)
+	if err != nil {
+		errorResponse.FromError(c, err)
+		return
+	}
+	from := version.Version
+	h.commit(c, http.StatusOK, AuditActionPolicyRollback, "policy_version", strconv.FormatUint(uint64(from), 10), &from, func(p *config.Policies) (before, after interface{}, err error) {
+		before, *p = *p, restored
+		return before, restored, nil
+	})
+}
+
+// loadVersion reads the version named by the :version path parameter.
+func (h *GatewayPolicyHandler) loadVersion(c *gin.Context) (*entity.GatewayPolicyVersion, config.Policies, error) {
+	var policies config.Policies
+	number, err := strconv.ParseUint(c.Param("version"), 10, 32)
+	if err != nil || number == 0 {
+		return nil, policies, domainerr.Validation(domainerr.FieldError{
+			Field:   "version",
+			Code:    domainerr.CodeInvalidID,
+			Message: "version must be a positive integer",
+		})
+	}
+	version, err := h.policies.GetVersion(uint(number))
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("GatewayPolicy").Error("GetVersion Error", logger.Any("Object", number), logger.Err(err))
+		return nil, policies, err
+	}
+	if err := json.Unmarshal([]byte(version.Document), &policies); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("GatewayPolicy").Error("Decoding Version", logger.Any("Object", number), logger.Err(err))
+		return nil, policies, err
+	}
+	return version, policies, nil
+}
+
+// change applies edit to a copy of the policies in force and commits the
+// result.
+func (h *GatewayPolicyHandler) change(c *gin.Context, status int, action, targetType, targetID string, edit func(*config.Policies) (before, after interface{}, err error)) {
+	h.commit(c, status, action, targetType, targetID, nil, edit)
+}
+
+// commit validates the policies edit produces and, unless the request is a
+// dry run, stores them as a new version, applies them and audits the change.
+// The response carries the resulting policies either way.
+func (h *GatewayPolicyHandler) commit(c *gin.Context, status int, action, targetType, targetID string, rolledBackFrom *uint, edit func(*config.Policies) (before, after interface{}, err error)) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		errorResponse.NewProblem(c, http.StatusBadRequest, errorResponse.CodeInvalidQuery, "dry_run must be true or false")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	current, _ := h.gateway.Policies()
+	next := current.Clone()
+	before, after, err := edit(&next)
+	if err == nil {
+		if invalid := h.gateway.ValidatePolicies(next); invalid != nil {
+			err = domainerr.Validation(domainerr.Field("policies", domainerr.CodeInvalidPolicy, invalid))
+		}
+	}
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("GatewayPolicy").Error("Invalid Change", logger.Any("Object", action), logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{"dry_run": true, "policies": next})
+		return
+	}
+
+	event := newAuditEvent(c, action, targetType, targetID)
+	document, _ := json.Marshal(next)
+	version := &entity.GatewayPolicyVersion{
+		Document:       string(document),
+		Change:         strings.TrimSpace(action + " " + targetID),
+		CreatedBy:      event.ActorUsername,
+		RolledBackFrom: rolledBackFrom,
+	}
+	err = h.policies.Save(version)
+	if err == nil {
+		err = h.gateway.ApplyPolicies(next, version.Version)
+	}
+	recordAudit(c, h.audit, event, policyAuditSnapshot(before), policyAuditSnapshot(after), err)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("GatewayPolicy").Error("Commit Error", logger.Any("Object", action), logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).WithSource("GatewayPolicy").Warn("Gateway policies changed at runtime", logger.Any("version", version.Version), logger.Any("change", version.Change))
+	c.JSON(status, gin.H{"version": version.Version, "policies": next})
+}
+
+// policyAuditSnapshot flattens a policy item, or the whole policies document,
+// into the attribute map audit diffs compare.
+func policyAuditSnapshot(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	encoded, _ := json.Marshal(v)
+	var snapshot map[string]interface{}
+	json.Unmarshal(encoded, &snapshot)
+	return snapshot
+}
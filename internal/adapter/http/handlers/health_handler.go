@@ -1,11 +1,45 @@
 package handlers
 
 import (
-	"net/http"
+	"encoding/json"
+
+	"zeneye-gateway/pkg/health"
+	"zeneye-gateway/pkg/server"
 
 	"github.com/gin-gonic/gin"
 )
 
-func HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"status": "healthy"})
+// Livez reports that the process is up and serving. It checks no dependencies,
+// so an outage never gets pods restarted in a loop.
+func Livez(c *gin.Context) {
+	writeHealth(c, health.Report{Status: health.StatusPass})
+}
+
+// Readyz reports whether this instance should receive traffic: the critical
+// checks pass and shutdown has not started.
+func Readyz(checker *health.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if server.Draining() {
+			writeHealth(c, health.Report{Status: health.StatusFail, Output: "draining"})
+			return
+		}
+		writeHealth(c, checker.Ready(c.Request.Context()))
+	}
+}
+
+// HealthCheck returns the status, latency and last error of every component.
+func HealthCheck(checker *health.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := checker.Report(c.Request.Context())
+		if server.Draining() {
+			report.Status, report.Output = health.StatusFail, "draining"
+		}
+		writeHealth(c, report)
+	}
+}
+
+func writeHealth(c *gin.Context, report health.Report) {
+	body, _ := json.Marshal(report)
+	c.Header("Cache-Control", "no-store")
+	c.Data(report.HTTPStatus(), health.ContentType, body)
 }
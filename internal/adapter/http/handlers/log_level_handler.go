@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"zeneye-gateway/internal/domain/domainerr"
+	error "zeneye-gateway/pkg/error"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type logLevelRequest struct {
+	Level string `json:"level" binding:"required"`
+}
+
+// GetLogLevel returns the current minimum log level.
+func GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"level": logger.Level()})
+}
+
+// SetLogLevel changes the minimum log level without a restart.
+func SetLogLevel(c *gin.Context) {
+	var req logLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("SetLogLevel").Error("Binding JSON", logger.Err(err))
+		error.FromError(c, err)
+		return
+	}
+
+	previous := logger.Level()
+	if err := logger.SetLevel(req.Level); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("SetLogLevel").Error("Invalid Level", logger.Any("Object", req.Level), logger.Err(err))
+		error.FromError(c, domainerr.Validation(domainerr.Field("level", "invalid_level", err)))
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).WithSource("SetLogLevel").Warn("Log level changed at runtime", logger.String("from", previous), logger.String("to", logger.Level()))
+	c.JSON(http.StatusOK, gin.H{"level": logger.Level()})
+}
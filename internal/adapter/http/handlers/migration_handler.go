@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	errorResponse "zeneye-gateway/pkg/error"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type migrationStepsRequest struct {
+	Steps int `json:"steps" binding:"required"`
+}
+
+type migrationVersionRequest struct {
+	Version *uint `json:"version" binding:"required"`
+}
+
+// MigrationHandler serves the schema migrations embedded in the gateway. Every
+// change is audited with the schema version before and after it.
+type MigrationHandler struct {
+	open  func() (port.Migrator, error)
+	audit port.AuditService
+	// mu serializes changes; golang-migrate also locks the database against
+	// other gateway instances.
+	mu sync.Mutex
+}
+
+// NewMigrationHandler returns a MigrationHandler that reaches the migrations
+// through open.
+func NewMigrationHandler(open func() (port.Migrator, error), audit port.AuditService) *MigrationHandler {
+	return &MigrationHandler{open: open, audit: audit}
+}
+
+// GetMigrations returns the schema version and every migration.
+func (h *MigrationHandler) GetMigrations(c *gin.Context) {
+	m, err := h.open()
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("GetMigrations").Error("Open Migrations", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+	defer m.Close()
+	h.respond(c, m)
+}
+
+// MigrateUp applies every pending migration.
+func (h *MigrationHandler) MigrateUp(c *gin.Context) {
+	h.change(c, AuditActionSchemaMigrate, "up", func(m port.Migrator) error {
+		return m.Up()
+	})
+}
+
+// MigrateDown reverts the latest steps migrations.
+func (h *MigrationHandler) MigrateDown(c *gin.Context) {
+	var req migrationStepsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("MigrateDown").Error("Binding JSON", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+	h.change(c, AuditActionSchemaMigrate, fmt.Sprintf("down %d", req.Steps), func(m port.Migrator) error {
+		return m.Down(req.Steps)
+	})
+}
+
+// MigrateGoto applies or reverts migrations until version is the latest
+// applied.
+func (h *MigrationHandler) MigrateGoto(c *gin.Context) {
+	var req migrationVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("MigrateGoto").Error("Binding JSON", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+	h.change(c, AuditActionSchemaMigrate, fmt.Sprintf("goto %d", *req.Version), func(m port.Migrator) error {
+		return m.Goto(*req.Version)
+	})
+}
+
+// ForceMigration records a version as applied and clean after a failed
+// migration was repaired by hand.
+func (h *MigrationHandler) ForceMigration(c *gin.Context) {
+	var req migrationVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ForceMigration").Error("Binding JSON", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+	h.change(c, AuditActionSchemaForce, fmt.Sprint(*req.Version), func(m port.Migrator) error {
+		return m.Force(*req.Version)
+	})
+}
+
+// change runs move against the migrations, audits it and answers with the
+// resulting schema state.
+func (h *MigrationHandler) change(c *gin.Context, action, targetID string, move func(port.Migrator) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	m, err := h.open()
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("Migrations").Error("Open Migrations", logger.Any("Object", action), logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+	defer m.Close()
+
+	event := newAuditEvent(c, action, "schema", targetID)
+	before := schemaAuditSnapshot(m)
+	err = move(m)
+	recordAudit(c, h.audit, event, before, schemaAuditSnapshot(m), err)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("Migrations").Error("Migration Error", logger.Any("Object", targetID), logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).WithSource("Migrations").Warn("Database schema changed at runtime", logger.String("Activity", "Schema Changed"), logger.Any("Object", targetID))
+	h.respond(c, m)
+}
+
+// respond answers with the schema version and every migration.
+func (h *MigrationHandler) respond(c *gin.Context, m port.Migrator) {
+	version, dirty, err := m.Version()
+	var migrations []entity.MigrationStatus
+	if err == nil {
+		migrations, err = m.Status()
+	}
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("Migrations").Error("Status Error", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"version": version, "dirty": dirty, "migrations": migrations})
+}
+
+// schemaAuditSnapshot is the schema version in the attribute map audit diffs
+// compare, or nil if it can't be read.
+func schemaAuditSnapshot(m port.Migrator) map[string]interface{} {
+	version, dirty, err := m.Version()
+	if err != nil {
+		return nil
+	}
+	return map[string]interface{}{"version": version, "dirty": dirty}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"zeneye-gateway/internal/application/user"
+	"zeneye-gateway/internal/domain/domainerr"
+	"zeneye-gateway/internal/domain/entity"
+	errorResponse "zeneye-gateway/pkg/error"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupTokenHeader carries the setup token issued at startup to
+// CreateSuperadmin.
+const SetupTokenHeader = "X-Setup-Token"
+
+func (h *UserHandler) CheckSuperadmin(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("CheckSuperadmin").Info("Starting CheckSuperadmin handler", logger.String("Activity", "Handler Start"))
+
+	superadminExists, err := h.users.IsSuperadminPresent()
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("CheckSuperadmin").Error("CheckSuperadmin Error", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).WithSource("CheckSuperadmin").Info("Superadmin existence checked successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", superadminExists))
+	if superadminExists {
+		c.JSON(http.StatusOK, gin.H{"superadmin_exists": true})
+	} else {
+		c.JSON(http.StatusOK, gin.H{"superadmin_exists": false})
+	}
+}
+
+// CreateSuperadmin creates the first superadmin. It needs the setup token the
+// gateway issued at startup, which works once.
+func (h *UserHandler) CreateSuperadmin(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("CreateSuperadmin").Info("Starting CreateSuperadmin handler", logger.String("Activity", "Handler Start"))
+
+	var req user.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("CreateSuperadmin").Error("Binding JSON", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	// Ensure the role is superadmin
+	if req.Role != "superadmin" {
+		err := domainerr.Validation(domainerr.FieldError{Field: "role", Code: domainerr.CodeInvalidRole, Message: "role must be superadmin"})
+		logger.FromContext(c.Request.Context()).WithSource("CreateSuperadmin").Error("Invalid Role", logger.Any("Object", req.Role), logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	// Check if superadmin already exists
+	superadminExists, err := h.users.IsSuperadminPresent()
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("CreateSuperadmin").Error("Check Superadmin Error", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	if superadminExists {
+		errorResponse.FromError(c, domainerr.ErrSuperadminExists)
+		return
+	}
+
+	event := newAuditEvent(c, AuditActionSuperadminCreate, "user", "")
+	err = h.setup.Redeem(c.GetHeader(SetupTokenHeader), func() error {
+		return h.controller.CreateUser(entity.SystemActorID, req)
+	})
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("CreateSuperadmin").Error("Create Superadmin Error", logger.Any("Object", req), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	// Bootstrap has no authenticated caller, so the new account is its own actor.
+	var after map[string]interface{}
+	if created, err := h.users.GetUserByUsername(req.Username); err == nil {
+		actorID := created.ID
+		event.ActorID, event.ActorUsername, event.ActorRole = &actorID, created.Username, created.Role
+		event.TargetID = strconv.FormatUint(uint64(created.ID), 10)
+		after = created.AuditSnapshot()
+	}
+	recordAudit(c, h.audit, event, nil, after, nil)
+
+	logger.FromContext(c.Request.Context()).WithSource("CreateSuperadmin").Info("Superadmin created successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", req))
+	c.JSON(http.StatusCreated, gin.H{"message": "Superadmin created successfully"})
+}
+
+// RequestSuperadminTransfer offers the caller's superadmin role to another
+// user, who has to accept it before anything changes.
+func (h *UserHandler) RequestSuperadminTransfer(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("RequestSuperadminTransfer").Info("Starting RequestSuperadminTransfer handler", logger.String("Activity", "Handler Start"))
+
+	var req user.SuperadminTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("RequestSuperadminTransfer").Error("Binding JSON", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	event := newAuditEvent(c, AuditActionTransferRequest, "user", strconv.FormatUint(uint64(req.UserID), 10))
+	transfer, err := h.controller.RequestSuperadminTransfer(callerClaims(c).UserID, req)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("RequestSuperadminTransfer").Error("RequestSuperadminTransfer Error", logger.Any("Object", req), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		errorResponse.FromError(c, err)
+		return
+	}
+	recordAudit(c, h.audit, event, nil, nil, nil)
+
+	logger.FromContext(c.Request.Context()).WithSource("RequestSuperadminTransfer").Info("Superadmin transfer requested successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", transfer))
+	c.JSON(http.StatusAccepted, transfer)
+}
+
+// GetSuperadminTransfer shows the pending transfer to its two parties.
+func (h *UserHandler) GetSuperadminTransfer(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("GetSuperadminTransfer").Info("Starting GetSuperadminTransfer handler", logger.String("Activity", "Handler Start"))
+
+	transfer, err := h.controller.GetSuperadminTransfer(callerClaims(c).UserID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("GetSuperadminTransfer").Error("GetSuperadminTransfer Error", logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).WithSource("GetSuperadminTransfer").Info("Superadmin transfer retrieved successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", transfer))
+	c.JSON(http.StatusOK, transfer)
+}
+
+// AcceptSuperadminTransfer makes the caller the superadmin. Both parties'
+// tokens are revoked; the caller gets new ones in the response headers and the
+// former superadmin has to sign in again.
+func (h *UserHandler) AcceptSuperadminTransfer(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("AcceptSuperadminTransfer").Info("Starting AcceptSuperadminTransfer handler", logger.String("Activity", "Handler Start"))
+
+	claims := callerClaims(c)
+	target := strconv.FormatUint(uint64(claims.UserID), 10)
+	event := newAuditEvent(c, AuditActionTransferAccept, "user", target)
+	before := userAuditSnapshot(h.users, target)
+	transfer, err := h.controller.AcceptSuperadminTransfer(claims.UserID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("AcceptSuperadminTransfer").Error("AcceptSuperadminTransfer Error", logger.Any("Object", claims.UserID), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		errorResponse.FromError(c, err)
+		return
+	}
+	recordAudit(c, h.audit, event, before, userAuditSnapshot(h.users, target), nil)
+
+	// The former superadmin's demotion is recorded as a role change of its own
+	demotion := newAuditEvent(c, AuditActionUserRoleChange, "user", strconv.FormatUint(uint64(transfer.FromUserID), 10))
+	recordAudit(c, h.audit, demotion, map[string]interface{}{"role": entity.RoleSuperadmin},
+		map[string]interface{}{"role": entity.RoleAdmin}, nil)
+
+	current, err := h.users.GetUser(claims.UserID)
+	if err == nil {
+		err = issueTokens(c, h.users, current)
+	}
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("AcceptSuperadminTransfer").Error("Could not issue tokens", logger.Any("Object", claims.UserID), logger.Err(err))
+		errorResponse.FromError(c, err)
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).WithSource("AcceptSuperadminTransfer").Info("Superadmin transfer accepted successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", transfer))
+	c.JSON(http.StatusOK, gin.H{"message": "Superadmin role transferred successfully"})
+}
+
+// CancelSuperadminTransfer lets the superadmin withdraw the offer or the
+// recipient decline it.
+func (h *UserHandler) CancelSuperadminTransfer(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("CancelSuperadminTransfer").Info("Starting CancelSuperadminTransfer handler", logger.String("Activity", "Handler Start"))
+
+	event := newAuditEvent(c, AuditActionTransferCancel, "user", "")
+	transfer, err := h.controller.CancelSuperadminTransfer(callerClaims(c).UserID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("CancelSuperadminTransfer").Error("CancelSuperadminTransfer Error", logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		errorResponse.FromError(c, err)
+		return
+	}
+	event.TargetID = strconv.FormatUint(uint64(transfer.ToUserID), 10)
+	recordAudit(c, h.audit, event, nil, nil, nil)
+
+	logger.FromContext(c.Request.Context()).WithSource("CancelSuperadminTransfer").Info("Superadmin transfer cancelled successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", transfer))
+	c.JSON(http.StatusOK, gin.H{"message": "Superadmin transfer cancelled successfully"})
+}
@@ -1,225 +1,247 @@
 package handlers
 
 import (
-	"errors"
 	"net/http"
-	"zeneye-gateway/internal/adapter/repository/postgres"
-	"zeneye-gateway/internal/adapter/service"
+	"strconv"
+	"strings"
+	"zeneye-gateway/internal/adapter/bootstrap"
 	user "zeneye-gateway/internal/application/user"
-	"zeneye-gateway/internal/dto"
+	"zeneye-gateway/internal/domain/port"
 	error "zeneye-gateway/pkg/error"
 	"zeneye-gateway/pkg/logger"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
-func CreateUser(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		logger.LogInfo("CreateUser", "Handler Start", "Starting CreateUser handler", "")
-
-		var req user.CreateUserRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			logger.LogError("CreateUser", "Binding JSON", "", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
-			return
-		}
-
-		repo := postgres.NewUserRepository(db)
-		userService := service.NewUserService(repo)
-		userController := user.NewUserController(userService)
-
-		if err := userController.CreateUser(req); err != nil {
-			logger.LogError("CreateUser", "CreateUser Error", req, err)
-			if err.Error() == "email already associated with another account" {
-				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-			} else {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			}
-			return
-		}
+// UserHandler serves user management and superadmin bootstrap.
+type UserHandler struct {
+	users      port.UserService
+	controller *user.UserController
+	accounts   *user.AccountController
+	audit      port.AuditService
+	// setup authorizes the superadmin bootstrap; nil refuses it.
+	setup *bootstrap.SetupToken
+}
 
-		logger.LogInfo("CreateUser", "Handler Success", "User created successfully", req)
-		c.JSON(http.StatusCreated, gin.H{"message": "User created successfully"})
+// NewUserHandler returns a UserHandler over the given services. setup is the
+// token issued at startup, or nil when none was.
+func NewUserHandler(users port.UserService, accounts port.AccountService, audit port.AuditService, setup *bootstrap.SetupToken) *UserHandler {
+	return &UserHandler{
+		users:      users,
+		controller: user.NewUserController(users),
+		accounts:   user.NewAccountController(users, accounts),
+		audit:      audit,
+		setup:      setup,
 	}
 }
 
-func EditUser(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		logger.LogInfo("EditUser", "Handler Start", "Starting EditUser handler", "")
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("CreateUser").Info("Starting CreateUser handler", logger.String("Activity", "Handler Start"))
 
-		var req user.EditUserRequest
-		id := c.Param("id")
-		if err := c.ShouldBindJSON(&req); err != nil {
-			logger.LogError("EditUser", "Binding JSON", "", err)
-			error.NewErrorResponse(c, http.StatusBadRequest, "Invalid request format", err.Error())
-			return
-		}
-
-		repo := postgres.NewUserRepository(db)
-		userService := service.NewUserService(repo)
-		userController := user.NewUserController(userService)
-
-		if err := userController.EditUser(id, req); err != nil {
-			logger.LogError("EditUser", "EditUser Error", req, err)
-			if err.Error() == "user not found" {
-				error.NewErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
-			} else if err.Error() == "email already associated with another account" || err.Error() == "username already taken" {
-				error.NewErrorResponse(c, http.StatusConflict, err.Error(), "")
-			} else {
-				error.NewErrorResponse(c, http.StatusInternalServerError, "Internal server error", err.Error())
-			}
-			return
-		}
+	var req user.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("CreateUser").Error("Binding JSON", logger.Err(err))
+		error.FromError(c, err)
+		return
+	}
 
-		logger.LogInfo("EditUser", "Handler Success", "User updated successfully", req)
-		c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
+	event := newAuditEvent(c, AuditActionUserCreate, "user", "")
+	if err := h.controller.CreateUser(callerClaims(c).UserID, req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("CreateUser").Error("CreateUser Error", logger.Any("Object", req), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		error.FromError(c, err)
+		return
 	}
-}
 
-func DeleteUser(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		logger.LogInfo("DeleteUser", "Handler Start", "Starting DeleteUser handler", "")
-
-		id := c.Param("id")
-		repo := postgres.NewUserRepository(db)
-		userService := service.NewUserService(repo)
-		userController := user.NewUserController(userService)
-
-		if err := userController.DeleteUser(id); err != nil {
-			logger.LogError("DeleteUser", "DeleteUser Error", id, err)
-			if err.Error() == "user not found" {
-				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			} else {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			}
-			return
-		}
-		logger.LogInfo("DeleteUser", "Handler Success", "User deleted successfully", id)
-		c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+	var after map[string]interface{}
+	if created, err := h.users.GetUserByUsername(req.Username); err == nil {
+		event.TargetID = strconv.FormatUint(uint64(created.ID), 10)
+		after = created.AuditSnapshot()
 	}
+	recordAudit(c, h.audit, event, nil, after, nil)
+
+	logger.FromContext(c.Request.Context()).WithSource("CreateUser").Info("User created successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", req))
+	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully"})
 }
 
-func GetUser(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		logger.LogInfo("GetUser", "Handler Start", "Starting GetUser handler", "")
+func (h *UserHandler) EditUser(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("EditUser").Info("Starting EditUser handler", logger.String("Activity", "Handler Start"))
 
-		id := c.Param("id")
-		repo := postgres.NewUserRepository(db)
-		userService := service.NewUserService(repo)
-		userController := user.NewUserController(userService)
+	var req user.EditUserRequest
+	id := c.Param("id")
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("EditUser").Error("Binding JSON", logger.Err(err))
+		error.FromError(c, err)
+		return
+	}
 
-		user, err := userController.GetUser(id)
-		if err != nil {
-			logger.LogError("GetUser", "GetUser Error", id, err)
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	event := newAuditEvent(c, AuditActionUserEdit, "user", id)
+	before := userAuditSnapshot(h.users, id)
+	if err := h.controller.EditUser(callerClaims(c).UserID, id, req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("EditUser").Error("EditUser Error", logger.Any("Object", req), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		error.FromError(c, err)
+		return
+	}
+	recordAudit(c, h.audit, event, before, userAuditSnapshot(h.users, id), nil)
+
+	// A new email is only applied once the user confirms it from the new
+	// address, or an edit could redirect the account's password resets
+	if current, err := h.controller.GetUser(id); err == nil && !strings.EqualFold(current.Email, req.Email) {
+		emailEvent := newAuditEvent(c, AuditActionEmailChangeRequest, "user", id)
+		if err := h.accounts.StartEmailChange(current.ID, req.Email); err != nil {
+			logger.FromContext(c.Request.Context()).WithSource("EditUser").Error("StartEmailChange Error", logger.Any("Object", req), logger.Err(err))
+			recordAudit(c, h.audit, emailEvent, nil, nil, err)
+			error.FromError(c, err)
 			return
 		}
+		recordAudit(c, h.audit, emailEvent, nil, map[string]interface{}{"email": req.Email}, nil)
 
-		logger.LogInfo("GetUser", "Handler Success", "User retrieved successfully", user)
-		c.JSON(http.StatusOK, user)
+		logger.FromContext(c.Request.Context()).WithSource("EditUser").Info("User updated, email change pending", logger.String("Activity", "Handler Success"), logger.Any("Object", req))
+		c.JSON(http.StatusAccepted, gin.H{"message": "User updated; the new email applies once the user confirms it"})
+		return
 	}
+
+	logger.FromContext(c.Request.Context()).WithSource("EditUser").Info("User updated successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", req))
+	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
 }
 
-func ListUsers(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		logger.LogInfo("ListUsers", "Handler Start", "Starting ListUsers handler", "")
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("DeleteUser").Info("Starting DeleteUser handler", logger.String("Activity", "Handler Start"))
+
+	id := c.Param("id")
+	event := newAuditEvent(c, AuditActionUserDelete, "user", id)
+	before := userAuditSnapshot(h.users, id)
+	if err := h.controller.DeleteUser(callerClaims(c).UserID, id); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("DeleteUser").Error("DeleteUser Error", logger.Any("Object", id), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		error.FromError(c, err)
+		return
+	}
+	recordAudit(c, h.audit, event, before, nil, nil)
 
-		repo := postgres.NewUserRepository(db)
-		userService := service.NewUserService(repo)
-		userController := user.NewUserController(userService)
+	logger.FromContext(c.Request.Context()).WithSource("DeleteUser").Info("User deleted successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", id))
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
 
-		users, err := userController.ListUsers()
-		if err != nil {
-			logger.LogError("ListUsers", "ListUsers Error", "", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch users"})
-			return
-		}
+// ChangeUserRole sets the role of the user in the path. The caller may only
+// grant roles below their own, to users below their own role.
+func (h *UserHandler) ChangeUserRole(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("ChangeUserRole").Info("Starting ChangeUserRole handler", logger.String("Activity", "Handler Start"))
 
-		var userList []dto.UserListResponse
-		for _, user := range users {
-			userList = append(userList, dto.UserListResponse{
-				ID:        user.ID,
-				Username:  user.Username,
-				Email:     user.Email,
-				Role:      user.Role,
-				UserUUID:  user.UserUUID,
-				CreatedAt: user.CreatedAt,
-				UpdatedAt: user.UpdatedAt,
-			})
-		}
+	var req user.ChangeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ChangeUserRole").Error("Binding JSON", logger.Err(err))
+		error.FromError(c, err)
+		return
+	}
 
-		logger.LogInfo("ListUsers", "Handler Success", "Users retrieved successfully", userList)
-		c.JSON(http.StatusOK, userList)
+	id := c.Param("id")
+	event := newAuditEvent(c, AuditActionUserRoleChange, "user", id)
+	before := userAuditSnapshot(h.users, id)
+	updated, err := h.controller.ChangeUserRole(callerClaims(c).UserID, id, req)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ChangeUserRole").Error("ChangeUserRole Error", logger.Any("Object", id), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		error.FromError(c, err)
+		return
 	}
-}
+	recordAudit(c, h.audit, event, before, userAuditSnapshot(h.users, id), nil)
 
-func CheckSuperadmin(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		logger.LogInfo("CheckSuperadmin", "Handler Start", "Starting CheckSuperadmin handler", "")
+	logger.FromContext(c.Request.Context()).WithSource("ChangeUserRole").Info("User role changed successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", updated))
+	c.JSON(http.StatusOK, updated)
+}
 
-		repo := postgres.NewUserRepository(db)
-		userService := service.NewUserService(repo)
+func (h *UserHandler) DisableUser(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("DisableUser").Info("Starting DisableUser handler", logger.String("Activity", "Handler Start"))
+
+	id := c.Param("id")
+	event := newAuditEvent(c, AuditActionUserDisable, "user", id)
+	before := userAuditSnapshot(h.users, id)
+	if err := h.controller.DisableUser(callerClaims(c).UserID, id); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("DisableUser").Error("DisableUser Error", logger.Any("Object", id), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		error.FromError(c, err)
+		return
+	}
+	recordAudit(c, h.audit, event, before, userAuditSnapshot(h.users, id), nil)
 
-		superadminExists, err := userService.IsSuperadminPresent()
-		if err != nil {
-			logger.LogError("CheckSuperadmin", "CheckSuperadmin Error", "", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking for superadmin"})
-			return
-		}
+	logger.FromContext(c.Request.Context()).WithSource("DisableUser").Info("User disabled successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", id))
+	c.JSON(http.StatusOK, gin.H{"message": "User disabled successfully"})
+}
 
-		logger.LogInfo("CheckSuperadmin", "Handler Success", "Superadmin existence checked successfully", superadminExists)
-		if superadminExists {
-			c.JSON(http.StatusOK, gin.H{"superadmin_exists": true})
-		} else {
-			c.JSON(http.StatusOK, gin.H{"superadmin_exists": false})
-		}
+func (h *UserHandler) EnableUser(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("EnableUser").Info("Starting EnableUser handler", logger.String("Activity", "Handler Start"))
+
+	id := c.Param("id")
+	event := newAuditEvent(c, AuditActionUserEnable, "user", id)
+	before := userAuditSnapshot(h.users, id)
+	if err := h.controller.EnableUser(callerClaims(c).UserID, id); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("EnableUser").Error("EnableUser Error", logger.Any("Object", id), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		error.FromError(c, err)
+		return
 	}
+	recordAudit(c, h.audit, event, before, userAuditSnapshot(h.users, id), nil)
+
+	logger.FromContext(c.Request.Context()).WithSource("EnableUser").Info("User enabled successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", id))
+	c.JSON(http.StatusOK, gin.H{"message": "User enabled successfully"})
 }
 
-func CreateSuperadmin(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		logger.LogInfo("CreateSuperadmin", "Handler Start", "Starting CreateSuperadmin handler", "")
+// RestoreUser undoes a delete. The user's tokens were revoked when it was
+// deleted, so it has to sign in again.
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("RestoreUser").Info("Starting RestoreUser handler", logger.String("Activity", "Handler Start"))
+
+	id := c.Param("id")
+	event := newAuditEvent(c, AuditActionUserRestore, "user", id)
+	before := userAuditSnapshot(h.users, id)
+	if err := h.controller.RestoreUser(callerClaims(c).UserID, id); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("RestoreUser").Error("RestoreUser Error", logger.Any("Object", id), logger.Err(err))
+		recordAudit(c, h.audit, event, nil, nil, err)
+		error.FromError(c, err)
+		return
+	}
+	recordAudit(c, h.audit, event, before, userAuditSnapshot(h.users, id), nil)
 
-		var req user.CreateUserRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			logger.LogError("CreateSuperadmin", "Binding JSON", nil, err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
-			return
-		}
+	logger.FromContext(c.Request.Context()).WithSource("RestoreUser").Info("User restored successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", id))
+	c.JSON(http.StatusOK, gin.H{"message": "User restored successfully"})
+}
 
-		// Ensure the role is superadmin
-		if req.Role != "superadmin" {
-			err := errors.New("role must be superadmin")
-			logger.LogError("CreateSuperadmin", "Invalid Role", req.Role, err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be superadmin"})
-			return
-		}
+func (h *UserHandler) GetUser(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("GetUser").Info("Starting GetUser handler", logger.String("Activity", "Handler Start"))
 
-		repo := postgres.NewUserRepository(db)
-		userService := service.NewUserService(repo)
-		userController := user.NewUserController(userService)
+	id := c.Param("id")
+	user, err := h.controller.GetUser(id)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("GetUser").Error("GetUser Error", logger.Any("Object", id), logger.Err(err))
+		error.FromError(c, err)
+		return
+	}
 
-		// Check if superadmin already exists
-		superadminExists, err := userService.IsSuperadminPresent()
-		if err != nil {
-			logger.LogError("CreateSuperadmin", "Check Superadmin Error", "", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking for superadmin"})
-			return
-		}
+	logger.FromContext(c.Request.Context()).WithSource("GetUser").Info("User retrieved successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", user))
+	c.JSON(http.StatusOK, user)
+}
 
-		if superadminExists {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Superadmin already exists"})
-			return
-		}
+// ListUsers returns one page of users. See user.ListUsersRequest for the
+// filters, sort order and paging it accepts.
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	logger.FromContext(c.Request.Context()).WithSource("ListUsers").Info("Starting ListUsers handler", logger.String("Activity", "Handler Start"))
 
-		if err := userController.CreateUser(req); err != nil {
-			logger.LogError("CreateSuperadmin", "Create Superadmin Error", req, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating superadmin"})
-			return
-		}
+	var req user.ListUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ListUsers").Error("Binding Query", logger.Any("Object", c.Request.URL.RawQuery), logger.Err(err))
+		error.FromError(c, err)
+		return
+	}
 
-		logger.LogInfo("CreateSuperadmin", "Handler Success", "Superadmin created successfully", req)
-		c.JSON(http.StatusCreated, gin.H{"message": "Superadmin created successfully"})
+	page, err := h.controller.ListUsers(req)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).WithSource("ListUsers").Error("ListUsers Error", logger.Any("Object", req), logger.Err(err))
+		error.FromError(c, err)
+		return
 	}
+
+	logger.FromContext(c.Request.Context()).WithSource("ListUsers").Info("Users retrieved successfully", logger.String("Activity", "Handler Success"), logger.Any("Object", len(page.Users)))
+	c.JSON(http.StatusOK, page)
 }
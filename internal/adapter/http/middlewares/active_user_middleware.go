@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"zeneye-gateway/internal/adapter/identity"
+	"zeneye-gateway/internal/domain/domainerr"
+	"zeneye-gateway/internal/domain/entity"
+	errorResponse "zeneye-gateway/pkg/error"
+	"zeneye-gateway/pkg/jwt"
+	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireActiveUser rejects callers whose account was disabled, locked or
+// deleted, or whose tokens were revoked by a role change, after their token
+// was issued. It resolves the caller like the
+// routing middleware does, so it shares its cache and, in claims mode, trusts
+// the token. It must run after AuthMiddleware.
+func RequireActiveUser(identities IdentityResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get(ClaimsKey)
+		claims, _ := value.(*jwt.Claims)
+		if claims == nil {
+			errorResponse.NewProblem(c, http.StatusUnauthorized, errorResponse.CodeUnauthenticated, "missing bearer token")
+			return
+		}
+
+		user, err := identities.Resolve(c.Request.Context(), claims)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).WithSource("RequireActiveUser").Error("Error resolving user identity", logger.Err(err))
+			metrics.AuthFailure("unknown_user")
+			errorResponse.NewProblem(c, http.StatusUnauthorized, errorResponse.CodeUnauthenticated, "unknown user")
+			return
+		}
+		if !acceptIdentity(c, "RequireActiveUser", claims, user) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// acceptIdentity aborts the request unless the token is still current for
+// user and user is active.
+func acceptIdentity(c *gin.Context, source string, claims *jwt.Claims, user identity.Identity) bool {
+	if claims.TokenVersion != user.TokenVersion {
+		logger.FromContext(c.Request.Context()).WithSource(source).Warn("Token was revoked", logger.Uint("UserID", claims.UserID))
+		metrics.AuthFailure("revoked_token")
+		errorResponse.NewProblem(c, http.StatusUnauthorized, domainerr.CodeTokenRevoked, "token was revoked, sign in again")
+		return false
+	}
+	if !user.Active() {
+		abortInactive(c, source, user.Status)
+		return false
+	}
+	return true
+}
+
+// abortInactive answers 403 with the code login uses for the same status.
+func abortInactive(c *gin.Context, source, status string) {
+	logger.FromContext(c.Request.Context()).WithSource(source).Warn("User is " + status)
+	metrics.AuthFailure("inactive_user")
+	code, detail := domainerr.CodeAccountDisabled, "account is disabled"
+	if status == entity.UserStatusLocked {
+		code, detail = domainerr.CodeAccountLocked, "account is locked"
+	}
+	errorResponse.NewProblem(c, http.StatusForbidden, code, detail)
+}
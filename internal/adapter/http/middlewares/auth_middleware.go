@@ -4,43 +4,58 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	errorResponse "zeneye-gateway/pkg/error"
 	"zeneye-gateway/pkg/jwt"
 	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/metrics"
+	"zeneye-gateway/pkg/tracing"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ClaimsKey is the gin context key holding the validated *jwt.Claims.
+const ClaimsKey = "claims"
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		logger.LogInfo("AuthMiddleware", "Handler Start", "Starting AuthMiddleware", "")
+		_, span := tracing.Start(c.Request.Context(), "auth.validate_token")
 
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			headerErr := errors.New("AUTH HEADER NOT FOUND")
-			logger.LogWarning("AuthMiddleware", "Missing Authorization Header", "", headerErr)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthenticated Access: invalid user"})
-			c.Abort()
+			logger.FromContext(c.Request.Context()).WithSource("AuthMiddleware").Warn("Missing Authorization Header", logger.Err(headerErr))
+			metrics.AuthFailure("missing_header")
+			tracing.RecordError(span, headerErr)
+			span.End()
+			errorResponse.NewProblem(c, http.StatusUnauthorized, errorResponse.CodeUnauthenticated, "missing bearer token")
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
 			headerErr := errors.New("INVALID AUTH HEADER")
-			logger.LogWarning("AuthMiddleware", "Invalid Authorization Header", authHeader, headerErr)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthenticated Access: invalid user"})
-			c.Abort()
+			logger.FromContext(c.Request.Context()).WithSource("AuthMiddleware").Warn("Invalid Authorization Header", logger.Err(headerErr))
+			metrics.AuthFailure("malformed_header")
+			tracing.RecordError(span, headerErr)
+			span.End()
+			errorResponse.NewProblem(c, http.StatusUnauthorized, errorResponse.CodeUnauthenticated, "malformed authorization header")
 			return
 		}
 
-		_, err := jwt.ValidateToken(tokenString)
+		claims, err := jwt.ValidateToken(tokenString)
 		if err != nil {
-			logger.LogError("AuthMiddleware", "Token Validation Error", tokenString, err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthenticated Access: invalid user"})
-			c.Abort()
+			logger.FromContext(c.Request.Context()).WithSource("AuthMiddleware").Warn("Token validation failed", logger.Err(err))
+			metrics.AuthFailure("invalid_token")
+			tracing.RecordError(span, err)
+			span.End()
+			errorResponse.NewProblem(c, http.StatusUnauthorized, errorResponse.CodeUnauthenticated, "invalid or expired token")
 			return
 		}
 
-		logger.LogInfo("AuthMiddleware", "Handler Success", "Authentication successful", "")
+		span.End()
+		c.Set(ClaimsKey, claims)
+		logger.FromContext(c.Request.Context()).WithSource("AuthMiddleware").Sampled().
+			Debug("Authentication successful", logger.Uint("UserID", claims.UserID))
 		c.Next()
 	}
 }
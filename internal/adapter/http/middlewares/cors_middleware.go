@@ -0,0 +1,80 @@
+package middlewares
+
+import (
+	"net/http"
+
+	errorResponse "zeneye-gateway/pkg/error"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSMiddleware applies the CORS policy of the request's snapshot to
+// cross-origin requests. It runs before authentication so preflights are
+// answered without a token, and after SnapshotMiddleware.
+func CORSMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rule := CurrentSnapshot(c).CORS.For(c.Request.URL.Path)
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		// Caches must key on Origin even for responses without CORS headers.
+		if rule.VaryOrigin() {
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		allowOrigin, ok := rule.AllowOrigin(origin)
+		if !ok {
+			if preflight {
+				logger.FromContext(c.Request.Context()).WithSource("CORSMiddleware").Warn("Origin not allowed", logger.String("origin", origin))
+				errorResponse.NewProblem(c, http.StatusForbidden, errorResponse.CodeOriginNotAllowed, "origin "+origin+" is not allowed")
+				return
+			}
+			// Let the request through; the browser withholds the response.
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("Access-Control-Allow-Origin", allowOrigin)
+		if rule.Credentials() {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if expose := rule.ExposeHeaders(); expose != "" {
+				header.Set("Access-Control-Expose-Headers", expose)
+			}
+			c.Next()
+			return
+		}
+
+		method := c.GetHeader("Access-Control-Request-Method")
+		allowHeaders, headersOK := rule.AllowHeaders(c.GetHeader("Access-Control-Request-Headers"))
+		if !rule.AllowMethod(method) || !headersOK {
+			logger.FromContext(c.Request.Context()).WithSource("CORSMiddleware").Warn("Preflight rejected",
+				logger.String("origin", origin),
+				logger.String("method", method),
+				logger.String("headers", c.GetHeader("Access-Control-Request-Headers")))
+			errorResponse.NewProblem(c, http.StatusForbidden, errorResponse.CodeOriginNotAllowed, "method or headers are not allowed for origin "+origin)
+			return
+		}
+
+		header.Set("Access-Control-Allow-Methods", rule.AllowMethods())
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		}
+		if maxAge := rule.MaxAge(); maxAge != "" {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
@@ -1,35 +1,86 @@
 package middlewares
 
 import (
+	"io"
 	"time"
-	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/accesslog"
+	"zeneye-gateway/pkg/jwt"
+	"zeneye-gateway/pkg/metrics"
+	"zeneye-gateway/pkg/requestid"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Context keys the proxy sets so the access log can report the upstream call.
+const (
+	UpstreamServiceKey = "upstream_service"
+	UpstreamLatencyKey = "upstream_latency"
+)
+
+// countingBody counts the request bytes actually read by handlers and the proxy,
+// which unlike Content-Length is also right for chunked bodies.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// LoggingMiddleware records request metrics and writes one access log line per request.
 func LoggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
-		logger.LogInfo("LoggingMiddleware", "Request Start", "Request started",
-			map[string]interface{}{
-				"method":    c.Request.Method,
-				"path":      c.Request.URL.Path,
-				"client_ip": c.ClientIP(),
-			},
-		)
+		done := metrics.RequestStarted()
+		defer done()
+
+		body := &countingBody{ReadCloser: c.Request.Body}
+		if c.Request.Body != nil {
+			c.Request.Body = body
+		}
 
 		c.Next()
 
 		duration := time.Since(start)
+		metrics.ObserveRequest(routeLabel(c), c.Request.Method, c.Writer.Status(), duration)
 
-		logger.LogInfo("LoggingMiddleware", "Request End", "Request completed",
-			map[string]interface{}{
-				"method":    c.Request.Method,
-				"path":      c.Request.URL.Path,
-				"status":    c.Writer.Status(),
-				"duration":  duration,
-				"client_ip": c.ClientIP(),
-			},
-		)
+		entry := &accesslog.Entry{
+			Time:       start,
+			RemoteAddr: c.ClientIP(),
+			Method:     c.Request.Method,
+			URI:        c.Request.RequestURI,
+			Proto:      c.Request.Proto,
+			Route:      c.FullPath(),
+			Status:     c.Writer.Status(),
+			BytesIn:    body.n,
+			BytesOut:   int64(max(c.Writer.Size(), 0)),
+			DurationMS: milliseconds(duration),
+			Referer:    c.Request.Referer(),
+			UserAgent:  c.Request.UserAgent(),
+			RequestID:  requestid.FromContext(c.Request.Context()),
+		}
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			entry.TraceID = sc.TraceID().String()
+		}
+		if value, ok := c.Get(ClaimsKey); ok {
+			if claims, ok := value.(*jwt.Claims); ok {
+				entry.User, entry.UserUUID = claims.Username, claims.UserUUID
+			}
+		}
+		entry.UpstreamService = c.GetString(UpstreamServiceKey)
+		if latency, ok := c.Get(UpstreamLatencyKey); ok {
+			if latency, ok := latency.(time.Duration); ok {
+				entry.UpstreamMS = milliseconds(latency)
+			}
+		}
+		accesslog.Log(entry)
 	}
 }
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -1,46 +1,77 @@
 package middlewares
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
-	"zeneye-gateway/internal/adapter/repository/postgres"
+	"zeneye-gateway/internal/adapter/identity"
+	errorResponse "zeneye-gateway/pkg/error"
 	"zeneye-gateway/pkg/jwt"
 	"zeneye-gateway/pkg/loadbalancer"
 	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/metrics"
+	"zeneye-gateway/pkg/tracing"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// IdentityResolver resolves the identity forwarded upstream for validated
+// claims. *identity.Resolver implements it over a port.UserRepository.
+type IdentityResolver interface {
+	Resolve(ctx context.Context, claims *jwt.Claims) (identity.Identity, error)
+}
+
 // MicroserviceRoutingMiddleware handles routing requests to the appropriate microservice.
-func MicroserviceRoutingMiddleware(userRepo *postgres.UserRepository) gin.HandlerFunc {
+// The forwarded user headers come from the identity resolver, which may serve them
+// from its cache or straight from the JWT claims instead of querying the database.
+// Routes come from the request's snapshot, so a reload never reroutes a request
+// halfway; routes that list roles reject every other role with 403.
+func MicroserviceRoutingMiddleware(identities IdentityResolver) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		balancer := CurrentSnapshot(c).Balancer
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
-			logger.LogError("MicroserviceRoutingMiddleware", "Authorization Check", "Authorization header missing", nil)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header missing"})
-			c.Abort()
+			logger.FromContext(c.Request.Context()).WithSource("MicroserviceRoutingMiddleware").Error("Authorization header missing")
+			errorResponse.NewProblem(c, http.StatusUnauthorized, errorResponse.CodeUnauthenticated, "missing bearer token")
 			return
 		}
 
 		// Extract user information from the token
 		claims, err := jwt.ValidateToken(strings.TrimPrefix(tokenString, "Bearer "))
 		if err != nil {
-			logger.LogError("MicroserviceRoutingMiddleware", "JWT Extraction", "Error extracting user info from JWT", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			c.Abort()
+			logger.FromContext(c.Request.Context()).WithSource("MicroserviceRoutingMiddleware").Error("Error extracting user info from JWT", logger.Err(err))
+			metrics.AuthFailure("invalid_token")
+			errorResponse.NewProblem(c, http.StatusUnauthorized, errorResponse.CodeUnauthenticated, "invalid or expired token")
 			return
 		}
 
-		// Additional user details from db
-		user, err := userRepo.GetUser(claims.UserID)
+		// Additional user details (cache, claims or db depending on the resolver mode)
+		user, err := identities.Resolve(c.Request.Context(), claims)
 		if err != nil {
-			logger.LogError("MicroserviceRoutingMiddleware", "Fetch User Details", "Error fetching user details from DB", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			c.Abort()
+			logger.FromContext(c.Request.Context()).WithSource("MicroserviceRoutingMiddleware").Error("Error resolving user identity", logger.Err(err))
+			metrics.AuthFailure("unknown_user")
+			errorResponse.NewProblem(c, http.StatusUnauthorized, errorResponse.CodeUnauthenticated, "unknown user")
+			return
+		}
+
+		if !acceptIdentity(c, "MicroserviceRoutingMiddleware", claims, user) {
+			return
+		}
+
+		route, _ := balancer.Match(c.Request.URL.Path)
+		if !routeAllows(route, user.Role) {
+			logger.FromContext(c.Request.Context()).WithSource("MicroserviceRoutingMiddleware").Warn("Insufficient role for route " + route.Prefix)
+			metrics.AuthFailure("forbidden_role")
+			errorResponse.NewProblem(c, http.StatusForbidden, errorResponse.CodeForbidden, "insufficient role")
 			return
 		}
 
@@ -50,57 +81,55 @@ func MicroserviceRoutingMiddleware(userRepo *postgres.UserRepository) gin.Handle
 		c.Request.Header.Set("X-User-UUID", user.UserUUID)
 
 		// Determine the microservice URL for the incoming request.
-		microservice := loadbalancer.RouteRequest(c.Request)
+		service := serviceLabel(c.Request.URL.Path)
+		c.Set(UpstreamServiceKey, service)
+		microservice := balancer.RouteRequest(c.Request)
 		if microservice == "" {
-			logger.LogError("MicroserviceRoutingMiddleware", "Route Request", "Unable to route request", errors.New("unable to route request"))
-			c.JSON(http.StatusBadGateway, gin.H{"error": "Unable to route request"})
-			c.Abort()
+			logger.FromContext(c.Request.Context()).WithSource("MicroserviceRoutingMiddleware").Error("Unable to route request", logger.Err(errors.New("unable to route request")))
+			metrics.UpstreamError(service, "no_route")
+			errorResponse.NewProblem(c, http.StatusBadGateway, errorResponse.CodeNoRoute, "no upstream is configured for this path")
 			return
 		}
 
-		logger.LogInfo("MicroserviceRoutingMiddleware", "Routing", "Routing request to microservice", map[string]interface{}{
+		logger.FromContext(c.Request.Context()).WithSource("MicroserviceRoutingMiddleware").Sampled().Info("Routing request to microservice", logger.Any("Object", map[string]interface{}{
 			"path":         c.Request.URL.Path,
 			"microservice": microservice,
-		})
+		}))
 
 		// Parse the microservice URL.
 		target, err := url.Parse(microservice)
 		if err != nil {
-			logger.LogError("MicroserviceRoutingMiddleware", "Parse URL", "Error parsing microservice URL", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			c.Abort()
+			logger.FromContext(c.Request.Context()).WithSource("MicroserviceRoutingMiddleware").Error("Error parsing microservice URL", logger.Err(err))
+			metrics.UpstreamError(service, "bad_url")
+			errorResponse.NewProblem(c, http.StatusInternalServerError, errorResponse.CodeInternal, "an unexpected error occurred")
 			return
 		}
 
-		// Request path for the target microservice.
-		originalPath := c.Request.URL.Path
-		prefixes := []string{
-			"/admin-management",
-			"/agent",
-			"/compliance",
-			"/config",
-			"/notify",
-			"/bot-detection",
-			"/waf",
-			"/breach",
-		}
-		for _, prefix := range prefixes {
-			if strings.HasPrefix(originalPath, prefix) {
-				target.Path = strings.TrimPrefix(originalPath, prefix)
-				break
-			}
-		}
+		// Request path for the target microservice, without the route prefix.
+		target.Path = strings.TrimPrefix(c.Request.URL.Path, "/"+route.Prefix)
 
 		if strings.HasPrefix(target.Path, "/") {
 			target.Path = "/" + strings.TrimPrefix(target.Path, "/")
 		}
 
+		// Client span covering the upstream call; its context is injected below
+		ctx, span := tracing.Start(c.Request.Context(), "proxy.upstream",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("gateway.upstream.service", service),
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("server.address", target.Host),
+			),
+		)
+		defer span.End()
+
 		// New request to the target service
-		newReq, err := http.NewRequest(c.Request.Method, target.String(), c.Request.Body)
+		newReq, err := http.NewRequestWithContext(ctx, c.Request.Method, target.String(), c.Request.Body)
 		if err != nil {
-			logger.LogError("MicroserviceRoutingMiddleware", "Creating Request", "Error creating new request", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			c.Abort()
+			logger.FromContext(c.Request.Context()).WithSource("MicroserviceRoutingMiddleware").Error("Error creating new request", logger.Err(err))
+			metrics.UpstreamError(service, "bad_request")
+			tracing.RecordError(span, err)
+			errorResponse.NewProblem(c, http.StatusInternalServerError, errorResponse.CodeInternal, "an unexpected error occurred")
 			return
 		}
 
@@ -111,24 +140,56 @@ func MicroserviceRoutingMiddleware(userRepo *postgres.UserRepository) gin.Handle
 			}
 		}
 
+		// Replace the caller's traceparent with one pointing at the upstream span
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(newReq.Header))
+
 		client := &http.Client{}
+		upstreamStart := time.Now()
 		resp, err := client.Do(newReq)
+		c.Set(UpstreamLatencyKey, time.Since(upstreamStart))
 		if err != nil {
-			logger.LogError("MicroserviceRoutingMiddleware", "Request to Target", "Error contacting target service", err)
-			c.JSON(http.StatusBadGateway, gin.H{"error": "Error contacting target service"})
-			c.Abort()
+			logger.FromContext(c.Request.Context()).WithSource("MicroserviceRoutingMiddleware").Error("Error contacting target service", logger.Err(err))
+			metrics.UpstreamError(service, "unreachable")
+			tracing.RecordError(span, err)
+			errorResponse.NewProblem(c, http.StatusBadGateway, errorResponse.CodeUpstreamUnavailable, "could not contact the target service")
 			return
 		}
 		defer resp.Body.Close()
+		metrics.ObserveUpstream(service, resp.StatusCode, time.Since(upstreamStart))
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
 		// Copy the response back to the client
 		for header, values := range resp.Header {
 			for _, value := range values {
 				c.Header(header, value)
 			}
-			c.Status(resp.StatusCode)
-			io.Copy(c.Writer, resp.Body)
-			c.Abort()
 		}
+		c.Status(resp.StatusCode)
+		io.Copy(c.Writer, resp.Body)
+		c.Abort()
+	}
+}
+
+// routeAllows reports whether role may use route; routes without roles are
+// open to every authenticated user.
+func routeAllows(route loadbalancer.Route, role string) bool {
+	if len(route.Roles) == 0 {
+		return true
+	}
+	for _, allowed := range route.Roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceLabel names the upstream service after the first path segment, which is
+// the routing prefix, so the metric label set stays bounded by the route table.
+func serviceLabel(path string) string {
+	segment := strings.SplitN(strings.Trim(path, "/"), "/", 2)[0]
+	if segment == "" {
+		return metrics.UnmatchedRoute
 	}
+	return segment
 }
@@ -1,32 +1,37 @@
 package middlewares
 
 import (
-	"errors"
 	"net/http"
 
+	errorResponse "zeneye-gateway/pkg/error"
 	"zeneye-gateway/pkg/logger"
-	"zeneye-gateway/pkg/rate_limiter"
+	"zeneye-gateway/pkg/metrics"
+	"zeneye-gateway/pkg/tracing"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// RateLimitingMiddleware rejects clients that exceed the budget of the
+// request's snapshot for the path with 429. It runs after SnapshotMiddleware.
 func RateLimitingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !rate_limiter.AllowRequest(c.ClientIP()) {
-			logger.LogError("RateLimitingMiddleware", "Rate Limiting",
-				map[string]interface{}{
-					"clientIP": c.ClientIP(),
-				}, errors.New("too many requests"))
+		_, span := tracing.Start(c.Request.Context(), "ratelimit.check")
+		allowed := CurrentSnapshot(c).Limiter.Allow(c.Request.URL.Path, c.ClientIP())
+		span.SetAttributes(attribute.Bool("ratelimit.allowed", allowed))
+		span.End()
 
-			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
-			c.Abort()
+		if !allowed {
+			// Rejections come in floods, so they are sampled too
+			logger.FromContext(c.Request.Context()).WithSource("RateLimitingMiddleware").Sampled().
+				Warn("Too many requests", logger.String("clientIP", c.ClientIP()))
+			metrics.RateLimitRejected(routeLabel(c))
+
+			errorResponse.NewProblem(c, http.StatusTooManyRequests, errorResponse.CodeRateLimited, "too many requests")
 			return
 		}
-		logger.LogInfo("RateLimitingMiddleware", "Rate Limiting", "Request allowed",
-			map[string]interface{}{
-				"clientIP": c.ClientIP(),
-			},
-		)
+		logger.FromContext(c.Request.Context()).WithSource("RateLimitingMiddleware").Sampled().
+			Debug("Request allowed", logger.String("clientIP", c.ClientIP()))
 		c.Next()
 	}
 }
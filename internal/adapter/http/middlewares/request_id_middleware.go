@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"zeneye-gateway/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDMiddleware reuses a well-formed incoming X-Request-ID or generates one,
+// stores it in the request context, echoes it in the response and leaves it on the
+// request headers so the proxy forwards it upstream.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		c.Request.Header.Set(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Set(requestid.GinKey, id)
+		c.Header(requestid.Header, id)
+
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("gateway.request_id", id))
+
+		c.Next()
+	}
+}
@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"net/http"
+
+	errorResponse "zeneye-gateway/pkg/error"
+	"zeneye-gateway/pkg/jwt"
+	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRoles only lets through callers whose token carries one of roles.
+// It must run after AuthMiddleware.
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get(ClaimsKey)
+		if claims, ok := value.(*jwt.Claims); ok {
+			for _, role := range roles {
+				if claims.Role == role {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		logger.FromContext(c.Request.Context()).WithSource("RequireRoles").Warn("Insufficient role")
+		metrics.AuthFailure("forbidden_role")
+		errorResponse.NewProblem(c, http.StatusForbidden, errorResponse.CodeForbidden, "insufficient role")
+	}
+}
@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	errorResponse "zeneye-gateway/pkg/error"
+	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/security"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityMiddleware sets the security response headers and rejects requests
+// with oversized headers or bodies, unexpected content types, or paths and
+// queries that do not decode cleanly, before any handler or upstream sees them.
+// The policy is the one of the request's snapshot, so this runs after
+// SnapshotMiddleware.
+func SecurityMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy := CurrentSnapshot(c).Security
+		policy.SetHeaders(c.Writer.Header())
+
+		if policy.HeaderTooLarge(c.Request) {
+			logger.FromContext(c.Request.Context()).WithSource("SecurityMiddleware").Error("Header Size", logger.Any("Value", c.Request.URL.Path), logger.Err(errors.New("request headers too large")))
+			errorResponse.NewProblem(c, http.StatusRequestHeaderFieldsTooLarge, errorResponse.CodeHeadersTooLarge, "request headers are too large")
+			return
+		}
+
+		rawPath, rawQuery := rawTarget(c.Request)
+		if err := security.ValidatePath(rawPath); err != nil {
+			logger.FromContext(c.Request.Context()).WithSource("SecurityMiddleware").Error("Path Check", logger.Any("Value", rawPath), logger.Err(err))
+			errorResponse.NewProblem(c, http.StatusBadRequest, errorResponse.CodeInvalidPath, err.Error())
+			return
+		}
+		if err := security.ValidateQuery(rawQuery); err != nil {
+			logger.FromContext(c.Request.Context()).WithSource("SecurityMiddleware").Error("Query Check", logger.Any("Value", rawQuery), logger.Err(err))
+			errorResponse.NewProblem(c, http.StatusBadRequest, errorResponse.CodeInvalidQuery, err.Error())
+			return
+		}
+
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		limits := policy.For(c.Request.URL.Path)
+		if c.Request.ContentLength != 0 && !limits.AllowContentType(c.GetHeader("Content-Type")) {
+			logger.FromContext(c.Request.Context()).WithSource("SecurityMiddleware").Error("Content Type", logger.Any("Value", c.GetHeader("Content-Type")), logger.Err(errors.New("content type not allowed")))
+			errorResponse.NewProblem(c, http.StatusUnsupportedMediaType, errorResponse.CodeUnsupportedMedia,
+				fmt.Sprintf("content type %q is not accepted", c.GetHeader("Content-Type")))
+			return
+		}
+		if maxBody := limits.MaxBodyBytes(); maxBody > 0 {
+			if c.Request.ContentLength > maxBody {
+				logger.FromContext(c.Request.Context()).WithSource("SecurityMiddleware").Error("Body Size", logger.Any("Value", c.Request.ContentLength), logger.Err(errors.New("request body too large")))
+				errorResponse.NewProblem(c, http.StatusRequestEntityTooLarge, errorResponse.CodePayloadTooLarge,
+					fmt.Sprintf("request body exceeds %d bytes", maxBody))
+				return
+			}
+			// Bodies without a declared length are cut off while being read.
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBody)
+		}
+		c.Next()
+	}
+}
+
+// rawTarget returns the path and query exactly as the client sent them.
+func rawTarget(r *http.Request) (string, string) {
+	if strings.HasPrefix(r.RequestURI, "/") {
+		path, query, _ := strings.Cut(r.RequestURI, "?")
+		return path, query
+	}
+	return r.URL.EscapedPath(), r.URL.RawQuery
+}
@@ -0,0 +1,23 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"zeneye-gateway/internal/adapter/bootstrap"
+	errorResponse "zeneye-gateway/pkg/error"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireSetupToken hides the bootstrap routes once token has been used, so
+// they answer like any unrouted path instead of lingering after the first
+// superadmin exists.
+func RequireSetupToken(token *bootstrap.SetupToken) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !token.Outstanding() {
+			errorResponse.NewProblem(c, http.StatusNotFound, errorResponse.CodeNotFound, "no route matches "+c.Request.URL.Path)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,80 @@
+package middlewares
+
+import (
+	"sync/atomic"
+
+	"zeneye-gateway/pkg/cors"
+	"zeneye-gateway/pkg/loadbalancer"
+	"zeneye-gateway/pkg/metrics"
+	"zeneye-gateway/pkg/rate_limiter"
+	"zeneye-gateway/pkg/security"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SnapshotKey is the gin context key holding the request's *Snapshot.
+const SnapshotKey = "gatewaySnapshot"
+
+// Snapshot is the part of the gateway a configuration reload replaces: the
+// route table with its per-route roles, the rate limiter and the CORS and
+// security policies.
+type Snapshot struct {
+	Balancer *loadbalancer.Balancer
+	Limiter  *rate_limiter.Limiter
+	CORS     *cors.Policy
+	Security *security.Policy
+}
+
+// Snapshots holds the current Snapshot. Swapping it never affects requests
+// already in flight, which keep the snapshot they started with.
+type Snapshots struct {
+	current atomic.Pointer[Snapshot]
+}
+
+// NewSnapshots returns a holder serving initial.
+func NewSnapshots(initial *Snapshot) *Snapshots {
+	s := &Snapshots{}
+	s.current.Store(initial)
+	return s
+}
+
+// Current returns the snapshot new requests use.
+func (s *Snapshots) Current() *Snapshot {
+	return s.current.Load()
+}
+
+// Swap makes next the snapshot for new requests and returns the previous one.
+func (s *Snapshots) Swap(next *Snapshot) *Snapshot {
+	return s.current.Swap(next)
+}
+
+// SnapshotMiddleware pins the current snapshot to the request. It must run
+// before every middleware that reads it.
+func SnapshotMiddleware(snapshots *Snapshots) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(SnapshotKey, snapshots.Current())
+		c.Next()
+	}
+}
+
+// CurrentSnapshot returns the snapshot pinned to c.
+func CurrentSnapshot(c *gin.Context) *Snapshot {
+	value, _ := c.Get(SnapshotKey)
+	snapshot, _ := value.(*Snapshot)
+	return snapshot
+}
+
+// routeLabel names the route of c for metrics and spans: the gin route
+// template, or for a proxied request the route table prefix it matched. Only
+// requests that match neither are labelled unmatched.
+func routeLabel(c *gin.Context) string {
+	if fullPath := c.FullPath(); fullPath != "" {
+		return fullPath
+	}
+	if snapshot := CurrentSnapshot(c); snapshot != nil && snapshot.Balancer != nil {
+		if route, ok := snapshot.Balancer.Match(c.Request.URL.Path); ok {
+			return "/" + route.Prefix
+		}
+	}
+	return metrics.UnmatchedRoute
+}
@@ -1,69 +1,232 @@
 package http
 
 import (
-	"log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
 	"zeneye-gateway/internal/adapter/http/handlers"
 	"zeneye-gateway/internal/adapter/http/middlewares"
 	"zeneye-gateway/internal/adapter/repository/postgres"
+	"zeneye-gateway/internal/app"
+	"zeneye-gateway/internal/config"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/cors"
+	errorResponse "zeneye-gateway/pkg/error"
+	"zeneye-gateway/pkg/health"
+	"zeneye-gateway/pkg/loadbalancer"
 	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/metrics"
+	"zeneye-gateway/pkg/rate_limiter"
+	"zeneye-gateway/pkg/security"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// SetupRouter sets up the Gin router with routes and middleware.
-func SetupRouter(db *gorm.DB) *gin.Engine {
-	router := gin.Default()
+// Gateway is the router together with the snapshot of routes and policies a
+// configuration reload or the gateway admin API replaces.
+type Gateway struct {
+	Router    *gin.Engine
+	Snapshots *middlewares.Snapshots
+
+	policies port.GatewayPolicyService
+	mu       sync.Mutex
+	cfg      *config.Config
+	// version is the stored policy version in force, zero while the policies
+	// come from the configuration.
+	version uint
+}
+
+// SetupRouter sets up the Gin router with routes and middleware configured by
+// cfg, wiring the services over db.
+func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
+	return NewGateway(app.New(db, cfg), cfg).Router
+}
+
+// NewGateway sets up the router over deps as configured by cfg, with the
+// latest policy version stored through the gateway admin API in place of the
+// configured routes, pools, rate limits and CORS policy. Those and the security
+// policy can later be replaced with Reload or the admin API; everything else is
+// fixed until restart.
+func NewGateway(deps *app.App, cfg *config.Config) *Gateway {
+	// gin's own request logger is replaced by the access log in LoggingMiddleware
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	cfg, version, err := WithStoredPolicies(deps.Policies, cfg)
+	if err != nil {
+		logger.LogError("SetupRouter", "Policies", "Could not load the stored gateway policies; using the configured ones", err)
+	}
+
+	gateway := &Gateway{
+		Router:   router,
+		policies: deps.Policies,
+		version:  version,
+		Snapshots: middlewares.NewSnapshots(&middlewares.Snapshot{
+			Balancer: loadbalancer.New(cfg.Routes, cfg.Pools),
+			Limiter:  rate_limiter.New(cfg.RateLimit),
+			CORS:     corsPolicy(cfg.CORS),
+			Security: securityPolicy(cfg.Security),
+		}),
+		cfg: cfg,
+	}
 
-	// Middleware setup for logging and rate limiting
+	// Unknown methods answer with problem documents like every other error
+	router.HandleMethodNotAllowed = true
+	router.NoMethod(func(c *gin.Context) {
+		errorResponse.NewProblem(c, http.StatusMethodNotAllowed, errorResponse.CodeMethodNotAllowed, c.Request.Method+" is not allowed on "+c.Request.URL.Path)
+	})
+
+	// Middleware setup for tracing, request IDs, logging, hardening, CORS and rate limiting
+	router.Use(middlewares.TracingMiddleware())
+	router.Use(middlewares.RequestIDMiddleware())
 	router.Use(middlewares.LoggingMiddleware())
+	// Each request keeps the snapshot it started with, even across a reload
+	router.Use(middlewares.SnapshotMiddleware(gateway.Snapshots))
+	router.Use(middlewares.SecurityMiddleware())
+	router.Use(middlewares.CORSMiddleware())
 	router.Use(middlewares.RateLimitingMiddleware())
 
-	logger.LogInfo("SetupRouter", "Initializing routes", "Setting up health check route", "")
-	router.GET("/health", handlers.HealthCheck)
+	// Metrics for the DB pool and the identity cache; request, upstream, rate-limit
+	// and auth metrics are recorded by the middleware themselves.
+	if sqlDB, err := deps.DB.DB(); err == nil {
+		metrics.RegisterDBStats("gateway", sqlDB)
+	}
+	metrics.RegisterCacheStats("identity", deps.Identities.Stats)
+
+	userHandler := handlers.NewUserHandler(deps.Users, deps.Accounts, deps.Audit, deps.SetupToken)
+	authHandler := handlers.NewAuthHandler(deps.Users, deps.Audit)
+	accountHandler := handlers.NewAccountHandler(deps.Users, deps.Accounts, deps.Audit)
+	auditHandler := handlers.NewAuditHandler(deps.Audit)
+	policyHandler := handlers.NewGatewayPolicyHandler(deps.Policies, deps.Audit, gateway)
+	migrationHandler := handlers.NewMigrationHandler(deps.Migrations, deps.Audit)
+
+	logger.LogInfo("SetupRouter", "Initializing routes", "Setting up health check routes", "")
+	// Upstream checks follow the routes configured at startup
+	checker := healthChecker(deps.DB, cfg.Health, gateway.Snapshots.Current().Balancer)
+	router.GET("/livez", handlers.Livez)
+	router.GET("/readyz", handlers.Readyz(checker))
+	router.GET("/health", handlers.HealthCheck(checker))
+
+	logger.LogInfo("SetupRouter", "Initializing routes", "Setting up metrics route", "")
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	logger.LogInfo("SetupRouter", "Initializing routes", "Setting up public routes", "")
 	// Public routes
-	router.POST("/login", handlers.Login(db))
-	router.POST("/refresh-token", handlers.RefreshToken(db))
+	router.POST("/login", authHandler.Login)
+	router.POST("/refresh-token", authHandler.RefreshToken)
+	router.POST("/password/forgot", accountHandler.ForgotPassword)
+	router.POST("/password/reset", accountHandler.ResetPassword)
 
 	logger.LogInfo("SetupRouter", "Initializing routes", "Setting up superadmin routes", "")
 	// Superadmin Routes
 	superadminGroup := router.Group("/superadmin")
 	{
-		superadminGroup.GET("/check", handlers.CheckSuperadmin(db))
-		superadminGroup.POST("/create", handlers.CreateSuperadmin(db))
+		// The bootstrap is only served while a setup token is outstanding,
+		// which never happens in production mode or once a superadmin exists
+		if cfg.BootstrapHTTP() && deps.SetupToken != nil {
+			setupToken := middlewares.RequireSetupToken(deps.SetupToken)
+			superadminGroup.GET("/check", setupToken, userHandler.CheckSuperadmin)
+			superadminGroup.POST("/create", setupToken, userHandler.CreateSuperadmin)
+		}
+
+		// Handing the role over takes the superadmin's request and the
+		// recipient's acceptance
+		transferGroup := superadminGroup.Group("/transfer")
+		transferGroup.Use(middlewares.AuthMiddleware(), middlewares.RequireActiveUser(deps.Identities))
+		{
+			transferGroup.POST("", middlewares.RequireRoles("superadmin"), userHandler.RequestSuperadminTransfer)
+			transferGroup.GET("", userHandler.GetSuperadminTransfer)
+			transferGroup.POST("/accept", userHandler.AcceptSuperadminTransfer)
+			transferGroup.DELETE("", userHandler.CancelSuperadminTransfer)
+		}
 	}
 
 	logger.LogInfo("SetupRouter", "Initializing routes", "Setting up protected routes", "")
 	// Protected routes for gateway-handled APIs
 	protectedRoutes := router.Group("/")
-	protectedRoutes.Use(middlewares.AuthMiddleware())
+	protectedRoutes.Use(middlewares.AuthMiddleware(), middlewares.RequireActiveUser(deps.Identities))
 	{
 		// User Routes
+		// Plain accounts have no part in user management; ranks decide the rest
 		userGroup := protectedRoutes.Group("/users")
+		userGroup.Use(middlewares.RequireRoles("superadmin", "admin", "department_admin", "auditor"))
 		{
-			userGroup.POST("/", handlers.CreateUser(db))
-			userGroup.PATCH("/:id", handlers.EditUser(db))
-			userGroup.DELETE("/:id", handlers.DeleteUser(db))
-			userGroup.GET("/:id", handlers.GetUser(db))
-			userGroup.GET("/", handlers.ListUsers(db))
+			userGroup.POST("", userHandler.CreateUser)
+			userGroup.PATCH("/:id", userHandler.EditUser)
+			userGroup.DELETE("/:id", userHandler.DeleteUser)
+			userGroup.GET("/:id", userHandler.GetUser)
+			userGroup.GET("", userHandler.ListUsers)
+			userGroup.PUT("/:id/role", userHandler.ChangeUserRole)
+			userGroup.POST("/:id/disable", middlewares.RequireRoles("admin", "superadmin"), userHandler.DisableUser)
+			userGroup.POST("/:id/enable", middlewares.RequireRoles("admin", "superadmin"), userHandler.EnableUser)
+			userGroup.POST("/:id/restore", middlewares.RequireRoles("admin", "superadmin"), userHandler.RestoreUser)
+		}
+
+		// The caller's own account, open to every role
+		meGroup := protectedRoutes.Group("/me")
+		{
+			meGroup.GET("", accountHandler.GetProfile)
+			meGroup.PATCH("", accountHandler.UpdateProfile)
+			meGroup.POST("/password", accountHandler.ChangePassword)
+			meGroup.POST("/email", accountHandler.RequestEmailChange)
+			meGroup.POST("/email/confirm", accountHandler.ConfirmEmailChange)
 		}
 	}
 
-	// Initialize user repository for middleware
-	userRepo := postgres.NewUserRepository(db)
+	logger.LogInfo("SetupRouter", "Initializing routes", "Setting up gateway admin routes", "")
+	// Gateway administration, superadmin only
+	gatewayAdminGroup := router.Group("/gateway-admin")
+	gatewayAdminGroup.Use(middlewares.AuthMiddleware(), middlewares.RequireActiveUser(deps.Identities), middlewares.RequireRoles("superadmin"))
+	{
+		gatewayAdminGroup.GET("/log-level", handlers.GetLogLevel)
+		gatewayAdminGroup.PUT("/log-level", handlers.SetLogLevel)
 
-	// Casting to concrete type *postgres.UserRepository
-	postgresUserRepo, ok := userRepo.(*postgres.UserRepository)
-	if !ok {
-		log.Fatalf("Failed to assert userRepo to *postgres.UserRepository")
+		// Routes, pools, rate limits and CORS rules, versioned and applied live
+		gatewayAdminGroup.GET("/policies", policyHandler.GetPolicies)
+		gatewayAdminGroup.PUT("/policies", policyHandler.ReplacePolicies)
+		for path, endpoints := range map[string]handlers.PolicyEndpoints{
+			"/routes":      policyHandler.Routes(),
+			"/pools":       policyHandler.Pools(),
+			"/rate-limits": policyHandler.RateLimits(),
+			"/cors-rules":  policyHandler.CORSRules(),
+		} {
+			gatewayAdminGroup.GET(path, endpoints.List)
+			gatewayAdminGroup.POST(path, endpoints.Create)
+			gatewayAdminGroup.PUT(path+"/*key", endpoints.Update)
+			gatewayAdminGroup.DELETE(path+"/*key", endpoints.Delete)
+		}
+		gatewayAdminGroup.GET("/versions", policyHandler.ListVersions)
+		gatewayAdminGroup.GET("/versions/:version", policyHandler.GetVersion)
+		gatewayAdminGroup.POST("/versions/:version/rollback", policyHandler.RollbackVersion)
+
+		// Schema migrations of the gateway database
+		gatewayAdminGroup.GET("/migrations", migrationHandler.GetMigrations)
+		gatewayAdminGroup.POST("/migrations/up", migrationHandler.MigrateUp)
+		gatewayAdminGroup.POST("/migrations/down", migrationHandler.MigrateDown)
+		gatewayAdminGroup.POST("/migrations/goto", migrationHandler.MigrateGoto)
+		gatewayAdminGroup.POST("/migrations/force", migrationHandler.ForceMigration)
+	}
+
+	logger.LogInfo("SetupRouter", "Initializing routes", "Setting up audit routes", "")
+	// Audit trail, read-only for auditors and superadmins
+	auditGroup := router.Group("/audit")
+	auditGroup.Use(middlewares.AuthMiddleware(), middlewares.RequireActiveUser(deps.Identities), middlewares.RequireRoles("auditor", "superadmin"))
+	{
+		auditGroup.GET("/events", auditHandler.ListAuditEvents)
+		auditGroup.GET("/export", auditHandler.ExportAuditEvents)
+		auditGroup.GET("/verify", auditHandler.VerifyAuditChain)
 	}
 
 	// Protected routes for microservices (no need for handlers, will be handled by middleware)
 	microserviceRoutes := router.Group("/")
 	microserviceRoutes.Use(middlewares.AuthMiddleware())
-	microserviceRoutes.Use(middlewares.MicroserviceRoutingMiddleware(postgresUserRepo))
+	microserviceRoutes.Use(middlewares.MicroserviceRoutingMiddleware(deps.Identities))
 	{
 		// Admin Management Routes
 		adminGroup := microserviceRoutes.Group("/admin-management")
@@ -72,5 +235,177 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 		}
 	}
 
-	return router
+	// Any other path is proxied when the current route table has a prefix for
+	// it, and otherwise answers 404 with a problem document
+	router.NoRoute(func(c *gin.Context) {
+		if _, ok := middlewares.CurrentSnapshot(c).Balancer.Match(c.Request.URL.Path); !ok {
+			errorResponse.NewProblem(c, http.StatusNotFound, errorResponse.CodeNotFound, "no route matches "+c.Request.URL.Path)
+		}
+	}, middlewares.AuthMiddleware(), middlewares.MicroserviceRoutingMiddleware(deps.Identities))
+
+	return gateway
+}
+
+// Reload swaps in the routes, pools, route roles, rate limits and CORS and
+// security policies of cfg for every request that starts afterwards; requests
+// in flight finish on the snapshot they started with. The latest stored policy
+// version, if any, still takes precedence over the routes, pools, rate limits
+// and CORS policy of cfg. An invalid section rejects the whole reload and
+// leaves the running snapshot untouched. Changes to other sections are logged
+// as needing a restart.
+func (g *Gateway) Reload(cfg *config.Config) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	cfg, version, err := WithStoredPolicies(g.policies, cfg)
+	if err != nil {
+		logger.LogError("Gateway", "Reload", "Could not load the stored gateway policies; keeping the ones in force", err)
+		version = g.version
+		if version > 0 {
+			cfg = cfg.WithPolicies(g.cfg.Policies())
+		}
+	}
+	return g.swap(cfg, version)
+}
+
+// Policies returns the routes and policies in force and their stored version,
+// zero while they come from the configuration.
+func (g *Gateway) Policies() (config.Policies, uint) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.cfg.Policies(), g.version
+}
+
+// ValidatePolicies reports whether policies would be accepted by
+// ApplyPolicies, without applying them.
+func (g *Gateway) ValidatePolicies(policies config.Policies) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, err := newSnapshot(g.cfg.WithPolicies(policies), g.Snapshots.Current())
+	return err
+}
+
+// ApplyPolicies swaps in policies, stored as version, the same way Reload
+// swaps in a configuration.
+func (g *Gateway) ApplyPolicies(policies config.Policies, version uint) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.swap(g.cfg.WithPolicies(policies), version)
+}
+
+// swap builds the snapshot for cfg and makes it current. g.mu must be held.
+func (g *Gateway) swap(cfg *config.Config, version uint) error {
+	current := g.Snapshots.Current()
+	next, err := newSnapshot(cfg, current)
+	if err != nil {
+		return err
+	}
+	var restart []string
+	for _, name := range g.cfg.Changed(cfg) {
+		if !reloadable[name] {
+			restart = append(restart, name)
+		}
+	}
+	if len(restart) > 0 {
+		logger.LogWarning("Gateway", "Reload", "Changed settings take effect after a restart", restart)
+	}
+
+	g.Snapshots.Swap(next)
+	g.cfg = cfg
+	g.version = version
+	logger.LogInfo("Gateway", "Reload", "Swapped routes and policies", map[string]interface{}{"version": version, "upstreams": next.Balancer.Upstreams()})
+	return nil
+}
+
+// WithStoredPolicies returns cfg with the latest policy version in policies in
+// place of its runtime-editable sections, and that version. Without a stored
+// version cfg is returned as is with version zero.
+func WithStoredPolicies(policies port.GatewayPolicyService, cfg *config.Config) (*config.Config, uint, error) {
+	latest, err := policies.LatestVersion()
+	if err != nil || latest == nil {
+		return cfg, 0, err
+	}
+	var stored config.Policies
+	if err := json.Unmarshal([]byte(latest.Document), &stored); err != nil {
+		return cfg, 0, fmt.Errorf("policy version %d: %w", latest.Version, err)
+	}
+	return cfg.WithPolicies(stored), latest.Version, nil
+}
+
+// reloadable names the configuration sections Reload applies.
+var reloadable = map[string]bool{"routes": true, "pools": true, "rate_limit": true, "cors": true, "security": true}
+
+// newSnapshot builds the snapshot for cfg, reporting every invalid section at
+// once. The rate limiter of previous is kept while its options are unchanged
+// so clients do not get a fresh budget on every reload.
+func newSnapshot(cfg *config.Config, previous *middlewares.Snapshot) (*middlewares.Snapshot, error) {
+	var errs []error
+	if err := loadbalancer.Validate(cfg.Routes, cfg.Pools); err != nil {
+		errs = append(errs, fmt.Errorf("routes: %w", err))
+	}
+	if err := cfg.RateLimit.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("rate limit: %w", err))
+	}
+	corsPolicy, err := cors.New(cfg.CORS)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cors: %w", err))
+	}
+	securityPolicy, err := security.New(cfg.Security)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("security: %w", err))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	limiter := previous.Limiter
+	if !reflect.DeepEqual(limiter.Options(), cfg.RateLimit) {
+		limiter = rate_limiter.New(cfg.RateLimit)
+	}
+	return &middlewares.Snapshot{
+		Balancer: loadbalancer.New(cfg.Routes, cfg.Pools),
+		Limiter:  limiter,
+		CORS:     corsPolicy,
+		Security: securityPolicy,
+	}, nil
+}
+
+// corsPolicy builds the CORS policy from opts. An invalid policy is logged and
+// replaced by one that allows no cross-origin access.
+func corsPolicy(opts cors.Options) *cors.Policy {
+	policy, err := cors.New(opts)
+	if err == nil {
+		return policy
+	}
+	logger.LogError("SetupRouter", "CORS", "Invalid CORS configuration; cross-origin requests are disabled", err)
+	policy, _ = cors.New(cors.DefaultOptions())
+	return policy
+}
+
+// securityPolicy builds the hardening policy from opts. An invalid policy is
+// logged and replaced by the defaults rather than disabled.
+func securityPolicy(opts security.Options) *security.Policy {
+	policy, err := security.New(opts)
+	if err == nil {
+		return policy
+	}
+	logger.LogError("SetupRouter", "Security", "Invalid security configuration; using defaults", err)
+	policy, _ = security.New(security.DefaultOptions())
+	return policy
+}
+
+// healthChecker checks the database and, when enabled, every upstream the
+// balancer routes to. Invalid options are logged and the defaults are used.
+func healthChecker(db *gorm.DB, opts health.Options, balancer *loadbalancer.Balancer) *health.Checker {
+	if err := opts.Validate(); err != nil {
+		logger.LogError("SetupRouter", "Health", "Invalid health check configuration; using defaults", err)
+		opts = health.DefaultOptions()
+	}
+	checks := postgres.HealthChecks(db)
+	if opts.CheckUpstreams {
+		for name, url := range balancer.Upstreams() {
+			checks = append(checks, health.HTTPCheck("upstream:"+name, strings.TrimSuffix(url, "/")+opts.UpstreamPath, opts.UpstreamsCritical, nil))
+		}
+	}
+	return health.New(opts, checks...)
 }
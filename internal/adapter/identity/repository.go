@@ -0,0 +1,73 @@
+package identity
+
+import (
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+)
+
+// invalidatingRepository drops cached identities whenever a user is changed
+// or removed through it, so the routing path never forwards a stale role.
+type invalidatingRepository struct {
+	port.UserRepository
+	resolver *Resolver
+}
+
+// WrapRepository returns repo with writes hooked into the resolver's cache.
+func (r *Resolver) WrapRepository(repo port.UserRepository) port.UserRepository {
+	return &invalidatingRepository{UserRepository: repo, resolver: r}
+}
+
+func (r *invalidatingRepository) EditUser(user *entity.User) error {
+	err := r.UserRepository.EditUser(user)
+	r.resolver.Invalidate(user.ID)
+	return err
+}
+
+func (r *invalidatingRepository) DeleteUser(id uint) error {
+	err := r.UserRepository.DeleteUser(id)
+	r.resolver.Invalidate(id)
+	return err
+}
+
+func (r *invalidatingRepository) SetUserStatus(id uint, status string) error {
+	err := r.UserRepository.SetUserStatus(id, status)
+	r.resolver.Invalidate(id)
+	return err
+}
+
+func (r *invalidatingRepository) RestoreUser(id uint) error {
+	err := r.UserRepository.RestoreUser(id)
+	r.resolver.Invalidate(id)
+	return err
+}
+
+func (r *invalidatingRepository) ChangeUserRole(id uint, role string) error {
+	err := r.UserRepository.ChangeUserRole(id, role)
+	r.resolver.Invalidate(id)
+	return err
+}
+
+func (r *invalidatingRepository) RevokeTokens(id uint) error {
+	err := r.UserRepository.RevokeTokens(id)
+	r.resolver.Invalidate(id)
+	return err
+}
+
+func (r *invalidatingRepository) ChangePassword(userID uint, hash string, keep int) error {
+	err := r.UserRepository.ChangePassword(userID, hash, keep)
+	r.resolver.Invalidate(userID)
+	return err
+}
+
+func (r *invalidatingRepository) ResetPassword(reset *entity.PasswordReset, hash string, keep int) error {
+	err := r.UserRepository.ResetPassword(reset, hash, keep)
+	r.resolver.Invalidate(reset.UserID)
+	return err
+}
+
+func (r *invalidatingRepository) CompleteSuperadminTransfer(transfer *entity.SuperadminTransfer, demoteTo string) error {
+	err := r.UserRepository.CompleteSuperadminTransfer(transfer, demoteTo)
+	r.resolver.Invalidate(transfer.FromUserID)
+	r.resolver.Invalidate(transfer.ToUserID)
+	return err
+}
@@ -0,0 +1,264 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/cache"
+	"zeneye-gateway/pkg/jwt"
+	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Mode selects where the routing path gets the identity it forwards upstream.
+type Mode string
+
+const (
+	// ModeDatabase looks the user up in the repository on every request.
+	ModeDatabase Mode = "db"
+	// ModeCache serves lookups from an in-memory TTL/LRU cache, falling back to the repository.
+	ModeCache Mode = "cache"
+	// ModeClaims trusts the signed JWT claims and never touches the repository.
+	// It cannot see a user being disabled, deleted or having their tokens
+	// revoked, so their tokens keep working until they expire.
+	ModeClaims Mode = "claims"
+)
+
+const (
+	defaultCacheSize = 10000
+	defaultCacheTTL  = 5 * time.Minute
+)
+
+// Identity is the subset of a user forwarded to upstream services.
+type Identity struct {
+	UserID   uint
+	Username string
+	Role     string
+	UserUUID string
+	// Status is empty in claims mode, where it is unknown.
+	Status string
+	// TokenVersion is the user's current token version; in claims mode it is
+	// the token's own.
+	TokenVersion uint
+}
+
+// Active reports whether the user may be routed. Identities built from claims
+// alone always are, since their status is unknown.
+func (i Identity) Active() bool {
+	return i.Status == "" || i.Status == entity.UserStatusActive
+}
+
+// Options configures a Resolver.
+type Options struct {
+	// Mode is db, cache or claims. Only db and cache see a user being
+	// disabled, deleted or having their tokens revoked before the tokens
+	// expire.
+	Mode      Mode          `yaml:"mode" toml:"mode"`
+	CacheSize int           `yaml:"cache_size" toml:"cache_size"`
+	CacheTTL  time.Duration `yaml:"cache_ttl" toml:"cache_ttl"`
+}
+
+// DefaultOptions caches identities for five minutes.
+func DefaultOptions() Options {
+	return Options{Mode: ModeCache, CacheSize: defaultCacheSize, CacheTTL: defaultCacheTTL}
+}
+
+// OptionsFromEnv applies the environment over the defaults; see LoadEnv.
+func OptionsFromEnv() (Options, error) {
+	opts := DefaultOptions()
+	if err := opts.LoadEnv(os.LookupEnv); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// LoadEnv overrides o with IDENTITY_MODE, IDENTITY_CACHE_SIZE and
+// IDENTITY_CACHE_TTL as found by lookup, usually os.LookupEnv.
+func (o *Options) LoadEnv(lookup func(string) (string, bool)) error {
+	getenv := func(key string) string { v, _ := lookup(key); return v }
+	var errs []error
+	if v := getenv("IDENTITY_MODE"); v != "" {
+		o.Mode = Mode(v)
+	}
+	if v := getenv("IDENTITY_CACHE_SIZE"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("IDENTITY_CACHE_SIZE: %w", err))
+		} else {
+			o.CacheSize = size
+		}
+	}
+	if v := getenv("IDENTITY_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("IDENTITY_CACHE_TTL: %w", err))
+		} else {
+			o.CacheTTL = ttl
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid option at once.
+func (o Options) Validate() error {
+	var errs []error
+	switch o.Mode {
+	case ModeDatabase, ModeCache, ModeClaims:
+	default:
+		errs = append(errs, fmt.Errorf("identity mode %q must be db, cache or claims", o.Mode))
+	}
+	if o.Mode == ModeCache {
+		if o.CacheSize < 1 {
+			errs = append(errs, errors.New("identity cache size must be positive"))
+		}
+		if o.CacheTTL <= 0 {
+			errs = append(errs, errors.New("identity cache TTL must be positive"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Resolver turns validated JWT claims into the identity forwarded upstream.
+type Resolver struct {
+	repo  port.UserRepository
+	mode  Mode
+	cache *cache.LRU[uint, Identity]
+
+	// generations counts the invalidations of each user, so a lookup that
+	// raced with one does not cache the user it read before.
+	mu          sync.Mutex
+	generations map[uint]uint64
+}
+
+// NewResolver creates a Resolver backed by repo.
+func NewResolver(repo port.UserRepository, opts Options) *Resolver {
+	r := &Resolver{repo: repo, mode: opts.Mode}
+	if r.mode == "" {
+		r.mode = ModeCache
+	}
+	if r.mode == ModeCache {
+		size, ttl := opts.CacheSize, opts.CacheTTL
+		if size < 1 {
+			size = defaultCacheSize
+		}
+		if ttl <= 0 {
+			ttl = defaultCacheTTL
+		}
+		r.cache = cache.New[uint, Identity](size, ttl)
+		r.generations = map[uint]uint64{}
+	}
+	if r.mode == ModeClaims {
+		logger.LogWarning("Identity", "NewResolver", "Identity mode claims cannot see disabled, deleted or revoked users; their tokens keep working until they expire", "")
+	}
+
+	logger.LogInfo("Identity", "NewResolver", "Identity resolver initialized", map[string]interface{}{
+		"mode":       r.mode,
+		"cache_size": opts.CacheSize,
+		"cache_ttl":  opts.CacheTTL.String(),
+	})
+	return r
+}
+
+// Mode returns the mode the resolver runs in.
+func (r *Resolver) Mode() Mode {
+	return r.mode
+}
+
+// Resolve returns the identity for the given claims according to the resolver mode.
+func (r *Resolver) Resolve(ctx context.Context, claims *jwt.Claims) (Identity, error) {
+	ctx, span := tracing.Start(ctx, "identity.resolve")
+	defer span.End()
+	span.SetAttributes(attribute.String("identity.mode", string(r.mode)))
+
+	if r.mode == ModeClaims {
+		return Identity{
+			UserID:       claims.UserID,
+			Username:     claims.Username,
+			Role:         claims.Role,
+			UserUUID:     claims.UserUUID,
+			TokenVersion: claims.TokenVersion,
+		}, nil
+	}
+
+	if r.cache != nil {
+		id, ok := r.cache.Get(claims.UserID)
+		span.SetAttributes(attribute.Bool("identity.cache_hit", ok))
+		if ok {
+			return id, nil
+		}
+	}
+
+	generation := r.generation(claims.UserID)
+	logger.FromContext(ctx).WithSource("Identity").Sampled().Debug("Loading user from repository", logger.Uint("UserID", claims.UserID))
+	_, dbSpan := tracing.Start(ctx, "db.get_user")
+	user, err := r.repo.GetUser(claims.UserID)
+	if err != nil {
+		logger.FromContext(ctx).WithSource("Identity").Error("Resolving user failed", logger.Uint("UserID", claims.UserID), logger.Err(err))
+		tracing.RecordError(dbSpan, err)
+		dbSpan.End()
+		tracing.RecordError(span, err)
+		return Identity{}, err
+	}
+	dbSpan.End()
+
+	id := fromUser(user)
+	if r.cache != nil {
+		r.mu.Lock()
+		if r.generations[id.UserID] == generation {
+			r.cache.Set(id.UserID, id)
+		}
+		r.mu.Unlock()
+	}
+	return id, nil
+}
+
+// Invalidate drops any cached identity for userID, and keeps lookups that
+// started before from caching what they read.
+func (r *Resolver) Invalidate(userID uint) {
+	if r.cache == nil {
+		return
+	}
+	r.mu.Lock()
+	r.generations[userID]++
+	r.cache.Delete(userID)
+	r.mu.Unlock()
+	logger.LogInfo("Identity", "Invalidate", "Cached identity invalidated", userID)
+}
+
+// generation returns the number of times userID was invalidated.
+func (r *Resolver) generation(userID uint) uint64 {
+	if r.cache == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.generations[userID]
+}
+
+// Stats returns the cache counters; all zero unless the resolver runs in cache mode.
+func (r *Resolver) Stats() cache.Stats {
+	if r.cache == nil {
+		return cache.Stats{}
+	}
+	return r.cache.Stats()
+}
+
+func fromUser(user *entity.User) Identity {
+	return Identity{
+		UserID:       user.ID,
+		Username:     user.Username,
+		Role:         user.Role,
+		UserUUID:     user.UserUUID,
+		Status:       user.Status,
+		TokenVersion: user.TokenVersion,
+	}
+}
@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+)
+
+// payload is the JSON body the webhook and the notification service receive.
+type payload struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// WebhookNotifier posts notifications to a URL, signed when it has a secret.
+type WebhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url, secret string, client *http.Client) port.Notifier {
+	return &WebhookNotifier{url: url, secret: secret, client: client}
+}
+
+func (n *WebhookNotifier) Notify(notification *entity.Notification) error {
+	body, err := json.Marshal(payload{To: notification.To, Subject: notification.Subject, Body: notification.Body})
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	if n.secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.secret))
+		mac.Write(body)
+		header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return post(n.client, n.url, header, body)
+}
+
+// ServiceNotifier posts notifications to the notification microservice.
+type ServiceNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewServiceNotifier(url string, client *http.Client) port.Notifier {
+	return &ServiceNotifier{url: url, client: client}
+}
+
+func (n *ServiceNotifier) Notify(notification *entity.Notification) error {
+	body, err := json.Marshal(payload{To: notification.To, Subject: notification.Subject, Body: notification.Body})
+	if err != nil {
+		return err
+	}
+	return post(n.client, n.url, http.Header{}, body)
+}
+
+// post sends body as JSON and treats any non-2xx answer as a failure.
+func post(client *http.Client, url string, header http.Header, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification delivery to %s failed with status %d", url, resp.StatusCode)
+	}
+	return nil
+}
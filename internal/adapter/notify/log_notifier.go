@@ -0,0 +1,24 @@
+// Package notify delivers user notifications.
+package notify
+
+import (
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/logger"
+)
+
+// LogNotifier writes notifications to the log instead of delivering them. It
+// is meant for development, where no mail server is available.
+type LogNotifier struct{}
+
+func NewLogNotifier() port.Notifier {
+	return LogNotifier{}
+}
+
+func (LogNotifier) Notify(notification *entity.Notification) error {
+	logger.LogInfo("LogNotifier", "Notify", notification.Subject, map[string]string{
+		"To":   notification.To,
+		"Body": notification.Body,
+	})
+	return nil
+}
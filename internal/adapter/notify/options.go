@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"zeneye-gateway/internal/domain/port"
+)
+
+// Backend selects how notifications are delivered.
+type Backend string
+
+const (
+	// BackendLog writes notifications to the log.
+	BackendLog Backend = "log"
+	// BackendSMTP sends them as plain-text email.
+	BackendSMTP Backend = "smtp"
+	// BackendWebhook posts them as JSON to a URL of your choice.
+	BackendWebhook Backend = "webhook"
+	// BackendService posts them as JSON to the notification microservice.
+	BackendService Backend = "service"
+)
+
+const (
+	defaultTimeout     = 10 * time.Second
+	defaultSMTPPort    = 587
+	defaultServicePath = "/notifications"
+)
+
+// Options configures notification delivery.
+type Options struct {
+	Backend Backend `yaml:"backend" toml:"backend"`
+	// Timeout bounds each delivery attempt.
+	Timeout time.Duration  `yaml:"timeout" toml:"timeout"`
+	SMTP    SMTPOptions    `yaml:"smtp" toml:"smtp"`
+	Webhook WebhookOptions `yaml:"webhook" toml:"webhook"`
+	Service ServiceOptions `yaml:"service" toml:"service"`
+}
+
+// SMTPOptions locates the mail server. Username and Password are optional;
+// without them the server must accept unauthenticated mail.
+type SMTPOptions struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password" log:"redact"`
+	From     string `yaml:"from" toml:"from"`
+}
+
+// WebhookOptions locates the webhook. With a Secret, every request carries an
+// X-Signature-256 header: "sha256=" and the hex HMAC-SHA256 of the body.
+type WebhookOptions struct {
+	URL    string `yaml:"url" toml:"url"`
+	Secret string `yaml:"secret" toml:"secret" log:"redact"`
+}
+
+// ServiceOptions locates the notification microservice. An empty URL means
+// the URL of the notify route.
+type ServiceOptions struct {
+	URL  string `yaml:"url" toml:"url"`
+	Path string `yaml:"path" toml:"path"`
+}
+
+// DefaultOptions logs notifications.
+func DefaultOptions() Options {
+	return Options{
+		Backend: BackendLog,
+		Timeout: defaultTimeout,
+		SMTP:    SMTPOptions{Port: defaultSMTPPort},
+		Service: ServiceOptions{Path: defaultServicePath},
+	}
+}
+
+// LoadEnv overrides o with NOTIFY_BACKEND, NOTIFY_TIMEOUT, SMTP_HOST,
+// SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM, NOTIFY_WEBHOOK_URL,
+// NOTIFY_WEBHOOK_SECRET and NOTIFY_SERVICE_PATH as found by lookup, usually
+// os.LookupEnv.
+func (o *Options) LoadEnv(lookup func(string) (string, bool)) error {
+	getenv := func(key string) string { v, _ := lookup(key); return v }
+	var errs []error
+	if v := getenv("NOTIFY_BACKEND"); v != "" {
+		o.Backend = Backend(v)
+	}
+	if v := getenv("NOTIFY_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("NOTIFY_TIMEOUT: %w", err))
+		} else {
+			o.Timeout = d
+		}
+	}
+	if v := getenv("SMTP_PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("SMTP_PORT: %w", err))
+		} else {
+			o.SMTP.Port = port
+		}
+	}
+	for key, target := range map[string]*string{
+		"SMTP_HOST":             &o.SMTP.Host,
+		"SMTP_USERNAME":         &o.SMTP.Username,
+		"SMTP_PASSWORD":         &o.SMTP.Password,
+		"SMTP_FROM":             &o.SMTP.From,
+		"NOTIFY_WEBHOOK_URL":    &o.Webhook.URL,
+		"NOTIFY_WEBHOOK_SECRET": &o.Webhook.Secret,
+		"NOTIFY_SERVICE_PATH":   &o.Service.Path,
+	} {
+		if v := getenv(key); v != "" {
+			*target = v
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid option of the selected backend at once.
+func (o Options) Validate() error {
+	var errs []error
+	if o.Timeout <= 0 {
+		errs = append(errs, errors.New("notify timeout must be positive"))
+	}
+	switch o.Backend {
+	case BackendLog:
+	case BackendSMTP:
+		if o.SMTP.Host == "" {
+			errs = append(errs, errors.New("smtp host must be set (SMTP_HOST)"))
+		}
+		if o.SMTP.Port < 1 || o.SMTP.Port > 65535 {
+			errs = append(errs, fmt.Errorf("smtp port %d is out of range", o.SMTP.Port))
+		}
+		if o.SMTP.From == "" || strings.ContainsAny(o.SMTP.From, "\r\n") {
+			errs = append(errs, errors.New("smtp from must be set (SMTP_FROM)"))
+		}
+	case BackendWebhook:
+		errs = append(errs, validateURL("webhook url", o.Webhook.URL))
+	case BackendService:
+		errs = append(errs, validateURL("notification service url", o.Service.URL))
+	default:
+		errs = append(errs, fmt.Errorf("notify backend %q must be log, smtp, webhook or service", o.Backend))
+	}
+	return errors.Join(errs...)
+}
+
+func validateURL(name, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s %q must be an absolute http(s) URL", name, raw)
+	}
+	return nil
+}
+
+// New returns the Notifier selected by opts, which must be valid.
+func New(opts Options) port.Notifier {
+	client := &http.Client{Timeout: opts.Timeout}
+	switch opts.Backend {
+	case BackendSMTP:
+		return NewSMTPNotifier(opts.SMTP, opts.Timeout)
+	case BackendWebhook:
+		return NewWebhookNotifier(opts.Webhook.URL, opts.Webhook.Secret, client)
+	case BackendService:
+		return NewServiceNotifier(strings.TrimSuffix(opts.Service.URL, "/")+opts.Service.Path, client)
+	default:
+		return NewLogNotifier()
+	}
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+)
+
+// SMTPNotifier sends notifications as plain-text email, upgrading the
+// connection with STARTTLS whenever the server offers it.
+type SMTPNotifier struct {
+	opts    SMTPOptions
+	timeout time.Duration
+}
+
+func NewSMTPNotifier(opts SMTPOptions, timeout time.Duration) port.Notifier {
+	return &SMTPNotifier{opts: opts, timeout: timeout}
+}
+
+func (n *SMTPNotifier) Notify(notification *entity.Notification) error {
+	// Header values come from user input; a line break would inject headers
+	if strings.ContainsAny(notification.To+notification.Subject, "\r\n") {
+		return errors.New("notification recipient and subject must be single lines")
+	}
+
+	addr := net.JoinHostPort(n.opts.Host, strconv.Itoa(n.opts.Port))
+	conn, err := net.DialTimeout("tcp", addr, n.timeout)
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(n.timeout)); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, n.opts.Host)
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.opts.Host}); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if n.opts.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.opts.Username, n.opts.Password, n.opts.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := client.Mail(n.opts.From); err != nil {
+		return fmt.Errorf("smtp mail: %w", err)
+	}
+	if err := client.Rcpt(notification.To); err != nil {
+		return fmt.Errorf("smtp rcpt: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(n.message(notification)); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	return client.Quit()
+}
+
+func (n *SMTPNotifier) message(notification *entity.Notification) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + n.opts.From + "\r\n")
+	b.WriteString("To: " + notification.To + "\r\n")
+	b.WriteString("Subject: " + notification.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(notification.Body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"errors"
+	"sync"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// auditLockKey is the Postgres advisory lock that serializes appends across
+// gateway instances; auditMu does the same within one process.
+const auditLockKey = 7_305_118_233
+
+var auditMu sync.Mutex
+
+// auditBatchSize bounds how many rows WalkEvents holds in memory.
+const auditBatchSize = 500
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) port.AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) AppendEvent(event *entity.AuditEvent) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditLockKey).Error; err != nil {
+				return err
+			}
+		}
+
+		var last entity.AuditEvent
+		err := tx.Order("id DESC").Limit(1).Take(&last).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			event.PrevHash = ""
+		case err != nil:
+			return err
+		default:
+			event.PrevHash = last.Hash
+		}
+		event.Hash = event.ComputeHash()
+		return tx.Create(event).Error
+	})
+	if err != nil {
+		logger.LogError("AuditRepository", "AppendEvent", event.Action, err)
+		return err
+	}
+	logger.LogInfo("AuditRepository", "AppendEvent", "Audit event appended", event.ID)
+	return nil
+}
+
+func (r *AuditRepository) ListEvents(filter entity.AuditFilter) ([]*entity.AuditEvent, error) {
+	var events []*entity.AuditEvent
+	query := applyAuditFilter(r.db.Model(&entity.AuditEvent{}), filter).Order("id DESC")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	if err := query.Find(&events).Error; err != nil {
+		logger.LogError("AuditRepository", "ListEvents", filter, err)
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *AuditRepository) WalkEvents(filter entity.AuditFilter, fn func(*entity.AuditEvent) error) error {
+	var lastID uint
+	for {
+		var batch []*entity.AuditEvent
+		err := applyAuditFilter(r.db.Model(&entity.AuditEvent{}), filter).
+			Where("id > ?", lastID).Order("id ASC").Limit(auditBatchSize).Find(&batch).Error
+		if err != nil {
+			logger.LogError("AuditRepository", "WalkEvents", filter, err)
+			return err
+		}
+		for _, event := range batch {
+			if err := fn(event); err != nil {
+				return err
+			}
+			lastID = event.ID
+		}
+		if len(batch) < auditBatchSize {
+			return nil
+		}
+	}
+}
+
+func applyAuditFilter(query *gorm.DB, filter entity.AuditFilter) *gorm.DB {
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor_username = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("occurred_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("occurred_at < ?", filter.To)
+	}
+	return query
+}
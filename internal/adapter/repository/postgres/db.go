@@ -4,23 +4,16 @@ import (
 	"database/sql"
 	"fmt"
 	"net/url"
-	"path/filepath"
 	"strings"
 	"zeneye-gateway/internal/adapter/service"
-	"zeneye-gateway/internal/domain/entity"
 	"zeneye-gateway/pkg/logger"
-	"zeneye-gateway/pkg/utils"
 
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-// NewDB initializes the database connection
-func NewDB() (*gorm.DB, error) {
-	dsn := utils.GetEnv("DATABASE_URL")
+// NewDB opens a connection to the database at dsn
+func NewDB(dsn string) (*gorm.DB, error) {
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		logger.LogError("NewDB", "Failed to open database", "", err)
@@ -41,19 +34,18 @@ func getDSNWithoutDB(dsn string) (string, string, error) {
 	return u.String(), dbName, nil
 }
 
-// checkAndCreateDatabase checks if the database exists and creates it if it doesn't
-func checkAndCreateDatabase() error {
-	dsn := utils.GetEnv("DATABASE_URL")
+// EnsureDatabase checks if the database exists and creates it if it doesn't
+func EnsureDatabase(dsn string) error {
 	dsnWithoutDB, dbName, err := getDSNWithoutDB(dsn)
 	if err != nil {
-		logger.LogError("checkAndCreateDatabase", "Failed to parse DSN", dsn, err)
+		logger.LogError("EnsureDatabase", "Failed to parse DSN", dsn, err)
 		return fmt.Errorf("failed to parse DSN: %w", err)
 	}
 
 	// Connect to the PostgreSQL server
 	db, err := sql.Open("postgres", dsnWithoutDB)
 	if err != nil {
-		logger.LogError("checkAndCreateDatabase", "Failed to connect to PostgreSQL server", dsnWithoutDB, err)
+		logger.LogError("EnsureDatabase", "Failed to connect to PostgreSQL server", dsnWithoutDB, err)
 		return fmt.Errorf("failed to connect to PostgreSQL server: %w", err)
 	}
 	defer db.Close()
@@ -63,7 +55,7 @@ func checkAndCreateDatabase() error {
 	query := fmt.Sprintf("SELECT EXISTS(SELECT datname FROM pg_catalog.pg_database WHERE datname = '%s')", dbName)
 	err = db.QueryRow(query).Scan(&exists)
 	if err != nil {
-		logger.LogError("checkAndCreateDatabase", "Failed to check if database exists", query, err)
+		logger.LogError("EnsureDatabase", "Failed to check if database exists", query, err)
 		return fmt.Errorf("failed to check if database exists: %w", err)
 	}
 
@@ -72,49 +64,28 @@ func checkAndCreateDatabase() error {
 		query = fmt.Sprintf("CREATE DATABASE %s", dbName)
 		_, err = db.Exec(query)
 		if err != nil {
-			logger.LogError("checkAndCreateDatabase", "Failed to create database", query, err)
+			logger.LogError("EnsureDatabase", "Failed to create database", query, err)
 			return fmt.Errorf("failed to create database: %w", err)
 		}
-		logger.LogInfo("checkAndCreateDatabase", "Database created successfully", dbName)
+		logger.LogInfo("EnsureDatabase", "Database created successfully", dbName)
 	}
 
 	return nil
 }
 
-// migrateDB runs the migrations using golang-migrate
-func migrateDB() {
-	logger.LogInfo("repository/db", "migrateDB", "Running Migration DB", "")
-
-	dsn := utils.GetEnv("DATABASE_URL")
-	migrationsDir, _ := filepath.Abs("db/migrations")
-	m, err := migrate.New(
-		"file://"+migrationsDir,
-		dsn,
-	)
-	if err != nil {
-		logger.LogFatal("migrateDB", "Could not start migration", "", err)
-	}
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		logger.LogFatal("migrateDB", "Could not run migration", "", err)
-	}
-	logger.LogInfo("repository/db", "migrateDB", "Migrations ran successfully", "")
-}
-
-// InitDB initializes the database and runs migrations
-func InitDB() *gorm.DB {
-	if err := checkAndCreateDatabase(); err != nil {
+// InitDB initializes the database at dsn and runs migrations. The schema
+// comes from the SQL migrations alone.
+func InitDB(dsn string) *gorm.DB {
+	if err := EnsureDatabase(dsn); err != nil {
 		logger.LogFatal("InitDB", "Failed to check and create database", "", err)
 	}
 
-	migrateDB()
-	db, err := NewDB()
+	migrateDB(dsn)
+	db, err := NewDB(dsn)
 	if err != nil {
 		logger.LogFatal("InitDB", "Failed to initialize database", "", err)
 	}
 
-	// Auto migrate the schemas
-	db.AutoMigrate(&entity.User{}, &entity.Session{}, &entity.RefreshToken{})
-
 	// Check if superadmin exists, and log the result
 	repo := NewUserRepository(db)
 	userService := service.NewUserService(repo)
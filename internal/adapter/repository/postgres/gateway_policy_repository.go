@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"errors"
+	"sync"
+	"zeneye-gateway/internal/domain/domainerr"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// policyLockKey is the Postgres advisory lock that serializes new policy
+// versions across gateway instances; policyMu does the same within one process.
+const policyLockKey = 7_305_118_234
+
+var policyMu sync.Mutex
+
+type GatewayPolicyRepository struct {
+	db *gorm.DB
+}
+
+func NewGatewayPolicyRepository(db *gorm.DB) port.GatewayPolicyRepository {
+	return &GatewayPolicyRepository{db: db}
+}
+
+func (r *GatewayPolicyRepository) CreateVersion(version *entity.GatewayPolicyVersion) error {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", policyLockKey).Error; err != nil {
+				return err
+			}
+		}
+
+		var latest uint
+		if err := tx.Model(&entity.GatewayPolicyVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		version.Version = latest + 1
+		return tx.Create(version).Error
+	})
+	if err != nil {
+		logger.LogError("GatewayPolicyRepository", "CreateVersion", version.Change, err)
+		return err
+	}
+	logger.LogInfo("GatewayPolicyRepository", "CreateVersion", "Gateway policy version created", version.Version)
+	return nil
+}
+
+func (r *GatewayPolicyRepository) GetVersion(version uint) (*entity.GatewayPolicyVersion, error) {
+	var found entity.GatewayPolicyVersion
+	err := r.db.Where("version = ?", version).Take(&found).Error
+	if err != nil {
+		logger.LogError("GatewayPolicyRepository", "GetVersion", version, err)
+		return nil, translateNotFound(err, domainerr.ErrVersionNotFound)
+	}
+	return &found, nil
+}
+
+func (r *GatewayPolicyRepository) LatestVersion() (*entity.GatewayPolicyVersion, error) {
+	var latest entity.GatewayPolicyVersion
+	err := r.db.Order("version DESC").Limit(1).Take(&latest).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, nil
+	case err != nil:
+		logger.LogError("GatewayPolicyRepository", "LatestVersion", "", err)
+		return nil, err
+	}
+	return &latest, nil
+}
+
+func (r *GatewayPolicyRepository) ListVersions(limit, offset int) ([]*entity.GatewayPolicyVersion, error) {
+	var versions []*entity.GatewayPolicyVersion
+	query := r.db.Order("version DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+	if err := query.Find(&versions).Error; err != nil {
+		logger.LogError("GatewayPolicyRepository", "ListVersions", limit, err)
+		return nil, err
+	}
+	return versions, nil
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	migrations "zeneye-gateway/db"
+	"zeneye-gateway/pkg/health"
+
+	"gorm.io/gorm"
+)
+
+// PingCheck reports whether the database answers.
+func PingCheck(db *gorm.DB) health.Check {
+	return health.Check{
+		Name:          "postgres",
+		ComponentType: "datastore",
+		Critical:      true,
+		Run: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+	}
+}
+
+// MigrationCheck reports whether the schema is clean and at the newest
+// migration in migrations. When they cannot be read only the dirty flag is checked.
+func MigrationCheck(db *gorm.DB, migrations fs.FS) health.Check {
+	latest, _ := latestMigrationVersion(migrations)
+	return health.Check{
+		Name:          "migrations",
+		ComponentType: "datastore",
+		Critical:      true,
+		Run: func(ctx context.Context) error {
+			var state struct {
+				Version uint
+				Dirty   bool
+			}
+			err := db.WithContext(ctx).Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&state).Error
+			if err != nil {
+				return fmt.Errorf("reading migration state: %w", err)
+			}
+			if state.Dirty {
+				return fmt.Errorf("migration %d is dirty", state.Version)
+			}
+			if state.Version < latest {
+				return fmt.Errorf("schema is at version %d, expected %d", state.Version, latest)
+			}
+			return nil
+		},
+	}
+}
+
+// HealthChecks returns the checks for db. Schema versions are only tracked
+// by golang-migrate on Postgres; other dialects are only used by tests.
+func HealthChecks(db *gorm.DB) []health.Check {
+	checks := []health.Check{PingCheck(db)}
+	if db.Dialector.Name() == "postgres" {
+		checks = append(checks, MigrationCheck(db, migrations.Migrations))
+	}
+	return checks
+}
+
+// latestMigrationVersion returns the highest NNNNNN_name.up.sql version in
+// migrations.
+func latestMigrationVersion(migrations fs.FS) (uint, error) {
+	entries, err := fs.ReadDir(migrations, ".")
+	if err != nil {
+		return 0, err
+	}
+	var latest uint
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			continue
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		latest = max(latest, uint(version))
+	}
+	if latest == 0 {
+		return 0, errors.New("no migrations found")
+	}
+	return latest, nil
+}
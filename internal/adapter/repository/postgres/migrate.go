@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"zeneye-gateway/db"
+	"zeneye-gateway/internal/domain/domainerr"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Migrator applies the migrations embedded in the binary to one database. It
+// implements port.Migrator.
+type Migrator struct {
+	m   *migrate.Migrate
+	src source.Driver
+}
+
+// NewMigrator opens the embedded migrations and the database at dsn. Close
+// releases both.
+func NewMigrator(dsn string) (*Migrator, error) {
+	src, err := iofs.New(db.Migrations, ".")
+	if err != nil {
+		return nil, fmt.Errorf("open migrations: %w", err)
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", src, dsn)
+	if err != nil {
+		src.Close()
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	return &Migrator{m: m, src: src}, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up() error {
+	return translateMigrateErr(m.m.Up())
+}
+
+// Down reverts the latest steps migrations. It refuses to revert more than
+// are applied.
+func (m *Migrator) Down(steps int) error {
+	current, _, err := m.Version()
+	if err != nil {
+		return err
+	}
+	versions, err := m.versions()
+	if err != nil {
+		return err
+	}
+	applied := 0
+	for _, version := range versions {
+		if version <= current {
+			applied++
+		}
+	}
+	if steps <= 0 || steps > applied {
+		return domainerr.Validation(domainerr.FieldError{
+			Field:   "steps",
+			Code:    domainerr.CodeInvalidSteps,
+			Message: fmt.Sprintf("steps must be between 1 and the %d applied migrations", applied),
+		})
+	}
+	return translateMigrateErr(m.m.Steps(-steps))
+}
+
+// Goto applies or reverts migrations until version is the latest applied;
+// zero reverts them all.
+func (m *Migrator) Goto(version uint) error {
+	if version == 0 {
+		return translateMigrateErr(m.m.Down())
+	}
+	if err := m.exists(version); err != nil {
+		return err
+	}
+	return translateMigrateErr(m.m.Migrate(version))
+}
+
+// Force records version as applied and clean without running any migration,
+// after a failed migration was repaired by hand; zero records that none is.
+func (m *Migrator) Force(version uint) error {
+	if version == 0 {
+		return m.m.Force(database.NilVersion)
+	}
+	if err := m.exists(version); err != nil {
+		return err
+	}
+	return m.m.Force(int(version))
+}
+
+// Version returns the latest applied migration, zero when there is none, and
+// whether it failed halfway and left the schema dirty.
+func (m *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Status lists every migration in order, marking those up to the current
+// version as applied.
+func (m *Migrator) Status() ([]entity.MigrationStatus, error) {
+	current, _, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	versions, err := m.versions()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]entity.MigrationStatus, 0, len(versions))
+	for _, version := range versions {
+		name, err := m.name(version)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, entity.MigrationStatus{Version: version, Name: name, Applied: version <= current})
+	}
+	return statuses, nil
+}
+
+// versions returns the version of every migration in order.
+func (m *Migrator) versions() ([]uint, error) {
+	var versions []uint
+	version, err := m.src.First()
+	for err == nil {
+		versions = append(versions, version)
+		version, err = m.src.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// exists reports ErrMigrationNotFound unless there is a migration version.
+func (m *Migrator) exists(version uint) error {
+	versions, err := m.versions()
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(versions, version) {
+		return domainerr.ErrMigrationNotFound
+	}
+	return nil
+}
+
+// name returns the identifier of migration version, e.g. create_users_table.
+func (m *Migrator) name(version uint) (string, error) {
+	r, name, err := m.src.ReadUp(version)
+	if err != nil {
+		return "", err
+	}
+	r.Close()
+	return name, nil
+}
+
+// Close releases the migrations and the database connection.
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+// translateMigrateErr treats having nothing to do as success and reports a
+// dirty schema as domainerr.ErrSchemaDirty.
+func translateMigrateErr(err error) error {
+	var dirty migrate.ErrDirty
+	switch {
+	case errors.Is(err, migrate.ErrNoChange):
+		return nil
+	case errors.As(err, &dirty):
+		return domainerr.ErrSchemaDirty.Wrap(err)
+	}
+	return err
+}
+
+// migrateDB runs the migrations using golang-migrate
+func migrateDB(dsn string) {
+	logger.LogInfo("repository/db", "migrateDB", "Running Migration DB", "")
+
+	m, err := NewMigrator(dsn)
+	if err != nil {
+		logger.LogFatal("migrateDB", "Could not start migration", "", err)
+	}
+	defer m.Close()
+	if err := m.Up(); err != nil {
+		logger.LogFatal("migrateDB", "Could not run migration", "", err)
+	}
+	logger.LogInfo("repository/db", "migrateDB", "Migrations ran successfully", "")
+}
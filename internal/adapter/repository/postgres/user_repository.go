@@ -1,6 +1,10 @@
 package postgres
 
 import (
+	"errors"
+	"strings"
+	"time"
+	"zeneye-gateway/internal/domain/domainerr"
 	"zeneye-gateway/internal/domain/entity"
 	"zeneye-gateway/internal/domain/port"
 	"zeneye-gateway/pkg/logger"
@@ -39,8 +43,15 @@ func (r *UserRepository) EditUser(user *entity.User) error {
 	return err
 }
 
+// DeleteUser soft-deletes the user, so audit events keep pointing at a row and
+// RestoreUser can bring it back, and revokes the user's refresh tokens.
 func (r *UserRepository) DeleteUser(id uint) error {
-	err := r.db.Delete(&entity.User{}, id).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := setStatus(tx, id, entity.UserStatusDeleted); err != nil {
+			return err
+		}
+		return tx.Delete(&entity.User{}, id).Error
+	})
 	if err != nil {
 		logger.LogError("UserRepository", "DeleteUser", id, err)
 	} else {
@@ -49,12 +60,119 @@ func (r *UserRepository) DeleteUser(id uint) error {
 	return err
 }
 
+func (r *UserRepository) SetUserStatus(id uint, status string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return setStatus(tx, id, status)
+	})
+	if err != nil {
+		logger.LogError("UserRepository", "SetUserStatus", id, err)
+		return err
+	}
+	logger.LogInfo("UserRepository", "SetUserStatus", "User status changed to "+status, id)
+	return nil
+}
+
+// setStatus sets the status of a user that is not deleted. Only active users
+// keep their refresh tokens.
+func setStatus(tx *gorm.DB, id uint, status string) error {
+	result := tx.Model(&entity.User{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domainerr.ErrUserNotFound
+	}
+	if status == entity.UserStatusActive {
+		return nil
+	}
+	return tx.Where("user_id = ?", id).Delete(&entity.RefreshToken{}).Error
+}
+
+func (r *UserRepository) GetDeletedUser(id uint) (*entity.User, error) {
+	var user entity.User
+	err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&user).Error
+	if err != nil {
+		logger.LogError("UserRepository", "GetDeletedUser", id, err)
+		return nil, translateNotFound(err, domainerr.ErrUserNotFound)
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) RestoreUser(id uint) error {
+	result := r.db.Unscoped().Model(&entity.User{}).Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "status": entity.UserStatusActive})
+	if result.Error != nil {
+		logger.LogError("UserRepository", "RestoreUser", id, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		logger.LogError("UserRepository", "RestoreUser", id, domainerr.ErrUserNotFound)
+		return domainerr.ErrUserNotFound
+	}
+	logger.LogInfo("UserRepository", "RestoreUser", "User restored successfully", id)
+	return nil
+}
+
+func (r *UserRepository) CountActiveSuperadmins() (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.User{}).Where("role = ? AND status = ?", "superadmin", entity.UserStatusActive).Count(&count).Error
+	if err != nil {
+		logger.LogError("UserRepository", "CountActiveSuperadmins", "", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *UserRepository) ChangeUserRole(id uint, role string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return changeRole(tx, id, role)
+	})
+	if err != nil {
+		logger.LogError("UserRepository", "ChangeUserRole", id, err)
+		return err
+	}
+	logger.LogInfo("UserRepository", "ChangeUserRole", "User role changed to "+role, id)
+	return nil
+}
+
+func (r *UserRepository) RevokeTokens(id uint) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return revokeTokens(tx, id, map[string]interface{}{})
+	})
+	if err != nil {
+		logger.LogError("UserRepository", "RevokeTokens", id, err)
+		return err
+	}
+	logger.LogInfo("UserRepository", "RevokeTokens", "User tokens revoked", id)
+	return nil
+}
+
+// changeRole sets the role of a user that is not deleted and revokes all of
+// their tokens.
+func changeRole(tx *gorm.DB, id uint, role string) error {
+	return revokeTokens(tx, id, map[string]interface{}{"role": role})
+}
+
+// revokeTokens applies updates to a user that is not deleted, bumps their
+// token version and deletes their refresh tokens.
+func revokeTokens(tx *gorm.DB, id uint, updates map[string]interface{}) error {
+	updates["token_version"] = gorm.Expr("token_version + 1")
+	result := tx.Model(&entity.User{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domainerr.ErrUserNotFound
+	}
+	return tx.Where("user_id = ?", id).Delete(&entity.RefreshToken{}).Error
+}
+
 func (r *UserRepository) GetUser(id uint) (*entity.User, error) {
 	var user entity.User
 	err := r.db.First(&user, id).Error
 	if err != nil {
 		logger.LogError("UserRepository", "GetUser", id, err)
-		return nil, err
+		return nil, translateNotFound(err, domainerr.ErrUserNotFound)
 	}
 	logger.LogInfo("UserRepository", "GetUser", "User retrieved successfully", user)
 	return &user, nil
@@ -65,18 +183,29 @@ func (r *UserRepository) GetUserByUsername(username string) (*entity.User, error
 	err := r.db.Where("username = ?", username).First(&user).Error
 	if err != nil {
 		logger.LogError("UserRepository", "GetUserByUsername", username, err)
-		return nil, err
+		return nil, translateNotFound(err, domainerr.ErrUserNotFound)
 	}
 	logger.LogInfo("UserRepository", "GetUserByUsername", "User retrieved successfully", user)
 	return &user, nil
 }
 
+func (r *UserRepository) GetUserByEmail(email string) (*entity.User, error) {
+	var user entity.User
+	err := r.db.Where("email = ?", email).First(&user).Error
+	if err != nil {
+		logger.LogError("UserRepository", "GetUserByEmail", email, err)
+		return nil, translateNotFound(err, domainerr.ErrUserNotFound)
+	}
+	logger.LogInfo("UserRepository", "GetUserByEmail", "User retrieved successfully", user)
+	return &user, nil
+}
+
 func (r *UserRepository) DeleteRefreshToken(token string) error {
 	err := r.db.Where("token = ?", token).Delete(&entity.RefreshToken{}).Error
 	if err != nil {
-		logger.LogError("UserRepository", "DeleteRefreshToken", token, err)
+		logger.LogError("UserRepository", "DeleteRefreshToken", "", err)
 	} else {
-		logger.LogInfo("UserRepository", "DeleteRefreshToken", "Refresh token deleted successfully", token)
+		logger.LogInfo("UserRepository", "DeleteRefreshToken", "Refresh token deleted successfully", "")
 	}
 	return err
 }
@@ -106,9 +235,9 @@ func (r *UserRepository) IsEmailExists(email string) (bool, error) {
 func (r *UserRepository) CreateRefreshToken(token *entity.RefreshToken) error {
 	err := r.db.Create(token).Error
 	if err != nil {
-		logger.LogError("UserRepository", "CreateRefreshToken", token, err)
+		logger.LogError("UserRepository", "CreateRefreshToken", token.UserID, err)
 	} else {
-		logger.LogInfo("UserRepository", "CreateRefreshToken", "Refresh token created successfully", token)
+		logger.LogInfo("UserRepository", "CreateRefreshToken", "Refresh token created successfully", token.UserID)
 	}
 	return err
 }
@@ -117,20 +246,291 @@ func (r *UserRepository) GetRefreshToken(refreshToken string) (*entity.RefreshTo
 	var token entity.RefreshToken
 	err := r.db.Where("token = ?", refreshToken).First(&token).Error
 	if err != nil {
-		logger.LogError("UserRepository", "GetRefreshToken", refreshToken, err)
+		logger.LogError("UserRepository", "GetRefreshToken", "", err)
 		return nil, err
 	}
-	logger.LogInfo("UserRepository", "GetRefreshToken", "Refresh token retrieved successfully", token)
+	logger.LogInfo("UserRepository", "GetRefreshToken", "Refresh token retrieved successfully", token.UserID)
 	return &token, nil
 }
 
-func (r *UserRepository) GetAllUsers() ([]*entity.User, error) {
-	var users []*entity.User
-	err := r.db.Find(&users).Error
+func (r *UserRepository) ChangePassword(userID uint, hash string, keep int) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return changePassword(tx, userID, hash, keep)
+	})
+	if err != nil {
+		logger.LogError("UserRepository", "ChangePassword", userID, err)
+		return err
+	}
+	logger.LogInfo("UserRepository", "ChangePassword", "Password changed and tokens revoked", userID)
+	return nil
+}
+
+// changePassword moves the current password to the history, keeping the
+// newest keep entries, stores hash and revokes every token issued to the
+// user, access tokens included.
+func changePassword(tx *gorm.DB, userID uint, hash string, keep int) error {
+	var user entity.User
+	if err := tx.Select("id", "password").First(&user, userID).Error; err != nil {
+		return translateNotFound(err, domainerr.ErrUserNotFound)
+	}
+	if err := tx.Create(&entity.PasswordHistory{UserID: userID, Password: user.Password}).Error; err != nil {
+		return err
+	}
+	// Drop all but the newest keep history entries
+	stale := tx.Model(&entity.PasswordHistory{}).Select("id").Where("user_id = ?", userID).Order("id DESC").Offset(keep).Limit(-1)
+	if err := tx.Where("user_id = ? AND id IN (?)", userID, stale).Delete(&entity.PasswordHistory{}).Error; err != nil {
+		return err
+	}
+	return revokeTokens(tx, userID, map[string]interface{}{"password": hash})
+}
+
+func (r *UserRepository) GetPasswordHistory(userID uint, limit int) ([]*entity.PasswordHistory, error) {
+	var history []*entity.PasswordHistory
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Limit(limit).Find(&history).Error
 	if err != nil {
-		logger.LogError("UserRepository", "GetAllUsers", "Retrieving all users", err)
+		logger.LogError("UserRepository", "GetPasswordHistory", userID, err)
 		return nil, err
 	}
-	logger.LogInfo("UserRepository", "GetAllUsers", "All users retrieved successfully", users)
-	return users, nil
+	return history, nil
+}
+
+func (r *UserRepository) CreateEmailChange(change *entity.EmailChange) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", change.UserID).Delete(&entity.EmailChange{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+	if err != nil {
+		logger.LogError("UserRepository", "CreateEmailChange", change.UserID, err)
+		return err
+	}
+	logger.LogInfo("UserRepository", "CreateEmailChange", "Email change requested", change.UserID)
+	return nil
+}
+
+func (r *UserRepository) GetEmailChange(userID uint, tokenHash string) (*entity.EmailChange, error) {
+	var change entity.EmailChange
+	err := r.db.Where("user_id = ? AND token_hash = ?", userID, tokenHash).First(&change).Error
+	if err != nil {
+		logger.LogError("UserRepository", "GetEmailChange", userID, err)
+		return nil, translateNotFound(err, domainerr.ErrInvalidToken)
+	}
+	return &change, nil
+}
+
+func (r *UserRepository) CompleteEmailChange(change *entity.EmailChange) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.User{}).Where("id = ?", change.UserID).Update("email", change.NewEmail).Error; err != nil {
+			return err
+		}
+		// Reset tokens were sent to the old address and must not outlive it
+		if err := tx.Where("user_id = ?", change.UserID).Delete(&entity.PasswordReset{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", change.UserID).Delete(&entity.EmailChange{}).Error
+	})
+	if err != nil {
+		logger.LogError("UserRepository", "CompleteEmailChange", change.UserID, err)
+		return err
+	}
+	logger.LogInfo("UserRepository", "CompleteEmailChange", "Email changed", change.UserID)
+	return nil
+}
+
+func (r *UserRepository) CreatePasswordReset(reset *entity.PasswordReset) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", reset.UserID).Delete(&entity.PasswordReset{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(reset).Error
+	})
+	if err != nil {
+		logger.LogError("UserRepository", "CreatePasswordReset", reset.UserID, err)
+		return err
+	}
+	logger.LogInfo("UserRepository", "CreatePasswordReset", "Password reset requested", reset.UserID)
+	return nil
+}
+
+func (r *UserRepository) GetPasswordReset(tokenHash string) (*entity.PasswordReset, error) {
+	var reset entity.PasswordReset
+	err := r.db.Where("token_hash = ?", tokenHash).First(&reset).Error
+	if err != nil {
+		logger.LogError("UserRepository", "GetPasswordReset", "", err)
+		return nil, translateNotFound(err, domainerr.ErrInvalidToken)
+	}
+	return &reset, nil
+}
+
+func (r *UserRepository) ResetPassword(reset *entity.PasswordReset, hash string, keep int) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Whoever deletes the reset first uses it; a concurrent attempt finds nothing
+		result := tx.Where("id = ?", reset.ID).Delete(&entity.PasswordReset{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domainerr.ErrInvalidToken
+		}
+		return changePassword(tx, reset.UserID, hash, keep)
+	})
+	if err != nil {
+		logger.LogError("UserRepository", "ResetPassword", reset.UserID, err)
+		return err
+	}
+	logger.LogInfo("UserRepository", "ResetPassword", "Password reset and tokens revoked", reset.UserID)
+	return nil
+}
+
+func (r *UserRepository) ListUsers(filter entity.UserFilter) ([]*entity.User, int64, error) {
+	var total int64
+	if err := applyUserFilter(r.db.Model(&entity.User{}), filter).Count(&total).Error; err != nil {
+		logger.LogError("UserRepository", "ListUsers", "Counting users", err)
+		return nil, 0, err
+	}
+
+	sort, direction, after := filter.Sort, "ASC", ">"
+	if sort == "" {
+		sort = entity.UserSortID
+	}
+	if filter.Desc {
+		direction, after = "DESC", "<"
+	}
+	query := applyUserFilter(r.db.Model(&entity.User{}), filter)
+	if cursor := filter.After; cursor != nil {
+		if sort == entity.UserSortID {
+			query = query.Where("id "+after+" ?", cursor.ID)
+		} else {
+			value := cursorValue(cursor)
+			query = query.Where(sort+" "+after+" ? OR ("+sort+" = ? AND id "+after+" ?)", value, value, cursor.ID)
+		}
+	}
+	if sort != entity.UserSortID {
+		query = query.Order(sort + " " + direction)
+	}
+	query = query.Order("id " + direction)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 && filter.After == nil {
+		query = query.Offset(filter.Offset)
+	}
+
+	var users []*entity.User
+	if err := query.Find(&users).Error; err != nil {
+		logger.LogError("UserRepository", "ListUsers", "Retrieving users", err)
+		return nil, 0, err
+	}
+	logger.LogInfo("UserRepository", "ListUsers", "Users retrieved successfully", len(users))
+	return users, total, nil
+}
+
+// applyUserFilter narrows query to the users matching filter, leaving out its
+// order, page and cursor. Sort names must already be validated.
+func applyUserFilter(query *gorm.DB, filter entity.UserFilter) *gorm.DB {
+	switch filter.Status {
+	case "":
+	case entity.UserStatusDeleted:
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	default:
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.EmailDomain != "" {
+		query = query.Where(`LOWER(email) LIKE ? ESCAPE '\'`, "%@"+escapeLike(strings.ToLower(filter.EmailDomain)))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query = query.Where("created_at < ?", filter.CreatedTo)
+	}
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(filter.Search)) + "%"
+		query = query.Where(`LOWER(username) LIKE ? ESCAPE '\' OR LOWER(email) LIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+	return query
+}
+
+// cursorValue returns the sort value of cursor typed like its column.
+func cursorValue(cursor *entity.UserCursor) interface{} {
+	if cursor.Sort == entity.UserSortCreatedAt {
+		t, _ := time.Parse(time.RFC3339Nano, cursor.Value)
+		return t
+	}
+	return cursor.Value
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// translateNotFound maps gorm's not-found error to the domain sentinel, so
+// callers never depend on the ORM.
+func translateNotFound(err error, notFound *domainerr.Error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound.Wrap(err)
+	}
+	return err
+}
+
+func (r *UserRepository) CreateSuperadminTransfer(transfer *entity.SuperadminTransfer) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&entity.SuperadminTransfer{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(transfer).Error
+	})
+	if err != nil {
+		logger.LogError("UserRepository", "CreateSuperadminTransfer", transfer, err)
+		return err
+	}
+	logger.LogInfo("UserRepository", "CreateSuperadminTransfer", "Superadmin transfer stored", transfer)
+	return nil
+}
+
+func (r *UserRepository) GetSuperadminTransfer() (*entity.SuperadminTransfer, error) {
+	var transfer entity.SuperadminTransfer
+	err := r.db.Order("id desc").First(&transfer).Error
+	if err != nil {
+		logger.LogError("UserRepository", "GetSuperadminTransfer", "", err)
+		return nil, translateNotFound(err, domainerr.ErrTransferNotFound)
+	}
+	return &transfer, nil
+}
+
+func (r *UserRepository) DeleteSuperadminTransfer() error {
+	err := r.db.Where("1 = 1").Delete(&entity.SuperadminTransfer{}).Error
+	if err != nil {
+		logger.LogError("UserRepository", "DeleteSuperadminTransfer", "", err)
+		return err
+	}
+	logger.LogInfo("UserRepository", "DeleteSuperadminTransfer", "Superadmin transfer deleted", "")
+	return nil
+}
+
+func (r *UserRepository) CompleteSuperadminTransfer(transfer *entity.SuperadminTransfer, demoteTo string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&entity.SuperadminTransfer{}, transfer.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domainerr.ErrTransferNotFound
+		}
+		if err := changeRole(tx, transfer.FromUserID, demoteTo); err != nil {
+			return err
+		}
+		return changeRole(tx, transfer.ToUserID, entity.RoleSuperadmin)
+	})
+	if err != nil {
+		logger.LogError("UserRepository", "CompleteSuperadminTransfer", transfer, err)
+		return err
+	}
+	logger.LogInfo("UserRepository", "CompleteSuperadminTransfer", "Superadmin role transferred", transfer)
+	return nil
 }
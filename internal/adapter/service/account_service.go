@@ -0,0 +1,341 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"zeneye-gateway/internal/domain/domainerr"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/logger"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// PasswordHistoryDepth is how many previous passwords, besides the current
+	// one, cannot be reused.
+	PasswordHistoryDepth = 5
+	// EmailChangeTTL is how long an email confirmation token stays valid.
+	EmailChangeTTL = 24 * time.Hour
+	// PasswordResetTTL is how long a password reset token stays valid.
+	PasswordResetTTL = time.Hour
+)
+
+type AccountService struct {
+	repo     port.UserRepository
+	notifier port.Notifier
+	// jobs issues password reset tokens after the request is answered.
+	jobs port.JobQueue
+}
+
+func NewAccountService(repo port.UserRepository, notifier port.Notifier, jobs port.JobQueue) port.AccountService {
+	return &AccountService{repo: repo, notifier: notifier, jobs: jobs}
+}
+
+func (s *AccountService) ChangePassword(userID uint, current, next string) error {
+	logger.LogInfo("AccountService", "ChangePassword", "Changing password", userID)
+
+	user, err := s.checkPassword(userID, "current_password", current)
+	if err != nil {
+		return err
+	}
+
+	hashed, err := s.hashNewPassword(user, next)
+	if err != nil {
+		logger.LogError("AccountService", "ChangePassword", userID, err)
+		return err
+	}
+	if err := s.repo.ChangePassword(userID, hashed, PasswordHistoryDepth); err != nil {
+		logger.LogError("AccountService", "ChangePassword", userID, err)
+		return err
+	}
+
+	logger.LogInfo("AccountService", "ChangePassword", "Password changed successfully", userID)
+	return nil
+}
+
+func (s *AccountService) RequestEmailChange(userID uint, password, email string) error {
+	logger.LogInfo("AccountService", "RequestEmailChange", "Requesting email change", userID)
+
+	user, err := s.checkPassword(userID, "password", password)
+	if err != nil {
+		return err
+	}
+	return s.requestEmailChange(user, email)
+}
+
+func (s *AccountService) StartEmailChange(userID uint, email string) error {
+	logger.LogInfo("AccountService", "StartEmailChange", "Starting email change", userID)
+
+	user, err := s.repo.GetUser(userID)
+	if err != nil {
+		logger.LogError("AccountService", "StartEmailChange", userID, err)
+		return err
+	}
+	return s.requestEmailChange(user, email)
+}
+
+// requestEmailChange stores a pending change of user's email to email and
+// sends its confirmation token to the new address.
+func (s *AccountService) requestEmailChange(user *entity.User, email string) error {
+	userID := user.ID
+	if strings.EqualFold(user.Email, email) {
+		err := domainerr.Validation(domainerr.FieldError{Field: "email", Code: domainerr.CodeInvalidEmail, Message: "email is already the current email"})
+		logger.LogError("AccountService", "RequestEmailChange", userID, err)
+		return err
+	}
+	if err := s.checkEmailFree(email); err != nil {
+		return err
+	}
+
+	token, tokenHash, err := newAccountToken()
+	if err != nil {
+		logger.LogError("AccountService", "RequestEmailChange", userID, err)
+		return err
+	}
+	change := &entity.EmailChange{UserID: userID, NewEmail: email, TokenHash: tokenHash, ExpiresAt: time.Now().Add(EmailChangeTTL)}
+	if err := s.repo.CreateEmailChange(change); err != nil {
+		logger.LogError("AccountService", "RequestEmailChange", userID, err)
+		return err
+	}
+
+	err = s.notifier.Notify(&entity.Notification{
+		To:      email,
+		Subject: "Confirm your new email address",
+		Body:    "Confirm this address for " + user.Username + " with the token " + token + ". It expires in " + EmailChangeTTL.String() + ".",
+	})
+	if err != nil {
+		logger.LogError("AccountService", "RequestEmailChange", userID, err)
+		return err
+	}
+	// The current address learns about the change too, in case it was not the owner's doing
+	if err := s.notifier.Notify(&entity.Notification{
+		To:      user.Email,
+		Subject: "Email change requested",
+		Body:    "A change of the email address of " + user.Username + " was requested. If this was not you, change your password.",
+	}); err != nil {
+		logger.LogError("AccountService", "RequestEmailChange", userID, err)
+	}
+
+	logger.LogInfo("AccountService", "RequestEmailChange", "Email change confirmation sent", userID)
+	return nil
+}
+
+func (s *AccountService) ConfirmEmailChange(userID uint, token string) (*entity.User, error) {
+	logger.LogInfo("AccountService", "ConfirmEmailChange", "Confirming email change", userID)
+
+	change, err := s.repo.GetEmailChange(userID, hashAccountToken(token))
+	if err != nil {
+		logger.LogError("AccountService", "ConfirmEmailChange", userID, err)
+		return nil, err
+	}
+	if change.ExpiresAt.Before(time.Now()) {
+		logger.LogError("AccountService", "ConfirmEmailChange", userID, domainerr.ErrInvalidToken)
+		return nil, domainerr.ErrInvalidToken
+	}
+	// The address may have been taken since the change was requested
+	if err := s.checkEmailFree(change.NewEmail); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CompleteEmailChange(change); err != nil {
+		logger.LogError("AccountService", "ConfirmEmailChange", userID, err)
+		return nil, err
+	}
+
+	logger.LogInfo("AccountService", "ConfirmEmailChange", "Email changed successfully", userID)
+	return s.repo.GetUser(userID)
+}
+
+func (s *AccountService) RequestPasswordReset(email string) error {
+	logger.LogInfo("AccountService", "RequestPasswordReset", "Requesting password reset", email)
+
+	user, err := s.repo.GetUserByEmail(email)
+	if errors.Is(err, domainerr.ErrUserNotFound) {
+		logger.LogInfo("AccountService", "RequestPasswordReset", "No account with this email", email)
+		return nil
+	}
+	if err != nil {
+		logger.LogError("AccountService", "RequestPasswordReset", email, err)
+		return err
+	}
+
+	if !user.Active() {
+		logger.LogInfo("AccountService", "RequestPasswordReset", "Account is not active", user.ID)
+		return nil
+	}
+
+	// Issuing and delivering the token run in the background so the response
+	// takes as long whether or not the account exists. A reset that cannot be
+	// queued is only logged, for the same reason.
+	if err := s.jobs.Submit(func() { s.issuePasswordReset(user) }); err != nil {
+		logger.LogError("AccountService", "RequestPasswordReset", user.ID, err)
+	}
+	return nil
+}
+
+// issuePasswordReset stores a new reset token for user and sends it to the
+// user's email. Failures are logged: the caller has already been answered.
+func (s *AccountService) issuePasswordReset(user *entity.User) {
+	token, tokenHash, err := newAccountToken()
+	if err != nil {
+		logger.LogError("AccountService", "RequestPasswordReset", user.ID, err)
+		return
+	}
+	reset := &entity.PasswordReset{UserID: user.ID, TokenHash: tokenHash, ExpiresAt: time.Now().Add(PasswordResetTTL)}
+	if err := s.repo.CreatePasswordReset(reset); err != nil {
+		logger.LogError("AccountService", "RequestPasswordReset", user.ID, err)
+		return
+	}
+
+	err = s.notifier.Notify(&entity.Notification{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    "Reset the password of " + user.Username + " with the token " + token + ". It expires in " + PasswordResetTTL.String() + ". If you did not ask for this, ignore this message.",
+	})
+	if err != nil {
+		logger.LogError("AccountService", "RequestPasswordReset", user.ID, err)
+		return
+	}
+	logger.LogInfo("AccountService", "RequestPasswordReset", "Password reset token issued", user.ID)
+}
+
+func (s *AccountService) ResetPassword(token, password string) (*entity.User, error) {
+	logger.LogInfo("AccountService", "ResetPassword", "Resetting password", "")
+
+	reset, err := s.repo.GetPasswordReset(hashAccountToken(token))
+	if err != nil {
+		logger.LogError("AccountService", "ResetPassword", "", err)
+		return nil, err
+	}
+	if reset.ExpiresAt.Before(time.Now()) {
+		logger.LogError("AccountService", "ResetPassword", reset.UserID, domainerr.ErrInvalidToken)
+		return nil, domainerr.ErrInvalidToken
+	}
+	user, err := s.repo.GetUser(reset.UserID)
+	if err != nil {
+		logger.LogError("AccountService", "ResetPassword", reset.UserID, err)
+		return nil, err
+	}
+	if err := statusError(user); err != nil {
+		logger.LogError("AccountService", "ResetPassword", reset.UserID, err)
+		return nil, err
+	}
+
+	hashed, err := s.hashNewPassword(user, password)
+	if err != nil {
+		logger.LogError("AccountService", "ResetPassword", user.ID, err)
+		return nil, err
+	}
+	if err := s.repo.ResetPassword(reset, hashed, PasswordHistoryDepth); err != nil {
+		logger.LogError("AccountService", "ResetPassword", user.ID, err)
+		return nil, err
+	}
+
+	logger.LogInfo("AccountService", "ResetPassword", "Password reset successfully", user.ID)
+	return user, nil
+}
+
+func (s *AccountService) SetPassword(userID uint, password string) (*entity.User, error) {
+	logger.LogInfo("AccountService", "SetPassword", "Setting password", userID)
+
+	user, err := s.repo.GetUser(userID)
+	if err != nil {
+		logger.LogError("AccountService", "SetPassword", userID, err)
+		return nil, err
+	}
+	if err := statusError(user); err != nil {
+		logger.LogError("AccountService", "SetPassword", userID, err)
+		return nil, err
+	}
+
+	hashed, err := s.hashNewPassword(user, password)
+	if err != nil {
+		logger.LogError("AccountService", "SetPassword", userID, err)
+		return nil, err
+	}
+	if err := s.repo.ChangePassword(userID, hashed, PasswordHistoryDepth); err != nil {
+		logger.LogError("AccountService", "SetPassword", userID, err)
+		return nil, err
+	}
+
+	logger.LogInfo("AccountService", "SetPassword", "Password set successfully", userID)
+	return user, nil
+}
+
+// hashNewPassword hashes next unless it matches the user's current password
+// or one in their history.
+func (s *AccountService) hashNewPassword(user *entity.User, next string) (string, error) {
+	history, err := s.repo.GetPasswordHistory(user.ID, PasswordHistoryDepth)
+	if err != nil {
+		return "", err
+	}
+	for _, hash := range append([]string{user.Password}, passwordHashes(history)...) {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(next)) == nil {
+			return "", domainerr.Validation(domainerr.FieldError{Field: "new_password", Code: domainerr.CodePasswordReused,
+				Message: "new password must differ from the current and recent passwords"})
+		}
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(next), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// checkPassword loads the user and reports a validation error on field when
+// password is not theirs.
+func (s *AccountService) checkPassword(userID uint, field, password string) (*entity.User, error) {
+	user, err := s.repo.GetUser(userID)
+	if err != nil {
+		logger.LogError("AccountService", "checkPassword", userID, err)
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		err := domainerr.Validation(domainerr.FieldError{Field: field, Code: domainerr.CodeWrongPassword, Message: field + " is incorrect"})
+		logger.LogError("AccountService", "checkPassword", userID, err)
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *AccountService) checkEmailFree(email string) error {
+	taken, err := s.repo.IsEmailExists(email)
+	if err != nil {
+		logger.LogError("AccountService", "checkEmailFree", email, err)
+		return err
+	}
+	if taken {
+		logger.LogError("AccountService", "checkEmailFree", email, domainerr.ErrEmailTaken)
+		return domainerr.ErrEmailTaken
+	}
+	return nil
+}
+
+func passwordHashes(history []*entity.PasswordHistory) []string {
+	hashes := make([]string, 0, len(history))
+	for _, entry := range history {
+		hashes = append(hashes, entry.Password)
+	}
+	return hashes
+}
+
+// newAccountToken returns a random token for the user to present and the hash
+// stored in its place.
+func newAccountToken() (token, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashAccountToken(token), nil
+}
+
+func hashAccountToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"errors"
+	"time"
+	"unicode/utf8"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/logger"
+)
+
+// Audit query page sizes.
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+// Column widths for the free-form fields, some of which (like the username of
+// a failed login or the target ID in a request path) come straight from the
+// client.
+const (
+	maxAuditUsername = 32
+	maxAuditRole     = 32
+	maxAuditTargetID = 64
+	maxAuditIP       = 64
+	maxAuditReason   = 256
+)
+
+// errStopWalk ends a chain walk early once a broken link has been found.
+var errStopWalk = errors.New("stop walk")
+
+type AuditService struct {
+	repo port.AuditRepository
+}
+
+func NewAuditService(repo port.AuditRepository) port.AuditService {
+	return &AuditService{repo: repo}
+}
+
+func (s *AuditService) Record(event *entity.AuditEvent) error {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	event.OccurredAt = event.OccurredAt.UTC().Truncate(time.Microsecond)
+	if event.Outcome == "" {
+		event.Outcome = entity.AuditOutcomeSuccess
+	}
+	event.ActorUsername = truncate(event.ActorUsername, maxAuditUsername)
+	event.ActorRole = truncate(event.ActorRole, maxAuditRole)
+	event.TargetID = truncate(event.TargetID, maxAuditTargetID)
+	event.IP = truncate(event.IP, maxAuditIP)
+	event.Reason = truncate(event.Reason, maxAuditReason)
+
+	if err := s.repo.AppendEvent(event); err != nil {
+		logger.LogError("AuditService", "Record", event.Action, err)
+		return err
+	}
+	return nil
+}
+
+func (s *AuditService) ListEvents(filter entity.AuditFilter) ([]*entity.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultAuditLimit
+	}
+	if filter.Limit > MaxAuditLimit {
+		filter.Limit = MaxAuditLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.ListEvents(filter)
+}
+
+func (s *AuditService) ExportEvents(filter entity.AuditFilter, fn func(*entity.AuditEvent) error) error {
+	return s.repo.WalkEvents(filter, fn)
+}
+
+// Verify walks the whole chain and reports the first row whose PrevHash does
+// not match its predecessor or whose Hash does not match its content.
+func (s *AuditService) Verify() (*entity.AuditVerification, error) {
+	result := &entity.AuditVerification{Valid: true}
+	prevHash := ""
+
+	err := s.repo.WalkEvents(entity.AuditFilter{}, func(event *entity.AuditEvent) error {
+		result.Checked++
+		if event.PrevHash != prevHash {
+			result.Valid, result.BrokenAt, result.Reason = false, event.ID, "previous hash does not match the preceding event"
+			return errStopWalk
+		}
+		if event.Hash != event.ComputeHash() {
+			result.Valid, result.BrokenAt, result.Reason = false, event.ID, "event content does not match its hash"
+			return errStopWalk
+		}
+		prevHash = event.Hash
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopWalk) {
+		logger.LogError("AuditService", "Verify", "", err)
+		return nil, err
+	}
+
+	result.LastHash = prevHash
+	if !result.Valid {
+		logger.LogWarning("AuditService", "Verify", "Audit chain is broken", result)
+	}
+	return result, nil
+}
+
+// truncate cuts s to at most n bytes without splitting a rune, so the stored
+// and hashed text stays valid UTF-8.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"time"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/logger"
+)
+
+// Policy version page sizes.
+const (
+	DefaultPolicyVersionLimit = 50
+	MaxPolicyVersionLimit     = 500
+)
+
+const (
+	maxPolicyChange    = 128
+	maxPolicyCreatedBy = 32
+)
+
+type GatewayPolicyService struct {
+	repo port.GatewayPolicyRepository
+}
+
+func NewGatewayPolicyService(repo port.GatewayPolicyRepository) port.GatewayPolicyService {
+	return &GatewayPolicyService{repo: repo}
+}
+
+func (s *GatewayPolicyService) Save(version *entity.GatewayPolicyVersion) error {
+	if version.CreatedAt.IsZero() {
+		version.CreatedAt = time.Now()
+	}
+	version.CreatedAt = version.CreatedAt.UTC().Truncate(time.Microsecond)
+	version.Change = truncate(version.Change, maxPolicyChange)
+	version.CreatedBy = truncate(version.CreatedBy, maxPolicyCreatedBy)
+
+	if err := s.repo.CreateVersion(version); err != nil {
+		logger.LogError("GatewayPolicyService", "Save", version.Change, err)
+		return err
+	}
+	return nil
+}
+
+func (s *GatewayPolicyService) GetVersion(version uint) (*entity.GatewayPolicyVersion, error) {
+	return s.repo.GetVersion(version)
+}
+
+func (s *GatewayPolicyService) LatestVersion() (*entity.GatewayPolicyVersion, error) {
+	return s.repo.LatestVersion()
+}
+
+func (s *GatewayPolicyService) ListVersions(limit, offset int) ([]*entity.GatewayPolicyVersion, error) {
+	if limit <= 0 {
+		limit = DefaultPolicyVersionLimit
+	}
+	if limit > MaxPolicyVersionLimit {
+		limit = MaxPolicyVersionLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.ListVersions(limit, offset)
+}
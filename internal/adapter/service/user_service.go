@@ -3,9 +3,8 @@ package service
 import (
 	"crypto/rand"
 	"encoding/hex"
-	"errors"
-	"os"
 	"time"
+	"zeneye-gateway/internal/domain/domainerr"
 	"zeneye-gateway/internal/domain/entity"
 	"zeneye-gateway/internal/domain/port"
 	"zeneye-gateway/pkg/jwt"
@@ -15,8 +14,14 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-// var refreshTokenExpiration, _ = time.ParseDuration(utils.GetEnv("REFRESH_TOKEN_EXPIRATION"))
-var refreshTokenExpiration, _ = time.ParseDuration(os.Getenv("REFRESH_TOKEN_EXPIRATION"))
+// User list page sizes.
+const (
+	DefaultUserLimit = 50
+	MaxUserLimit     = 500
+)
+
+// SuperadminTransferTTL is how long the recipient has to accept a transfer.
+const SuperadminTransferTTL = 24 * time.Hour
 
 type UserService struct {
 	repo port.UserRepository
@@ -26,9 +31,14 @@ func NewUserService(repo port.UserRepository) port.UserService {
 	return &UserService{repo: repo}
 }
 
-func (s *UserService) CreateUser(user *entity.User) error {
+func (s *UserService) CreateUser(actorID uint, user *entity.User) error {
 	logger.LogInfo("UserService", "CreateUser", "Creating a new user", user)
 
+	if err := s.checkGrants(actorID, user.Role); err != nil {
+		logger.LogError("UserService", "CreateUser", user, err)
+		return err
+	}
+
 	// Check if a superadmin already exists
 	if user.Role == "superadmin" {
 		superadminExists, err := s.repo.IsSuperadminPresent()
@@ -37,7 +47,7 @@ func (s *UserService) CreateUser(user *entity.User) error {
 			return err
 		}
 		if superadminExists {
-			err = errors.New("superadmin already exists")
+			err = domainerr.ErrSuperadminExists
 			logger.LogError("UserService", "CreateUser", user, err)
 			return err
 		}
@@ -50,7 +60,7 @@ func (s *UserService) CreateUser(user *entity.User) error {
 		return err
 	}
 	if emailExists {
-		err = errors.New("email already associated with another account")
+		err = domainerr.ErrEmailTaken
 		logger.LogError("UserService", "CreateUser", user, err)
 		return err
 	}
@@ -74,13 +84,19 @@ func (s *UserService) CreateUser(user *entity.User) error {
 	return nil
 }
 
-func (s *UserService) EditUser(user *entity.User) error {
+func (s *UserService) EditUser(actorID uint, user *entity.User) error {
 	logger.LogInfo("UserService", "EditUser", "Editing user", user)
 
 	existingUser, err := s.repo.GetUser(user.ID)
 	if err != nil {
 		logger.LogError("UserService", "EditUser", user, err)
-		return errors.New("user not found")
+		return err
+	}
+	if actorID != user.ID {
+		if err := s.checkGrants(actorID, existingUser.Role); err != nil {
+			logger.LogError("UserService", "EditUser", user, err)
+			return err
+		}
 	}
 
 	// If email already exists
@@ -90,7 +106,7 @@ func (s *UserService) EditUser(user *entity.User) error {
 		return err
 	}
 	if emailExists && existingUser.Email != user.Email {
-		err = errors.New("email already associated with another account")
+		err = domainerr.ErrEmailTaken
 		logger.LogError("UserService", "EditUser", user, err)
 		return err
 	}
@@ -98,13 +114,14 @@ func (s *UserService) EditUser(user *entity.User) error {
 	// If username already exists
 	userWithSameUsername, err := s.repo.GetUserByUsername(user.Username)
 	if err == nil && userWithSameUsername.ID != user.ID {
-		err = errors.New("username already taken")
+		err = domainerr.ErrUsernameTaken
 		logger.LogError("UserService", "EditUser", user, err)
 		return err
 	}
 
+	// The email only changes once the new address is confirmed, see
+	// AccountService.StartEmailChange
 	existingUser.Username = user.Username
-	existingUser.Email = user.Email
 	err = s.repo.EditUser(existingUser)
 	if err != nil {
 		logger.LogError("UserService", "EditUser", user, err)
@@ -115,13 +132,21 @@ func (s *UserService) EditUser(user *entity.User) error {
 	return nil
 }
 
-func (s *UserService) DeleteUser(id uint) error {
+func (s *UserService) DeleteUser(actorID, id uint) error {
 	logger.LogInfo("UserService", "DeleteUser", "Deleting user", id)
 
-	_, err := s.repo.GetUser(id)
+	user, err := s.repo.GetUser(id)
 	if err != nil {
 		logger.LogError("UserService", "DeleteUser", id, err)
-		return errors.New("user not found")
+		return err
+	}
+	if err := s.checkGrants(actorID, user.Role); err != nil {
+		logger.LogError("UserService", "DeleteUser", id, err)
+		return err
+	}
+	if err := s.checkNotLastSuperadmin(user); err != nil {
+		logger.LogError("UserService", "DeleteUser", id, err)
+		return err
 	}
 
 	err = s.repo.DeleteUser(id)
@@ -134,6 +159,321 @@ func (s *UserService) DeleteUser(id uint) error {
 	return nil
 }
 
+func (s *UserService) DisableUser(actorID, id uint) error {
+	logger.LogInfo("UserService", "DisableUser", "Disabling user", id)
+
+	user, err := s.repo.GetUser(id)
+	if err != nil {
+		logger.LogError("UserService", "DisableUser", id, err)
+		return err
+	}
+	if err := s.checkOutranks(actorID, user); err != nil {
+		logger.LogError("UserService", "DisableUser", id, err)
+		return err
+	}
+	if err := s.checkNotLastSuperadmin(user); err != nil {
+		logger.LogError("UserService", "DisableUser", id, err)
+		return err
+	}
+
+	if err := s.repo.SetUserStatus(id, entity.UserStatusDisabled); err != nil {
+		logger.LogError("UserService", "DisableUser", id, err)
+		return err
+	}
+
+	logger.LogInfo("UserService", "DisableUser", "User disabled successfully", id)
+	return nil
+}
+
+// EnableUser makes a disabled or locked user active again.
+func (s *UserService) EnableUser(actorID, id uint) error {
+	logger.LogInfo("UserService", "EnableUser", "Enabling user", id)
+
+	user, err := s.repo.GetUser(id)
+	if err != nil {
+		logger.LogError("UserService", "EnableUser", id, err)
+		return err
+	}
+	if err := s.checkOutranks(actorID, user); err != nil {
+		logger.LogError("UserService", "EnableUser", id, err)
+		return err
+	}
+
+	if err := s.repo.SetUserStatus(id, entity.UserStatusActive); err != nil {
+		logger.LogError("UserService", "EnableUser", id, err)
+		return err
+	}
+
+	logger.LogInfo("UserService", "EnableUser", "User enabled successfully", id)
+	return nil
+}
+
+// RestoreUser brings back a deleted user as active, unless another user took
+// their username, email or the superadmin role in the meantime.
+func (s *UserService) RestoreUser(actorID, id uint) error {
+	logger.LogInfo("UserService", "RestoreUser", "Restoring user", id)
+
+	user, err := s.repo.GetDeletedUser(id)
+	if err != nil {
+		if _, getErr := s.repo.GetUser(id); getErr == nil {
+			err = domainerr.ErrUserNotDeleted
+		}
+		logger.LogError("UserService", "RestoreUser", id, err)
+		return err
+	}
+	if err := s.checkOutranks(actorID, user); err != nil {
+		logger.LogError("UserService", "RestoreUser", id, err)
+		return err
+	}
+
+	emailExists, err := s.repo.IsEmailExists(user.Email)
+	if err != nil {
+		logger.LogError("UserService", "RestoreUser", id, err)
+		return err
+	}
+	if emailExists {
+		logger.LogError("UserService", "RestoreUser", id, domainerr.ErrEmailTaken)
+		return domainerr.ErrEmailTaken
+	}
+	if _, err := s.repo.GetUserByUsername(user.Username); err == nil {
+		logger.LogError("UserService", "RestoreUser", id, domainerr.ErrUsernameTaken)
+		return domainerr.ErrUsernameTaken
+	}
+	if user.Role == "superadmin" {
+		superadminExists, err := s.repo.IsSuperadminPresent()
+		if err != nil {
+			logger.LogError("UserService", "RestoreUser", id, err)
+			return err
+		}
+		if superadminExists {
+			logger.LogError("UserService", "RestoreUser", id, domainerr.ErrSuperadminExists)
+			return domainerr.ErrSuperadminExists
+		}
+	}
+
+	if err := s.repo.RestoreUser(id); err != nil {
+		logger.LogError("UserService", "RestoreUser", id, err)
+		return err
+	}
+
+	logger.LogInfo("UserService", "RestoreUser", "User restored successfully", id)
+	return nil
+}
+
+// ChangeUserRole gives the user role on behalf of actor. The actor may only
+// change users ranked below them, to roles ranked below them, so nobody can
+// grant more than they hold or change their own role. The superadmin role only
+// changes hands through a transfer.
+func (s *UserService) ChangeUserRole(actorID, id uint, role string) (*entity.User, error) {
+	logger.LogInfo("UserService", "ChangeUserRole", "Changing user role to "+role, id)
+
+	user, err := s.repo.GetUser(id)
+	if err != nil {
+		logger.LogError("UserService", "ChangeUserRole", id, err)
+		return nil, err
+	}
+	if err := s.checkGrants(actorID, user.Role, role); err != nil {
+		logger.LogError("UserService", "ChangeUserRole", id, err)
+		return nil, err
+	}
+
+	if user.Role != role {
+		if err := s.repo.ChangeUserRole(id, role); err != nil {
+			logger.LogError("UserService", "ChangeUserRole", id, err)
+			return nil, err
+		}
+	}
+	user, err = s.repo.GetUser(id)
+	if err != nil {
+		logger.LogError("UserService", "ChangeUserRole", id, err)
+		return nil, err
+	}
+
+	logger.LogInfo("UserService", "ChangeUserRole", "User role changed successfully", user)
+	return user, nil
+}
+
+func (s *UserService) RevokeTokens(id uint) error {
+	logger.LogInfo("UserService", "RevokeTokens", "Revoking user tokens", id)
+
+	if err := s.repo.RevokeTokens(id); err != nil {
+		logger.LogError("UserService", "RevokeTokens", id, err)
+		return err
+	}
+
+	logger.LogInfo("UserService", "RevokeTokens", "User tokens revoked successfully", id)
+	return nil
+}
+
+// RequestSuperadminTransfer offers the superadmin role of fromID to toID,
+// replacing any pending offer. Nothing changes until toID accepts.
+func (s *UserService) RequestSuperadminTransfer(fromID, toID uint) (*entity.SuperadminTransfer, error) {
+	logger.LogInfo("UserService", "RequestSuperadminTransfer", "Requesting superadmin transfer", toID)
+
+	from, err := s.repo.GetUser(fromID)
+	if err != nil {
+		logger.LogError("UserService", "RequestSuperadminTransfer", fromID, err)
+		return nil, err
+	}
+	if from.Role != entity.RoleSuperadmin {
+		err := domainerr.ErrRoleEscalation
+		logger.LogError("UserService", "RequestSuperadminTransfer", fromID, err)
+		return nil, err
+	}
+	to, err := s.repo.GetUser(toID)
+	if err != nil {
+		logger.LogError("UserService", "RequestSuperadminTransfer", toID, err)
+		return nil, err
+	}
+	if to.ID == from.ID || !to.Active() {
+		err := domainerr.Validation(domainerr.FieldError{Field: "user_id", Code: domainerr.CodeInvalidTransfer,
+			Message: "the superadmin role can only be transferred to another active user"})
+		logger.LogError("UserService", "RequestSuperadminTransfer", toID, err)
+		return nil, err
+	}
+
+	transfer := &entity.SuperadminTransfer{FromUserID: from.ID, ToUserID: to.ID, ExpiresAt: time.Now().Add(SuperadminTransferTTL)}
+	if err := s.repo.CreateSuperadminTransfer(transfer); err != nil {
+		logger.LogError("UserService", "RequestSuperadminTransfer", transfer, err)
+		return nil, err
+	}
+
+	logger.LogInfo("UserService", "RequestSuperadminTransfer", "Superadmin transfer requested successfully", transfer)
+	return transfer, nil
+}
+
+// GetSuperadminTransfer returns the pending transfer to either of its parties.
+// Everyone else, and everyone once it expired, is told there is none.
+func (s *UserService) GetSuperadminTransfer(callerID uint) (*entity.SuperadminTransfer, error) {
+	logger.LogInfo("UserService", "GetSuperadminTransfer", "Getting superadmin transfer", callerID)
+
+	transfer, err := s.repo.GetSuperadminTransfer()
+	if err != nil {
+		logger.LogError("UserService", "GetSuperadminTransfer", callerID, err)
+		return nil, err
+	}
+	if (transfer.FromUserID != callerID && transfer.ToUserID != callerID) || transfer.ExpiresAt.Before(time.Now()) {
+		err := domainerr.ErrTransferNotFound
+		logger.LogError("UserService", "GetSuperadminTransfer", callerID, err)
+		return nil, err
+	}
+
+	logger.LogInfo("UserService", "GetSuperadminTransfer", "Superadmin transfer retrieved successfully", transfer)
+	return transfer, nil
+}
+
+// AcceptSuperadminTransfer completes the pending transfer to callerID: the
+// caller becomes the superadmin and the sender an admin. Both lose their
+// tokens, since their roles changed.
+func (s *UserService) AcceptSuperadminTransfer(callerID uint) (*entity.SuperadminTransfer, error) {
+	logger.LogInfo("UserService", "AcceptSuperadminTransfer", "Accepting superadmin transfer", callerID)
+
+	transfer, err := s.GetSuperadminTransfer(callerID)
+	if err == nil && transfer.ToUserID != callerID {
+		err = domainerr.ErrTransferNotFound
+	}
+	if err != nil {
+		logger.LogError("UserService", "AcceptSuperadminTransfer", callerID, err)
+		return nil, err
+	}
+	// The sender may have been disabled or demoted since the offer
+	if from, err := s.repo.GetUser(transfer.FromUserID); err != nil || from.Role != entity.RoleSuperadmin || !from.Active() {
+		err := domainerr.ErrTransferNotFound
+		logger.LogError("UserService", "AcceptSuperadminTransfer", transfer, err)
+		return nil, err
+	}
+
+	if err := s.repo.CompleteSuperadminTransfer(transfer, entity.RoleAdmin); err != nil {
+		logger.LogError("UserService", "AcceptSuperadminTransfer", transfer, err)
+		return nil, err
+	}
+
+	logger.LogInfo("UserService", "AcceptSuperadminTransfer", "Superadmin transfer accepted successfully", transfer)
+	return transfer, nil
+}
+
+// CancelSuperadminTransfer lets either party withdraw or decline the pending
+// transfer.
+func (s *UserService) CancelSuperadminTransfer(callerID uint) (*entity.SuperadminTransfer, error) {
+	logger.LogInfo("UserService", "CancelSuperadminTransfer", "Cancelling superadmin transfer", callerID)
+
+	transfer, err := s.GetSuperadminTransfer(callerID)
+	if err != nil {
+		logger.LogError("UserService", "CancelSuperadminTransfer", callerID, err)
+		return nil, err
+	}
+	if err := s.repo.DeleteSuperadminTransfer(); err != nil {
+		logger.LogError("UserService", "CancelSuperadminTransfer", transfer, err)
+		return nil, err
+	}
+
+	logger.LogInfo("UserService", "CancelSuperadminTransfer", "Superadmin transfer cancelled successfully", transfer)
+	return transfer, nil
+}
+
+// checkNotLastSuperadmin refuses to take away the only active superadmin.
+func (s *UserService) checkNotLastSuperadmin(user *entity.User) error {
+	if user.Role != "superadmin" || !user.Active() {
+		return nil
+	}
+	count, err := s.repo.CountActiveSuperadmins()
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return domainerr.ErrLastSuperadmin
+	}
+	return nil
+}
+
+// checkGrants fails with domainerr.ErrRoleEscalation unless the actor is
+// ranked above every one of roles. The command line, entity.SystemActorID, is
+// ranked above every role.
+func (s *UserService) checkGrants(actorID uint, roles ...string) error {
+	if actorID == entity.SystemActorID {
+		return nil
+	}
+	actor, err := s.repo.GetUser(actorID)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if entity.RoleRank(role) >= entity.RoleRank(actor.Role) {
+			return domainerr.ErrRoleEscalation
+		}
+	}
+	return nil
+}
+
+// checkOutranks fails with domainerr.ErrInsufficientRank unless the actor is
+// ranked above user. The command line, entity.SystemActorID, outranks everyone.
+func (s *UserService) checkOutranks(actorID uint, user *entity.User) error {
+	if actorID == entity.SystemActorID {
+		return nil
+	}
+	actor, err := s.repo.GetUser(actorID)
+	if err != nil {
+		return err
+	}
+	if entity.RoleRank(user.Role) >= entity.RoleRank(actor.Role) {
+		return domainerr.ErrInsufficientRank
+	}
+	return nil
+}
+
+// statusError is the error reported to a user who may not sign in because of
+// their status, or nil for active users.
+func statusError(user *entity.User) error {
+	switch {
+	case user.Active():
+		return nil
+	case user.Status == entity.UserStatusLocked:
+		return domainerr.ErrAccountLocked
+	default:
+		return domainerr.ErrAccountDisabled
+	}
+}
+
 func (s *UserService) GetUser(id uint) (*entity.User, error) {
 	logger.LogInfo("UserService", "GetUser", "Getting user", id)
 
@@ -147,17 +487,54 @@ func (s *UserService) GetUser(id uint) (*entity.User, error) {
 	return user, nil
 }
 
-func (s *UserService) ListUsers() ([]*entity.User, error) {
-	logger.LogInfo("UserService", "ListUsers", "Listing all users", "")
+func (s *UserService) GetUserByUsername(username string) (*entity.User, error) {
+	logger.LogInfo("UserService", "GetUserByUsername", "Getting user", username)
+
+	user, err := s.repo.GetUserByUsername(username)
+	if err != nil {
+		logger.LogError("UserService", "GetUserByUsername", username, err)
+		return nil, err
+	}
+
+	logger.LogInfo("UserService", "GetUserByUsername", "User retrieved successfully", user)
+	return user, nil
+}
+
+// ListUsers returns one page of the users matching filter. The limit is
+// clamped to MaxUserLimit, and a cursor to the next page is set unless this is
+// the last one.
+func (s *UserService) ListUsers(filter entity.UserFilter) (*entity.UserPage, error) {
+	logger.LogInfo("UserService", "ListUsers", "Listing users", filter)
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultUserLimit
+	}
+	if filter.Limit > MaxUserLimit {
+		filter.Limit = MaxUserLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
 
-	users, err := s.repo.GetAllUsers()
+	// One extra row tells whether another page follows
+	limit := filter.Limit
+	filter.Limit++
+	users, total, err := s.repo.ListUsers(filter)
 	if err != nil {
-		logger.LogError("UserService", "ListUsers", "", err)
+		logger.LogError("UserService", "ListUsers", filter, err)
 		return nil, err
 	}
 
-	logger.LogInfo("UserService", "ListUsers", "All users retrieved successfully", users)
-	return users, nil
+	page := &entity.UserPage{Users: users, Total: total, Limit: limit, Offset: filter.Offset}
+	if filter.After != nil {
+		page.Offset = 0
+	}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.Next = entity.CursorAfter(page.Users[limit-1], filter.Sort, filter.Desc)
+	}
+	logger.LogInfo("UserService", "ListUsers", "Users retrieved successfully", len(page.Users))
+	return page, nil
 }
 
 func (s *UserService) AuthenticateUser(username, password string) (*entity.User, error) {
@@ -166,12 +543,18 @@ func (s *UserService) AuthenticateUser(username, password string) (*entity.User,
 	user, err := s.repo.GetUserByUsername(username)
 	if err != nil {
 		logger.LogError("UserService", "AuthenticateUser", username, err)
-		return nil, errors.New("invalid username or password")
+		return nil, domainerr.ErrInvalidCredentials.Wrap(err)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
 		logger.LogError("UserService", "AuthenticateUser", username, err)
-		return nil, errors.New("invalid username or password")
+		return nil, domainerr.ErrInvalidCredentials.Wrap(err)
+	}
+	// Only checked after the password, so the status never tells a stranger
+	// that the account exists
+	if err := statusError(user); err != nil {
+		logger.LogError("UserService", "AuthenticateUser", username, err)
+		return nil, err
 	}
 
 	logger.LogInfo("UserService", "AuthenticateUser", "User authenticated successfully", user)
@@ -204,7 +587,7 @@ func (s *UserService) GenerateRefreshToken(userID uint) (string, error) {
 	token := &entity.RefreshToken{
 		Token:     tokenString,
 		UserID:    userID,
-		ExpiresAt: time.Now().Add(refreshTokenExpiration), // from .env
+		ExpiresAt: time.Now().Add(jwt.RefreshTokenTTL()),
 	}
 
 	if err := s.repo.CreateRefreshToken(token); err != nil {
@@ -212,22 +595,22 @@ func (s *UserService) GenerateRefreshToken(userID uint) (string, error) {
 		return "", err
 	}
 
-	logger.LogInfo("UserService", "GenerateRefreshToken", "Refresh token generated successfully", tokenString)
+	logger.LogInfo("UserService", "GenerateRefreshToken", "Refresh token generated successfully", userID)
 	return tokenString, nil
 }
 
 func (s *UserService) RefreshAccessToken(refreshToken string) (string, error) {
-	logger.LogInfo("UserService", "RefreshAccessToken", "Refreshing access token", refreshToken)
+	logger.LogInfo("UserService", "RefreshAccessToken", "Refreshing access token", "")
 
 	token, err := s.repo.GetRefreshToken(refreshToken)
 	if err != nil {
-		logger.LogError("UserService", "RefreshAccessToken", refreshToken, err)
-		return "", errors.New("invalid refresh token")
+		logger.LogError("UserService", "RefreshAccessToken", "", err)
+		return "", domainerr.ErrInvalidRefreshToken.Wrap(err)
 	}
 
 	if token.ExpiresAt.Before(time.Now()) {
-		err = errors.New("refresh token has expired")
-		logger.LogError("UserService", "RefreshAccessToken", refreshToken, err)
+		err = domainerr.ErrRefreshTokenExpired
+		logger.LogError("UserService", "RefreshAccessToken", "", err)
 		return "", err
 	}
 
@@ -237,14 +620,18 @@ func (s *UserService) RefreshAccessToken(refreshToken string) (string, error) {
 		logger.LogError("UserService", "GetUser", token.UserID, err)
 		return "", err
 	}
+	if err := statusError(user); err != nil {
+		logger.LogError("UserService", "RefreshAccessToken", token.UserID, err)
+		return "", err
+	}
 
 	// Generate new access token with additional user details
-	newToken, err := jwt.GenerateToken(user.ID, user.Username, user.Role, user.UserUUID)
+	newToken, err := jwt.GenerateVersionedToken(user.ID, user.Username, user.Role, user.UserUUID, user.TokenVersion)
 	if err != nil {
 		logger.LogError("UserService", "RefreshAccessToken", user.ID, err)
 		return "", err
 	}
 
-	logger.LogInfo("UserService", "RefreshAccessToken", "Access token refreshed successfully", newToken)
+	logger.LogInfo("UserService", "RefreshAccessToken", "Access token refreshed successfully", user.ID)
 	return newToken, nil
 }
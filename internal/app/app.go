@@ -0,0 +1,106 @@
+// Package app is the composition root: it builds the repositories, services
+// and identity resolver once at startup and hands them to the HTTP layer, which
+// only sees the port interfaces.
+package app
+
+import (
+	"context"
+	"io"
+
+	"zeneye-gateway/internal/adapter/bootstrap"
+	"zeneye-gateway/internal/adapter/identity"
+	"zeneye-gateway/internal/adapter/notify"
+	"zeneye-gateway/internal/adapter/repository/postgres"
+	"zeneye-gateway/internal/adapter/service"
+	"zeneye-gateway/internal/config"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/worker"
+
+	"gorm.io/gorm"
+)
+
+// Background jobs, such as issuing password reset tokens, run on a few
+// workers. Jobs beyond the queue are refused and logged rather than piling up.
+const (
+	jobWorkers = 4
+	jobQueue   = 256
+)
+
+// App holds the gateway's long-lived dependencies.
+type App struct {
+	DB *gorm.DB
+
+	// Users writes through the identity resolver, so edits and deletes
+	// invalidate the identities it caches.
+	Users port.UserService
+	// Accounts serves the caller's own account and sends its notifications
+	// through Notifier.
+	Accounts port.AccountService
+	Notifier port.Notifier
+	// Jobs runs the work the services do after answering a request. Close
+	// drains it.
+	Jobs       *worker.Pool
+	Audit      port.AuditService
+	Identities *identity.Resolver
+	// Policies stores the versions of the routes and policies edited through
+	// the gateway admin API.
+	Policies port.GatewayPolicyService
+	// SetupToken authorizes creating the first superadmin over HTTP. It is
+	// only issued at startup while there is no superadmin; see IssueSetupToken.
+	SetupToken *bootstrap.SetupToken
+	// Migrations opens the schema migrations of the database for the gateway
+	// admin API; the caller closes them.
+	Migrations func() (port.Migrator, error)
+}
+
+// New wires the services over db as configured by cfg.
+func New(db *gorm.DB, cfg *config.Config) *App {
+	userRepo := postgres.NewUserRepository(db)
+	identities := identity.NewResolver(userRepo, cfg.Identity)
+	users := identities.WrapRepository(userRepo)
+	notifier := notify.New(cfg.Notifier())
+	jobs := worker.New(jobWorkers, jobQueue)
+
+	return &App{
+		DB:         db,
+		Users:      service.NewUserService(users),
+		Accounts:   service.NewAccountService(users, notifier, jobs),
+		Notifier:   notifier,
+		Jobs:       jobs,
+		Audit:      service.NewAuditService(postgres.NewAuditRepository(db)),
+		Identities: identities,
+		Policies:   service.NewGatewayPolicyService(postgres.NewGatewayPolicyRepository(db)),
+		Migrations: func() (port.Migrator, error) {
+			m, err := postgres.NewMigrator(cfg.Database.URL.Value())
+			if err != nil {
+				return nil, err
+			}
+			return m, nil
+		},
+	}
+}
+
+// Close waits for the background jobs to finish, or for ctx to be done. Call
+// it after the server has stopped and before the database is closed.
+func (a *App) Close(ctx context.Context) error {
+	return a.Jobs.Stop(ctx)
+}
+
+// IssueSetupToken hands a setup token to the operator through out, or the
+// configured token file, when the HTTP bootstrap is enabled and no superadmin
+// exists yet.
+func (a *App) IssueSetupToken(cfg *config.Config, out io.Writer) error {
+	if !cfg.BootstrapHTTP() {
+		return nil
+	}
+	exists, err := a.Users.IsSuperadminPresent()
+	if err != nil || exists {
+		return err
+	}
+	token, err := bootstrap.Issue(cfg.Bootstrap, out)
+	if err != nil {
+		return err
+	}
+	a.SetupToken = token
+	return nil
+}
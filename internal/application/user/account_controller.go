@@ -0,0 +1,199 @@
+package user
+
+import (
+	"zeneye-gateway/internal/domain/domainerr"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/internal/dto"
+	"zeneye-gateway/pkg/logger"
+	validation "zeneye-gateway/pkg/validation"
+)
+
+// AccountController serves users' own accounts: the /me actions, where the user
+// ID always comes from the caller's token and never from the request, the
+// password reset for users who cannot sign in, and the password an operator
+// sets from the command line.
+type AccountController struct {
+	userService    port.UserService
+	accountService port.AccountService
+}
+
+func NewAccountController(userService port.UserService, accountService port.AccountService) *AccountController {
+	return &AccountController{userService: userService, accountService: accountService}
+}
+
+func (c *AccountController) GetProfile(userID uint) (*dto.UserResponse, error) {
+	logger.LogInfo("AccountController", "GetProfile", "Getting profile", userID)
+
+	user, err := c.userService.GetUser(userID)
+	if err != nil {
+		logger.LogError("AccountController", "GetProfile", userID, err)
+		return nil, err
+	}
+
+	logger.LogInfo("AccountController", "GetProfile", "Profile retrieved successfully", user)
+	return userResponse(user), nil
+}
+
+func (c *AccountController) UpdateProfile(userID uint, req UpdateProfileRequest) (*dto.UserResponse, error) {
+	logger.LogInfo("AccountController", "UpdateProfile", "Validating profile update request", req)
+
+	if err := validation.ValidateUsername(req.Username); err != nil {
+		err := domainerr.Validation(domainerr.Field("username", domainerr.CodeInvalidUsername, err))
+		logger.LogError("AccountController", "UpdateProfile", req, err)
+		return nil, err
+	}
+
+	current, err := c.userService.GetUser(userID)
+	if err != nil {
+		logger.LogError("AccountController", "UpdateProfile", userID, err)
+		return nil, err
+	}
+	user := &entity.User{ID: userID, Username: req.Username, Email: current.Email}
+	if err := c.userService.EditUser(userID, user); err != nil {
+		logger.LogError("AccountController", "UpdateProfile", user, err)
+		return nil, err
+	}
+
+	logger.LogInfo("AccountController", "UpdateProfile", "Profile updated successfully", user)
+	return c.GetProfile(userID)
+}
+
+func (c *AccountController) ChangePassword(userID uint, req ChangePasswordRequest) error {
+	logger.LogInfo("AccountController", "ChangePassword", "Validating password change request", userID)
+
+	if err := validation.ValidatePassword(req.NewPassword); err != nil {
+		err := domainerr.Validation(domainerr.Field("new_password", domainerr.CodeInvalidPassword, err))
+		logger.LogError("AccountController", "ChangePassword", userID, err)
+		return err
+	}
+
+	if err := c.accountService.ChangePassword(userID, req.CurrentPassword, req.NewPassword); err != nil {
+		logger.LogError("AccountController", "ChangePassword", userID, err)
+		return err
+	}
+
+	logger.LogInfo("AccountController", "ChangePassword", "Password changed successfully", userID)
+	return nil
+}
+
+func (c *AccountController) RequestEmailChange(userID uint, req ChangeEmailRequest) error {
+	logger.LogInfo("AccountController", "RequestEmailChange", "Validating email change request", req)
+
+	if err := validation.ValidateEmail(req.Email); err != nil {
+		err := domainerr.Validation(domainerr.Field("email", domainerr.CodeInvalidEmail, err))
+		logger.LogError("AccountController", "RequestEmailChange", req, err)
+		return err
+	}
+
+	if err := c.accountService.RequestEmailChange(userID, req.Password, req.Email); err != nil {
+		logger.LogError("AccountController", "RequestEmailChange", req, err)
+		return err
+	}
+
+	logger.LogInfo("AccountController", "RequestEmailChange", "Email change requested successfully", userID)
+	return nil
+}
+
+// StartEmailChange sends the confirmation of an email change an
+// administrator made to user userID.
+func (c *AccountController) StartEmailChange(userID uint, email string) error {
+	logger.LogInfo("AccountController", "StartEmailChange", "Starting email change", userID)
+
+	if err := c.accountService.StartEmailChange(userID, email); err != nil {
+		logger.LogError("AccountController", "StartEmailChange", userID, err)
+		return err
+	}
+
+	logger.LogInfo("AccountController", "StartEmailChange", "Email change started successfully", userID)
+	return nil
+}
+
+func (c *AccountController) ConfirmEmailChange(userID uint, req ConfirmEmailRequest) (*dto.UserResponse, error) {
+	logger.LogInfo("AccountController", "ConfirmEmailChange", "Confirming email change", userID)
+
+	user, err := c.accountService.ConfirmEmailChange(userID, req.Token)
+	if err != nil {
+		logger.LogError("AccountController", "ConfirmEmailChange", userID, err)
+		return nil, err
+	}
+
+	logger.LogInfo("AccountController", "ConfirmEmailChange", "Email changed successfully", user)
+	return userResponse(user), nil
+}
+
+// RequestPasswordReset answers the same for unknown and known emails, so
+// callers cannot probe for accounts; only malformed input is rejected.
+func (c *AccountController) RequestPasswordReset(req ForgotPasswordRequest) error {
+	logger.LogInfo("AccountController", "RequestPasswordReset", "Validating password reset request", req)
+
+	if err := validation.ValidateEmail(req.Email); err != nil {
+		err := domainerr.Validation(domainerr.Field("email", domainerr.CodeInvalidEmail, err))
+		logger.LogError("AccountController", "RequestPasswordReset", req, err)
+		return err
+	}
+
+	if err := c.accountService.RequestPasswordReset(req.Email); err != nil {
+		logger.LogError("AccountController", "RequestPasswordReset", req, err)
+		return err
+	}
+
+	logger.LogInfo("AccountController", "RequestPasswordReset", "Password reset request handled", req)
+	return nil
+}
+
+func (c *AccountController) ResetPassword(req ResetPasswordRequest) (*entity.User, error) {
+	logger.LogInfo("AccountController", "ResetPassword", "Validating password reset", "")
+
+	if err := validation.ValidatePassword(req.NewPassword); err != nil {
+		err := domainerr.Validation(domainerr.Field("new_password", domainerr.CodeInvalidPassword, err))
+		logger.LogError("AccountController", "ResetPassword", "", err)
+		return nil, err
+	}
+
+	user, err := c.accountService.ResetPassword(req.Token, req.NewPassword)
+	if err != nil {
+		logger.LogError("AccountController", "ResetPassword", "", err)
+		return nil, err
+	}
+
+	logger.LogInfo("AccountController", "ResetPassword", "Password reset successfully", user.ID)
+	return user, nil
+}
+
+func (c *AccountController) SetPassword(id string, req SetPasswordRequest) (*entity.User, error) {
+	logger.LogInfo("AccountController", "SetPassword", "Validating password", id)
+
+	userID, err := parseUserID(id)
+	if err != nil {
+		logger.LogError("AccountController", "SetPassword", id, err)
+		return nil, err
+	}
+	if err := validation.ValidatePassword(req.NewPassword); err != nil {
+		err := domainerr.Validation(domainerr.Field("new_password", domainerr.CodeInvalidPassword, err))
+		logger.LogError("AccountController", "SetPassword", id, err)
+		return nil, err
+	}
+
+	user, err := c.accountService.SetPassword(userID, req.NewPassword)
+	if err != nil {
+		logger.LogError("AccountController", "SetPassword", id, err)
+		return nil, err
+	}
+
+	logger.LogInfo("AccountController", "SetPassword", "Password set successfully", id)
+	return user, nil
+}
+
+func userResponse(user *entity.User) *dto.UserResponse {
+	return &dto.UserResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		UserUUID:  user.UserUUID,
+		Role:      user.Role,
+		Status:    user.Status,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+}
@@ -0,0 +1,96 @@
+package user
+
+import (
+	"zeneye-gateway/internal/domain/domainerr"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/dto"
+	"zeneye-gateway/pkg/logger"
+	validation "zeneye-gateway/pkg/validation"
+)
+
+// ChangeUserRole changes the role of the user with the given id on behalf of
+// actorID.
+func (c *UserController) ChangeUserRole(actorID uint, id string, req ChangeRoleRequest) (*dto.UserResponse, error) {
+	logger.LogInfo("UserController", "ChangeUserRole", "Validating role change request", req)
+
+	userID, err := parseUserID(id)
+	if err != nil {
+		logger.LogError("UserController", "ChangeUserRole", id, err)
+		return nil, err
+	}
+	if err := validation.ValidateRole(req.Role); err != nil {
+		err := domainerr.Validation(domainerr.Field("role", domainerr.CodeInvalidRole, err))
+		logger.LogError("UserController", "ChangeUserRole", req, err)
+		return nil, err
+	}
+
+	user, err := c.userService.ChangeUserRole(actorID, userID, req.Role)
+	if err != nil {
+		logger.LogError("UserController", "ChangeUserRole", id, err)
+		return nil, err
+	}
+
+	logger.LogInfo("UserController", "ChangeUserRole", "User role changed successfully", user)
+	return userResponse(user), nil
+}
+
+func (c *UserController) RequestSuperadminTransfer(callerID uint, req SuperadminTransferRequest) (*dto.SuperadminTransferResponse, error) {
+	logger.LogInfo("UserController", "RequestSuperadminTransfer", "Requesting superadmin transfer", req)
+
+	transfer, err := c.userService.RequestSuperadminTransfer(callerID, req.UserID)
+	if err != nil {
+		logger.LogError("UserController", "RequestSuperadminTransfer", req, err)
+		return nil, err
+	}
+
+	logger.LogInfo("UserController", "RequestSuperadminTransfer", "Superadmin transfer requested successfully", transfer)
+	return transferResponse(transfer), nil
+}
+
+func (c *UserController) GetSuperadminTransfer(callerID uint) (*dto.SuperadminTransferResponse, error) {
+	logger.LogInfo("UserController", "GetSuperadminTransfer", "Getting superadmin transfer", callerID)
+
+	transfer, err := c.userService.GetSuperadminTransfer(callerID)
+	if err != nil {
+		logger.LogError("UserController", "GetSuperadminTransfer", callerID, err)
+		return nil, err
+	}
+
+	logger.LogInfo("UserController", "GetSuperadminTransfer", "Superadmin transfer retrieved successfully", transfer)
+	return transferResponse(transfer), nil
+}
+
+func (c *UserController) AcceptSuperadminTransfer(callerID uint) (*entity.SuperadminTransfer, error) {
+	logger.LogInfo("UserController", "AcceptSuperadminTransfer", "Accepting superadmin transfer", callerID)
+
+	transfer, err := c.userService.AcceptSuperadminTransfer(callerID)
+	if err != nil {
+		logger.LogError("UserController", "AcceptSuperadminTransfer", callerID, err)
+		return nil, err
+	}
+
+	logger.LogInfo("UserController", "AcceptSuperadminTransfer", "Superadmin transfer accepted successfully", transfer)
+	return transfer, nil
+}
+
+func (c *UserController) CancelSuperadminTransfer(callerID uint) (*entity.SuperadminTransfer, error) {
+	logger.LogInfo("UserController", "CancelSuperadminTransfer", "Cancelling superadmin transfer", callerID)
+
+	transfer, err := c.userService.CancelSuperadminTransfer(callerID)
+	if err != nil {
+		logger.LogError("UserController", "CancelSuperadminTransfer", callerID, err)
+		return nil, err
+	}
+
+	logger.LogInfo("UserController", "CancelSuperadminTransfer", "Superadmin transfer cancelled successfully", transfer)
+	return transfer, nil
+}
+
+func transferResponse(transfer *entity.SuperadminTransfer) *dto.SuperadminTransferResponse {
+	return &dto.SuperadminTransferResponse{
+		FromUserID: transfer.FromUserID,
+		ToUserID:   transfer.ToUserID,
+		ExpiresAt:  transfer.ExpiresAt,
+		CreatedAt:  transfer.CreatedAt,
+	}
+}
@@ -1,7 +1,11 @@
 package user
 
 import (
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	"zeneye-gateway/internal/domain/domainerr"
 	"zeneye-gateway/internal/domain/entity"
 	"zeneye-gateway/internal/domain/port"
 	"zeneye-gateway/internal/dto"
@@ -17,29 +21,26 @@ func NewUserController(userService port.UserService) *UserController {
 	return &UserController{userService: userService}
 }
 
-func (c *UserController) CreateUser(req CreateUserRequest) error {
+// CreateUser creates the user of req on behalf of actorID.
+func (c *UserController) CreateUser(actorID uint, req CreateUserRequest) error {
 	logger.LogInfo("UserController", "CreateUser", "Validating user creation request", req)
 
-	// Validate username
+	// Validate every field so the caller sees all problems at once
+	var fields []domainerr.FieldError
 	if err := validation.ValidateUsername(req.Username); err != nil {
-		logger.LogError("UserController", "CreateUser", req, err)
-		return err
+		fields = append(fields, domainerr.Field("username", domainerr.CodeInvalidUsername, err))
 	}
-
-	// Validate password
 	if err := validation.ValidatePassword(req.Password); err != nil {
-		logger.LogError("UserController", "CreateUser", req, err)
-		return err
+		fields = append(fields, domainerr.Field("password", domainerr.CodeInvalidPassword, err))
 	}
-
-	// Validate role
 	if err := validation.ValidateRole(req.Role); err != nil {
-		logger.LogError("UserController", "CreateUser", req, err)
-		return err
+		fields = append(fields, domainerr.Field("role", domainerr.CodeInvalidRole, err))
 	}
-
-	// Validate email
 	if err := validation.ValidateEmail(req.Email); err != nil {
+		fields = append(fields, domainerr.Field("email", domainerr.CodeInvalidEmail, err))
+	}
+	if len(fields) > 0 {
+		err := domainerr.Validation(fields...)
 		logger.LogError("UserController", "CreateUser", req, err)
 		return err
 	}
@@ -51,7 +52,7 @@ func (c *UserController) CreateUser(req CreateUserRequest) error {
 		Role:     req.Role,
 	}
 
-	err := c.userService.CreateUser(user)
+	err := c.userService.CreateUser(actorID, user)
 	if err != nil {
 		logger.LogError("UserController", "CreateUser", user, err)
 		return err
@@ -61,34 +62,37 @@ func (c *UserController) CreateUser(req CreateUserRequest) error {
 	return nil
 }
 
-func (c *UserController) EditUser(id string, req EditUserRequest) error {
+// EditUser renames the user with the given id on behalf of actorID. The new
+// email is validated and checked for conflicts but not applied.
+func (c *UserController) EditUser(actorID uint, id string, req EditUserRequest) error {
 	logger.LogInfo("UserController", "EditUser", "Validating user edit request", req)
 
-	userID, err := strconv.ParseUint(id, 10, 32)
+	userID, err := parseUserID(id)
 	if err != nil {
 		logger.LogError("UserController", "EditUser", id, err)
 		return err
 	}
 
-	// Validate username
+	var fields []domainerr.FieldError
 	if err := validation.ValidateUsername(req.Username); err != nil {
-		logger.LogError("UserController", "EditUser", req.Username, err)
-		return err
+		fields = append(fields, domainerr.Field("username", domainerr.CodeInvalidUsername, err))
 	}
-
-	// Validate email
 	if err := validation.ValidateEmail(req.Email); err != nil {
-		logger.LogError("UserController", "EditUser", req.Email, err)
+		fields = append(fields, domainerr.Field("email", domainerr.CodeInvalidEmail, err))
+	}
+	if len(fields) > 0 {
+		err := domainerr.Validation(fields...)
+		logger.LogError("UserController", "EditUser", req, err)
 		return err
 	}
 
 	user := &entity.User{
-		ID:       uint(userID),
+		ID:       userID,
 		Username: req.Username,
 		Email:    req.Email,
 	}
 
-	err = c.userService.EditUser(user)
+	err = c.userService.EditUser(actorID, user)
 	if err != nil {
 		logger.LogError("UserController", "EditUser", user, err)
 		return err
@@ -98,16 +102,16 @@ func (c *UserController) EditUser(id string, req EditUserRequest) error {
 	return nil
 }
 
-func (c *UserController) DeleteUser(id string) error {
+func (c *UserController) DeleteUser(actorID uint, id string) error {
 	logger.LogInfo("UserController", "DeleteUser", "Deleting user", id)
 
-	userID, err := strconv.ParseUint(id, 10, 32)
+	userID, err := parseUserID(id)
 	if err != nil {
 		logger.LogError("UserController", "DeleteUser", id, err)
 		return err
 	}
 
-	err = c.userService.DeleteUser(uint(userID))
+	err = c.userService.DeleteUser(actorID, userID)
 	if err != nil {
 		logger.LogError("UserController", "DeleteUser", id, err)
 		return err
@@ -117,55 +121,219 @@ func (c *UserController) DeleteUser(id string) error {
 	return nil
 }
 
+func (c *UserController) DisableUser(actorID uint, id string) error {
+	return c.changeStatus("DisableUser", actorID, id, c.userService.DisableUser)
+}
+
+func (c *UserController) EnableUser(actorID uint, id string) error {
+	return c.changeStatus("EnableUser", actorID, id, c.userService.EnableUser)
+}
+
+func (c *UserController) RestoreUser(actorID uint, id string) error {
+	return c.changeStatus("RestoreUser", actorID, id, c.userService.RestoreUser)
+}
+
+func (c *UserController) RevokeTokens(id string) error {
+	logger.LogInfo("UserController", "RevokeTokens", "Revoking user tokens", id)
+
+	userID, err := parseUserID(id)
+	if err != nil {
+		logger.LogError("UserController", "RevokeTokens", id, err)
+		return err
+	}
+
+	if err := c.userService.RevokeTokens(userID); err != nil {
+		logger.LogError("UserController", "RevokeTokens", id, err)
+		return err
+	}
+
+	logger.LogInfo("UserController", "RevokeTokens", "User tokens revoked successfully", id)
+	return nil
+}
+
+// changeStatus parses id and applies one of the status changes to it.
+func (c *UserController) changeStatus(activity string, actorID uint, id string, change func(actorID, id uint) error) error {
+	logger.LogInfo("UserController", activity, "Changing user status", id)
+
+	userID, err := parseUserID(id)
+	if err != nil {
+		logger.LogError("UserController", activity, id, err)
+		return err
+	}
+
+	if err := change(actorID, userID); err != nil {
+		logger.LogError("UserController", activity, id, err)
+		return err
+	}
+
+	logger.LogInfo("UserController", activity, "User status changed successfully", id)
+	return nil
+}
+
 func (c *UserController) GetUser(id string) (*dto.UserResponse, error) {
 	logger.LogInfo("UserController", "GetUser", "Getting user", id)
 
-	userID, err := strconv.ParseUint(id, 10, 32)
+	userID, err := parseUserID(id)
 	if err != nil {
 		logger.LogError("UserController", "GetUser", id, err)
 		return nil, err
 	}
 
-	user, err := c.userService.GetUser(uint(userID))
+	user, err := c.userService.GetUser(userID)
 	if err != nil {
 		logger.LogError("UserController", "GetUser", id, err)
 		return nil, err
 	}
 
 	logger.LogInfo("UserController", "GetUser", "User retrieved successfully", user)
-	return &dto.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		UserUUID:  user.UserUUID,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	}, nil
+	return userResponse(user), nil
 }
 
-func (c *UserController) ListUsers() ([]*entity.User, error) {
-	logger.LogInfo("UserController", "ListUsers", "Listing all users", "")
+func (c *UserController) ListUsers(req ListUsersRequest) (*dto.UserListPage, error) {
+	logger.LogInfo("UserController", "ListUsers", "Listing users", req)
+
+	filter, err := userFilterFromRequest(req)
+	if err != nil {
+		logger.LogError("UserController", "ListUsers", req, err)
+		return nil, err
+	}
 
-	users, err := c.userService.ListUsers()
+	page, err := c.userService.ListUsers(filter)
 	if err != nil {
 		logger.LogError("UserController", "ListUsers", "", err)
 		return nil, err
 	}
 
-	logger.LogInfo("UserController", "ListUsers", "All users listed successfully", users)
-	return users, nil
+	response := &dto.UserListPage{
+		Users:  make([]dto.UserListResponse, 0, len(page.Users)),
+		Total:  page.Total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+	for _, user := range page.Users {
+		response.Users = append(response.Users, dto.UserListResponse{
+			ID:        user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			Role:      user.Role,
+			Status:    user.Status,
+			UserUUID:  user.UserUUID,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+		})
+	}
+	if page.Next != nil {
+		response.NextCursor = page.Next.Encode()
+	}
+
+	logger.LogInfo("UserController", "ListUsers", "Users listed successfully", len(response.Users))
+	return response, nil
 }
 
 func (c *UserController) Login(req LoginRequest) (*entity.User, error) {
-	logger.LogInfo("UserController", "Login", "Authenticating user", req)
+	logger.LogInfo("UserController", "Login", "Authenticating user", req.Username)
 
 	user, err := c.userService.AuthenticateUser(req.Username, req.Password)
 	if err != nil {
-		logger.LogError("UserController", "Login", req, err)
+		logger.LogError("UserController", "Login", req.Username, err)
 		return nil, err
 	}
 
 	logger.LogInfo("UserController", "Login", "User authenticated successfully", user)
 	return user, nil
 }
+
+// parseUserID validates a user ID taken from the URL.
+func parseUserID(id string) (uint, error) {
+	userID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, domainerr.Validation(domainerr.FieldError{
+			Field:   "id",
+			Code:    domainerr.CodeInvalidID,
+			Message: "id must be a positive integer",
+		})
+	}
+	return uint(userID), nil
+}
+
+// userFilterFromRequest validates every query parameter of req at once.
+func userFilterFromRequest(req ListUsersRequest) (entity.UserFilter, error) {
+	filter := entity.UserFilter{
+		Role:        req.Role,
+		Status:      req.Status,
+		EmailDomain: strings.TrimPrefix(req.EmailDomain, "@"),
+		Search:      strings.TrimSpace(req.Search),
+		Sort:        req.Sort,
+	}
+
+	var fields []domainerr.FieldError
+	if filter.Status != "" && !slices.Contains(entity.UserStatuses, filter.Status) {
+		fields = append(fields, domainerr.FieldError{Field: "status", Code: domainerr.CodeInvalidStatus,
+			Message: "status must be one of " + strings.Join(entity.UserStatuses, ", ")})
+	}
+	if filter.Role != "" {
+		if err := validation.ValidateRole(filter.Role); err != nil {
+			fields = append(fields, domainerr.Field("role", domainerr.CodeInvalidRole, err))
+		}
+	}
+	if filter.Sort == "" {
+		filter.Sort = entity.UserSortID
+	}
+	if !slices.Contains(entity.UserSortFields, filter.Sort) {
+		fields = append(fields, domainerr.FieldError{Field: "sort", Code: domainerr.CodeInvalidSort,
+			Message: "sort must be one of " + strings.Join(entity.UserSortFields, ", ")})
+	}
+	switch strings.ToLower(req.Order) {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		fields = append(fields, domainerr.FieldError{Field: "order", Code: domainerr.CodeInvalidSort, Message: "order must be asc or desc"})
+	}
+	for _, param := range []struct {
+		name, value string
+		target      *time.Time
+	}{{"created_from", req.CreatedFrom, &filter.CreatedFrom}, {"created_to", req.CreatedTo, &filter.CreatedTo}} {
+		if param.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, param.value)
+		if err != nil {
+			fields = append(fields, domainerr.FieldError{Field: param.name, Code: domainerr.CodeInvalidDate, Message: param.name + " must be an RFC 3339 timestamp"})
+			continue
+		}
+		*param.target = t
+	}
+	for _, param := range []struct {
+		name, value string
+		target      *int
+	}{{"limit", req.Limit, &filter.Limit}, {"offset", req.Offset, &filter.Offset}} {
+		if param.value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(param.value)
+		if err != nil || n < 0 {
+			fields = append(fields, domainerr.FieldError{Field: param.name, Code: domainerr.CodeInvalidPage, Message: param.name + " must be a non-negative integer"})
+			continue
+		}
+		*param.target = n
+	}
+	if req.Cursor != "" {
+		cursor, err := entity.ParseUserCursor(req.Cursor)
+		switch {
+		case err != nil:
+			fields = append(fields, domainerr.Field("cursor", domainerr.CodeInvalidCursor, err))
+		case req.Offset != "":
+			fields = append(fields, domainerr.FieldError{Field: "cursor", Code: domainerr.CodeInvalidCursor, Message: "cursor and offset cannot be combined"})
+		case cursor.Sort != filter.Sort || cursor.Desc != filter.Desc:
+			fields = append(fields, domainerr.FieldError{Field: "cursor", Code: domainerr.CodeInvalidCursor, Message: "cursor belongs to a listing with a different sort order"})
+		default:
+			filter.After = cursor
+		}
+	}
+
+	if len(fields) > 0 {
+		return filter, domainerr.Validation(fields...)
+	}
+	return filter, nil
+}
@@ -2,7 +2,7 @@ package user
 
 type CreateUserRequest struct {
 	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Password string `json:"password" binding:"required" log:"redact"`
 	Email    string `json:"email" binding:"required,email"`
 	Role     string `json:"role" binding:"required"`
 }
@@ -14,5 +14,70 @@ type EditUserRequest struct {
 
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Password string `json:"password" binding:"required" log:"redact"`
+}
+
+// ListUsersRequest is the query of GET /users. Every field is optional.
+type ListUsersRequest struct {
+	Role string `form:"role"`
+	// Status lists the users with one status; deleted users are only listed
+	// with status=deleted.
+	Status      string `form:"status"`
+	EmailDomain string `form:"email_domain"`
+	// CreatedFrom and CreatedTo are RFC 3339 timestamps; the range includes
+	// CreatedFrom and excludes CreatedTo.
+	CreatedFrom string `form:"created_from"`
+	CreatedTo   string `form:"created_to"`
+	// Search matches part of the username or email.
+	Search string `form:"q"`
+	Sort   string `form:"sort"`
+	// Order is asc (default) or desc.
+	Order  string `form:"order"`
+	Limit  string `form:"limit"`
+	Offset string `form:"offset"`
+	// Cursor is the next_cursor of the previous page; it replaces Offset.
+	Cursor string `form:"cursor"`
+}
+
+// UpdateProfileRequest is the body of PATCH /me. The email is changed through
+// the verified flow of POST /me/email instead.
+type UpdateProfileRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required" log:"redact"`
+	NewPassword     string `json:"new_password" binding:"required" log:"redact"`
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required" log:"redact"`
+}
+
+type ConfirmEmailRequest struct {
+	Token string `json:"token" binding:"required" log:"redact"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required" log:"redact"`
+	NewPassword string `json:"new_password" binding:"required" log:"redact"`
+}
+
+// SetPasswordRequest carries the password an operator sets for a user.
+type SetPasswordRequest struct {
+	NewPassword string `json:"new_password" binding:"required" log:"redact"`
+}
+
+type ChangeRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// SuperadminTransferRequest names the user offered the superadmin role.
+type SuperadminTransferRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
 }
@@ -0,0 +1,297 @@
+// Package cli implements the administrative subcommands of the gateway
+// binary. They load the same configuration as the server and go through the
+// same controllers, services and repositories, so they apply the same rules
+// and leave the same audit trail, with "cli" as the actor.
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"zeneye-gateway/internal/adapter/repository/postgres"
+	"zeneye-gateway/internal/app"
+	"zeneye-gateway/internal/config"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/jwt"
+	"zeneye-gateway/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// AuditActor is the actor name of the audit events the commands record.
+const AuditActor = "cli"
+
+// Exit codes: a command that ran into an error exits with 1, and one that was
+// called wrongly or with an invalid configuration with 2.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// Env is what the commands run against.
+type Env struct {
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+	// Lookup reads the environment, usually os.LookupEnv.
+	Lookup func(string) (string, bool)
+	// OpenDB connects to the configured database, creating it and applying the
+	// pending migrations first.
+	OpenDB func(cfg *config.Config) (*gorm.DB, error)
+	// OpenMigrator opens the migrations of the configured database.
+	OpenMigrator func(cfg *config.Config) (port.Migrator, error)
+}
+
+// DefaultEnv runs the commands in the process environment against the
+// PostgreSQL database of the configuration.
+func DefaultEnv() *Env {
+	return &Env{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Lookup: os.LookupEnv,
+		OpenDB: func(cfg *config.Config) (*gorm.DB, error) {
+			return postgres.InitDB(cfg.Database.URL.Value()), nil
+		},
+		OpenMigrator: func(cfg *config.Config) (port.Migrator, error) {
+			dsn := cfg.Database.URL.Value()
+			if err := postgres.EnsureDatabase(dsn); err != nil {
+				return nil, err
+			}
+			m, err := postgres.NewMigrator(dsn)
+			if err != nil {
+				return nil, err
+			}
+			return m, nil
+		},
+	}
+}
+
+// command is one subcommand, named by one or two words.
+type command struct {
+	name  string
+	args  string
+	about string
+	run   func(env *Env, args []string) int
+}
+
+// commands lists the subcommands in the order the usage shows them. The
+// server itself, serve, is run by main. It is a function rather than a
+// variable because the commands look themselves up in it for their usage.
+func commands() []command {
+	return []command{
+		{"bootstrap", "-username U -email E", "create the first superadmin; the password is read from stdin", runBootstrap},
+		{"migrate up", "", "apply every pending migration", migrateUp},
+		{"migrate down", "[N]", "revert the latest N migrations (default 1)", migrateDown},
+		{"migrate goto", "<version>", "apply or revert migrations until version is the latest applied", migrateGoto},
+		{"migrate force", "<version>", "record version as applied after repairing a failed migration", migrateForce},
+		{"migrate status", "", "list the migrations and which are applied", migrateStatus},
+		{"user create", "-username U -email E -role R", "create a user; the password is read from stdin", userCreate},
+		{"user list", "[-role R] [-status S] [-q TEXT] [-limit N] [-json]", "list users", userList},
+		{"user disable", "<id|username>", "disable a user and sign them out", userDisable},
+		{"user reset-password", "<id|username>", "set a user's password, read from stdin, and sign them out", userResetPassword},
+		{"token issue", "<id|username>", "print an access token for a user", tokenIssue},
+		{"token inspect", "[-offline] <token|->", "show the claims of a token and whether the gateway accepts it", tokenInspect},
+		{"token revoke", "<id|username>", "revoke every token issued to a user", tokenRevoke},
+		{"config validate", "", "check the configuration", configValidate},
+		{"config print", "", "print the effective configuration with secrets masked", configPrint},
+		{"routes test", "[-role R] [-config-only] <path>", "show the upstream and policies a path hits", routesTest},
+	}
+}
+
+// Run runs the subcommand named by the leading words of args and returns the
+// process exit code. Gateway flags such as -config follow a "--".
+func Run(env *Env, args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		Usage(env.Stdout)
+		return exitOK
+	}
+	for _, cmd := range commands() {
+		words := strings.Fields(cmd.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == cmd.name {
+			defer logger.SyncLogger()
+			return cmd.run(env, args[len(words):])
+		}
+	}
+
+	fmt.Fprintf(env.Stderr, "unknown command %q\n\n", strings.Join(args, " "))
+	Usage(env.Stderr)
+	return exitUsage
+}
+
+// Usage describes the commands on w.
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: zeneye-gateway [serve] [gateway flags]")
+	fmt.Fprintln(w, "       zeneye-gateway <command> [flags] [arguments] [-- gateway flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  serve\trun the gateway (the default)\n")
+	for _, cmd := range commands() {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.synopsis(), cmd.about)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Gateway flags, such as -config, select the configuration like they do for serve.")
+}
+
+// invocation is a parsed command line.
+type invocation struct {
+	*Env
+	name string
+	cfg  *config.Config
+	// args are the positional arguments of the command.
+	args []string
+}
+
+// parse parses the command's flags into fs from args up to a "--", loads the
+// configuration from the gateway flags after it, and configures the logger
+// and token signing. The command takes min to max positional arguments, and
+// the flags named in required must be set. On failure the problem is reported
+// and parse returns nil and the exit code.
+func (e *Env) parse(fs *flag.FlagSet, args []string, min, max int, required ...string) (*invocation, int) {
+	fs.SetOutput(e.Stderr)
+	var gateway []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, gateway = args[:i], args[i+1:]
+			break
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, exitOK
+		}
+		return nil, exitUsage
+	}
+	if fs.NArg() < min || fs.NArg() > max {
+		fmt.Fprintf(e.Stderr, "%s: expected %s\n", fs.Name(), argumentCount(min, max))
+		fs.Usage()
+		return nil, exitUsage
+	}
+	for _, name := range required {
+		if fs.Lookup(name).Value.String() == "" {
+			fmt.Fprintf(e.Stderr, "%s: -%s is required\n", fs.Name(), name)
+			return nil, exitUsage
+		}
+	}
+
+	cfg, err := config.Load(gateway, e.Lookup)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil, exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(e.Stderr, "invalid configuration:\n%s\n", err)
+		return nil, exitUsage
+	}
+	if err := logger.Configure(cfg.Log); err != nil {
+		fmt.Fprintf(e.Stderr, "%s: %s\n", fs.Name(), err)
+		return nil, exitError
+	}
+	if err := jwt.Configure(cfg.JWT()); err != nil {
+		fmt.Fprintf(e.Stderr, "%s: %s\n", fs.Name(), err)
+		return nil, exitError
+	}
+	return &invocation{Env: e, name: fs.Name(), cfg: cfg, args: fs.Args()}, exitOK
+}
+
+func argumentCount(min, max int) string {
+	switch {
+	case max == 0:
+		return "no arguments"
+	case min == max:
+		return fmt.Sprintf("%d argument(s)", min)
+	default:
+		return fmt.Sprintf("%d to %d arguments", min, max)
+	}
+}
+
+// synopsis is the command's name and arguments.
+func (c command) synopsis() string {
+	return strings.TrimSpace(c.name + " " + c.args)
+}
+
+// newFlagSet returns the flag set of the command name, whose usage shows the
+// command's synopsis and flags.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		synopsis := name
+		for _, cmd := range commands() {
+			if cmd.name == name {
+				synopsis = cmd.synopsis()
+			}
+		}
+		fmt.Fprintf(fs.Output(), "Usage: zeneye-gateway %s [-- gateway flags]\n", synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// open connects to the database and builds the services over it.
+func (inv *invocation) open() (*app.App, error) {
+	db, err := inv.OpenDB(inv.cfg)
+	if err != nil {
+		return nil, err
+	}
+	return app.New(db, inv.cfg), nil
+}
+
+// fail reports err and returns the exit code for it.
+func (inv *invocation) fail(err error) int {
+	fmt.Fprintf(inv.Stderr, "%s: %s\n", inv.name, err)
+	return exitError
+}
+
+// readPassword reads the password from the first line of stdin, which keeps
+// it out of the shell history and the process list.
+func (inv *invocation) readPassword() (string, error) {
+	password, err := bufio.NewReader(inv.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	return strings.TrimRight(password, "\r\n"), nil
+}
+
+// record stores the audit event of an action taken from the command line,
+// with the given outcome and before/after diff. Failures are logged: the
+// action already happened.
+func (inv *invocation) record(audit port.AuditService, event *entity.AuditEvent, before, after map[string]interface{}, err error) {
+	if event.ActorUsername == "" {
+		event.ActorUsername = AuditActor
+	}
+	if changes := entity.DiffAudit(before, after); len(changes) > 0 {
+		encoded, _ := json.Marshal(changes)
+		event.Changes = string(encoded)
+	}
+	event.Outcome = entity.AuditOutcomeSuccess
+	if err != nil {
+		event.Outcome = entity.AuditOutcomeFailure
+		event.Reason = err.Error()
+	}
+	if recordErr := audit.Record(event); recordErr != nil {
+		logger.LogError("CLI", "Record", event.Action, recordErr)
+	}
+}
+
+// userEvent starts the audit event of action on user.
+func userEvent(action string, user *entity.User) *entity.AuditEvent {
+	return &entity.AuditEvent{Action: action, TargetType: "user", TargetID: strconv.FormatUint(uint64(user.ID), 10)}
+}
+
+// lookupUser finds a user by ID or, when ref is not a number, by username.
+func lookupUser(users port.UserService, ref string) (*entity.User, error) {
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		return users.GetUser(uint(id))
+	}
+	return users.GetUserByUsername(ref)
+}
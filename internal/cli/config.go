@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configValidate loads the configuration like serve does; parse reports every
+// problem and exits with 2 when it is invalid.
+func configValidate(env *Env, args []string) int {
+	inv, code := env.parse(newFlagSet("config validate"), args, 0, 0)
+	if inv == nil {
+		return code
+	}
+
+	fmt.Fprintln(inv.Stdout, "Configuration is valid")
+	if len(inv.cfg.Files) > 0 {
+		fmt.Fprintf(inv.Stdout, "Read from %s\n", strings.Join(inv.cfg.Files, ", "))
+	}
+	return exitOK
+}
+
+// configPrint prints the effective configuration as YAML that config files
+// accept, with secrets masked.
+func configPrint(env *Env, args []string) int {
+	inv, code := env.parse(newFlagSet("config print"), args, 0, 0)
+	if inv == nil {
+		return code
+	}
+
+	encoder := yaml.NewEncoder(inv.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(inv.cfg.Redacted()); err != nil {
+		return inv.fail(err)
+	}
+	if err := encoder.Close(); err != nil {
+		return inv.fail(err)
+	}
+	return exitOK
+}
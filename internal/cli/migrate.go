@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"zeneye-gateway/internal/domain/port"
+)
+
+func migrateUp(env *Env, args []string) int {
+	return env.migrate("migrate up", args, 0, 0, func(inv *invocation, m port.Migrator) error {
+		if err := m.Up(); err != nil {
+			return err
+		}
+		return printVersion(inv, m)
+	})
+}
+
+func migrateDown(env *Env, args []string) int {
+	return env.migrate("migrate down", args, 0, 1, func(inv *invocation, m port.Migrator) error {
+		steps := 1
+		if len(inv.args) > 0 {
+			n, err := strconv.Atoi(inv.args[0])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("N must be a positive number, got %q", inv.args[0])
+			}
+			steps = n
+		}
+		if err := m.Down(steps); err != nil {
+			return err
+		}
+		return printVersion(inv, m)
+	})
+}
+
+func migrateGoto(env *Env, args []string) int {
+	return env.migrate("migrate goto", args, 1, 1, func(inv *invocation, m port.Migrator) error {
+		version, err := strconv.ParseUint(inv.args[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf("version must be a migration version or 0, got %q", inv.args[0])
+		}
+		if err := m.Goto(uint(version)); err != nil {
+			return err
+		}
+		return printVersion(inv, m)
+	})
+}
+
+// migrateForce only records the version; it is meant for a schema left dirty
+// by a failed migration once that was repaired by hand.
+func migrateForce(env *Env, args []string) int {
+	return env.migrate("migrate force", args, 1, 1, func(inv *invocation, m port.Migrator) error {
+		version, err := strconv.ParseUint(inv.args[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf("version must be a migration version or 0, got %q", inv.args[0])
+		}
+		if err := m.Force(uint(version)); err != nil {
+			return err
+		}
+		return printVersion(inv, m)
+	})
+}
+
+func migrateStatus(env *Env, args []string) int {
+	return env.migrate("migrate status", args, 0, 0, func(inv *invocation, m port.Migrator) error {
+		if err := printVersion(inv, m); err != nil {
+			return err
+		}
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(inv.Stdout, "  %-8s %06d %s\n", state, s.Version, s.Name)
+		}
+		return nil
+	})
+}
+
+// migrate parses the command line of the migrate command name and runs fn
+// with the migrations of the configured database.
+func (e *Env) migrate(name string, args []string, min, max int, fn func(*invocation, port.Migrator) error) int {
+	inv, code := e.parse(newFlagSet(name), args, min, max)
+	if inv == nil {
+		return code
+	}
+
+	m, err := inv.OpenMigrator(inv.cfg)
+	if err != nil {
+		return inv.fail(err)
+	}
+	defer m.Close()
+	if err := fn(inv, m); err != nil {
+		return inv.fail(err)
+	}
+	return exitOK
+}
+
+// printVersion prints the schema version and whether it is dirty.
+func printVersion(inv *invocation, m port.Migrator) error {
+	version, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		fmt.Fprintf(inv.Stdout, "Schema version %d (dirty: the last migration failed halfway)\n", version)
+		return nil
+	}
+	fmt.Fprintf(inv.Stdout, "Schema version %d\n", version)
+	return nil
+}
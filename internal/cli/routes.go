@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+
+	gateway "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/pkg/loadbalancer"
+	"zeneye-gateway/pkg/rate_limiter"
+)
+
+// routesTest shows where the gateway would proxy a path and the route roles,
+// rate limit and CORS rule it would apply, using the stored policy version
+// like the running gateway unless -config-only is given. It exits with 1 when
+// the path would not reach an upstream.
+func routesTest(env *Env, args []string) int {
+	fs := newFlagSet("routes test")
+	role := fs.String("role", "", "also tell whether a user with this role gets through")
+	configOnly := fs.Bool("config-only", false, "ignore the policies stored through the gateway admin API")
+	inv, code := env.parse(fs, args, 1, 1)
+	if inv == nil {
+		return code
+	}
+
+	path := inv.args[0]
+	if u, err := url.Parse(path); err == nil {
+		path = u.Path
+	}
+	path = "/" + strings.TrimPrefix(path, "/")
+
+	cfg, source := inv.cfg, "configuration"
+	if !*configOnly {
+		deps, err := inv.open()
+		if err != nil {
+			return inv.fail(err)
+		}
+		var version uint
+		cfg, version, err = gateway.WithStoredPolicies(deps.Policies, cfg)
+		if err != nil {
+			return inv.fail(err)
+		}
+		if version > 0 {
+			source = fmt.Sprintf("stored version %d", version)
+		}
+	}
+
+	w := tabwriter.NewWriter(inv.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintf(w, "path\t%s\n", path)
+	fmt.Fprintf(w, "policies\t%s\n", source)
+
+	var err error
+	balancer := loadbalancer.New(cfg.Routes, cfg.Pools)
+	route, ok := balancer.Match(path)
+	switch {
+	case !ok:
+		fmt.Fprintf(w, "route\tnone\n")
+		err = errors.New("no route matches " + path + "; the gateway answers 404")
+	case route.URL == "" && route.Pool == "":
+		fmt.Fprintf(w, "route\t%s\n", route.Prefix)
+		fmt.Fprintf(w, "upstream\tnone configured\n")
+		err = errors.New("route " + route.Prefix + " has no upstream; the gateway answers 502")
+	default:
+		fmt.Fprintf(w, "route\t%s\n", route.Prefix)
+		upstreamPath := "/" + strings.TrimPrefix(strings.TrimPrefix(path, "/"+route.Prefix), "/")
+		if route.Pool != "" {
+			for _, pool := range cfg.Pools {
+				if pool.Name == route.Pool {
+					fmt.Fprintf(w, "upstream\tpool %s, in turn: %s\n", pool.Name, strings.Join(pool.Targets, ", "))
+				}
+			}
+		} else {
+			fmt.Fprintf(w, "upstream\t%s\n", route.URL)
+		}
+		fmt.Fprintf(w, "upstream path\t%s\n", upstreamPath)
+		err = describeRoles(w, route, *role)
+	}
+
+	limit := rate_limiter.New(cfg.RateLimit).PolicyFor(path)
+	fmt.Fprintf(w, "rate limit\t%d requests/s per client, burst %d (%s)\n", limit.RequestsPerSecond, limit.Burst, scope(limit.Prefix))
+
+	cors, prefix := cfg.CORS.Resolve(path)
+	origins := "none"
+	if len(cors.AllowedOrigins) > 0 {
+		origins = strings.Join(cors.AllowedOrigins, ", ")
+	}
+	fmt.Fprintf(w, "cors\torigins %s; methods %s; credentials %t (%s)\n", origins, strings.Join(cors.AllowedMethods, ", "), cors.AllowCredentials, scope(prefix))
+
+	if err != nil {
+		w.Flush()
+		return inv.fail(err)
+	}
+	return exitOK
+}
+
+// describeRoles writes who may use route and, when role is set, whether it
+// may. It returns an error when role is turned away.
+func describeRoles(w io.Writer, route loadbalancer.Route, role string) error {
+	if len(route.Roles) == 0 {
+		fmt.Fprintf(w, "roles\tany authenticated user\n")
+	} else {
+		fmt.Fprintf(w, "roles\t%s\n", strings.Join(route.Roles, ", "))
+	}
+	if role == "" {
+		return nil
+	}
+	allowed := len(route.Roles) == 0
+	for _, r := range route.Roles {
+		allowed = allowed || r == role
+	}
+	if !allowed {
+		fmt.Fprintf(w, "role %s\tdenied\n", role)
+		return errors.New("role " + role + " may not use route " + route.Prefix + "; the gateway answers 403")
+	}
+	fmt.Fprintf(w, "role %s\tallowed\n", role)
+	return nil
+}
+
+// scope names where a per-prefix policy comes from.
+func scope(prefix string) string {
+	if prefix == "" {
+		return "gateway-wide"
+	}
+	return "route " + prefix
+}
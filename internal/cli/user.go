@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"zeneye-gateway/internal/adapter/http/handlers"
+	"zeneye-gateway/internal/app"
+	"zeneye-gateway/internal/application/user"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+)
+
+// runBootstrap creates the first superadmin, which is the only way to get one
+// in production mode. Like the HTTP bootstrap, the new account is the actor
+// of its own audit event.
+func runBootstrap(env *Env, args []string) int {
+	fs := newFlagSet("bootstrap")
+	username := fs.String("username", "", "superadmin username")
+	email := fs.String("email", "", "superadmin email")
+	inv, code := env.parse(fs, args, 0, 0, "username", "email")
+	if inv == nil {
+		return code
+	}
+
+	req := user.CreateUserRequest{Username: *username, Email: *email, Role: entity.RoleSuperadmin}
+	created, code := inv.createUser(req, handlers.AuditActionSuperadminCreate, true)
+	if created != nil {
+		fmt.Fprintf(inv.Stdout, "Superadmin %s created\n", created.Username)
+	}
+	return code
+}
+
+func userCreate(env *Env, args []string) int {
+	fs := newFlagSet("user create")
+	username := fs.String("username", "", "username")
+	email := fs.String("email", "", "email")
+	role := fs.String("role", "", "role: superadmin, admin, department_admin or auditor")
+	inv, code := env.parse(fs, args, 0, 0, "username", "email", "role")
+	if inv == nil {
+		return code
+	}
+
+	req := user.CreateUserRequest{Username: *username, Email: *email, Role: *role}
+	created, code := inv.createUser(req, handlers.AuditActionUserCreate, false)
+	if created != nil {
+		fmt.Fprintf(inv.Stdout, "User %s created with ID %d\n", created.Username, created.ID)
+	}
+	return code
+}
+
+// createUser creates the user of req with the password read from stdin and
+// records action, attributed to the new user itself when self is set.
+func (inv *invocation) createUser(req user.CreateUserRequest, action string, self bool) (*entity.User, int) {
+	password, err := inv.readPassword()
+	if err != nil {
+		return nil, inv.fail(err)
+	}
+	req.Password = password
+
+	deps, err := inv.open()
+	if err != nil {
+		return nil, inv.fail(err)
+	}
+	if err := user.NewUserController(deps.Users).CreateUser(entity.SystemActorID, req); err != nil {
+		return nil, inv.fail(err)
+	}
+
+	created, err := deps.Users.GetUserByUsername(req.Username)
+	if err != nil {
+		return nil, inv.fail(err)
+	}
+	event := userEvent(action, created)
+	if self {
+		actorID := created.ID
+		event.ActorID = &actorID
+		event.ActorUsername = created.Username
+		event.ActorRole = created.Role
+	}
+	inv.record(deps.Audit, event, nil, created.AuditSnapshot(), nil)
+	return created, exitOK
+}
+
+func userList(env *Env, args []string) int {
+	fs := newFlagSet("user list")
+	req := user.ListUsersRequest{}
+	fs.StringVar(&req.Role, "role", "", "only users with this role")
+	fs.StringVar(&req.Status, "status", "", "only users with this status; deleted users are only listed with deleted")
+	fs.StringVar(&req.Search, "q", "", "only users whose username or email contains this")
+	fs.StringVar(&req.Limit, "limit", "", "page size")
+	fs.StringVar(&req.Offset, "offset", "", "users to skip")
+	asJSON := fs.Bool("json", false, "print the page as JSON, like GET /users")
+	inv, code := env.parse(fs, args, 0, 0)
+	if inv == nil {
+		return code
+	}
+
+	deps, err := inv.open()
+	if err != nil {
+		return inv.fail(err)
+	}
+	page, err := user.NewUserController(deps.Users).ListUsers(req)
+	if err != nil {
+		return inv.fail(err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(inv.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(page); err != nil {
+			return inv.fail(err)
+		}
+		return exitOK
+	}
+	w := tabwriter.NewWriter(inv.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSERNAME\tEMAIL\tROLE\tSTATUS\tCREATED")
+	for _, u := range page.Users {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", u.ID, u.Username, u.Email, u.Role, u.Status, u.CreatedAt.Format(time.RFC3339))
+	}
+	w.Flush()
+	fmt.Fprintf(inv.Stdout, "%d of %d users\n", len(page.Users), page.Total)
+	return exitOK
+}
+
+func userDisable(env *Env, args []string) int {
+	inv, code := env.parse(newFlagSet("user disable"), args, 1, 1)
+	if inv == nil {
+		return code
+	}
+
+	deps, target, err := inv.openUser(inv.args[0])
+	if err != nil {
+		return inv.fail(err)
+	}
+	id := strconv.FormatUint(uint64(target.ID), 10)
+	err = user.NewUserController(deps.Users).DisableUser(entity.SystemActorID, id)
+	inv.record(deps.Audit, userEvent(handlers.AuditActionUserDisable, target), target.AuditSnapshot(), userSnapshot(deps.Users, target.ID), err)
+	if err != nil {
+		return inv.fail(err)
+	}
+
+	fmt.Fprintf(inv.Stdout, "User %s disabled\n", target.Username)
+	return exitOK
+}
+
+func userResetPassword(env *Env, args []string) int {
+	inv, code := env.parse(newFlagSet("user reset-password"), args, 1, 1)
+	if inv == nil {
+		return code
+	}
+
+	password, err := inv.readPassword()
+	if err != nil {
+		return inv.fail(err)
+	}
+	deps, target, err := inv.openUser(inv.args[0])
+	if err != nil {
+		return inv.fail(err)
+	}
+	id := strconv.FormatUint(uint64(target.ID), 10)
+	_, err = user.NewAccountController(deps.Users, deps.Accounts).SetPassword(id, user.SetPasswordRequest{NewPassword: password})
+	inv.record(deps.Audit, userEvent(handlers.AuditActionPasswordSet, target), nil, nil, err)
+	if err != nil {
+		return inv.fail(err)
+	}
+
+	fmt.Fprintf(inv.Stdout, "Password of %s set; their sessions were signed out\n", target.Username)
+	return exitOK
+}
+
+// openUser connects to the database and finds the user ref names.
+func (inv *invocation) openUser(ref string) (*app.App, *entity.User, error) {
+	deps, err := inv.open()
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := lookupUser(deps.Users, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	return deps, target, nil
+}
+
+// userSnapshot loads the audited state of user id, or nil if it can't be read.
+func userSnapshot(users port.UserService, id uint) map[string]interface{} {
+	target, err := users.GetUser(id)
+	if err != nil {
+		return nil
+	}
+	return target.AuditSnapshot()
+}
@@ -0,0 +1,355 @@
+// Package config assembles the gateway configuration from defaults, a YAML or
+// TOML file, a dotenv file, the environment and command-line flags, and
+// validates it once at startup so components receive typed values instead of
+// reading the environment themselves.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"zeneye-gateway/internal/adapter/bootstrap"
+	"zeneye-gateway/internal/adapter/identity"
+	"zeneye-gateway/internal/adapter/notify"
+	"zeneye-gateway/pkg/accesslog"
+	"zeneye-gateway/pkg/cors"
+	"zeneye-gateway/pkg/health"
+	"zeneye-gateway/pkg/jwt"
+	"zeneye-gateway/pkg/loadbalancer"
+	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/rate_limiter"
+	"zeneye-gateway/pkg/security"
+	"zeneye-gateway/pkg/server"
+	"zeneye-gateway/pkg/tracing"
+)
+
+// Config is the complete gateway configuration.
+type Config struct {
+	// Env names the deployment, e.g. development or production, and selects the
+	// default .env.<Env> file.
+	Env string `yaml:"env" toml:"env"`
+	// GinMode is debug, release or test; development and production are
+	// accepted as aliases of debug and release. Empty derives it from Env.
+	GinMode string `yaml:"gin_mode" toml:"gin_mode"`
+
+	Database  DatabaseConfig       `yaml:"database" toml:"database"`
+	Auth      AuthConfig           `yaml:"auth" toml:"auth"`
+	Server    server.Options       `yaml:"server" toml:"server"`
+	RateLimit rate_limiter.Options `yaml:"rate_limit" toml:"rate_limit"`
+	// Routes map path prefixes to upstream services; a file that sets them
+	// replaces the built-in table.
+	Routes []loadbalancer.Route `yaml:"routes" toml:"routes"`
+	// Pools are the upstream pools routes may use instead of a single URL.
+	Pools     []loadbalancer.Pool `yaml:"pools" toml:"pools"`
+	Log       logger.Options      `yaml:"log" toml:"log"`
+	AccessLog accesslog.Options   `yaml:"access_log" toml:"access_log"`
+	Tracing   tracing.Options     `yaml:"tracing" toml:"tracing"`
+	CORS      cors.Options        `yaml:"cors" toml:"cors"`
+	Security  security.Options    `yaml:"security" toml:"security"`
+	Health    health.Options      `yaml:"health" toml:"health"`
+	Identity  identity.Options    `yaml:"identity" toml:"identity"`
+	Notify    notify.Options      `yaml:"notify" toml:"notify"`
+	Bootstrap bootstrap.Options   `yaml:"bootstrap" toml:"bootstrap"`
+	Reload    ReloadConfig        `yaml:"reload" toml:"reload"`
+
+	// Files lists the configuration and dotenv files Load read, which are the
+	// ones a reload watches.
+	Files []string `yaml:"-" toml:"-"`
+}
+
+// ReloadConfig controls how the running gateway picks up configuration changes.
+type ReloadConfig struct {
+	// Watch reloads when a configuration file changes; SIGHUP always reloads.
+	Watch bool `yaml:"watch" toml:"watch"`
+	// Interval is how often the files are checked for changes.
+	Interval time.Duration `yaml:"interval" toml:"interval"`
+}
+
+// DatabaseConfig locates the database.
+type DatabaseConfig struct {
+	URL Secret `yaml:"url" toml:"url" log:"redact"`
+}
+
+// AuthConfig configures token signing.
+type AuthConfig struct {
+	JWTSecret       Secret        `yaml:"jwt_secret" toml:"jwt_secret" log:"redact"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+}
+
+// Secret is a string that is masked whenever it is printed or marshaled. The
+// logger walks structs itself, so Secret fields also carry `log:"redact"`.
+type Secret string
+
+const masked = "[REDACTED]"
+
+// String masks non-empty secrets.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return masked
+}
+
+// GoString masks the secret in %#v output.
+func (s Secret) GoString() string {
+	return strconv.Quote(s.String())
+}
+
+// MarshalText masks the secret in JSON, YAML and log output.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Value returns the secret itself.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// Redacted returns a copy of c that is safe to print: every non-empty string
+// field tagged `log:"redact"`, Secret or not, reads "[REDACTED]".
+func (c *Config) Redacted() *Config {
+	out := *c
+	redactFields(reflect.ValueOf(&out).Elem())
+	return &out
+}
+
+// redactFields masks the tagged fields of the struct v and of the structs it
+// holds by value. Slices and pointers are shared with the original, so they
+// are left alone; no tagged field lives behind one.
+func redactFields(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		switch {
+		case !field.IsExported():
+		case value.Kind() == reflect.Struct:
+			redactFields(value)
+		case value.Kind() == reflect.String && value.Len() > 0 && field.Tag.Get("log") == logger.RedactTag:
+			value.SetString(masked)
+		}
+	}
+}
+
+// Default returns the built-in configuration. It has no database URL or JWT
+// secret, so it does not validate on its own.
+func Default() *Config {
+	jwtOpts := jwt.DefaultOptions()
+	return &Config{
+		Env: "development",
+		Auth: AuthConfig{
+			AccessTokenTTL:  jwtOpts.AccessTokenTTL,
+			RefreshTokenTTL: jwtOpts.RefreshTokenTTL,
+		},
+		Server:    server.DefaultOptions(),
+		RateLimit: rate_limiter.DefaultOptions(),
+		Routes:    loadbalancer.DefaultRoutes(),
+		Log:       logger.DefaultOptions(),
+		AccessLog: accesslog.DefaultOptions(),
+		Tracing:   tracing.DefaultOptions(),
+		CORS:      cors.DefaultOptions(),
+		Security:  security.DefaultOptions(),
+		Health:    health.DefaultOptions(),
+		Identity:  identity.DefaultOptions(),
+		Notify:    notify.DefaultOptions(),
+		Bootstrap: bootstrap.DefaultOptions(),
+		Reload:    ReloadConfig{Watch: true, Interval: 5 * time.Second},
+	}
+}
+
+// LoadEnv overrides c with the variables lookup finds: GO_ENV, GIN_MODE,
+// DATABASE_URL, JWT_SECRET, JWT_EXPIRATION and REFRESH_TOKEN_EXPIRATION (hours,
+// or Go durations such as 90m), CONFIG_WATCH, CONFIG_WATCH_INTERVAL, the
+// <SERVICE>_SERVICE_URL of each route, and every variable read by the
+// component options. Every unparsable value is
+// reported at once.
+func (c *Config) LoadEnv(lookup func(string) (string, bool)) error {
+	getenv := func(key string) string { v, _ := lookup(key); return v }
+	var errs []error
+
+	if v := getenv("GO_ENV"); v != "" {
+		c.Env = v
+	}
+	if v := getenv("GIN_MODE"); v != "" {
+		c.GinMode = v
+	}
+	if v := getenv("DATABASE_URL"); v != "" {
+		c.Database.URL = Secret(v)
+	}
+	if v := getenv("JWT_SECRET"); v != "" {
+		c.Auth.JWTSecret = Secret(v)
+	}
+	for key, target := range map[string]*time.Duration{
+		"JWT_EXPIRATION":           &c.Auth.AccessTokenTTL,
+		"REFRESH_TOKEN_EXPIRATION": &c.Auth.RefreshTokenTTL,
+	} {
+		if v := getenv(key); v != "" {
+			d, err := parseTTL(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				continue
+			}
+			*target = d
+		}
+	}
+	if v := getenv("CONFIG_WATCH"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("CONFIG_WATCH: %w", err))
+		} else {
+			c.Reload.Watch = b
+		}
+	}
+	if v := getenv("CONFIG_WATCH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("CONFIG_WATCH_INTERVAL: %w", err))
+		} else {
+			c.Reload.Interval = d
+		}
+	}
+	loadbalancer.LoadEnv(c.Routes, lookup)
+
+	errs = append(errs,
+		c.Server.LoadEnv(lookup),
+		c.RateLimit.LoadEnv(lookup),
+		c.Log.LoadEnv(lookup),
+		c.AccessLog.LoadEnv(lookup),
+		c.Tracing.LoadEnv(lookup),
+		c.CORS.LoadEnv(lookup),
+		c.Security.LoadEnv(lookup),
+		c.Health.LoadEnv(lookup),
+		c.Identity.LoadEnv(lookup),
+		c.Notify.LoadEnv(lookup),
+		c.Bootstrap.LoadEnv(lookup),
+	)
+	return errors.Join(errs...)
+}
+
+// parseTTL reads a bare integer as hours, as JWT_EXPIRATION always has, and
+// anything else as a Go duration.
+func parseTTL(v string) (time.Duration, error) {
+	if hours, err := strconv.Atoi(v); err == nil {
+		return time.Duration(hours) * time.Hour, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// Validate reports every invalid setting at once, each prefixed with its
+// section.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Env == "" {
+		errs = append(errs, errors.New("env must not be empty"))
+	}
+	switch strings.ToLower(c.GinMode) {
+	case "", "debug", "release", "test", "development", "production":
+	default:
+		errs = append(errs, fmt.Errorf("gin mode %q must be debug, release or test", c.GinMode))
+	}
+	if c.Reload.Watch && c.Reload.Interval <= 0 {
+		errs = append(errs, errors.New("reload: interval must be positive when watching"))
+	}
+	if c.Database.URL == "" {
+		errs = append(errs, errors.New("database: url must be set (DATABASE_URL)"))
+	}
+	errs = append(errs,
+		section("auth", c.JWT().Validate()),
+		section("server", c.Server.Validate()),
+		section("rate limit", c.RateLimit.Validate()),
+		section("routes", loadbalancer.Validate(c.Routes, c.Pools)),
+		section("log", c.Log.Validate()),
+		section("access log", c.AccessLog.Validate()),
+		section("tracing", c.Tracing.Validate()),
+		section("cors", c.CORS.Validate()),
+		section("security", c.Security.Validate()),
+		section("health", c.Health.Validate()),
+		section("identity", c.Identity.Validate()),
+		section("notify", c.Notifier().Validate()),
+		section("bootstrap", c.Bootstrap.Validate()),
+	)
+	return errors.Join(errs...)
+}
+
+// section prefixes every error joined in err with name.
+func section(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, fmt.Errorf("%s: %w", name, e))
+	}
+	return errors.Join(errs...)
+}
+
+// Changed returns the file names of the top-level sections that differ
+// between c and other, e.g. "database" or "rate_limit".
+func (c *Config) Changed(other *Config) []string {
+	var changed []string
+	a, b := reflect.ValueOf(c).Elem(), reflect.ValueOf(other).Elem()
+	for i := 0; i < a.NumField(); i++ {
+		name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// JWT returns the token signing options.
+func (c *Config) JWT() jwt.Options {
+	return jwt.Options{
+		Secret:          c.Auth.JWTSecret.Value(),
+		AccessTokenTTL:  c.Auth.AccessTokenTTL,
+		RefreshTokenTTL: c.Auth.RefreshTokenTTL,
+	}
+}
+
+// Notifier returns the notification options, with the notification service
+// URL taken from the notify route unless it is set.
+func (c *Config) Notifier() notify.Options {
+	opts := c.Notify
+	if opts.Service.URL == "" {
+		for _, route := range c.Routes {
+			if route.Prefix == "notify" {
+				opts.Service.URL = route.URL
+			}
+		}
+	}
+	return opts
+}
+
+// BootstrapHTTP reports whether the first superadmin may be created over
+// HTTP, which is never the case in production mode.
+func (c *Config) BootstrapHTTP() bool {
+	return c.Bootstrap.HTTP && c.Mode() != "release"
+}
+
+// Mode returns the gin mode, resolving aliases and deriving it from Env when
+// unset.
+func (c *Config) Mode() string {
+	mode := strings.ToLower(c.GinMode)
+	if mode == "" {
+		mode = strings.ToLower(c.Env)
+	}
+	switch mode {
+	case "release", "production":
+		return "release"
+	case "test":
+		return "test"
+	default:
+		return "debug"
+	}
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the configuration from, in increasing precedence, the defaults,
+// a YAML or TOML file (-config or CONFIG_FILE), a dotenv file (-env-file, by
+// default .env.<env> when it exists), the environment as seen by lookup
+// (usually os.LookupEnv) and the flags in args, then validates the result.
+// Every problem found on the way is reported in one error.
+func Load(args []string, lookup func(string) (string, bool)) (*Config, error) {
+	fs := flag.NewFlagSet("zeneye-gateway", flag.ContinueOnError)
+	configFile := fs.String("config", "", "YAML or TOML configuration `file` (CONFIG_FILE)")
+	envFile := fs.String("env-file", "", "dotenv `file` (default .env.<env>)")
+	env := fs.String("env", "", "deployment environment (GO_ENV)")
+	addr := fs.String("addr", "", "listen address (SERVER_ADDR)")
+	ginMode := fs.String("gin-mode", "", "gin mode (GIN_MODE)")
+	logLevel := fs.String("log-level", "", "log level (LOG_LEVEL)")
+	rateLimit := fs.Int("rate-limit", 0, "requests per second per client (RATE_LIMIT)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	cfg := Default()
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookup("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, err
+		}
+		cfg.Files = append(cfg.Files, path)
+	}
+
+	envName := *env
+	if envName == "" {
+		envName, _ = lookup("GO_ENV")
+	}
+	if envName == "" {
+		envName = cfg.Env
+	}
+	dotenv, dotenvPath, err := readDotenv(*envFile, ".env."+envName)
+	if err != nil {
+		return nil, err
+	}
+	if dotenvPath != "" {
+		cfg.Files = append(cfg.Files, dotenvPath)
+	}
+	layered := func(key string) (string, bool) {
+		if v, ok := lookup(key); ok {
+			return v, true
+		}
+		v, ok := dotenv[key]
+		return v, ok
+	}
+	envErr := cfg.LoadEnv(layered)
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "env":
+			cfg.Env = *env
+		case "addr":
+			cfg.Server.Addr = *addr
+		case "gin-mode":
+			cfg.GinMode = *ginMode
+		case "log-level":
+			cfg.Log.Level = strings.ToLower(*logLevel)
+		case "rate-limit":
+			cfg.RateLimit.RequestsPerSecond = *rateLimit
+		}
+	})
+
+	if err := errors.Join(envErr, cfg.Validate()); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile decodes path over cfg, choosing YAML or TOML by its extension.
+// Unknown keys are errors so typos do not silently fall back to defaults.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+			return fmt.Errorf("config file %s: unknown keys %s", path, strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("config file %s: extension must be .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// readDotenv reads explicit, which must exist, or else fallback when it exists,
+// and returns the path it read.
+func readDotenv(explicit, fallback string) (map[string]string, string, error) {
+	path := explicit
+	if path == "" {
+		if _, err := os.Stat(fallback); err != nil {
+			return nil, "", nil
+		}
+		path = fallback
+	}
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("env file: %w", err)
+	}
+	return values, path, nil
+}
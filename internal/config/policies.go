@@ -0,0 +1,42 @@
+package config
+
+import (
+	"encoding/json"
+
+	"zeneye-gateway/pkg/cors"
+	"zeneye-gateway/pkg/loadbalancer"
+	"zeneye-gateway/pkg/rate_limiter"
+)
+
+// Policies are the sections operators may change at runtime through the
+// gateway admin API. Once a version of them is stored, it takes precedence over
+// the same sections of the file and environment.
+type Policies struct {
+	Routes    []loadbalancer.Route `json:"routes"`
+	Pools     []loadbalancer.Pool  `json:"pools"`
+	RateLimit rate_limiter.Options `json:"rate_limit"`
+	CORS      cors.Options         `json:"cors"`
+}
+
+// Policies returns a deep copy of c's runtime-editable sections.
+func (c *Config) Policies() Policies {
+	p := Policies{Routes: c.Routes, Pools: c.Pools, RateLimit: c.RateLimit, CORS: c.CORS}
+	return p.Clone()
+}
+
+// WithPolicies returns a copy of c with its runtime-editable sections replaced
+// by p.
+func (c *Config) WithPolicies(p Policies) *Config {
+	out := *c
+	p = p.Clone()
+	out.Routes, out.Pools, out.RateLimit, out.CORS = p.Routes, p.Pools, p.RateLimit, p.CORS
+	return &out
+}
+
+// Clone returns a copy of p that shares no slices with it.
+func (p Policies) Clone() Policies {
+	var out Policies
+	encoded, _ := json.Marshal(p)
+	json.Unmarshal(encoded, &out)
+	return out
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"sync"
+	"time"
+
+	"zeneye-gateway/pkg/logger"
+)
+
+// Reloader loads the configuration again on demand and hands every valid
+// result to apply. A configuration that fails to load, validate or apply is
+// logged and dropped, leaving the running one in place.
+type Reloader struct {
+	args   []string
+	lookup func(string) (string, bool)
+	apply  func(*Config) error
+	mu     sync.Mutex
+}
+
+// NewReloader returns a Reloader that loads with the same args and lookup the
+// gateway started with.
+func NewReloader(args []string, lookup func(string) (string, bool), apply func(*Config) error) *Reloader {
+	return &Reloader{args: args, lookup: lookup, apply: apply}
+}
+
+// Reload loads and applies the configuration once. Concurrent calls run one
+// after the other.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := Load(r.args, r.lookup)
+	if err == nil {
+		err = r.apply(cfg)
+	}
+	if err != nil {
+		logger.LogError("Config", "Reload", "Configuration rejected; keeping the running configuration", err)
+		return err
+	}
+	logger.LogInfo("Config", "Reload", "Configuration reloaded", cfg.Files)
+	return nil
+}
+
+// Watch reloads whenever signals delivers, e.g. SIGHUP, and, when interval is
+// positive, whenever the content of one of files changes, until ctx is done.
+// Files are compared by content, so editors that replace a file and mounts
+// that swap a symlink are both noticed.
+func (r *Reloader) Watch(ctx context.Context, files []string, interval time.Duration, signals <-chan os.Signal) {
+	var tick <-chan time.Time
+	if interval > 0 && len(files) > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	last := fingerprint(files)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			last = fingerprint(files)
+			r.Reload()
+		case <-tick:
+			current := fingerprint(files)
+			if current == last {
+				continue
+			}
+			last = current
+			r.Reload()
+		}
+	}
+}
+
+// fingerprint hashes the content of files; a missing file hashes as empty.
+func fingerprint(files []string) [sha256.Size]byte {
+	h := sha256.New()
+	for _, path := range files {
+		data, _ := os.ReadFile(path)
+		h.Write([]byte(path))
+		h.Write([]byte{0})
+		h.Write(data)
+		h.Write([]byte{0})
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
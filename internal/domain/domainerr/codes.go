@@ -0,0 +1,66 @@
+package domainerr
+
+// Stable error codes. Clients may branch on these, so never rename one.
+const (
+	CodeValidationFailed    = "validation_failed"
+	CodeInvalidUsername     = "invalid_username"
+	CodeInvalidPassword     = "invalid_password"
+	CodeInvalidEmail        = "invalid_email"
+	CodeInvalidRole         = "invalid_role"
+	CodeInvalidID           = "invalid_id"
+	CodeInvalidSort         = "invalid_sort"
+	CodeInvalidCursor       = "invalid_cursor"
+	CodeInvalidDate         = "invalid_date"
+	CodeInvalidPage         = "invalid_page"
+	CodeWrongPassword       = "wrong_password"
+	CodePasswordReused      = "password_reused"
+	CodeInvalidToken        = "invalid_token"
+	CodeInvalidStatus       = "invalid_status"
+	CodeUserNotFound        = "user_not_found"
+	CodeUserNotDeleted      = "user_not_deleted"
+	CodeAccountDisabled     = "account_disabled"
+	CodeAccountLocked       = "account_locked"
+	CodeLastSuperadmin      = "last_superadmin"
+	CodeRoleEscalation      = "role_escalation"
+	CodeInsufficientRank    = "insufficient_rank"
+	CodeTokenRevoked        = "token_revoked"
+	CodeTransferNotFound    = "transfer_not_found"
+	CodeInvalidTransfer     = "invalid_transfer"
+	CodeInvalidSetupToken   = "invalid_setup_token"
+	CodeEmailTaken          = "email_taken"
+	CodeUsernameTaken       = "username_taken"
+	CodeSuperadminExists    = "superadmin_exists"
+	CodeInvalidCredentials  = "invalid_credentials"
+	CodeInvalidRefreshToken = "invalid_refresh_token"
+	CodeRefreshTokenExpired = "refresh_token_expired"
+	CodeInvalidPolicy       = "invalid_policy"
+	CodePolicyNotFound      = "policy_not_found"
+	CodePolicyExists        = "policy_exists"
+	CodeVersionNotFound     = "policy_version_not_found"
+	CodeMigrationNotFound   = "migration_not_found"
+	CodeInvalidSteps        = "invalid_steps"
+	CodeSchemaDirty         = "schema_dirty"
+)
+
+// Sentinels returned by the services and controllers. Compare with errors.Is.
+var (
+	ErrUserNotFound        = NotFound(CodeUserNotFound, "user not found")
+	ErrEmailTaken          = Conflict(CodeEmailTaken, "email already associated with another account")
+	ErrUsernameTaken       = Conflict(CodeUsernameTaken, "username already taken")
+	ErrSuperadminExists    = Conflict(CodeSuperadminExists, "superadmin already exists")
+	ErrUserNotDeleted      = Conflict(CodeUserNotDeleted, "user is not deleted")
+	ErrAccountDisabled     = Forbidden(CodeAccountDisabled, "account is disabled")
+	ErrAccountLocked       = Forbidden(CodeAccountLocked, "account is locked")
+	ErrLastSuperadmin      = Forbidden(CodeLastSuperadmin, "the last active superadmin cannot be deleted, disabled or demoted")
+	ErrRoleEscalation      = Forbidden(CodeRoleEscalation, "users can only be created, changed or deleted below your own role, with roles below your own role")
+	ErrInsufficientRank    = Forbidden(CodeInsufficientRank, "users can only be disabled, enabled or restored by users ranked above them")
+	ErrInvalidSetupToken   = Forbidden(CodeInvalidSetupToken, "setup token is missing, wrong or already used")
+	ErrTransferNotFound    = NotFound(CodeTransferNotFound, "no pending superadmin transfer")
+	ErrInvalidCredentials  = Unauthorized(CodeInvalidCredentials, "invalid username or password")
+	ErrInvalidRefreshToken = Unauthorized(CodeInvalidRefreshToken, "invalid refresh token")
+	ErrRefreshTokenExpired = Unauthorized(CodeRefreshTokenExpired, "refresh token has expired")
+	ErrInvalidToken        = Validation(FieldError{Field: "token", Code: CodeInvalidToken, Message: "token is invalid or has expired"})
+	ErrVersionNotFound     = NotFound(CodeVersionNotFound, "policy version not found")
+	ErrMigrationNotFound   = NotFound(CodeMigrationNotFound, "migration not found")
+	ErrSchemaDirty         = Conflict(CodeSchemaDirty, "the schema is dirty: the last migration failed halfway; repair it and force its version")
+)
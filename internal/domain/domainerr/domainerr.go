@@ -0,0 +1,110 @@
+// Package domainerr defines the typed errors returned by the domain and
+// application layers. Adapters map them to transport responses by Kind and
+// Code instead of comparing error strings.
+package domainerr
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kind classifies an error independently of its transport.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+)
+
+// FieldError describes one invalid input field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is a classified error with a stable, machine-readable Code.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	// Err is the underlying cause; it is logged but never shown to clients.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is matches any *Error with the same Kind and Code, so wrapped copies of a
+// sentinel still satisfy errors.Is.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+// Wrap returns a copy of e that records cause.
+func (e *Error) Wrap(cause error) *Error {
+	copied := *e
+	copied.Err = cause
+	return &copied
+}
+
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func Unauthorized(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+// Validation reports one or more invalid fields.
+func Validation(fields ...FieldError) *Error {
+	messages := make([]string, 0, len(fields))
+	for _, f := range fields {
+		messages = append(messages, f.Message)
+	}
+	message := "request validation failed"
+	if len(messages) > 0 {
+		message = strings.Join(messages, "; ")
+	}
+	return &Error{Kind: KindValidation, Code: CodeValidationFailed, Message: message, Fields: fields}
+}
+
+// Field builds a FieldError from err's message.
+func Field(field, code string, err error) FieldError {
+	return FieldError{Field: field, Code: code, Message: err.Error()}
+}
+
+// As returns the *Error in err's chain, if any.
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// KindOf returns the Kind of err, KindInternal for unclassified errors.
+func KindOf(err error) Kind {
+	if e, ok := As(err); ok {
+		return e.Kind
+	}
+	return KindInternal
+}
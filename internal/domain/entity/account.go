@@ -0,0 +1,51 @@
+package entity
+
+import "time"
+
+// PasswordHistory is a password hash a user had before changing it.
+type PasswordHistory struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Password  string    `gorm:"size:128;not null" log:"redact"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// EmailChange is a requested change of a user's email that takes effect once
+// the token sent to the new address is confirmed. Only the token's hash is
+// stored.
+type EmailChange struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	NewEmail  string    `gorm:"size:128;not null"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex" log:"redact"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// PasswordReset lets a user who forgot their password set a new one with the
+// token sent to their email. Only the token's hash is stored, and it is deleted
+// once used.
+type PasswordReset struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex" log:"redact"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// SuperadminTransfer is a handover of the superadmin role that takes effect
+// once the recipient accepts it. There is at most one pending transfer.
+type SuperadminTransfer struct {
+	ID         uint      `gorm:"primaryKey"`
+	FromUserID uint      `gorm:"not null"`
+	ToUserID   uint      `gorm:"not null"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// Notification is a message for a user, such as a verification link.
+type Notification struct {
+	To      string
+	Subject string
+	Body    string
+}
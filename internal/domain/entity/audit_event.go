@@ -0,0 +1,125 @@
+package entity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// Audit outcomes.
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent is one append-only entry of the audit trail. Hash covers every
+// other column plus PrevHash, chaining each row to the one before it.
+type AuditEvent struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	OccurredAt    time.Time `gorm:"not null;index" json:"occurred_at"`
+	ActorID       *uint     `gorm:"index" json:"actor_id,omitempty"`
+	ActorUsername string    `gorm:"size:32" json:"actor_username,omitempty"`
+	ActorRole     string    `gorm:"size:32" json:"actor_role,omitempty"`
+	Action        string    `gorm:"size:64;not null;index" json:"action"`
+	TargetType    string    `gorm:"size:32" json:"target_type,omitempty"`
+	TargetID      string    `gorm:"size:64;index" json:"target_id,omitempty"`
+	Changes       string    `gorm:"type:text" json:"changes,omitempty"`
+	IP            string    `gorm:"size:64" json:"ip,omitempty"`
+	RequestID     string    `gorm:"size:128" json:"request_id,omitempty"`
+	Outcome       string    `gorm:"size:16;not null" json:"outcome"`
+	Reason        string    `gorm:"size:256" json:"reason,omitempty"`
+	PrevHash      string    `gorm:"size:64;not null" json:"prev_hash"`
+	Hash          string    `gorm:"size:64;not null;uniqueIndex" json:"hash"`
+}
+
+// AuditChange is the before/after value of one changed attribute.
+type AuditChange struct {
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// AuditFilter narrows an audit query. Zero values match everything.
+type AuditFilter struct {
+	ActorID    *uint
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   string
+	Outcome    string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// AuditVerification is the result of walking the hash chain.
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int    `json:"checked"`
+	BrokenAt uint   `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	LastHash string `json:"last_hash,omitempty"`
+}
+
+// ComputeHash returns the SHA-256 over the event's content and PrevHash.
+// Timestamps are hashed in UTC at microsecond precision, which is what
+// Postgres stores, so a row hashes the same before and after a round trip.
+func (e *AuditEvent) ComputeHash() string {
+	var actorID uint
+	if e.ActorID != nil {
+		actorID = *e.ActorID
+	}
+	payload, _ := json.Marshal([]interface{}{
+		e.PrevHash,
+		e.OccurredAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		actorID,
+		e.ActorUsername,
+		e.ActorRole,
+		e.Action,
+		e.TargetType,
+		e.TargetID,
+		e.Changes,
+		e.IP,
+		e.RequestID,
+		e.Outcome,
+		e.Reason,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// DiffAudit returns the attributes whose values differ between before and
+// after. A nil map stands for a resource that did not exist (create) or no
+// longer exists (delete).
+func DiffAudit(before, after map[string]interface{}) map[string]AuditChange {
+	changes := make(map[string]AuditChange)
+	for key, from := range before {
+		to, ok := after[key]
+		if !ok || !reflect.DeepEqual(from, to) {
+			changes[key] = AuditChange{From: from, To: to}
+		}
+	}
+	for key, to := range after {
+		if _, ok := before[key]; !ok {
+			changes[key] = AuditChange{To: to}
+		}
+	}
+	return changes
+}
+
+// AuditSnapshot is the user state recorded in audit diffs. The password hash
+// is deliberately left out.
+func (u *User) AuditSnapshot() map[string]interface{} {
+	if u == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"username":  u.Username,
+		"email":     u.Email,
+		"role":      u.Role,
+		"status":    u.Status,
+		"user_uuid": u.UserUUID,
+	}
+}
@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// GatewayPolicyVersion is one immutable revision of the runtime-editable
+// gateway policies. The highest version is the one in force; a rollback stores
+// a copy of an older document as a new version.
+type GatewayPolicyVersion struct {
+	Version uint `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	// Document is the policies as JSON.
+	Document  string    `gorm:"type:text;not null" json:"-"`
+	Change    string    `gorm:"size:128;not null" json:"change"`
+	CreatedBy string    `gorm:"size:32" json:"created_by,omitempty"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	// RolledBackFrom is the version whose document this one restored.
+	RolledBackFrom *uint `json:"rolled_back_from,omitempty"`
+}
@@ -0,0 +1,8 @@
+package entity
+
+// MigrationStatus is one schema migration and whether the database has it.
+type MigrationStatus struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
@@ -4,7 +4,7 @@ import "time"
 
 type RefreshToken struct {
 	ID        uint      `gorm:"primaryKey"`
-	Token     string    `gorm:"size:128;not null"`
+	Token     string    `gorm:"size:128;not null" log:"redact"`
 	UserID    uint      `gorm:"not null"`
 	ExpiresAt time.Time `gorm:"not null"`
 	CreatedAt time.Time `gorm:"autoCreateTime"`
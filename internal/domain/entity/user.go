@@ -9,15 +9,66 @@ import (
 	"gorm.io/gorm"
 )
 
+// User statuses. Only active users can sign in or be routed; deleted users
+// are soft-deleted and can be restored.
+const (
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+	UserStatusLocked   = "locked"
+	UserStatusDeleted  = "deleted"
+)
+
+// UserStatuses lists every status, for validation.
+var UserStatuses = []string{UserStatusActive, UserStatusDisabled, UserStatusLocked, UserStatusDeleted}
+
+// Roles.
+const (
+	RoleSuperadmin      = "superadmin"
+	RoleAdmin           = "admin"
+	RoleDepartmentAdmin = "department_admin"
+	RoleAuditor         = "auditor"
+)
+
+// roleRanks orders the roles by privilege. A user may only grant roles ranked
+// below their own, to users ranked below them.
+var roleRanks = map[string]int{
+	RoleSuperadmin:      3,
+	RoleAdmin:           2,
+	RoleDepartmentAdmin: 1,
+	RoleAuditor:         1,
+}
+
+// SystemActorID is the actor ID of actions taken without a signed-in user:
+// from the command line, which has direct access to the database, and the
+// setup token bootstrap. Role ranks do not bind it. It is the largest ID
+// rather than zero, so missing claims can never pass for it.
+const SystemActorID = ^uint(0)
+
+// RoleRank returns the privilege rank of role, or 0 for unknown roles.
+func RoleRank(role string) int {
+	return roleRanks[role]
+}
+
 type User struct {
-	ID        uint      `gorm:"primaryKey"`
-	UserUUID  string    `gorm:"size:36;not null;uniqueIndex"`
-	Username  string    `gorm:"size:32;not null"`
-	Password  string    `gorm:"size:128;not null"`
-	Email     string    `gorm:"size:128;not null"`
-	Role      string    `gorm:"size:32;not null"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	ID       uint   `gorm:"primaryKey"`
+	UserUUID string `gorm:"size:36;not null;uniqueIndex"`
+	Username string `gorm:"size:32;not null"`
+	Password string `gorm:"size:128;not null" log:"redact"`
+	Email    string `gorm:"size:128;not null"`
+	Role     string `gorm:"size:32;not null"`
+	Status   string `gorm:"size:16;not null;default:active;index"`
+	// TokenVersion is carried by access tokens; bumping it revokes every
+	// token issued before.
+	TokenVersion uint      `gorm:"not null;default:0"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+	// DeletedAt hides the user from every query that is not Unscoped.
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// Active reports whether the user may sign in and be routed.
+func (u *User) Active() bool {
+	return u.Status == "" || u.Status == UserStatusActive
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// User list sort fields.
+const (
+	UserSortID        = "id"
+	UserSortUsername  = "username"
+	UserSortEmail     = "email"
+	UserSortRole      = "role"
+	UserSortCreatedAt = "created_at"
+)
+
+// UserSortFields are the columns a user list may be ordered by.
+var UserSortFields = []string{UserSortID, UserSortUsername, UserSortEmail, UserSortRole, UserSortCreatedAt}
+
+// UserFilter narrows and orders a user query. Zero values match everything;
+// users are ordered by ID when Sort is empty.
+type UserFilter struct {
+	Role string
+	// Status matches one status. Deleted users are only listed when it is
+	// UserStatusDeleted.
+	Status string
+	// EmailDomain matches the part of the email after the @, ignoring case.
+	EmailDomain string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// Search matches a substring of the username or email, ignoring case.
+	Search string
+	Sort   string
+	Desc   bool
+	Limit  int
+	Offset int
+	// After resumes the listing after the last user of a previous page, in
+	// place of Offset.
+	After *UserCursor
+}
+
+// UserCursor marks the position after a user in a listing ordered by Sort.
+// Ties on the sort value are broken by ID.
+type UserCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v,omitempty"`
+	ID    uint   `json:"id"`
+}
+
+// UserPage is one page of a user listing.
+type UserPage struct {
+	Users []*User
+	// Total counts every user matching the filter, on any page.
+	Total int64
+	// Limit and Offset are the page size and offset actually applied.
+	Limit  int
+	Offset int
+	// Next continues the listing, or is nil on the last page.
+	Next *UserCursor
+}
+
+// CursorAfter returns the cursor positioned after user in a listing ordered
+// by sort.
+func CursorAfter(user *User, sort string, desc bool) *UserCursor {
+	cursor := &UserCursor{Sort: sort, Desc: desc, ID: user.ID}
+	switch sort {
+	case UserSortUsername:
+		cursor.Value = user.Username
+	case UserSortEmail:
+		cursor.Value = user.Email
+	case UserSortRole:
+		cursor.Value = user.Role
+	case UserSortCreatedAt:
+		cursor.Value = user.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return cursor
+}
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c *UserCursor) Encode() string {
+	encoded, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// ParseUserCursor decodes a token returned by Encode.
+func ParseUserCursor(token string) (*UserCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("cursor is malformed")
+	}
+	var cursor UserCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil || cursor.ID == 0 {
+		return nil, errors.New("cursor is malformed")
+	}
+	if cursor.Sort == UserSortCreatedAt {
+		if _, err := time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+			return nil, errors.New("cursor is malformed")
+		}
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,29 @@
+package port
+
+import "zeneye-gateway/internal/domain/entity"
+
+// AccountService holds the actions users take on their own account.
+type AccountService interface {
+	// ChangePassword replaces the password after checking current and the
+	// password history, and revokes every token of the user, access tokens
+	// included.
+	ChangePassword(userID uint, current, next string) error
+	// RequestEmailChange sends a confirmation token to email after checking
+	// password; the email changes once ConfirmEmailChange receives the token.
+	RequestEmailChange(userID uint, password, email string) error
+	// StartEmailChange sends the confirmation of RequestEmailChange without
+	// asking for the password, for administrators editing a user. The email
+	// still only changes once the user confirms it.
+	StartEmailChange(userID uint, email string) error
+	ConfirmEmailChange(userID uint, token string) (*entity.User, error)
+	// RequestPasswordReset sends a reset token to the account with email. It
+	// reports success whether or not there is such an account.
+	RequestPasswordReset(email string) error
+	// ResetPassword sets the password of the token's user like ChangePassword
+	// and returns that user. The token works once.
+	ResetPassword(token, password string) (*entity.User, error)
+	// SetPassword sets the password of an active user without their current
+	// one or a reset token, for operators, and otherwise works like
+	// ChangePassword.
+	SetPassword(userID uint, password string) (*entity.User, error)
+}
@@ -0,0 +1,12 @@
+package port
+
+import "zeneye-gateway/internal/domain/entity"
+
+type AuditRepository interface {
+	// AppendEvent chains event to the latest row (setting PrevHash and Hash)
+	// and inserts it. Appends are serialized so the chain never forks.
+	AppendEvent(event *entity.AuditEvent) error
+	ListEvents(filter entity.AuditFilter) ([]*entity.AuditEvent, error)
+	// WalkEvents calls fn for every event in insertion order, in batches.
+	WalkEvents(filter entity.AuditFilter, fn func(*entity.AuditEvent) error) error
+}
@@ -0,0 +1,10 @@
+package port
+
+import "zeneye-gateway/internal/domain/entity"
+
+type AuditService interface {
+	Record(event *entity.AuditEvent) error
+	ListEvents(filter entity.AuditFilter) ([]*entity.AuditEvent, error)
+	ExportEvents(filter entity.AuditFilter, fn func(*entity.AuditEvent) error) error
+	Verify() (*entity.AuditVerification, error)
+}
@@ -0,0 +1,12 @@
+package port
+
+import "zeneye-gateway/internal/domain/entity"
+
+type GatewayPolicyRepository interface {
+	// CreateVersion stores version as the next version number and sets it.
+	CreateVersion(version *entity.GatewayPolicyVersion) error
+	GetVersion(version uint) (*entity.GatewayPolicyVersion, error)
+	// LatestVersion returns the version in force, or nil when none is stored.
+	LatestVersion() (*entity.GatewayPolicyVersion, error)
+	ListVersions(limit, offset int) ([]*entity.GatewayPolicyVersion, error)
+}
@@ -0,0 +1,10 @@
+package port
+
+import "zeneye-gateway/internal/domain/entity"
+
+type GatewayPolicyService interface {
+	Save(version *entity.GatewayPolicyVersion) error
+	GetVersion(version uint) (*entity.GatewayPolicyVersion, error)
+	LatestVersion() (*entity.GatewayPolicyVersion, error)
+	ListVersions(limit, offset int) ([]*entity.GatewayPolicyVersion, error)
+}
@@ -0,0 +1,8 @@
+package port
+
+// JobQueue runs work after the request that asked for it has been answered.
+type JobQueue interface {
+	// Submit queues job without waiting for it, or fails when it cannot take
+	// any more work.
+	Submit(job func()) error
+}
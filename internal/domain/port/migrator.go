@@ -0,0 +1,25 @@
+package port
+
+import "zeneye-gateway/internal/domain/entity"
+
+// Migrator moves the database schema between the versions of the embedded
+// migrations. A migration that fails halfway leaves the schema dirty; every
+// move is refused until Force records the version it was repaired to.
+type Migrator interface {
+	// Up applies every pending migration.
+	Up() error
+	// Down reverts the latest steps migrations.
+	Down(steps int) error
+	// Goto applies or reverts migrations until version is the latest applied;
+	// zero reverts them all.
+	Goto(version uint) error
+	// Force records version as applied and clean without running anything;
+	// zero records that none is.
+	Force(version uint) error
+	// Version returns the latest applied migration, zero when there is none,
+	// and whether the schema is dirty.
+	Version() (uint, bool, error)
+	// Status lists every migration in order.
+	Status() ([]entity.MigrationStatus, error)
+	Close() error
+}
@@ -0,0 +1,8 @@
+package port
+
+import "zeneye-gateway/internal/domain/entity"
+
+// Notifier delivers notifications to users.
+type Notifier interface {
+	Notify(notification *entity.Notification) error
+}
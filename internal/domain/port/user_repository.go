@@ -5,14 +5,65 @@ import "zeneye-gateway/internal/domain/entity"
 type UserRepository interface {
 	CreateUser(user *entity.User) error
 	EditUser(user *entity.User) error
+	// DeleteUser soft-deletes the user and revokes their refresh tokens.
 	DeleteUser(id uint) error
+	// SetUserStatus changes the status of a user that is not deleted; every
+	// status but active revokes the user's refresh tokens.
+	SetUserStatus(id uint, status string) error
+	// GetDeletedUser and RestoreUser only see soft-deleted users.
+	GetDeletedUser(id uint) (*entity.User, error)
+	RestoreUser(id uint) error
+	CountActiveSuperadmins() (int64, error)
+	// ChangeUserRole sets the user's role, bumps their token version and
+	// revokes their refresh tokens.
+	ChangeUserRole(id uint, role string) error
+	// RevokeTokens bumps the user's token version and deletes their refresh
+	// tokens, so every token issued so far stops working.
+	RevokeTokens(id uint) error
 	GetUser(id uint) (*entity.User, error)
 	GetUserByUsername(username string) (*entity.User, error)
-	GetAllUsers() ([]*entity.User, error)
+	GetUserByEmail(email string) (*entity.User, error)
+	// ListUsers returns one page of the users matching filter and the number
+	// of matching users on all pages.
+	ListUsers(filter entity.UserFilter) ([]*entity.User, int64, error)
 
 	IsSuperadminPresent() (bool, error)
 	CreateRefreshToken(token *entity.RefreshToken) error
 	GetRefreshToken(token string) (*entity.RefreshToken, error)
 	DeleteRefreshToken(token string) error
 	IsEmailExists(email string) (bool, error)
+
+	// ChangePassword stores hash as the user's password, moves the old one to
+	// the password history, keeping the latest keep entries, and revokes
+	// every token issued to the user, all at once.
+	ChangePassword(userID uint, hash string, keep int) error
+	// GetPasswordHistory returns the user's previous password hashes, newest
+	// first.
+	GetPasswordHistory(userID uint, limit int) ([]*entity.PasswordHistory, error)
+	// CreateEmailChange stores change in place of any pending change of the
+	// same user.
+	CreateEmailChange(change *entity.EmailChange) error
+	GetEmailChange(userID uint, tokenHash string) (*entity.EmailChange, error)
+	// CompleteEmailChange sets the user's email to change.NewEmail and drops
+	// the user's pending changes and password resets.
+	CompleteEmailChange(change *entity.EmailChange) error
+	// CreatePasswordReset stores reset in place of any pending reset of the
+	// same user.
+	CreatePasswordReset(reset *entity.PasswordReset) error
+	GetPasswordReset(tokenHash string) (*entity.PasswordReset, error)
+	// ResetPassword consumes reset and then changes the password like
+	// ChangePassword. It fails with domainerr.ErrInvalidToken when reset was
+	// already used.
+	ResetPassword(reset *entity.PasswordReset, hash string, keep int) error
+	// CreateSuperadminTransfer stores transfer in place of any pending one.
+	CreateSuperadminTransfer(transfer *entity.SuperadminTransfer) error
+	// GetSuperadminTransfer returns the pending transfer, or
+	// domainerr.ErrTransferNotFound.
+	GetSuperadminTransfer() (*entity.SuperadminTransfer, error)
+	DeleteSuperadminTransfer() error
+	// CompleteSuperadminTransfer consumes transfer, makes its recipient the
+	// superadmin and gives its sender demoteTo, changing both like
+	// ChangeUserRole. It fails with domainerr.ErrTransferNotFound when
+	// transfer was already consumed or cancelled.
+	CompleteSuperadminTransfer(transfer *entity.SuperadminTransfer, demoteTo string) error
 }
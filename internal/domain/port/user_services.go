@@ -3,12 +3,37 @@ package port
 import "zeneye-gateway/internal/domain/entity"
 
 type UserService interface {
-	CreateUser(user *entity.User) error
-	EditUser(user *entity.User) error
-	DeleteUser(id uint) error
+	// CreateUser, EditUser and DeleteUser act on behalf of the actor, who must
+	// be ranked above the user and, on create, above its role; users may edit
+	// themselves. entity.SystemActorID is not bound by ranks.
+	CreateUser(actorID uint, user *entity.User) error
+	// EditUser renames the user. user.Email is checked for conflicts but not
+	// stored: an email changes only once the new address is confirmed.
+	EditUser(actorID uint, user *entity.User) error
+	// DeleteUser soft-deletes the user; RestoreUser undoes it.
+	DeleteUser(actorID, id uint) error
+	// DisableUser, EnableUser and RestoreUser act on behalf of the actor, who
+	// must be ranked above the user, or be entity.SystemActorID.
+	DisableUser(actorID, id uint) error
+	EnableUser(actorID, id uint) error
+	RestoreUser(actorID, id uint) error
+	// ChangeUserRole gives the user role on behalf of the actor, who may only
+	// grant roles below their own to users below their own role.
+	ChangeUserRole(actorID, id uint, role string) (*entity.User, error)
+	// RevokeTokens signs the user out everywhere: tokens issued so far stop
+	// working, and the user signs in again to get new ones.
+	RevokeTokens(id uint) error
+	// The superadmin role changes hands in two steps: the superadmin requests
+	// a transfer and the recipient accepts it. Either party may cancel it.
+	RequestSuperadminTransfer(fromID, toID uint) (*entity.SuperadminTransfer, error)
+	GetSuperadminTransfer(callerID uint) (*entity.SuperadminTransfer, error)
+	AcceptSuperadminTransfer(callerID uint) (*entity.SuperadminTransfer, error)
+	CancelSuperadminTransfer(callerID uint) (*entity.SuperadminTransfer, error)
 	GetUser(id uint) (*entity.User, error)
-	ListUsers() ([]*entity.User, error)
+	GetUserByUsername(username string) (*entity.User, error)
+	ListUsers(filter entity.UserFilter) (*entity.UserPage, error)
 
+	// AuthenticateUser only accepts active users.
 	AuthenticateUser(username, password string) (*entity.User, error)
 	IsSuperadminPresent() (bool, error)
 	GenerateRefreshToken(userID uint) (string, error)
@@ -0,0 +1,10 @@
+package dto
+
+import "time"
+
+type SuperadminTransferResponse struct {
+	FromUserID uint      `json:"from_user_id"`
+	ToUserID   uint      `json:"to_user_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -1,28 +1,130 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"zeneye-gateway/internal/adapter/http"
 	"zeneye-gateway/internal/adapter/repository/postgres"
+	"zeneye-gateway/internal/app"
+	"zeneye-gateway/internal/cli"
+	"zeneye-gateway/internal/config"
+	"zeneye-gateway/pkg/accesslog"
+	"zeneye-gateway/pkg/jwt"
 	"zeneye-gateway/pkg/logger"
-	"zeneye-gateway/pkg/utils"
+	"zeneye-gateway/pkg/server"
+	"zeneye-gateway/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
 )
 
+// main runs the gateway, the default, or one of the administrative commands
+// of the cli package, e.g. "zeneye-gateway user list -- -config gateway.yaml".
 func main() {
+	args := os.Args[1:]
+	switch {
+	case len(args) > 0 && args[0] == "serve":
+		args = args[1:]
+	case len(args) > 0 && !strings.HasPrefix(args[0], "-"):
+		os.Exit(cli.Run(cli.DefaultEnv(), args))
+	}
+	os.Exit(serve(args))
+}
 
-	// Load Env
-	utils.LoadConfig()
+// serve runs the gateway configured by the flags in args until a signal stops
+// it, and returns the exit code.
+func serve(args []string) int {
+	// Load and validate the configuration: defaults, file, .env, environment, flags
+	cfg, err := config.Load(args, os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		cli.Usage(os.Stderr)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%s\n", err)
+		return 2
+	}
+	gin.SetMode(cfg.Mode())
 
-	// Initialize and ensure logs are flushed
-	logger.InitLogger()
-	defer logger.SyncLogger()
+	// Initialize the loggers; they are flushed last, after shutdown
+	if err := logger.Configure(cfg.Log); err != nil {
+		log.Fatal(err)
+	}
+	if err := accesslog.Configure(cfg.AccessLog); err != nil {
+		log.Fatal(err)
+	}
+	logger.LogInfo("main", "Config", "Configuration loaded", cfg)
 
-	db := postgres.InitDB() // Initialize the database
+	if err := jwt.Configure(cfg.JWT()); err != nil {
+		log.Fatal(err)
+	}
 
-	// Setup and run the HTTP router
-	router := http.SetupRouter(db)
-	if err := router.Run(":8080"); err != nil {
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
 		log.Fatal(err)
 	}
+
+	db := postgres.InitDB(cfg.Database.URL.Value()) // Initialize the database
+
+	// SIGINT and SIGTERM start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Build the services once and hand them to the HTTP layer. Without a
+	// superadmin, the operator gets a one-time token to create one with.
+	deps := app.New(db, cfg)
+	if err := deps.IssueSetupToken(cfg, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+	gateway := http.NewGateway(deps, cfg)
+
+	// SIGHUP, and edits to the config files when watching, reload the routes and
+	// policies; an invalid configuration is logged and the running one kept
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	interval := cfg.Reload.Interval
+	if !cfg.Reload.Watch {
+		interval = 0
+	}
+	reloader := config.NewReloader(args, os.LookupEnv, gateway.Reload)
+	go reloader.Watch(ctx, cfg.Files, interval, hup)
+
+	// Setup and run the HTTP server until a signal arrives and requests drain
+	srv := server.New(gateway.Router, cfg.Server)
+	exitCode := 0
+	if err := server.Run(ctx, srv, cfg.Server); err != nil {
+		logger.LogError("main", "Server", "Server stopped with an error", err)
+		exitCode = 1
+	}
+
+	// Finish the background jobs while the database and notifier still work
+	jobsCtx, cancelJobs := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	if err := deps.Close(jobsCtx); err != nil {
+		logger.LogError("main", "Shutdown", "Background jobs did not finish in time", err)
+		exitCode = 1
+	}
+	cancelJobs()
+
+	// Release resources in reverse order of acquisition
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			logger.LogError("main", "Shutdown", "Failed to close the database pool", err)
+		}
+	}
+	if err := shutdownTracing(context.Background()); err != nil {
+		logger.LogError("main", "Shutdown", "Failed to flush traces", err)
+	}
+	logger.LogInfo("main", "Shutdown", "Gateway stopped", "")
+	accesslog.Close()
+	logger.SyncLogger()
+	return exitCode
 }
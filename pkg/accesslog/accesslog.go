@@ -0,0 +1,182 @@
+// Package accesslog writes one line per HTTP request in Common, Combined,
+// JSON or templated form to a file, stdout or syslog.
+package accesslog
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/natefinch/lumberjack"
+)
+
+// Entry is everything known about a finished request.
+type Entry struct {
+	Time            time.Time `json:"time"`
+	RemoteAddr      string    `json:"remote_addr"`
+	User            string    `json:"user,omitempty"`
+	UserUUID        string    `json:"user_uuid,omitempty"`
+	Method          string    `json:"method"`
+	URI             string    `json:"uri"`
+	Proto           string    `json:"proto"`
+	Route           string    `json:"route,omitempty"`
+	Status          int       `json:"status"`
+	BytesIn         int64     `json:"bytes_in"`
+	BytesOut        int64     `json:"bytes_out"`
+	DurationMS      float64   `json:"duration_ms"`
+	Referer         string    `json:"referer,omitempty"`
+	UserAgent       string    `json:"user_agent,omitempty"`
+	RequestID       string    `json:"request_id,omitempty"`
+	TraceID         string    `json:"trace_id,omitempty"`
+	UpstreamService string    `json:"upstream_service,omitempty"`
+	UpstreamMS      float64   `json:"upstream_ms,omitempty"`
+}
+
+// Logger formats entries and writes them to one sink.
+type Logger struct {
+	mu     sync.Mutex
+	format formatter
+	out    io.Writer
+	closer io.Closer
+}
+
+// New builds a logger from opts. Close it to release file or syslog handles.
+func New(opts Options) (*Logger, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	format, err := newFormatter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Logger{format: format}
+	switch opts.Output {
+	case OutputStdout:
+		l.out = os.Stdout
+	case OutputNone:
+		l.out = io.Discard
+	case OutputFile:
+		path, err := resolveFile(opts.File)
+		if err != nil {
+			return nil, err
+		}
+		file := &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    opts.MaxSizeMB,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAgeDays,
+			LocalTime:  true,
+		}
+		l.out, l.closer = file, file
+	case OutputSyslog:
+		writer, err := dialSyslog(opts)
+		if err != nil {
+			return nil, fmt.Errorf("access log syslog: %w", err)
+		}
+		l.out, l.closer = writer, writer
+	}
+	return l, nil
+}
+
+// Log writes e as a single line. Write errors are reported to the application
+// log, never to the client.
+func (l *Logger) Log(e *Entry) {
+	line := l.format(e)
+	l.mu.Lock()
+	_, err := l.out.Write(line)
+	l.mu.Unlock()
+	if err != nil {
+		logger.LogError("AccessLog", "Write", "", err)
+	}
+}
+
+// Close releases the sink.
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger *Logger
+)
+
+// Init configures the package-level logger from the ACCESS_LOG_* environment
+// variables, falling back to the defaults if they are invalid.
+func Init() {
+	opts, err := OptionsFromEnv()
+	if err != nil {
+		log.Printf("Invalid access log configuration, using defaults: %s", err.Error())
+		opts = DefaultOptions()
+	}
+	if err := Configure(opts); err != nil {
+		log.Fatalf("Error configuring access log: %s", err.Error())
+	}
+}
+
+// Configure replaces the package-level logger, closing the previous one.
+func Configure(opts Options) error {
+	l, err := New(opts)
+	if err != nil {
+		return err
+	}
+	defaultMu.Lock()
+	previous := defaultLogger
+	defaultLogger = l
+	defaultMu.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+	return nil
+}
+
+// Log writes e through the package-level logger, configuring it from the
+// environment on first use.
+func Log(e *Entry) {
+	defaultMu.RLock()
+	l := defaultLogger
+	defaultMu.RUnlock()
+	if l == nil {
+		Init()
+		defaultMu.RLock()
+		l = defaultLogger
+		defaultMu.RUnlock()
+	}
+	l.Log(e)
+}
+
+// Close closes the package-level logger.
+func Close() error {
+	defaultMu.Lock()
+	l := defaultLogger
+	defaultLogger = nil
+	defaultMu.Unlock()
+	if l == nil {
+		return nil
+	}
+	return l.Close()
+}
+
+// resolveFile defaults to a date-named file next to the application logs.
+func resolveFile(path string) (string, error) {
+	if path == "" {
+		root, err := logger.GetRootDirectoryPath()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(root, ".logs", fmt.Sprintf("access_%s.log", time.Now().Format("2006-01-02")))
+	}
+	if _, err := logger.EnsureDirectory(filepath.Dir(path)); err != nil {
+		return "", err
+	}
+	return path, nil
+}
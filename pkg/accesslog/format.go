@@ -0,0 +1,165 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clfTime is the timestamp layout of the Common and Combined Log Formats.
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+type formatter func(e *Entry) []byte
+
+func newFormatter(opts Options) (formatter, error) {
+	switch opts.Format {
+	case FormatCommon:
+		return formatCommon, nil
+	case FormatCombined:
+		return formatCombined, nil
+	case FormatJSON:
+		return formatJSON, nil
+	case FormatTemplate:
+		return parseTemplate(opts.Template)
+	}
+	return nil, fmt.Errorf("unknown access log format %q", opts.Format)
+}
+
+// formatCommon writes `host ident authuser [date] "request" status bytes`,
+// with the user UUID as authuser.
+func formatCommon(e *Entry) []byte {
+	var b strings.Builder
+	writeCommon(&b, e)
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// formatCombined appends the quoted referer and user agent to formatCommon.
+func formatCombined(e *Entry) []byte {
+	var b strings.Builder
+	writeCommon(&b, e)
+	b.WriteString(` "`)
+	b.WriteString(escape(e.Referer))
+	b.WriteString(`" "`)
+	b.WriteString(escape(e.UserAgent))
+	b.WriteString("\"\n")
+	return []byte(b.String())
+}
+
+func writeCommon(b *strings.Builder, e *Entry) {
+	b.WriteString(dash(e.RemoteAddr))
+	b.WriteString(" - ")
+	b.WriteString(dash(e.UserUUID))
+	b.WriteString(" [")
+	b.WriteString(e.Time.Format(clfTime))
+	b.WriteString(`] "`)
+	b.WriteString(escape(e.Method + " " + e.URI + " " + e.Proto))
+	b.WriteString(`" `)
+	b.WriteString(strconv.Itoa(e.Status))
+	b.WriteByte(' ')
+	if e.BytesOut > 0 {
+		b.WriteString(strconv.FormatInt(e.BytesOut, 10))
+	} else {
+		b.WriteByte('-')
+	}
+}
+
+func formatJSON(e *Entry) []byte {
+	line, _ := json.Marshal(e)
+	return append(line, '\n')
+}
+
+// templateFields are the ${name} placeholders a custom template may use.
+var templateFields = map[string]func(e *Entry) string{
+	"time":             func(e *Entry) string { return e.Time.Format(time.RFC3339) },
+	"time_clf":         func(e *Entry) string { return e.Time.Format(clfTime) },
+	"remote_addr":      func(e *Entry) string { return dash(e.RemoteAddr) },
+	"user":             func(e *Entry) string { return dash(e.User) },
+	"user_uuid":        func(e *Entry) string { return dash(e.UserUUID) },
+	"method":           func(e *Entry) string { return e.Method },
+	"uri":              func(e *Entry) string { return escape(e.URI) },
+	"proto":            func(e *Entry) string { return e.Proto },
+	"route":            func(e *Entry) string { return dash(e.Route) },
+	"status":           func(e *Entry) string { return strconv.Itoa(e.Status) },
+	"bytes_in":         func(e *Entry) string { return strconv.FormatInt(e.BytesIn, 10) },
+	"bytes_out":        func(e *Entry) string { return strconv.FormatInt(e.BytesOut, 10) },
+	"duration_ms":      func(e *Entry) string { return strconv.FormatFloat(e.DurationMS, 'f', 3, 64) },
+	"referer":          func(e *Entry) string { return escape(e.Referer) },
+	"user_agent":       func(e *Entry) string { return escape(e.UserAgent) },
+	"request_id":       func(e *Entry) string { return dash(e.RequestID) },
+	"trace_id":         func(e *Entry) string { return dash(e.TraceID) },
+	"upstream_service": func(e *Entry) string { return dash(e.UpstreamService) },
+	"upstream_ms":      func(e *Entry) string { return strconv.FormatFloat(e.UpstreamMS, 'f', 3, 64) },
+}
+
+// parseTemplate compiles a ${field} template once so formatting a line is a
+// walk over literal and field segments.
+func parseTemplate(tmpl string) (formatter, error) {
+	if strings.TrimSpace(tmpl) == "" {
+		return nil, fmt.Errorf("access log template must not be empty")
+	}
+
+	var segments []func(e *Entry) string
+	rest := tmpl
+	for {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("access log template: unterminated placeholder at %q", rest[start:])
+		}
+		literal := rest[:start]
+		name := rest[start+2 : start+end]
+		field, ok := templateFields[name]
+		if !ok {
+			return nil, fmt.Errorf("access log template: unknown field %q", name)
+		}
+		if literal != "" {
+			segments = append(segments, func(*Entry) string { return literal })
+		}
+		segments = append(segments, field)
+		rest = rest[start+end+1:]
+	}
+	if rest != "" {
+		literal := rest
+		segments = append(segments, func(*Entry) string { return literal })
+	}
+
+	return func(e *Entry) []byte {
+		var b strings.Builder
+		for _, segment := range segments {
+			b.WriteString(segment(e))
+		}
+		b.WriteByte('\n')
+		return []byte(b.String())
+	}, nil
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// escape keeps client-controlled values on one line and inside their quotes.
+func escape(s string) string {
+	if !strings.ContainsAny(s, "\"\\\r\n\t") && isPrintable(s) {
+		return s
+	}
+	quoted := strconv.Quote(s)
+	return quoted[1 : len(quoted)-1]
+}
+
+func isPrintable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
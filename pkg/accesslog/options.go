@@ -0,0 +1,140 @@
+package accesslog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Formats accepted in ACCESS_LOG_FORMAT.
+const (
+	FormatCommon   = "common"
+	FormatCombined = "combined"
+	FormatJSON     = "json"
+	FormatTemplate = "template"
+)
+
+// Sinks accepted in ACCESS_LOG_OUTPUT.
+const (
+	OutputStdout = "stdout"
+	OutputFile   = "file"
+	OutputSyslog = "syslog"
+	OutputNone   = "none"
+)
+
+// Options configures the access log.
+type Options struct {
+	Format string `yaml:"format" toml:"format"`
+	// Template is used with FormatTemplate, e.g. `${remote_addr} ${status} ${request_id}`.
+	Template string `yaml:"template" toml:"template"`
+	Output   string `yaml:"output" toml:"output"`
+	// File overrides the default .logs/access_<date>.log path.
+	File       string `yaml:"file" toml:"file"`
+	MaxSizeMB  int    `yaml:"max_size_mb" toml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups" toml:"max_backups"`
+	MaxAgeDays int    `yaml:"max_age_days" toml:"max_age_days"`
+	// SyslogNetwork and SyslogAddress select the syslog socket; both empty
+	// means the local daemon (/dev/log and friends).
+	SyslogNetwork string `yaml:"syslog_network" toml:"syslog_network"`
+	SyslogAddress string `yaml:"syslog_address" toml:"syslog_address"`
+	SyslogTag     string `yaml:"syslog_tag" toml:"syslog_tag"`
+}
+
+// DefaultOptions writes Combined Log Format lines to stdout, where gin's own
+// request logger used to write.
+func DefaultOptions() Options {
+	return Options{
+		Format:     FormatCombined,
+		Output:     OutputStdout,
+		MaxSizeMB:  100,
+		MaxBackups: 3,
+		MaxAgeDays: 30,
+		SyslogTag:  "zeneye-gateway",
+	}
+}
+
+// OptionsFromEnv applies the environment over the defaults; see LoadEnv.
+func OptionsFromEnv() (Options, error) {
+	opts := DefaultOptions()
+	if err := opts.LoadEnv(os.LookupEnv); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// LoadEnv overrides o with ACCESS_LOG_FORMAT, ACCESS_LOG_TEMPLATE,
+// ACCESS_LOG_OUTPUT, ACCESS_LOG_FILE, ACCESS_LOG_MAX_SIZE_MB,
+// ACCESS_LOG_MAX_BACKUPS, ACCESS_LOG_MAX_AGE_DAYS, ACCESS_LOG_SYSLOG_NETWORK,
+// ACCESS_LOG_SYSLOG_ADDRESS and ACCESS_LOG_SYSLOG_TAG as found by lookup,
+// usually os.LookupEnv.
+func (o *Options) LoadEnv(lookup func(string) (string, bool)) error {
+	getenv := func(key string) string { v, _ := lookup(key); return v }
+	if v := getenv("ACCESS_LOG_FORMAT"); v != "" {
+		o.Format = strings.ToLower(v)
+	}
+	if v := getenv("ACCESS_LOG_TEMPLATE"); v != "" {
+		o.Template = v
+		if getenv("ACCESS_LOG_FORMAT") == "" {
+			o.Format = FormatTemplate
+		}
+	}
+	if v := getenv("ACCESS_LOG_OUTPUT"); v != "" {
+		o.Output = strings.ToLower(v)
+	}
+	for key, target := range map[string]*string{
+		"ACCESS_LOG_FILE":           &o.File,
+		"ACCESS_LOG_SYSLOG_NETWORK": &o.SyslogNetwork,
+		"ACCESS_LOG_SYSLOG_ADDRESS": &o.SyslogAddress,
+		"ACCESS_LOG_SYSLOG_TAG":     &o.SyslogTag,
+	} {
+		if v := getenv(key); v != "" {
+			*target = v
+		}
+	}
+
+	var errs []error
+	for key, target := range map[string]*int{
+		"ACCESS_LOG_MAX_SIZE_MB":  &o.MaxSizeMB,
+		"ACCESS_LOG_MAX_BACKUPS":  &o.MaxBackups,
+		"ACCESS_LOG_MAX_AGE_DAYS": &o.MaxAgeDays,
+	} {
+		if v := getenv(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				continue
+			}
+			*target = n
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid option at once.
+func (o Options) Validate() error {
+	var errs []error
+	switch o.Format {
+	case FormatCommon, FormatCombined, FormatJSON:
+	case FormatTemplate:
+		if _, err := parseTemplate(o.Template); err != nil {
+			errs = append(errs, err)
+		}
+	default:
+		errs = append(errs, fmt.Errorf("access log format %q must be common, combined, json or template", o.Format))
+	}
+	switch o.Output {
+	case OutputStdout, OutputFile, OutputNone:
+	case OutputSyslog:
+		if (o.SyslogNetwork == "") != (o.SyslogAddress == "") {
+			errs = append(errs, errors.New("access log syslog network and address must be set together"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("access log output %q must be stdout, file, syslog or none", o.Output))
+	}
+	if o.MaxSizeMB < 0 || o.MaxBackups < 0 || o.MaxAgeDays < 0 {
+		errs = append(errs, errors.New("access log rotation values must not be negative"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,12 @@
+//go:build !windows && !plan9
+
+package accesslog
+
+import (
+	"io"
+	"log/syslog"
+)
+
+func dialSyslog(opts Options) (io.WriteCloser, error) {
+	return syslog.Dial(opts.SyslogNetwork, opts.SyslogAddress, syslog.LOG_INFO|syslog.LOG_LOCAL0, opts.SyslogTag)
+}
@@ -0,0 +1,278 @@
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// regexPrefix marks an allowed origin as a regular expression.
+const regexPrefix = "regex:"
+
+// Policy is a compiled set of CORS rules.
+type Policy struct {
+	base   *Rule
+	routes []routeRule
+}
+
+type routeRule struct {
+	prefix string
+	rule   *Rule
+}
+
+// Rule is the CORS policy in force for one path.
+type Rule struct {
+	anyOrigin   bool
+	exact       map[string]bool
+	wildcards   []wildcardOrigin
+	patterns    []*regexp.Regexp
+	methods     map[string]bool
+	headers     map[string]bool
+	anyHeader   bool
+	credentials bool
+
+	allowMethods  string
+	allowHeaders  string
+	exposeHeaders string
+	maxAge        string
+}
+
+// wildcardOrigin matches "scheme://*.domain" against any subdomain of domain.
+type wildcardOrigin struct {
+	scheme string
+	suffix string
+}
+
+// New compiles opts, reporting every invalid origin, method and route at once.
+func New(opts Options) (*Policy, error) {
+	var errs []error
+	base, err := compile(opts)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	p := &Policy{base: base}
+	for _, route := range opts.Routes {
+		if !strings.HasPrefix(route.Prefix, "/") {
+			errs = append(errs, fmt.Errorf("cors route prefix %q must start with /", route.Prefix))
+			continue
+		}
+		rule, err := compile(route.apply(opts))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cors route %s: %w", route.Prefix, err))
+			continue
+		}
+		p.routes = append(p.routes, routeRule{prefix: route.Prefix, rule: rule})
+	}
+	sort.SliceStable(p.routes, func(i, j int) bool {
+		return len(p.routes[i].prefix) > len(p.routes[j].prefix)
+	})
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// For returns the rule for path: the longest matching route override, or the
+// gateway-wide rule.
+func (p *Policy) For(path string) *Rule {
+	for _, route := range p.routes {
+		if path == route.prefix || strings.HasPrefix(path, strings.TrimSuffix(route.prefix, "/")+"/") {
+			return route.rule
+		}
+	}
+	return p.base
+}
+
+// Resolve returns the options behind the rule For picks for path, and the
+// prefix of the route override they come from, empty for the gateway-wide
+// policy.
+func (o Options) Resolve(path string) (Options, string) {
+	var match *RouteOptions
+	for i, route := range o.Routes {
+		if path != route.Prefix && !strings.HasPrefix(path, strings.TrimSuffix(route.Prefix, "/")+"/") {
+			continue
+		}
+		if match == nil || len(route.Prefix) > len(match.Prefix) {
+			match = &o.Routes[i]
+		}
+	}
+	if match == nil {
+		out := o
+		out.Routes = nil
+		return out, ""
+	}
+	return match.apply(o), match.Prefix
+}
+
+// apply returns base with the fields the override sets replaced.
+func (r RouteOptions) apply(base Options) Options {
+	out := base
+	out.Routes = nil
+	if r.AllowedOrigins != nil {
+		out.AllowedOrigins = r.AllowedOrigins
+	}
+	if r.AllowedMethods != nil {
+		out.AllowedMethods = r.AllowedMethods
+	}
+	if r.AllowedHeaders != nil {
+		out.AllowedHeaders = r.AllowedHeaders
+	}
+	if r.ExposedHeaders != nil {
+		out.ExposedHeaders = r.ExposedHeaders
+	}
+	if r.AllowCredentials != nil {
+		out.AllowCredentials = *r.AllowCredentials
+	}
+	if r.MaxAge != nil {
+		out.MaxAge = *r.MaxAge
+	}
+	return out
+}
+
+func compile(opts Options) (*Rule, error) {
+	var errs []error
+	r := &Rule{
+		exact:       map[string]bool{},
+		methods:     map[string]bool{},
+		headers:     map[string]bool{},
+		credentials: opts.AllowCredentials,
+	}
+
+	for _, origin := range opts.AllowedOrigins {
+		switch {
+		case origin == "*":
+			r.anyOrigin = true
+		case strings.HasPrefix(origin, regexPrefix):
+			re, err := regexp.Compile(strings.TrimPrefix(origin, regexPrefix))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("cors origin %q: %w", origin, err))
+				continue
+			}
+			r.patterns = append(r.patterns, re)
+		case strings.Contains(origin, "://*."):
+			scheme, domain, _ := strings.Cut(origin, "://*.")
+			if scheme == "" || domain == "" || strings.ContainsAny(domain, "*/") {
+				errs = append(errs, fmt.Errorf("cors origin %q must look like scheme://*.domain", origin))
+				continue
+			}
+			r.wildcards = append(r.wildcards, wildcardOrigin{scheme: strings.ToLower(scheme), suffix: "." + strings.ToLower(domain)})
+		default:
+			u, err := url.Parse(origin)
+			if err != nil || u.Scheme == "" || u.Host == "" || strings.Contains(u.Host, "*") || (u.Path != "" && u.Path != "/") {
+				errs = append(errs, fmt.Errorf("cors origin %q must be scheme://host[:port]", origin))
+				continue
+			}
+			r.exact[strings.ToLower(u.Scheme+"://"+u.Host)] = true
+		}
+	}
+	if r.anyOrigin && r.credentials {
+		errs = append(errs, errors.New("cors origin * cannot be combined with credentials"))
+	}
+
+	methods := make([]string, 0, len(opts.AllowedMethods))
+	for _, method := range opts.AllowedMethods {
+		method = strings.ToUpper(method)
+		if strings.ContainsAny(method, " \t,") {
+			errs = append(errs, fmt.Errorf("cors method %q is not a valid token", method))
+			continue
+		}
+		if !r.methods[method] {
+			r.methods[method] = true
+			methods = append(methods, method)
+		}
+	}
+	for _, header := range opts.AllowedHeaders {
+		if header == "*" {
+			r.anyHeader = true
+			continue
+		}
+		r.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	if opts.MaxAge < 0 {
+		errs = append(errs, errors.New("cors max age must not be negative"))
+	}
+
+	r.allowMethods = strings.Join(methods, ", ")
+	r.allowHeaders = strings.Join(opts.AllowedHeaders, ", ")
+	r.exposeHeaders = strings.Join(opts.ExposedHeaders, ", ")
+	if opts.MaxAge > 0 {
+		r.maxAge = strconv.Itoa(opts.MaxAge)
+	}
+	return r, errors.Join(errs...)
+}
+
+// AllowOrigin returns the Access-Control-Allow-Origin value for origin, or
+// false when origin may not make cross-origin requests.
+func (r *Rule) AllowOrigin(origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+	if r.anyOrigin {
+		return "*", true
+	}
+	normalized := strings.ToLower(origin)
+	if r.exact[normalized] {
+		return origin, true
+	}
+	if u, err := url.Parse(normalized); err == nil && u.Host != "" {
+		host := u.Hostname()
+		for _, w := range r.wildcards {
+			if u.Scheme == w.scheme && strings.HasSuffix(host, w.suffix) && len(host) > len(w.suffix) {
+				return origin, true
+			}
+		}
+	}
+	for _, re := range r.patterns {
+		if re.MatchString(origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// VaryOrigin reports whether responses differ by Origin, which is the case
+// unless every origin is allowed.
+func (r *Rule) VaryOrigin() bool {
+	return !r.anyOrigin
+}
+
+// AllowMethod reports whether a preflight for method may succeed. Simple
+// methods are always allowed.
+func (r *Rule) AllowMethod(method string) bool {
+	method = strings.ToUpper(method)
+	return r.methods[method] || method == http.MethodGet || method == http.MethodHead || method == http.MethodPost
+}
+
+// AllowHeaders returns the Access-Control-Allow-Headers value for the
+// comma-separated headers a preflight asks for, or false if one is not allowed.
+func (r *Rule) AllowHeaders(requested string) (string, bool) {
+	if r.anyHeader {
+		return requested, true
+	}
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header != "" && !r.headers[http.CanonicalHeaderKey(header)] {
+			return "", false
+		}
+	}
+	return r.allowHeaders, true
+}
+
+// AllowMethods is the Access-Control-Allow-Methods value.
+func (r *Rule) AllowMethods() string { return r.allowMethods }
+
+// ExposeHeaders is the Access-Control-Expose-Headers value.
+func (r *Rule) ExposeHeaders() string { return r.exposeHeaders }
+
+// Credentials reports whether Access-Control-Allow-Credentials is sent.
+func (r *Rule) Credentials() bool { return r.credentials }
+
+// MaxAge is the Access-Control-Max-Age value, empty to leave it unset.
+func (r *Rule) MaxAge() string { return r.maxAge }
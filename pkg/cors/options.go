@@ -0,0 +1,125 @@
+package cors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Options configures the gateway-wide CORS policy.
+type Options struct {
+	// AllowedOrigins lists exact origins ("https://admin.example.com"), wildcard
+	// subdomains ("https://*.example.com"), regular expressions prefixed with
+	// "regex:" or "*" for any origin. Empty disables cross-origin access.
+	AllowedOrigins   []string `json:"allowed_origins" yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods" yaml:"allowed_methods" toml:"allowed_methods"`
+	AllowedHeaders   []string `json:"allowed_headers" yaml:"allowed_headers" toml:"allowed_headers"`
+	ExposedHeaders   []string `json:"exposed_headers" yaml:"exposed_headers" toml:"exposed_headers"`
+	AllowCredentials bool     `json:"allow_credentials" yaml:"allow_credentials" toml:"allow_credentials"`
+	// MaxAge is how long, in seconds, browsers may cache a preflight result.
+	MaxAge int `json:"max_age" yaml:"max_age" toml:"max_age"`
+	// Routes override the policy for paths under their prefix; the longest
+	// matching prefix wins.
+	Routes []RouteOptions `json:"routes" yaml:"routes" toml:"routes"`
+}
+
+// RouteOptions overrides the fields it sets for paths under Prefix.
+type RouteOptions struct {
+	Prefix           string   `json:"prefix" yaml:"prefix" toml:"prefix"`
+	AllowedOrigins   []string `json:"allowed_origins,omitempty" yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods,omitempty" yaml:"allowed_methods" toml:"allowed_methods"`
+	AllowedHeaders   []string `json:"allowed_headers,omitempty" yaml:"allowed_headers" toml:"allowed_headers"`
+	ExposedHeaders   []string `json:"exposed_headers,omitempty" yaml:"exposed_headers" toml:"exposed_headers"`
+	AllowCredentials *bool    `json:"allow_credentials,omitempty" yaml:"allow_credentials" toml:"allow_credentials"`
+	MaxAge           *int     `json:"max_age,omitempty" yaml:"max_age" toml:"max_age"`
+}
+
+// DefaultOptions allows the usual verbs and the headers the gateway reads and
+// returns, but no origins.
+func DefaultOptions() Options {
+	return Options{
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders: []string{"Authorization", "Content-Type", "X-Request-ID", "traceparent", "tracestate"},
+		ExposedHeaders: []string{"Authorization", "X-Refresh-Token", "X-Token-Expires-In", "X-Refresh-Token-Expires-In", "X-Request-ID"},
+		MaxAge:         600,
+	}
+}
+
+// OptionsFromEnv applies the environment over the defaults; see LoadEnv.
+func OptionsFromEnv() (Options, error) {
+	opts := DefaultOptions()
+	if err := opts.LoadEnv(os.LookupEnv); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// LoadEnv overrides o with CORS_ALLOWED_ORIGINS, CORS_ALLOWED_METHODS,
+// CORS_ALLOWED_HEADERS, CORS_EXPOSED_HEADERS (all comma separated),
+// CORS_ALLOW_CREDENTIALS, CORS_MAX_AGE and CORS_ROUTES (a JSON array of
+// RouteOptions) as found by lookup, usually os.LookupEnv. When no origins are
+// configured at all, the admin panel origin from ADMIN_PANEL_SERVICE_URL is
+// allowed.
+func (o *Options) LoadEnv(lookup func(string) (string, bool)) error {
+	getenv := func(key string) string { v, _ := lookup(key); return v }
+	var errs []error
+
+	if v := getenv("CORS_ALLOWED_ORIGINS"); v != "" {
+		o.AllowedOrigins = splitList(v)
+	} else if v := getenv("ADMIN_PANEL_SERVICE_URL"); v != "" && len(o.AllowedOrigins) == 0 {
+		if u, err := url.Parse(v); err == nil && u.Scheme != "" && u.Host != "" {
+			o.AllowedOrigins = []string{u.Scheme + "://" + u.Host}
+		}
+	}
+	for key, target := range map[string]*[]string{
+		"CORS_ALLOWED_METHODS": &o.AllowedMethods,
+		"CORS_ALLOWED_HEADERS": &o.AllowedHeaders,
+		"CORS_EXPOSED_HEADERS": &o.ExposedHeaders,
+	} {
+		if v, ok := lookup(key); ok {
+			*target = splitList(v)
+		}
+	}
+	if v := getenv("CORS_ALLOW_CREDENTIALS"); v != "" {
+		allow, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("CORS_ALLOW_CREDENTIALS: %w", err))
+		} else {
+			o.AllowCredentials = allow
+		}
+	}
+	if v := getenv("CORS_MAX_AGE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("CORS_MAX_AGE: %w", err))
+		} else {
+			o.MaxAge = n
+		}
+	}
+	if v := getenv("CORS_ROUTES"); v != "" {
+		if err := json.Unmarshal([]byte(v), &o.Routes); err != nil {
+			errs = append(errs, fmt.Errorf("CORS_ROUTES: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid option at once.
+func (o Options) Validate() error {
+	_, err := New(o)
+	return err
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
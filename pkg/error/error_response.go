@@ -1,18 +1,158 @@
 package error
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"zeneye-gateway/internal/domain/domainerr"
+	"zeneye-gateway/pkg/requestid"
+
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ContentType is the media type of every error response (RFC 7807).
+const ContentType = "application/problem+json"
+
+// Codes for errors raised by the HTTP layer itself; domain codes live in
+// domainerr. Clients may branch on these, so never rename one.
+const (
+	CodeMalformedBody       = "malformed_body"
+	CodePayloadTooLarge     = "payload_too_large"
+	CodeHeadersTooLarge     = "headers_too_large"
+	CodeUnsupportedMedia    = "unsupported_media_type"
+	CodeInvalidPath         = "invalid_path"
+	CodeInvalidQuery        = "invalid_query"
+	CodeUnauthenticated     = "unauthenticated"
+	CodeForbidden           = "forbidden"
+	CodeOriginNotAllowed    = "origin_not_allowed"
+	CodeNotFound            = "not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeRateLimited         = "rate_limited"
+	CodeNoRoute             = "no_route"
+	CodeUpstreamUnavailable = "upstream_unavailable"
+	CodeInternal            = "internal_error"
 )
 
-type ErrorResponse struct {
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+// Problem is an RFC 7807 problem details body with the gateway's extensions:
+// a stable code, per-field errors and the request ID.
+type Problem struct {
+	Type      string                 `json:"type"`
+	Title     string                 `json:"title"`
+	Status    int                    `json:"status"`
+	Detail    string                 `json:"detail,omitempty"`
+	Instance  string                 `json:"instance,omitempty"`
+	Code      string                 `json:"code"`
+	Errors    []domainerr.FieldError `json:"errors,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
 }
 
-func NewErrorResponse(c *gin.Context, statusCode int, message string, details string) {
-	c.JSON(statusCode, ErrorResponse{
-		Message: message,
-		Details: details,
-	})
+// NewProblem writes a problem response and aborts the request.
+func NewProblem(c *gin.Context, status int, code, detail string, fields ...domainerr.FieldError) {
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		Errors:    fields,
+		RequestID: requestid.FromContext(c.Request.Context()),
+	}
+	body, _ := json.Marshal(problem)
+	c.Data(status, ContentType, body)
 	c.Abort()
 }
+
+// FromError is the single mapping from errors to responses. Domain errors keep
+// their code and message; binding errors become validation or malformed-body
+// problems; anything else is a 500 whose cause is never shown to the client.
+func FromError(c *gin.Context, err error) {
+	if e, ok := domainerr.As(err); ok {
+		NewProblem(c, statusOf(e.Kind), e.Code, e.Message, e.Fields...)
+		return
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]domainerr.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, fieldFromValidator(fe))
+		}
+		e := domainerr.Validation(fields...)
+		NewProblem(c, http.StatusBadRequest, e.Code, e.Message, e.Fields...)
+		return
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		NewProblem(c, http.StatusRequestEntityTooLarge, CodePayloadTooLarge,
+			fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+		return
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		NewProblem(c, http.StatusBadRequest, CodeMalformedBody, "request body is not valid JSON")
+		return
+	case errors.As(err, &typeErr):
+		NewProblem(c, http.StatusBadRequest, CodeMalformedBody, "request body is invalid",
+			domainerr.FieldError{Field: typeErr.Field, Code: "invalid_type", Message: fmt.Sprintf("%s must be a %s", typeErr.Field, typeErr.Type)})
+		return
+	}
+
+	NewProblem(c, http.StatusInternalServerError, CodeInternal, "an unexpected error occurred")
+}
+
+func statusOf(kind domainerr.Kind) int {
+	switch kind {
+	case domainerr.KindNotFound:
+		return http.StatusNotFound
+	case domainerr.KindConflict:
+		return http.StatusConflict
+	case domainerr.KindValidation:
+		return http.StatusBadRequest
+	case domainerr.KindUnauthorized:
+		return http.StatusUnauthorized
+	case domainerr.KindForbidden:
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+func fieldFromValidator(fe validator.FieldError) domainerr.FieldError {
+	field := fe.Field()
+	var message string
+	switch fe.Tag() {
+	case "required":
+		message = field + " is required"
+	case "email":
+		message = field + " must be a valid email address"
+	case "min", "max", "len":
+		message = fmt.Sprintf("%s must satisfy %s=%s", field, fe.Tag(), fe.Param())
+	default:
+		message = fmt.Sprintf("%s failed the %s check", field, fe.Tag())
+	}
+	return domainerr.FieldError{Field: field, Code: fe.Tag(), Message: message}
+}
+
+// Report binding errors under the JSON field names clients actually send.
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "" || name == "-" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ContentType is the media type of health reports
+// (draft-inadarei-api-health-check).
+const ContentType = "application/health+json"
+
+// Statuses used by reports and components.
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Options configures how checks run.
+type Options struct {
+	// Timeout bounds a check that does not set its own.
+	Timeout time.Duration `yaml:"timeout" toml:"timeout"`
+	// CacheTTL is how long a result is reused before the check runs again.
+	CacheTTL time.Duration `yaml:"cache_ttl" toml:"cache_ttl"`
+	// CheckUpstreams adds a check per upstream service to the detailed report.
+	CheckUpstreams bool `yaml:"check_upstreams" toml:"check_upstreams"`
+	// UpstreamsCritical makes failing upstreams fail readiness too.
+	UpstreamsCritical bool `yaml:"upstreams_critical" toml:"upstreams_critical"`
+	// UpstreamPath is requested on each upstream, e.g. /health.
+	UpstreamPath string `yaml:"upstream_path" toml:"upstream_path"`
+}
+
+// DefaultOptions checks only local dependencies, each for up to two seconds,
+// and caches results for five.
+func DefaultOptions() Options {
+	return Options{
+		Timeout:      2 * time.Second,
+		CacheTTL:     5 * time.Second,
+		UpstreamPath: "/health",
+	}
+}
+
+// OptionsFromEnv applies the environment over the defaults; see LoadEnv.
+func OptionsFromEnv() (Options, error) {
+	opts := DefaultOptions()
+	if err := opts.LoadEnv(os.LookupEnv); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// LoadEnv overrides o with HEALTH_CHECK_TIMEOUT, HEALTH_CACHE_TTL,
+// HEALTH_CHECK_UPSTREAMS, HEALTH_UPSTREAMS_CRITICAL and HEALTH_UPSTREAM_PATH
+// as found by lookup, usually os.LookupEnv.
+func (o *Options) LoadEnv(lookup func(string) (string, bool)) error {
+	getenv := func(key string) string { v, _ := lookup(key); return v }
+	var errs []error
+	for key, target := range map[string]*time.Duration{
+		"HEALTH_CHECK_TIMEOUT": &o.Timeout,
+		"HEALTH_CACHE_TTL":     &o.CacheTTL,
+	} {
+		if v := getenv(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				continue
+			}
+			*target = d
+		}
+	}
+	for key, target := range map[string]*bool{
+		"HEALTH_CHECK_UPSTREAMS":    &o.CheckUpstreams,
+		"HEALTH_UPSTREAMS_CRITICAL": &o.UpstreamsCritical,
+	} {
+		if v := getenv(key); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				continue
+			}
+			*target = b
+		}
+	}
+	if v := getenv("HEALTH_UPSTREAM_PATH"); v != "" {
+		o.UpstreamPath = v
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid option at once.
+func (o Options) Validate() error {
+	var errs []error
+	if o.Timeout <= 0 {
+		errs = append(errs, errors.New("health check timeout must be positive"))
+	}
+	if o.CacheTTL < 0 {
+		errs = append(errs, errors.New("health cache TTL must not be negative"))
+	}
+	if o.UpstreamPath == "" || o.UpstreamPath[0] != '/' {
+		errs = append(errs, fmt.Errorf("health upstream path %q must start with /", o.UpstreamPath))
+	}
+	return errors.Join(errs...)
+}
+
+// Check is one dependency probe.
+type Check struct {
+	// Name keys the component in the report, e.g. "postgres".
+	Name string
+	// ComponentType is "datastore", "component" or "system".
+	ComponentType string
+	// Critical checks decide readiness; the others only degrade the report to warn.
+	Critical bool
+	// Timeout overrides Options.Timeout.
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// Component is the latest result of one check.
+type Component struct {
+	ComponentType string  `json:"componentType,omitempty"`
+	Status        string  `json:"status"`
+	ObservedValue float64 `json:"observedValue"`
+	ObservedUnit  string  `json:"observedUnit"`
+	Time          string  `json:"time"`
+	Output        string  `json:"output,omitempty"`
+	Critical      bool    `json:"critical"`
+	LastError     string  `json:"lastError,omitempty"`
+	LastErrorTime string  `json:"lastErrorTime,omitempty"`
+}
+
+// Report is a health check response body.
+type Report struct {
+	Status string                 `json:"status"`
+	Output string                 `json:"output,omitempty"`
+	Checks map[string][]Component `json:"checks,omitempty"`
+}
+
+// HTTPStatus is 503 for failing reports and 200 otherwise.
+func (r Report) HTTPStatus() int {
+	if r.Status == StatusFail {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+type entry struct {
+	check Check
+	// mu serializes runs so concurrent probes share one result.
+	mu        sync.Mutex
+	result    Component
+	checkedAt time.Time
+	lastErr   string
+	lastErrAt time.Time
+}
+
+// Checker runs checks concurrently with timeouts and caches their results.
+type Checker struct {
+	opts    Options
+	entries []*entry
+	now     func() time.Time
+}
+
+// New returns a Checker for checks.
+func New(opts Options, checks ...Check) *Checker {
+	c := &Checker{opts: opts, now: time.Now}
+	for _, check := range checks {
+		c.entries = append(c.entries, &entry{check: check})
+	}
+	return c
+}
+
+// Report runs every check and aggregates them: any critical failure fails the
+// report, other failures make it warn.
+func (c *Checker) Report(ctx context.Context) Report {
+	return c.run(ctx, false)
+}
+
+// Ready runs only the critical checks.
+func (c *Checker) Ready(ctx context.Context) Report {
+	return c.run(ctx, true)
+}
+
+func (c *Checker) run(ctx context.Context, criticalOnly bool) Report {
+	var selected []*entry
+	for _, e := range c.entries {
+		if !criticalOnly || e.check.Critical {
+			selected = append(selected, e)
+		}
+	}
+
+	results := make([]Component, len(selected))
+	var wg sync.WaitGroup
+	for i, e := range selected {
+		wg.Add(1)
+		go func(i int, e *entry) {
+			defer wg.Done()
+			results[i] = c.result(ctx, e)
+		}(i, e)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusPass, Checks: make(map[string][]Component, len(selected))}
+	for i, e := range selected {
+		result := results[i]
+		report.Checks[e.check.Name] = []Component{result}
+		switch {
+		case result.Status == StatusFail && e.check.Critical:
+			report.Status = StatusFail
+		case result.Status == StatusFail && report.Status == StatusPass:
+			report.Status = StatusWarn
+		}
+	}
+	return report
+}
+
+// result returns the cached result of e, running the check when it is stale.
+func (c *Checker) result(ctx context.Context, e *entry) Component {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := c.now()
+	if !e.checkedAt.IsZero() && now.Sub(e.checkedAt) < c.opts.CacheTTL {
+		return e.result
+	}
+
+	timeout := e.check.Timeout
+	if timeout <= 0 {
+		timeout = c.opts.Timeout
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- e.check.Run(checkCtx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-checkCtx.Done():
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	latency := time.Since(start)
+
+	result := Component{
+		ComponentType: e.check.ComponentType,
+		Status:        StatusPass,
+		ObservedValue: float64(latency.Microseconds()) / 1000,
+		ObservedUnit:  "ms",
+		Time:          now.UTC().Format(time.RFC3339),
+		Critical:      e.check.Critical,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Output = err.Error()
+		e.lastErr, e.lastErrAt = err.Error(), now
+	}
+	if e.lastErr != "" {
+		result.LastError = e.lastErr
+		result.LastErrorTime = e.lastErrAt.UTC().Format(time.RFC3339)
+	}
+
+	e.result, e.checkedAt = result, now
+	return result
+}
+
+// HTTPCheck probes url with GET and fails on transport errors and 5xx answers.
+func HTTPCheck(name, url string, critical bool, client *http.Client) Check {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return Check{
+		Name:          name,
+		ComponentType: "component",
+		Critical:      critical,
+		Run: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= http.StatusInternalServerError {
+				return fmt.Errorf("upstream answered %d", resp.StatusCode)
+			}
+			return nil
+		},
+	}
+}
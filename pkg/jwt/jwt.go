@@ -3,20 +3,80 @@ package jwt
 import (
 	"crypto/rand"
 	"encoding/hex"
-	"strconv"
+	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
 
 	"zeneye-gateway/pkg/logger"
-	utils "zeneye-gateway/pkg/utils"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var jwtSecret []byte
+// Options configures token signing and lifetimes.
+type Options struct {
+	Secret          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// DefaultOptions issues access tokens for two hours and refresh tokens for 30
+// days. The secret has no default.
+func DefaultOptions() Options {
+	return Options{AccessTokenTTL: 2 * time.Hour, RefreshTokenTTL: 720 * time.Hour}
+}
+
+// Validate reports every invalid option at once.
+func (o Options) Validate() error {
+	var errs []error
+	if o.Secret == "" {
+		errs = append(errs, errors.New("JWT secret must be set"))
+	}
+	if o.AccessTokenTTL < time.Minute {
+		errs = append(errs, fmt.Errorf("access token TTL %s must be at least a minute", o.AccessTokenTTL))
+	}
+	if o.RefreshTokenTTL < o.AccessTokenTTL {
+		errs = append(errs, fmt.Errorf("refresh token TTL %s must not be shorter than the access token TTL", o.RefreshTokenTTL))
+	}
+	return errors.Join(errs...)
+}
+
+var current atomic.Pointer[Options]
+
+// Configure sets the signing secret and token lifetimes. Tokens cannot be
+// issued or validated before it is called.
+func Configure(opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	current.Store(&opts)
+	return nil
+}
+
+var errNotConfigured = errors.New("jwt: not configured")
+
+func options() (*Options, error) {
+	opts := current.Load()
+	if opts == nil {
+		return nil, errNotConfigured
+	}
+	return opts, nil
+}
 
-// InitJWTSecret initializes the JWT secret from environment variables.
-func InitJWTSecret() {
-	jwtSecret = []byte(utils.GetEnv("JWT_SECRET"))
+// AccessTokenTTL is how long issued access tokens stay valid.
+func AccessTokenTTL() time.Duration {
+	if opts := current.Load(); opts != nil {
+		return opts.AccessTokenTTL
+	}
+	return DefaultOptions().AccessTokenTTL
+}
+
+// RefreshTokenTTL is how long issued refresh tokens stay valid.
+func RefreshTokenTTL() time.Duration {
+	if opts := current.Load(); opts != nil {
+		return opts.RefreshTokenTTL
+	}
+	return DefaultOptions().RefreshTokenTTL
 }
 
 type Claims struct {
@@ -24,47 +84,41 @@ type Claims struct {
 	Username string `json:"username"`
 	Role     string `json:"role"`
 	UserUUID string `json:"user_uuid"`
+	// TokenVersion is the user's token version when the token was issued.
+	TokenVersion uint `json:"token_version,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func getExpirationTime() time.Duration {
-	expirationTimeStr := utils.GetEnv("JWT_EXPIRATION")
-	expirationTime, err := strconv.Atoi(expirationTimeStr)
-	if err != nil {
-		logger.LogError("JWT", "getExpirationTime", "Invalid JWT_EXPIRATION, using default 2 hours", err)
-		return 2 * time.Hour
-	}
-	return time.Duration(expirationTime) * time.Hour
+func GenerateToken(userID uint, username, role, userUUID string) (string, error) {
+	return GenerateVersionedToken(userID, username, role, userUUID, 0)
 }
 
-func getRefreshTokenExpirationTime() time.Duration {
-	expirationTimeStr := utils.GetEnv("REFRESH_TOKEN_EXPIRATION")
-	expirationTime, err := strconv.Atoi(expirationTimeStr)
+// GenerateVersionedToken issues an access token that stops being accepted once
+// the user's token version moves past version.
+func GenerateVersionedToken(userID uint, username, role, userUUID string, version uint) (string, error) {
+	opts, err := options()
 	if err != nil {
-		logger.LogError("JWT", "getRefreshTokenExpirationTime", "Invalid REFRESH_TOKEN_EXPIRATION, using default 30 days", err)
-		return 720 * time.Hour
+		logger.LogError("JWT", "GenerateToken", userID, err)
+		return "", err
 	}
-	return time.Duration(expirationTime) * time.Hour
-}
-
-func GenerateToken(userID uint, username, role, userUUID string) (string, error) {
-	expirationTime := time.Now().Add(getExpirationTime())
+	expirationTime := time.Now().Add(opts.AccessTokenTTL)
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
-		UserUUID: userUUID,
+		UserID:       userID,
+		Username:     username,
+		Role:         role,
+		UserUUID:     userUUID,
+		TokenVersion: version,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString(jwtSecret)
+	signedToken, err := token.SignedString([]byte(opts.Secret))
 	if err != nil {
 		logger.LogError("JWT", "GenerateToken", userID, err)
 		return "", err
 	}
-	logger.LogInfo("JWT", "GenerateToken", "Generated JWT token", signedToken)
+	logger.LogInfo("JWT", "GenerateToken", "Generated JWT token", userID)
 	return signedToken, nil
 }
 
@@ -75,14 +129,19 @@ func GenerateRefreshToken() (string, error) {
 		return "", err
 	}
 	refreshTokenString := hex.EncodeToString(refreshToken)
-	logger.LogInfo("JWT", "GenerateRefreshToken", "Generated refresh token", refreshTokenString)
+	logger.LogInfo("JWT", "GenerateRefreshToken", "Generated refresh token", "")
 	return refreshTokenString, nil
 }
 
 func ValidateToken(tokenString string) (*Claims, error) {
+	opts, err := options()
+	if err != nil {
+		logger.LogError("JWT", "ValidateToken", "Error validating token", err)
+		return nil, err
+	}
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
+		return []byte(opts.Secret), nil
 	})
 	if err != nil {
 		logger.LogError("JWT", "ValidateToken", "Error validating token", err)
@@ -92,6 +151,5 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		logger.LogError("JWT", "ValidateToken", "Invalid token signature", err)
 		return nil, jwt.ErrSignatureInvalid
 	}
-	logger.LogInfo("JWT", "ValidateToken", "Token validated successfully", tokenString)
 	return claims, nil
 }
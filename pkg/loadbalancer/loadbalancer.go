@@ -2,41 +2,179 @@ package loadbalancer
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync/atomic"
 	"zeneye-gateway/pkg/logger"
-	"zeneye-gateway/pkg/utils"
 )
 
-// RouteRequest routes the request to the appropriate microservice based on the path.
-func RouteRequest(req *http.Request) string {
-
-	var serviceURL string
-	path := strings.Trim(req.URL.Path, "/")
-
-	switch {
-	case strings.HasPrefix(path, "agent"):
-		serviceURL = utils.GetEnv("AGENT_SERVICE_URL")
-	case strings.HasPrefix(path, "compliance"):
-		serviceURL = utils.GetEnv("COMPLIANCE_SERVICE_URL")
-	case strings.HasPrefix(path, "config"):
-		serviceURL = utils.GetEnv("CONFIGURATION_SERVICE_URL")
-	case strings.HasPrefix(path, "notify"):
-		serviceURL = utils.GetEnv("NOTIFICATION_SERVICE_URL")
-	case strings.HasPrefix(path, "bot-detection"):
-		serviceURL = utils.GetEnv("BOT_DETECTION_SERVICE_URL")
-	case strings.HasPrefix(path, "waf"):
-		serviceURL = utils.GetEnv("WAF_SERVICE_URL")
-	case strings.HasPrefix(path, "breach"):
-		serviceURL = utils.GetEnv("BREACH_DETECTION_SERVICE_URL")
-	case strings.HasPrefix(path, "admin-management"):
-		serviceURL = utils.GetEnv("ADMIN_MANAGEMENT_SERVICE_URL")
-	default:
-		defaultErr := errors.New("default route: " + req.URL.Path)
-		logger.LogError("LoadBalancer", "RouteRequest", "Unable to route request", defaultErr)
-		return ""
-	}
-
-	logger.LogInfo("LoadBalancer", "RouteRequest", "Routed request", map[string]string{"Path": req.URL.Path, "ServiceURL": serviceURL})
-	return serviceURL
+// Route maps a path prefix to the service URL, or the pool of URLs, that
+// serves it.
+type Route struct {
+	// Prefix is matched against the path without its leading slash.
+	Prefix string `json:"prefix" yaml:"prefix" toml:"prefix"`
+	URL    string `json:"url,omitempty" yaml:"url" toml:"url"`
+	// Pool names the upstream pool serving the route instead of URL.
+	Pool string `json:"pool,omitempty" yaml:"pool" toml:"pool"`
+	// Env names the variable that overrides URL, e.g. AGENT_SERVICE_URL.
+	Env string `json:"env,omitempty" yaml:"env" toml:"env"`
+	// Roles, when set, are the only user roles allowed through the route.
+	Roles []string `json:"roles,omitempty" yaml:"roles" toml:"roles"`
+}
+
+// Pool is a named set of interchangeable upstream URLs, used in turn.
+type Pool struct {
+	Name    string   `json:"name" yaml:"name" toml:"name"`
+	Targets []string `json:"targets" yaml:"targets" toml:"targets"`
+}
+
+// DefaultRoutes returns the built-in services with their URLs unset.
+func DefaultRoutes() []Route {
+	return []Route{
+		{Prefix: "agent", Env: "AGENT_SERVICE_URL"},
+		{Prefix: "compliance", Env: "COMPLIANCE_SERVICE_URL"},
+		{Prefix: "config", Env: "CONFIGURATION_SERVICE_URL"},
+		{Prefix: "notify", Env: "NOTIFICATION_SERVICE_URL"},
+		{Prefix: "bot-detection", Env: "BOT_DETECTION_SERVICE_URL"},
+		{Prefix: "waf", Env: "WAF_SERVICE_URL"},
+		{Prefix: "breach", Env: "BREACH_DETECTION_SERVICE_URL"},
+		{Prefix: "admin-management", Env: "ADMIN_MANAGEMENT_SERVICE_URL"},
+	}
+}
+
+// LoadEnv sets the URL of every route whose Env variable lookup finds.
+func LoadEnv(routes []Route, lookup func(string) (string, bool)) {
+	for i, r := range routes {
+		if r.Env == "" {
+			continue
+		}
+		if v, _ := lookup(r.Env); v != "" {
+			routes[i].URL = v
+		}
+	}
+}
+
+// Validate reports every invalid route and pool at once. Routes without a URL
+// or pool are allowed and answer 502 until one is configured.
+func Validate(routes []Route, pools []Pool) error {
+	var errs []error
+	named := make(map[string]bool, len(pools))
+	for _, p := range pools {
+		if p.Name == "" {
+			errs = append(errs, errors.New("pool name must not be empty"))
+		}
+		if named[p.Name] {
+			errs = append(errs, fmt.Errorf("pool %q is defined twice", p.Name))
+		}
+		named[p.Name] = true
+		if len(p.Targets) == 0 {
+			errs = append(errs, fmt.Errorf("pool %q must have at least one target", p.Name))
+		}
+		for _, target := range p.Targets {
+			if !absoluteHTTP(target) {
+				errs = append(errs, fmt.Errorf("pool %q target %q must be an absolute http(s) URL", p.Name, target))
+			}
+		}
+	}
+
+	seen := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		if r.Prefix == "" || strings.HasPrefix(r.Prefix, "/") {
+			errs = append(errs, fmt.Errorf("route prefix %q must be non-empty and not start with /", r.Prefix))
+		}
+		if seen[r.Prefix] {
+			errs = append(errs, fmt.Errorf("route prefix %q is defined twice", r.Prefix))
+		}
+		seen[r.Prefix] = true
+		if r.Pool != "" {
+			if r.URL != "" {
+				errs = append(errs, fmt.Errorf("route %q must set either a URL or a pool, not both", r.Prefix))
+			}
+			if !named[r.Pool] {
+				errs = append(errs, fmt.Errorf("route %q uses unknown pool %q", r.Prefix, r.Pool))
+			}
+			continue
+		}
+		if r.URL != "" && !absoluteHTTP(r.URL) {
+			errs = append(errs, fmt.Errorf("route %q URL %q must be an absolute http(s) URL", r.Prefix, r.URL))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func absoluteHTTP(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Balancer picks the upstream service for a request.
+type Balancer struct {
+	routes []Route
+	pools  map[string]*pool
+}
+
+type pool struct {
+	targets []string
+	next    atomic.Uint64
+}
+
+// New returns a Balancer over routes, which are matched in order, and the
+// pools they refer to.
+func New(routes []Route, pools []Pool) *Balancer {
+	b := &Balancer{routes: append([]Route(nil), routes...), pools: make(map[string]*pool, len(pools))}
+	for _, p := range pools {
+		b.pools[p.Name] = &pool{targets: append([]string(nil), p.Targets...)}
+	}
+	return b
+}
+
+// Match returns the first route whose prefix is a whole leading segment of
+// path, so "agent" matches /agent and /agent/x but not /agents.
+func (b *Balancer) Match(path string) (Route, bool) {
+	path = strings.Trim(path, "/")
+	for _, r := range b.routes {
+		if path == r.Prefix || strings.HasPrefix(path, r.Prefix+"/") {
+			return r, true
+		}
+	}
+	return Route{}, false
+}
+
+// RouteRequest routes the request to the appropriate microservice based on the
+// path. Routes backed by a pool take its targets in turn.
+func (b *Balancer) RouteRequest(req *http.Request) string {
+	if r, ok := b.Match(req.URL.Path); ok {
+		target := r.URL
+		if p := b.pools[r.Pool]; r.Pool != "" && p != nil && len(p.targets) > 0 {
+			target = p.targets[(p.next.Add(1)-1)%uint64(len(p.targets))]
+		}
+		if target != "" {
+			logger.LogInfo("LoadBalancer", "RouteRequest", "Routed request", map[string]string{"Path": req.URL.Path, "ServiceURL": target})
+			return target
+		}
+	}
+
+	defaultErr := errors.New("default route: " + req.URL.Path)
+	logger.LogError("LoadBalancer", "RouteRequest", "Unable to route request", defaultErr)
+	return ""
+}
+
+// Upstreams returns the configured service URL of every route, keyed by its
+// prefix, and of every pool target, keyed by pool name and position
+// ("search#0"). Routes without a URL are left out.
+func (b *Balancer) Upstreams() map[string]string {
+	upstreams := make(map[string]string, len(b.routes))
+	for _, r := range b.routes {
+		if r.URL != "" {
+			upstreams[r.Prefix] = r.URL
+		}
+	}
+	for name, p := range b.pools {
+		for i, target := range p.targets {
+			upstreams[fmt.Sprintf("%s#%d", name, i)] = target
+		}
+	}
+	return upstreams
 }
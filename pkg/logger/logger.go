@@ -5,7 +5,6 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"runtime"
 	"time"
 
 	"github.com/natefinch/lumberjack"
@@ -15,24 +14,103 @@ import (
 
 var Logging *zap.Logger
 
-// InitLogger initializes the logger
+// sampledLogging shares Logging's sinks but drops repeated entries beyond the
+// configured per-second budget; see Logger.Sampled.
+var sampledLogging *zap.Logger
+var sampledBase *zap.Logger
+
+// level is shared by every core so SetLevel takes effect immediately.
+var level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+// InitLogger initializes the logger from the LOG_* environment variables,
+// falling back to the defaults if they are invalid.
 func InitLogger() {
 	log.Println("Initializing logger!!")
 
+	opts, err := OptionsFromEnv()
+	if err != nil {
+		log.Printf("Invalid logger configuration, using defaults: %s", err.Error())
+		opts = DefaultOptions()
+	}
+
+	if err := Configure(opts); err != nil {
+		log.Fatalf("Error configuring logger: %s", err.Error())
+	}
+}
+
+// Configure (re)builds the global loggers from opts.
+func Configure(opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if err := SetRedaction(opts.Redaction); err != nil {
+		return err
+	}
+
+	var sinks []zapcore.WriteSyncer
+	if opts.Output == OutputFile || opts.Output == OutputBoth {
+		path, err := resolveLogFile(opts.File)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, zapcore.AddSync(&lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    opts.MaxSizeMB,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAgeDays,
+			Compress:   false,
+			LocalTime:  true,
+		}))
+	}
+	if opts.Output == OutputStdout || opts.Output == OutputBoth {
+		sinks = append(sinks, zapcore.Lock(os.Stdout))
+	}
+
+	encoderCfg := zap.NewProductionEncoderConfig()
+	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoder := zapcore.NewJSONEncoder(encoderCfg)
+	if opts.Format == FormatConsole {
+		encoderCfg.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderCfg)
+	}
+
+	parsed, _ := zapcore.ParseLevel(opts.Level)
+	level.SetLevel(parsed)
+
+	// Every entry is redacted before it reaches a sink.
+	core := newRedactingCore(zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(sinks...), level))
+
+	// The helpers below and *Logger methods are one frame above the real caller.
+	Logging = zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
+	sampledLogging, sampledBase = Logging, Logging
+	if opts.SampleInitial > 0 {
+		sampledLogging = Logging.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			return zapcore.NewSamplerWithOptions(c, time.Second, opts.SampleInitial, opts.SampleThereafter)
+		}))
+	}
+	return nil
+}
+
+// resolveLogFile returns the log file path, defaulting to a date-named file in
+// the .logs directory under the working directory.
+func resolveLogFile(path string) (string, error) {
+	if path != "" {
+		if _, err := EnsureDirectory(filepath.Dir(path)); err != nil {
+			return "", err
+		}
+		return path, EnsureLogFile(path)
+	}
+
 	rootPath, err := GetRootDirectoryPath()
 	if err != nil {
-		log.Fatalf("Error getting root directory path: %s", err.Error())
-	} else {
-		log.Printf("Root directory path: %s\n", rootPath)
+		return "", err
 	}
 
 	// Check for log directory (if not, create)
 	logDirectory := fmt.Sprintf("%s/.logs", rootPath)
 	info, err := EnsureDirectory(logDirectory)
 	if err != nil {
-		log.Fatalf("Error creating log directory: %s", err.Error())
-	} else {
-		log.Printf("Log directory ensured: %s\n", logDirectory)
+		return "", err
 	}
 
 	// Create log file based on current date (if exists, append)
@@ -41,30 +119,22 @@ func InitLogger() {
 		logPath = fmt.Sprintf("%s/log_%s.log", logDirectory, info.ModTime().Format("2006-01-02"))
 	}
 
-	if err := EnsureLogFile(logPath); err != nil {
-		log.Fatalf("Error creating log file: %s", err.Error())
-	} else {
-		log.Printf("Log file ensured: %s\n", logPath)
-	}
+	return logPath, EnsureLogFile(logPath)
+}
 
-	// Custom log configuration
-	encoderCfg := zap.NewProductionEncoderConfig()
-	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
-	core := zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderCfg),
-		zapcore.AddSync(&lumberjack.Logger{
-			Filename:   logPath,
-			MaxSize:    100, // MB
-			MaxBackups: 3,
-			MaxAge:     365, // days
-			Compress:   false,
-			LocalTime:  true,
-		}),
-		zapcore.DebugLevel,
-	)
+// Level returns the current minimum level as a string.
+func Level() string {
+	return level.Level().String()
+}
 
-	// Zap logger core setup
-	Logging = zap.New(core, zap.AddCaller())
+// SetLevel changes the minimum level of every logger at runtime.
+func SetLevel(name string) error {
+	parsed, err := zapcore.ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(parsed)
+	return nil
 }
 
 // SyncLogger flushes any buffered log entries
@@ -78,13 +148,10 @@ func LogInfo(source, activity, debugString string, object ...interface{}) {
 	if Logging == nil {
 		InitLogger()
 	}
-	_, file, line, _ := runtime.Caller(1)
-	caller := fmt.Sprintf("%s:%d", file, line)
 	Logging.Info(debugString,
 		zap.String("Source", source),
 		zap.Any("Object", object),
 		zap.String("Activity", activity),
-		zap.String("Caller", caller),
 	)
 }
 
@@ -92,13 +159,10 @@ func LogError(source string, activity string, object interface{}, err error) {
 	if Logging == nil {
 		InitLogger()
 	}
-	_, file, line, _ := runtime.Caller(1)
-	caller := fmt.Sprintf("%s:%d", file, line)
 	Logging.Error("Error",
 		zap.String("Source", source),
 		zap.Any("Object", object),
 		zap.String("Activity", activity),
-		zap.String("Caller", caller),
 		zap.Error(err),
 	)
 }
@@ -107,13 +171,10 @@ func LogFatal(source string, activity string, object interface{}, err error) {
 	if Logging == nil {
 		InitLogger()
 	}
-	_, file, line, _ := runtime.Caller(1)
-	caller := fmt.Sprintf("%s:%d", file, line)
 	Logging.Fatal("Fatal",
 		zap.String("Source", source),
 		zap.Any("Object", object),
 		zap.String("Activity", activity),
-		zap.String("Caller", caller),
 		zap.Error(err),
 	)
 }
@@ -122,13 +183,10 @@ func LogWarning(source string, activity string, message string, object interface
 	if Logging == nil {
 		InitLogger()
 	}
-	_, file, line, _ := runtime.Caller(1)
-	caller := fmt.Sprintf("%s:%d", file, line)
 	Logging.Warn("Warning",
 		zap.String("Source", source),
 		zap.Any("Object", object),
 		zap.String("Activity", activity),
-		zap.String("Caller", caller),
 		zap.String("Message", message),
 	)
 }
@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Output destinations accepted in LOG_OUTPUT.
+const (
+	OutputFile   = "file"
+	OutputStdout = "stdout"
+	OutputBoth   = "both"
+)
+
+// Encoders accepted in LOG_FORMAT.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Options configures the global loggers.
+type Options struct {
+	Level  string `yaml:"level" toml:"level"`
+	Output string `yaml:"output" toml:"output"`
+	Format string `yaml:"format" toml:"format"`
+	// File overrides the default .logs/log_<date>.log path.
+	File       string `yaml:"file" toml:"file"`
+	MaxSizeMB  int    `yaml:"max_size_mb" toml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups" toml:"max_backups"`
+	MaxAgeDays int    `yaml:"max_age_days" toml:"max_age_days"`
+	// SampleInitial and SampleThereafter bound Logger.Sampled output: per second,
+	// the first SampleInitial entries with the same message are kept, then every
+	// SampleThereafter-th. SampleInitial of zero disables sampling.
+	SampleInitial    int `yaml:"sample_initial" toml:"sample_initial"`
+	SampleThereafter int `yaml:"sample_thereafter" toml:"sample_thereafter"`
+	// Redaction adds keys and patterns to the built-in secret/PII masking,
+	// which cannot be turned off.
+	Redaction RedactionOptions `yaml:"redaction" toml:"redaction"`
+}
+
+// DefaultOptions mirrors the historical behavior: JSON lines to a rotated file.
+func DefaultOptions() Options {
+	return Options{
+		Level:            "info",
+		Output:           OutputFile,
+		Format:           FormatJSON,
+		MaxSizeMB:        100,
+		MaxBackups:       3,
+		MaxAgeDays:       365,
+		SampleInitial:    100,
+		SampleThereafter: 100,
+		Redaction:        DefaultRedactionOptions(),
+	}
+}
+
+// OptionsFromEnv applies the environment over the defaults; see LoadEnv.
+func OptionsFromEnv() (Options, error) {
+	opts := DefaultOptions()
+	if err := opts.LoadEnv(os.LookupEnv); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// LoadEnv overrides o with LOG_LEVEL, LOG_OUTPUT, LOG_FORMAT, LOG_FILE,
+// LOG_SAMPLE_INITIAL, LOG_SAMPLE_THEREAFTER, LOG_REDACT_KEYS (comma separated),
+// LOG_REDACT_PATTERNS (name=regex, semicolon separated) and LOG_REDACT_EMAILS
+// as found by lookup, usually os.LookupEnv.
+func (o *Options) LoadEnv(lookup func(string) (string, bool)) error {
+	getenv := func(key string) string { v, _ := lookup(key); return v }
+	if v := getenv("LOG_LEVEL"); v != "" {
+		o.Level = strings.ToLower(v)
+	}
+	if v := getenv("LOG_OUTPUT"); v != "" {
+		o.Output = strings.ToLower(v)
+	}
+	if v := getenv("LOG_FORMAT"); v != "" {
+		o.Format = strings.ToLower(v)
+	}
+	if v := getenv("LOG_FILE"); v != "" {
+		o.File = v
+	}
+
+	var errs []error
+	if v := getenv("LOG_REDACT_KEYS"); v != "" {
+		for _, key := range strings.Split(v, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				o.Redaction.Keys = append(o.Redaction.Keys, key)
+			}
+		}
+	}
+	if v := getenv("LOG_REDACT_PATTERNS"); v != "" {
+		for _, spec := range strings.Split(v, ";") {
+			name, expr, ok := strings.Cut(strings.TrimSpace(spec), "=")
+			if !ok || name == "" || expr == "" {
+				errs = append(errs, fmt.Errorf("LOG_REDACT_PATTERNS: %q is not name=regex", spec))
+				continue
+			}
+			o.Redaction.Patterns = append(o.Redaction.Patterns, RedactionPattern{Name: name, Expr: expr})
+		}
+	}
+	if v := getenv("LOG_REDACT_EMAILS"); v != "" {
+		maskEmails, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("LOG_REDACT_EMAILS: %w", err))
+		} else {
+			o.Redaction.MaskEmails = maskEmails
+		}
+	}
+	for key, target := range map[string]*int{
+		"LOG_SAMPLE_INITIAL":    &o.SampleInitial,
+		"LOG_SAMPLE_THEREAFTER": &o.SampleThereafter,
+	} {
+		if v := getenv(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				continue
+			}
+			*target = n
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid option at once.
+func (o Options) Validate() error {
+	var errs []error
+	if _, err := zapcore.ParseLevel(o.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log level: %w", err))
+	}
+	switch o.Output {
+	case OutputFile, OutputStdout, OutputBoth:
+	default:
+		errs = append(errs, fmt.Errorf("log output %q must be file, stdout or both", o.Output))
+	}
+	switch o.Format {
+	case FormatJSON, FormatConsole:
+	default:
+		errs = append(errs, fmt.Errorf("log format %q must be json or console", o.Format))
+	}
+	if o.SampleInitial < 0 || o.SampleThereafter < 0 {
+		errs = append(errs, errors.New("log sampling values must not be negative"))
+	}
+	if _, err := NewRedactor(o.Redaction); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,331 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces values that must never be logged.
+const Redacted = "[REDACTED]"
+
+// RedactTag marks a struct field whose value is always masked: `log:"redact"`.
+const RedactTag = "redact"
+
+// maxRedactDepth bounds the walk over nested values (and cyclic pointers).
+const maxRedactDepth = 8
+
+// defaultSensitiveKeys are matched, case- and separator-insensitively, as
+// substrings of field and map key names.
+var defaultSensitiveKeys = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"authorization",
+	"apikey",
+	"privatekey",
+	"cookie",
+}
+
+// RedactionPattern masks every match of Expr in logged strings.
+type RedactionPattern struct {
+	Name string `yaml:"name" toml:"name"`
+	Expr string `yaml:"expr" toml:"expr"`
+	// Replacement defaults to "[REDACTED_<NAME>]".
+	Replacement string `yaml:"replacement" toml:"replacement"`
+}
+
+// defaultPatterns cover secrets that travel as free text rather than named fields.
+var defaultPatterns = []RedactionPattern{
+	{Name: "jwt", Expr: `eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`},
+	{Name: "bearer", Expr: `(?i)bearer\s+[A-Za-z0-9._~+/=-]+`, Replacement: "Bearer " + Redacted},
+	{Name: "bcrypt", Expr: `\$2[aby]?\$\d{2}\$[./A-Za-z0-9]{53}`},
+	// Long enough to skip 32-char trace IDs but catch 64-char refresh tokens.
+	{Name: "key", Expr: `\b[A-Fa-f0-9]{48,}\b`},
+}
+
+var emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+
+// RedactionOptions configures what the redactor masks on top of the defaults.
+type RedactionOptions struct {
+	// Keys are extra sensitive field or map key names.
+	Keys []string `yaml:"keys" toml:"keys"`
+	// Patterns are extra expressions masked in every logged string.
+	Patterns []RedactionPattern `yaml:"patterns" toml:"patterns"`
+	// MaskEmails keeps the first character and domain of email addresses.
+	MaskEmails bool `yaml:"mask_emails" toml:"mask_emails"`
+}
+
+// DefaultRedactionOptions masks the built-in keys, JWTs, bearer tokens, bcrypt
+// hashes, long hex keys and email addresses.
+func DefaultRedactionOptions() RedactionOptions {
+	return RedactionOptions{MaskEmails: true}
+}
+
+type compiledPattern struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// Redactor masks secrets and PII in values before they are logged.
+type Redactor struct {
+	keys       []string
+	patterns   []compiledPattern
+	maskEmails bool
+}
+
+// NewRedactor compiles opts on top of the default keys and patterns.
+func NewRedactor(opts RedactionOptions) (*Redactor, error) {
+	r := &Redactor{maskEmails: opts.MaskEmails}
+	for _, key := range append(append([]string{}, defaultSensitiveKeys...), opts.Keys...) {
+		if key = normalizeKey(key); key != "" {
+			r.keys = append(r.keys, key)
+		}
+	}
+
+	var errs []error
+	for _, p := range append(append([]RedactionPattern{}, defaultPatterns...), opts.Patterns...) {
+		re, err := regexp.Compile(p.Expr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("redaction pattern %q: %w", p.Name, err))
+			continue
+		}
+		replacement := p.Replacement
+		if replacement == "" {
+			replacement = "[REDACTED_" + strings.ToUpper(p.Name) + "]"
+		}
+		r.patterns = append(r.patterns, compiledPattern{re: re, replacement: replacement})
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+var redactor atomic.Pointer[Redactor]
+
+func init() {
+	r, _ := NewRedactor(DefaultRedactionOptions())
+	redactor.Store(r)
+}
+
+// SetRedaction replaces the global redaction rules.
+func SetRedaction(opts RedactionOptions) error {
+	r, err := NewRedactor(opts)
+	if err != nil {
+		return err
+	}
+	redactor.Store(r)
+	return nil
+}
+
+// Redact returns v with secrets and PII masked by the global rules.
+func Redact(v interface{}) interface{} {
+	return redactor.Load().Value(v)
+}
+
+// RedactString masks secrets and PII in s using the global rules.
+func RedactString(s string) string {
+	return redactor.Load().String(s)
+}
+
+// IsSensitiveKey reports whether a field or map key name must be masked.
+func (r *Redactor) IsSensitiveKey(key string) bool {
+	key = normalizeKey(key)
+	if key == "" {
+		return false
+	}
+	for _, k := range r.keys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// String masks every configured pattern in s.
+func (r *Redactor) String(s string) string {
+	for _, p := range r.patterns {
+		s = p.re.ReplaceAllString(s, p.replacement)
+	}
+	if r.maskEmails {
+		s = emailPattern.ReplaceAllString(s, "$1***@$2")
+	}
+	return s
+}
+
+// Value returns a copy of v that is safe to log. Structs and maps become
+// map[string]interface{} keyed like their JSON encoding, with sensitive and
+// `log:"redact"` fields replaced.
+func (r *Redactor) Value(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return r.value(reflect.ValueOf(v), 0)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (r *Redactor) value(v reflect.Value, depth int) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if depth > maxRedactDepth {
+		return "[TRUNCATED]"
+	}
+
+	if v.Type() == timeType {
+		return v.Interface()
+	}
+	if v.CanInterface() {
+		if err, ok := v.Interface().(error); ok && v.Kind() != reflect.Struct {
+			if v.Kind() == reflect.Ptr && v.IsNil() {
+				return nil
+			}
+			return r.String(err.Error())
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return r.value(v.Elem(), depth+1)
+	case reflect.String:
+		return r.String(v.String())
+	case reflect.Struct:
+		out := make(map[string]interface{}, v.NumField())
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if tag, ok := field.Tag.Lookup("json"); ok {
+				tagName := strings.Split(tag, ",")[0]
+				if tagName == "-" {
+					continue
+				}
+				if tagName != "" {
+					name = tagName
+				}
+			}
+			if field.Tag.Get("log") == RedactTag || r.IsSensitiveKey(field.Name) || r.IsSensitiveKey(name) {
+				out[name] = Redacted
+				continue
+			}
+			out[name] = r.value(v.Field(i), depth+1)
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if r.IsSensitiveKey(key) {
+				out[key] = Redacted
+				continue
+			}
+			out[key] = r.value(iter.Value(), depth+1)
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return r.String(string(v.Bytes()))
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = r.value(v.Index(i), depth+1)
+		}
+		return out
+	default:
+		if v.CanInterface() {
+			return v.Interface()
+		}
+		return nil
+	}
+}
+
+// Field returns f with its value masked as needed.
+func (r *Redactor) Field(f zapcore.Field) zapcore.Field {
+	if r.IsSensitiveKey(f.Key) {
+		return zap.String(f.Key, Redacted)
+	}
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = r.String(f.String)
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			return zap.String(f.Key, r.String(string(b)))
+		}
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			return zap.String(f.Key, r.String(err.Error()))
+		}
+	case zapcore.ReflectType, zapcore.StringerType:
+		return zap.Any(f.Key, r.Value(f.Interface))
+	case zapcore.ArrayMarshalerType, zapcore.ObjectMarshalerType:
+		// Encode the value to plain slices and maps so the elements go
+		// through the same rules as any other value
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		return zap.Any(f.Key, r.Value(enc.Fields[f.Key]))
+	}
+	return f
+}
+
+// redactingCore masks every entry before it reaches a sink, so secrets are
+// dropped regardless of which logging API produced them.
+type redactingCore struct {
+	zapcore.Core
+}
+
+func newRedactingCore(core zapcore.Core) zapcore.Core {
+	return &redactingCore{Core: core}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = RedactString(ent.Message)
+	return c.Core.Write(ent, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	r := redactor.Load()
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		out[i] = r.Field(f)
+	}
+	return out
+}
+
+func normalizeKey(key string) string {
+	key = strings.ToLower(key)
+	return strings.NewReplacer("_", "", "-", "", " ", "", ".", "").Replace(key)
+}
@@ -0,0 +1,209 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"zeneye-gateway/pkg/cache"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gateway"
+
+// UnmatchedRoute is the route label used for requests that matched no registered route,
+// so arbitrary request paths never become label values.
+const UnmatchedRoute = "unmatched"
+
+// Registry holds every gateway collector. It is separate from the global default
+// registry so tests and tools can scrape exactly what the gateway exposes.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled by the gateway, by route template, method and status.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "End-to-end latency of requests handled by the gateway.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "Requests currently being handled by the gateway.",
+	})
+
+	upstreamRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Requests proxied to upstream services, by service and response status.",
+	}, []string{"service", "status"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Latency of requests proxied to upstream services.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service"})
+
+	upstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_errors_total",
+		Help:      "Proxy failures before an upstream response was received, by service and reason.",
+	}, []string{"service", "reason"})
+
+	rateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by the rate limiter, by route template.",
+	}, []string{"route"})
+
+	authFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Authentication failures, by reason.",
+	}, []string{"reason"})
+)
+
+var (
+	dynamicMu sync.Mutex
+	dynamic   = make(map[string]prometheus.Collector)
+)
+
+func init() {
+	Registry.MustRegister(
+		httpRequests,
+		httpDuration,
+		httpInFlight,
+		upstreamRequests,
+		upstreamDuration,
+		upstreamErrors,
+		rateLimitRejections,
+		authFailures,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler returns the HTTP handler serving the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// RouteLabel normalizes a gin route template for use as a label value.
+func RouteLabel(fullPath string) string {
+	if fullPath == "" {
+		return UnmatchedRoute
+	}
+	return fullPath
+}
+
+// RequestStarted tracks a request entering the gateway; call the returned func when it ends.
+func RequestStarted() func() {
+	httpInFlight.Inc()
+	return httpInFlight.Dec
+}
+
+// ObserveRequest records one handled request.
+func ObserveRequest(route, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(route, method, code).Inc()
+	httpDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// ObserveUpstream records a response received from an upstream service.
+func ObserveUpstream(service string, status int, duration time.Duration) {
+	upstreamRequests.WithLabelValues(service, strconv.Itoa(status)).Inc()
+	upstreamDuration.WithLabelValues(service).Observe(duration.Seconds())
+}
+
+// UpstreamError records a proxy failure for service.
+func UpstreamError(service, reason string) {
+	upstreamErrors.WithLabelValues(service, reason).Inc()
+}
+
+// RateLimitRejected records a request rejected by the rate limiter.
+func RateLimitRejected(route string) {
+	rateLimitRejections.WithLabelValues(route).Inc()
+}
+
+// AuthFailure records a failed authentication attempt.
+func AuthFailure(reason string) {
+	authFailures.WithLabelValues(reason).Inc()
+}
+
+// RegisterDBStats exposes connection pool statistics for db, replacing any pool
+// registered earlier under the same name.
+func RegisterDBStats(name string, db *sql.DB) {
+	replace("db:"+name, collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterCacheStats exposes hit, miss and eviction counters and the size of a cache,
+// replacing any cache registered earlier under the same name.
+func RegisterCacheStats(name string, stats func() cache.Stats) {
+	replace("cache:"+name, newCacheCollector(name, stats))
+}
+
+func replace(key string, c prometheus.Collector) {
+	dynamicMu.Lock()
+	defer dynamicMu.Unlock()
+
+	if previous, ok := dynamic[key]; ok {
+		Registry.Unregister(previous)
+	}
+	if err := Registry.Register(c); err != nil {
+		var already prometheus.AlreadyRegisteredError
+		if !errors.As(err, &already) {
+			logger.LogError("Metrics", "Register", key, err)
+			return
+		}
+	}
+	dynamic[key] = c
+}
+
+type cacheCollector struct {
+	stats     func() cache.Stats
+	hits      *prometheus.Desc
+	misses    *prometheus.Desc
+	evictions *prometheus.Desc
+	size      *prometheus.Desc
+}
+
+func newCacheCollector(name string, stats func() cache.Stats) *cacheCollector {
+	labels := prometheus.Labels{"cache": name}
+	return &cacheCollector{
+		stats:     stats,
+		hits:      prometheus.NewDesc(namespace+"_cache_hits_total", "Cache lookups served from memory.", nil, labels),
+		misses:    prometheus.NewDesc(namespace+"_cache_misses_total", "Cache lookups that fell through to the backing store.", nil, labels),
+		evictions: prometheus.NewDesc(namespace+"_cache_evictions_total", "Entries evicted to respect the cache capacity.", nil, labels),
+		size:      prometheus.NewDesc(namespace+"_cache_entries", "Entries currently held in the cache.", nil, labels),
+	}
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.evictions
+	ch <- c.size
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(s.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(s.Size))
+}
@@ -1,51 +1,191 @@
 package rate_limiter
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"zeneye-gateway/pkg/logger"
 
 	"golang.org/x/time/rate"
 )
 
-var visitors = make(map[string]*rate.Limiter)
-var mu sync.Mutex
-var rateLimit int
+// Options configures the per-client token bucket.
+type Options struct {
+	// RequestsPerSecond is the sustained rate allowed per client IP.
+	RequestsPerSecond int `json:"requests_per_second" yaml:"requests_per_second" toml:"requests_per_second"`
+	// Burst is how many requests a client may make at once; zero means
+	// RequestsPerSecond.
+	Burst int `json:"burst" yaml:"burst" toml:"burst"`
+	// Routes override the rate for paths under their prefix; the longest
+	// matching prefix wins and keeps its own per-client buckets.
+	Routes []RoutePolicy `json:"routes" yaml:"routes" toml:"routes"`
+}
+
+// RoutePolicy is the rate allowed per client for paths under Prefix.
+type RoutePolicy struct {
+	Prefix            string `json:"prefix" yaml:"prefix" toml:"prefix"`
+	RequestsPerSecond int    `json:"requests_per_second" yaml:"requests_per_second" toml:"requests_per_second"`
+	// Burst of zero means RequestsPerSecond.
+	Burst int `json:"burst,omitempty" yaml:"burst" toml:"burst"`
+}
+
+// DefaultOptions allows 100 requests per second per client.
+func DefaultOptions() Options {
+	return Options{RequestsPerSecond: 100}
+}
+
+// OptionsFromEnv applies the environment over the defaults; see LoadEnv.
+func OptionsFromEnv() (Options, error) {
+	opts := DefaultOptions()
+	if err := opts.LoadEnv(os.LookupEnv); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// LoadEnv overrides o with RATE_LIMIT, RATE_LIMIT_BURST and RATE_LIMIT_ROUTES
+// (a JSON array of RoutePolicy) as found by lookup, usually os.LookupEnv.
+func (o *Options) LoadEnv(lookup func(string) (string, bool)) error {
+	getenv := func(key string) string { v, _ := lookup(key); return v }
+	var errs []error
+	for key, target := range map[string]*int{
+		"RATE_LIMIT":       &o.RequestsPerSecond,
+		"RATE_LIMIT_BURST": &o.Burst,
+	} {
+		if v := getenv(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				continue
+			}
+			*target = n
+		}
+	}
+	if v := getenv("RATE_LIMIT_ROUTES"); v != "" {
+		if err := json.Unmarshal([]byte(v), &o.Routes); err != nil {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_ROUTES: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid option at once.
+func (o Options) Validate() error {
+	var errs []error
+	if o.RequestsPerSecond < 1 {
+		errs = append(errs, errors.New("rate limit must be at least one request per second"))
+	}
+	if o.Burst < 0 {
+		errs = append(errs, errors.New("rate limit burst must not be negative"))
+	}
+	seen := make(map[string]bool, len(o.Routes))
+	for _, route := range o.Routes {
+		if !strings.HasPrefix(route.Prefix, "/") {
+			errs = append(errs, fmt.Errorf("rate limit route prefix %q must start with /", route.Prefix))
+		}
+		if seen[route.Prefix] {
+			errs = append(errs, fmt.Errorf("rate limit route prefix %q is defined twice", route.Prefix))
+		}
+		seen[route.Prefix] = true
+		if route.RequestsPerSecond < 1 {
+			errs = append(errs, fmt.Errorf("rate limit route %s must allow at least one request per second", route.Prefix))
+		}
+		if route.Burst < 0 {
+			errs = append(errs, fmt.Errorf("rate limit route %s burst must not be negative", route.Prefix))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Limiter keeps one token bucket per client IP and rate policy.
+type Limiter struct {
+	mu       sync.Mutex
+	visitors map[string]*rate.Limiter
+	limit    rate.Limit
+	burst    int
+	routes   []RoutePolicy
+	opts     Options
+}
 
-func init() {
-	logger.InitLogger() // ensure log
+// New returns a Limiter enforcing opts.
+func New(opts Options) *Limiter {
+	routes := append([]RoutePolicy(nil), opts.Routes...)
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].Prefix) > len(routes[j].Prefix)
+	})
+	return &Limiter{
+		visitors: make(map[string]*rate.Limiter),
+		limit:    rate.Limit(opts.RequestsPerSecond),
+		burst:    burstOf(opts.RequestsPerSecond, opts.Burst),
+		routes:   routes,
+		opts:     opts,
+	}
+}
 
-	// limit, err := strconv.Atoi(utils.GetEnv("RATE_LIMIT"))
-	limit, err := strconv.Atoi(os.Getenv("RATE_LIMIT"))
-	if err != nil {
-		limit = 100 // Default limit
-		logger.LogError("RateLimiter", "init", "Invalid RATE_LIMIT environment variable; using default rate limit", err)
-	} else {
-		logger.LogInfo("RateLimiter", "init", "Rate limit initialized from environment variable", map[string]int{"RateLimit": limit})
+func burstOf(rps, burst int) int {
+	if burst == 0 {
+		return rps
 	}
-	rateLimit = limit
+	return burst
+}
+
+// Options returns the options l enforces.
+func (l *Limiter) Options() Options {
+	return l.opts
 }
 
-func getVisitor(ip string) *rate.Limiter {
+// getVisitor returns the bucket of ip under the policy keyed by prefix, where
+// the empty prefix is the gateway-wide rate.
+func (l *Limiter) getVisitor(prefix, ip string, limit rate.Limit, burst int) *rate.Limiter {
 
-	mu.Lock()
-	defer mu.Unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-	limiter, exists := visitors[ip]
+	key := prefix + " " + ip
+	limiter, exists := l.visitors[key]
 	if !exists {
-		limiter = rate.NewLimiter(rate.Limit(rateLimit), rateLimit)
-		visitors[ip] = limiter
-		logger.LogInfo("RateLimiter", "getVisitor", "Created new rate limiter for IP", map[string]string{"IP": ip})
+		limiter = rate.NewLimiter(limit, burst)
+		l.visitors[key] = limiter
+		logger.LogInfo("RateLimiter", "getVisitor", "Created new rate limiter for IP", map[string]string{"IP": ip, "Prefix": prefix})
 	}
 
 	return limiter
 }
 
-func AllowRequest(ip string) bool {
+// AllowRequest reports whether ip may make another request now under the
+// gateway-wide rate.
+func (l *Limiter) AllowRequest(ip string) bool {
+	return l.allow(l.getVisitor("", ip, l.limit, l.burst), ip)
+}
+
+// Allow reports whether ip may make another request to path now, under the
+// longest route policy matching path or else the gateway-wide rate.
+func (l *Limiter) Allow(path, ip string) bool {
+	policy := l.PolicyFor(path)
+	if policy.Prefix == "" {
+		return l.AllowRequest(ip)
+	}
+	return l.allow(l.getVisitor(policy.Prefix, ip, rate.Limit(policy.RequestsPerSecond), policy.Burst), ip)
+}
+
+// PolicyFor returns the policy Allow applies to path, with its burst filled
+// in. The gateway-wide rate has an empty prefix.
+func (l *Limiter) PolicyFor(path string) RoutePolicy {
+	for _, route := range l.routes {
+		if path == route.Prefix || strings.HasPrefix(path, strings.TrimSuffix(route.Prefix, "/")+"/") {
+			route.Burst = burstOf(route.RequestsPerSecond, route.Burst)
+			return route
+		}
+	}
+	return RoutePolicy{RequestsPerSecond: l.opts.RequestsPerSecond, Burst: l.burst}
+}
 
-	limiter := getVisitor(ip)
+func (l *Limiter) allow(limiter *rate.Limiter, ip string) bool {
 	allowed := limiter.Allow()
 
 	if !allowed {
@@ -0,0 +1,44 @@
+package requestid
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+// Header is the HTTP header carrying the request ID in both directions.
+const Header = "X-Request-ID"
+
+// GinKey is the key the request ID is stored under in the gin context.
+const GinKey = "request_id"
+
+type contextKey struct{}
+
+// Incoming IDs are echoed into logs and upstream headers, so only short,
+// header-safe values are accepted.
+var validID = regexp.MustCompile(`^[A-Za-z0-9._:\-]{1,128}$`)
+
+// New returns a fresh random request ID.
+func New() string {
+	return uuid.New().String()
+}
+
+// Valid reports whether id may be reused as a request ID.
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or "" if there is none.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,120 @@
+package security
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Options configures the response headers and request limits enforced on
+// every request. An empty header value leaves that header unset.
+type Options struct {
+	StrictTransportSecurity string `yaml:"hsts" toml:"hsts"`
+	ContentTypeOptions      string `yaml:"content_type_options" toml:"content_type_options"`
+	FrameOptions            string `yaml:"frame_options" toml:"frame_options"`
+	ReferrerPolicy          string `yaml:"referrer_policy" toml:"referrer_policy"`
+	ContentSecurityPolicy   string `yaml:"csp" toml:"csp"`
+
+	// MaxBodyBytes caps request bodies; zero or less means unlimited.
+	MaxBodyBytes int64 `yaml:"max_body_bytes" toml:"max_body_bytes"`
+	// MaxHeaderBytes caps the request line plus all header names and values.
+	MaxHeaderBytes int `yaml:"max_header_bytes" toml:"max_header_bytes"`
+	// AllowedContentTypes lists the media types accepted for requests with a
+	// body. Empty accepts any.
+	AllowedContentTypes []string `yaml:"allowed_content_types" toml:"allowed_content_types"`
+	// Routes override the limits for paths under their prefix; the longest
+	// matching prefix wins.
+	Routes []RouteOptions `yaml:"routes" toml:"routes"`
+}
+
+// RouteOptions overrides the request limits it sets for paths under Prefix.
+type RouteOptions struct {
+	Prefix              string   `json:"prefix" yaml:"prefix" toml:"prefix"`
+	MaxBodyBytes        *int64   `json:"max_body_bytes,omitempty" yaml:"max_body_bytes" toml:"max_body_bytes"`
+	AllowedContentTypes []string `json:"allowed_content_types,omitempty" yaml:"allowed_content_types" toml:"allowed_content_types"`
+}
+
+// DefaultOptions suits a JSON API that is never framed or rendered as a page.
+func DefaultOptions() Options {
+	return Options{
+		StrictTransportSecurity: "max-age=31536000; includeSubDomains",
+		ContentTypeOptions:      "nosniff",
+		FrameOptions:            "DENY",
+		ReferrerPolicy:          "no-referrer",
+		ContentSecurityPolicy:   "default-src 'none'; frame-ancestors 'none'",
+		MaxBodyBytes:            1 << 20,
+		MaxHeaderBytes:          32 << 10,
+		AllowedContentTypes:     []string{"application/json", "application/x-www-form-urlencoded", "multipart/form-data"},
+	}
+}
+
+// OptionsFromEnv applies the environment over the defaults; see LoadEnv.
+func OptionsFromEnv() (Options, error) {
+	opts := DefaultOptions()
+	if err := opts.LoadEnv(os.LookupEnv); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// LoadEnv overrides o with SECURITY_HSTS, SECURITY_CONTENT_TYPE_OPTIONS,
+// SECURITY_FRAME_OPTIONS, SECURITY_REFERRER_POLICY, SECURITY_CSP (set to an
+// empty value to drop a header), SECURITY_MAX_BODY_BYTES,
+// SECURITY_MAX_HEADER_BYTES, SECURITY_ALLOWED_CONTENT_TYPES (comma separated)
+// and SECURITY_ROUTES (a JSON array of RouteOptions) as found by lookup,
+// usually os.LookupEnv.
+func (o *Options) LoadEnv(lookup func(string) (string, bool)) error {
+	getenv := func(key string) string { v, _ := lookup(key); return v }
+	for key, target := range map[string]*string{
+		"SECURITY_HSTS":                 &o.StrictTransportSecurity,
+		"SECURITY_CONTENT_TYPE_OPTIONS": &o.ContentTypeOptions,
+		"SECURITY_FRAME_OPTIONS":        &o.FrameOptions,
+		"SECURITY_REFERRER_POLICY":      &o.ReferrerPolicy,
+		"SECURITY_CSP":                  &o.ContentSecurityPolicy,
+	} {
+		if v, ok := lookup(key); ok {
+			*target = strings.TrimSpace(v)
+		}
+	}
+
+	var errs []error
+	if v := getenv("SECURITY_MAX_BODY_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("SECURITY_MAX_BODY_BYTES: %w", err))
+		} else {
+			o.MaxBodyBytes = n
+		}
+	}
+	if v := getenv("SECURITY_MAX_HEADER_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("SECURITY_MAX_HEADER_BYTES: %w", err))
+		} else {
+			o.MaxHeaderBytes = n
+		}
+	}
+	if v, ok := lookup("SECURITY_ALLOWED_CONTENT_TYPES"); ok {
+		o.AllowedContentTypes = nil
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				o.AllowedContentTypes = append(o.AllowedContentTypes, item)
+			}
+		}
+	}
+	if v := getenv("SECURITY_ROUTES"); v != "" {
+		if err := json.Unmarshal([]byte(v), &o.Routes); err != nil {
+			errs = append(errs, fmt.Errorf("SECURITY_ROUTES: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid option at once.
+func (o Options) Validate() error {
+	_, err := New(o)
+	return err
+}
@@ -0,0 +1,74 @@
+package security
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Path validation errors.
+var (
+	ErrMalformedEncoding = errors.New("path contains a malformed percent-encoding")
+	ErrEncodedSeparator  = errors.New("path contains an encoded separator")
+	ErrDoubleEncoding    = errors.New("path is percent-encoded more than once")
+	ErrInvalidCharacter  = errors.New("path contains invalid characters")
+	ErrPathTraversal     = errors.New("path contains a dot segment")
+	ErrMalformedQuery    = errors.New("query string is not valid percent-encoded UTF-8")
+)
+
+// ValidatePath checks the raw (still encoded) request path. It rejects
+// anything an upstream service could decode into a different path than the one
+// the gateway routed: malformed or double encodings, encoded slashes, control
+// characters, invalid UTF-8 and "." or ".." segments.
+func ValidatePath(rawPath string) error {
+	lower := strings.ToLower(rawPath)
+	if strings.Contains(lower, "%2f") || strings.Contains(lower, "%5c") {
+		return ErrEncodedSeparator
+	}
+
+	decoded, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return ErrMalformedEncoding
+	}
+	if _, err := url.PathUnescape(decoded); err == nil && decoded != rawPath && strings.Contains(decoded, "%") {
+		return ErrDoubleEncoding
+	}
+	if !utf8.ValidString(decoded) {
+		return ErrInvalidCharacter
+	}
+	for _, r := range decoded {
+		if unicode.IsControl(r) || r == '\\' {
+			return ErrInvalidCharacter
+		}
+	}
+	for _, segment := range strings.Split(decoded, "/") {
+		if segment == "." || segment == ".." {
+			return ErrPathTraversal
+		}
+	}
+	return nil
+}
+
+// ValidateQuery checks that the raw query string decodes cleanly.
+func ValidateQuery(rawQuery string) error {
+	if rawQuery == "" {
+		return nil
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return ErrMalformedQuery
+	}
+	for key, vs := range values {
+		if !utf8.ValidString(key) {
+			return ErrMalformedQuery
+		}
+		for _, v := range vs {
+			if !utf8.ValidString(v) || strings.ContainsRune(v, 0) {
+				return ErrMalformedQuery
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Policy is a compiled set of security headers and request limits.
+type Policy struct {
+	headers        [][2]string
+	maxHeaderBytes int
+	base           *Limits
+	routes         []routeLimits
+}
+
+type routeLimits struct {
+	prefix string
+	limits *Limits
+}
+
+// Limits are the request limits in force for one path.
+type Limits struct {
+	maxBodyBytes int64
+	contentTypes map[string]bool
+}
+
+// New compiles opts, reporting every invalid value at once.
+func New(opts Options) (*Policy, error) {
+	var errs []error
+	p := &Policy{maxHeaderBytes: opts.MaxHeaderBytes}
+	for _, h := range [][2]string{
+		{"Strict-Transport-Security", opts.StrictTransportSecurity},
+		{"X-Content-Type-Options", opts.ContentTypeOptions},
+		{"X-Frame-Options", opts.FrameOptions},
+		{"Referrer-Policy", opts.ReferrerPolicy},
+		{"Content-Security-Policy", opts.ContentSecurityPolicy},
+	} {
+		if strings.ContainsAny(h[1], "\r\n") {
+			errs = append(errs, fmt.Errorf("%s must be a single line", h[0]))
+			continue
+		}
+		if h[1] != "" {
+			p.headers = append(p.headers, h)
+		}
+	}
+	if opts.MaxHeaderBytes < 0 {
+		errs = append(errs, errors.New("max header bytes must not be negative"))
+	}
+
+	base, err := compileLimits(opts.MaxBodyBytes, opts.AllowedContentTypes)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	p.base = base
+	for _, route := range opts.Routes {
+		if !strings.HasPrefix(route.Prefix, "/") {
+			errs = append(errs, fmt.Errorf("security route prefix %q must start with /", route.Prefix))
+			continue
+		}
+		maxBody, types := opts.MaxBodyBytes, opts.AllowedContentTypes
+		if route.MaxBodyBytes != nil {
+			maxBody = *route.MaxBodyBytes
+		}
+		if route.AllowedContentTypes != nil {
+			types = route.AllowedContentTypes
+		}
+		limits, err := compileLimits(maxBody, types)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("security route %s: %w", route.Prefix, err))
+			continue
+		}
+		p.routes = append(p.routes, routeLimits{prefix: route.Prefix, limits: limits})
+	}
+	sort.SliceStable(p.routes, func(i, j int) bool {
+		return len(p.routes[i].prefix) > len(p.routes[j].prefix)
+	})
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func compileLimits(maxBodyBytes int64, contentTypes []string) (*Limits, error) {
+	var errs []error
+	l := &Limits{maxBodyBytes: maxBodyBytes, contentTypes: map[string]bool{}}
+	for _, ct := range contentTypes {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("content type %q: %w", ct, err))
+			continue
+		}
+		l.contentTypes[mediaType] = true
+	}
+	return l, errors.Join(errs...)
+}
+
+// SetHeaders adds the configured security headers to h.
+func (p *Policy) SetHeaders(h http.Header) {
+	for _, header := range p.headers {
+		h.Set(header[0], header[1])
+	}
+}
+
+// HeaderTooLarge reports whether the request line and headers of r exceed the
+// configured size.
+func (p *Policy) HeaderTooLarge(r *http.Request) bool {
+	if p.maxHeaderBytes <= 0 {
+		return false
+	}
+	size := len(r.Method) + len(r.RequestURI) + len(r.Proto)
+	for name, values := range r.Header {
+		for _, value := range values {
+			size += len(name) + len(value) + 4 // ": " and CRLF
+		}
+	}
+	return size > p.maxHeaderBytes
+}
+
+// For returns the limits for path: the longest matching route override, or
+// the gateway-wide limits.
+func (p *Policy) For(path string) *Limits {
+	for _, route := range p.routes {
+		if path == route.prefix || strings.HasPrefix(path, strings.TrimSuffix(route.prefix, "/")+"/") {
+			return route.limits
+		}
+	}
+	return p.base
+}
+
+// MaxBodyBytes is the largest accepted body, zero or less for unlimited.
+func (l *Limits) MaxBodyBytes() int64 {
+	return l.maxBodyBytes
+}
+
+// AllowContentType reports whether a body with the Content-Type header value
+// ct is accepted.
+func (l *Limits) AllowContentType(ct string) bool {
+	if len(l.contentTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	return err == nil && l.contentTypes[mediaType]
+}
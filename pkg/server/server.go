@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"zeneye-gateway/pkg/logger"
+)
+
+// Options configures the HTTP server and its shutdown.
+type Options struct {
+	Addr              string        `yaml:"addr" toml:"addr"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	// WriteTimeout bounds the whole response, so it must outlast the slowest
+	// proxied upstream call.
+	WriteTimeout   time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout    time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	MaxHeaderBytes int           `yaml:"max_header_bytes" toml:"max_header_bytes"`
+	// DrainDelay is how long readiness reports failure before the listener
+	// closes, giving load balancers time to stop sending traffic.
+	DrainDelay time.Duration `yaml:"drain_delay" toml:"drain_delay"`
+	// ShutdownTimeout bounds how long in-flight requests may take to finish.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+// DefaultOptions listens on :8080 with timeouts that stop slow clients from
+// holding connections open.
+func DefaultOptions() Options {
+	return Options{
+		Addr:              ":8080",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		MaxHeaderBytes:    64 << 10,
+		DrainDelay:        5 * time.Second,
+		ShutdownTimeout:   30 * time.Second,
+	}
+}
+
+// OptionsFromEnv applies the environment over the defaults; see LoadEnv.
+func OptionsFromEnv() (Options, error) {
+	opts := DefaultOptions()
+	if err := opts.LoadEnv(os.LookupEnv); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// LoadEnv overrides o with SERVER_ADDR, SERVER_READ_HEADER_TIMEOUT,
+// SERVER_READ_TIMEOUT, SERVER_WRITE_TIMEOUT, SERVER_IDLE_TIMEOUT,
+// SERVER_MAX_HEADER_BYTES, SERVER_DRAIN_DELAY and SERVER_SHUTDOWN_TIMEOUT as
+// found by lookup, usually os.LookupEnv. Durations use Go syntax, e.g. 30s.
+func (o *Options) LoadEnv(lookup func(string) (string, bool)) error {
+	getenv := func(key string) string { v, _ := lookup(key); return v }
+	if v := getenv("SERVER_ADDR"); v != "" {
+		o.Addr = v
+	}
+
+	var errs []error
+	for key, target := range map[string]*time.Duration{
+		"SERVER_READ_HEADER_TIMEOUT": &o.ReadHeaderTimeout,
+		"SERVER_READ_TIMEOUT":        &o.ReadTimeout,
+		"SERVER_WRITE_TIMEOUT":       &o.WriteTimeout,
+		"SERVER_IDLE_TIMEOUT":        &o.IdleTimeout,
+		"SERVER_DRAIN_DELAY":         &o.DrainDelay,
+		"SERVER_SHUTDOWN_TIMEOUT":    &o.ShutdownTimeout,
+	} {
+		if v := getenv(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				continue
+			}
+			*target = d
+		}
+	}
+	if v := getenv("SERVER_MAX_HEADER_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("SERVER_MAX_HEADER_BYTES: %w", err))
+		} else {
+			o.MaxHeaderBytes = n
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid option at once.
+func (o Options) Validate() error {
+	var errs []error
+	if _, _, err := net.SplitHostPort(o.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("server address %q: %w", o.Addr, err))
+	}
+	for name, d := range map[string]time.Duration{
+		"read header timeout": o.ReadHeaderTimeout,
+		"read timeout":        o.ReadTimeout,
+		"write timeout":       o.WriteTimeout,
+		"idle timeout":        o.IdleTimeout,
+		"shutdown timeout":    o.ShutdownTimeout,
+	} {
+		if d <= 0 {
+			errs = append(errs, fmt.Errorf("server %s must be positive", name))
+		}
+	}
+	if o.DrainDelay < 0 {
+		errs = append(errs, errors.New("server drain delay must not be negative"))
+	}
+	if o.MaxHeaderBytes <= 0 {
+		errs = append(errs, errors.New("server max header bytes must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+var draining atomic.Bool
+
+// Draining reports whether the server is shutting down; readiness checks
+// must fail from then on.
+func Draining() bool {
+	return draining.Load()
+}
+
+// New returns an http.Server for handler configured by opts.
+func New(handler http.Handler, opts Options) *http.Server {
+	return &http.Server{
+		Addr:              opts.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		ReadTimeout:       opts.ReadTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+		MaxHeaderBytes:    opts.MaxHeaderBytes,
+	}
+}
+
+// Run listens on srv.Addr and serves until ctx is done, then shuts down
+// gracefully as described in Serve.
+func Run(ctx context.Context, srv *http.Server, opts Options) error {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	return Serve(ctx, srv, ln, opts)
+}
+
+// Serve serves on ln until ctx is done. It then fails readiness, waits
+// DrainDelay, stops accepting connections and waits up to ShutdownTimeout for
+// in-flight requests before closing whatever is left. It returns nil after a
+// clean drain.
+func Serve(ctx context.Context, srv *http.Server, ln net.Listener, opts Options) error {
+	draining.Store(false)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+	logger.LogInfo("Server", "Serve", "Listening", ln.Addr().String())
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	draining.Store(true)
+	logger.LogInfo("Server", "Shutdown", "Shutdown requested; failing readiness", map[string]string{
+		"drain_delay":      opts.DrainDelay.String(),
+		"shutdown_timeout": opts.ShutdownTimeout.String(),
+	})
+	time.Sleep(opts.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), opts.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.LogError("Server", "Shutdown", "In-flight requests did not finish in time", err)
+		srv.Close()
+		return err
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	logger.LogInfo("Server", "Shutdown", "All requests drained", "")
+	return nil
+}
@@ -0,0 +1,64 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/pkg/accesslog"
+	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/requestid"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLogRecordsUpstream(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	logger.LogInfo("TestAccessLogRecordsUpstream", "Test", "Starting integration test for the access log", "")
+
+	path := filepath.Join(t.TempDir(), "access.log")
+	opts := accesslog.DefaultOptions()
+	opts.Output, opts.File, opts.Format = accesslog.OutputFile, path, accesslog.FormatJSON
+	assert.Nil(t, accesslog.Configure(opts))
+	defer accesslog.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items":[]}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("ADMIN_MANAGEMENT_SERVICE_URL", upstream.URL)
+
+	db := SetupTestDB()
+	router := internal.SetupRouter(db, TestConfig())
+
+	user := &entity.User{Username: "accessuser", Password: "x", Email: "access@example.com", Role: "admin", UserUUID: "acc12345"}
+	db.Create(user)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin-management/get-all", nil)
+	req.Header.Set("Authorization", GenerateTestTokenForUser(user))
+	req.Header.Set(requestid.Header, "req-upstream-1")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 1)
+
+	var entry accesslog.Entry
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "req-upstream-1", entry.RequestID)
+	assert.Equal(t, user.UserUUID, entry.UserUUID)
+	assert.Equal(t, "accessuser", entry.User)
+	assert.Equal(t, "admin-management", entry.UpstreamService)
+	assert.Greater(t, entry.UpstreamMS, 0.0)
+	assert.Equal(t, int64(len(`{"items":[]}`)), entry.BytesOut)
+}
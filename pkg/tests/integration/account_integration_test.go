@@ -0,0 +1,304 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/internal/adapter/repository/postgres"
+	"zeneye-gateway/internal/adapter/service"
+	"zeneye-gateway/internal/app"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// capturingNotifier keeps the notifications it is asked to send.
+type capturingNotifier struct {
+	mu   sync.Mutex
+	sent []entity.Notification
+}
+
+func (n *capturingNotifier) Notify(notification *entity.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, *notification)
+	return nil
+}
+
+func (n *capturingNotifier) last(to string) *entity.Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for i := len(n.sent) - 1; i >= 0; i-- {
+		if n.sent[i].To == to {
+			return &n.sent[i]
+		}
+	}
+	return nil
+}
+
+func TestAccountSelfServiceIntegration(t *testing.T) {
+	logger.LogInfo("TestAccountSelfServiceIntegration", "Test", "Starting integration test for the /me endpoints", "")
+
+	cfg := TestConfig()
+	db := SetupTestDB()
+	notifier := &capturingNotifier{}
+	deps := app.New(db, cfg)
+	deps.Accounts = service.NewAccountService(deps.Identities.WrapRepository(postgres.NewUserRepository(db)), notifier, deps.Jobs)
+	gateway := internal.NewGateway(deps, cfg)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Old@Passw0rd"), bcrypt.MinCost)
+	member := &entity.User{Username: "selfservice", Password: string(hash), Email: "self@example.com", Role: "user"}
+	other := &entity.User{Username: "bystander", Password: string(hash), Email: "taken@example.com", Role: "user"}
+	db.Create(member)
+	db.Create(other)
+	db.Create(&entity.RefreshToken{Token: "other-session", UserID: member.ID})
+	token := GenerateTestTokenForUser(member)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		gateway.Router.ServeHTTP(w, req)
+		return w
+	}
+	profile := func(w *httptest.ResponseRecorder) map[string]interface{} {
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return body
+	}
+
+	// Any role can read and edit its own profile
+	w := send("GET", "/me", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "selfservice", profile(w)["username"])
+	assert.NotContains(t, w.Body.String(), "password")
+
+	w = send("PATCH", "/me", `{"username":"selfserved"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "selfserved", profile(w)["username"])
+	assert.Equal(t, "self@example.com", profile(w)["email"])
+	w = send("PATCH", "/me", `{"username":"bystander"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// The current password is required and the new one must pass validation
+	w = send("POST", "/me/password", `{"current_password":"Wrong@Passw0rd","new_password":"New@Passw0rd"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "wrong_password")
+	w = send("POST", "/me/password", `{"current_password":"Old@Passw0rd","new_password":"short"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_password")
+	w = send("POST", "/me/password", `{"current_password":"Old@Passw0rd","new_password":"Old@Passw0rd"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "password_reused")
+
+	// A change signs out the other sessions and hands the caller new tokens
+	w = send("POST", "/me/password", `{"current_password":"Old@Passw0rd","new_password":"New@Passw0rd"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Authorization"))
+	assert.NotEmpty(t, w.Header().Get("X-Refresh-Token"))
+	var sessions int64
+	db.Model(&entity.RefreshToken{}).Where("token = ?", "other-session").Count(&sessions)
+	assert.Equal(t, int64(0), sessions)
+	// Access tokens issued before the change are rejected too
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/me", "").Code)
+	token = w.Header().Get("Authorization")
+	assert.Equal(t, http.StatusOK, send("GET", "/me", "").Code)
+
+	// Recent passwords cannot come back
+	w = send("POST", "/me/password", `{"current_password":"New@Passw0rd","new_password":"Old@Passw0rd"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "password_reused")
+
+	// An email change only applies once the token sent to the new address is confirmed
+	w = send("POST", "/me/email", `{"email":"taken@example.com","password":"New@Passw0rd"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = send("POST", "/me/email", `{"email":"moved@example.com","password":"Old@Passw0rd"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = send("POST", "/me/email", `{"email":"moved@example.com","password":"New@Passw0rd"}`)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.NotNil(t, notifier.last("self@example.com"))
+	confirmation := notifier.last("moved@example.com")
+	if !assert.NotNil(t, confirmation) {
+		return
+	}
+	w = send("GET", "/me", "")
+	assert.Equal(t, "self@example.com", profile(w)["email"])
+
+	w = send("POST", "/me/email/confirm", `{"token":"not-the-token"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_token")
+
+	confirmToken := strings.Fields(strings.SplitAfter(confirmation.Body, "the token ")[1])[0]
+	confirmToken = strings.TrimSuffix(confirmToken, ".")
+	w = send("POST", "/me/email/confirm", `{"token":"`+confirmToken+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "moved@example.com", profile(w)["email"])
+
+	// Tokens are single use
+	w = send("POST", "/me/email/confirm", `{"token":"`+confirmToken+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var actions []string
+	db.Model(&entity.AuditEvent{}).Where("target_id = ?", member.ID).Order("id").Pluck("action", &actions)
+	assert.Contains(t, actions, "user.password_change")
+	assert.Contains(t, actions, "user.email_change_request")
+	assert.Contains(t, actions, "user.email_change")
+}
+
+func TestPasswordResetIntegration(t *testing.T) {
+	logger.LogInfo("TestPasswordResetIntegration", "Test", "Starting integration test for the password reset", "")
+
+	cfg := TestConfig()
+	db := SetupTestDB()
+	notifier := &capturingNotifier{}
+	deps := app.New(db, cfg)
+	deps.Accounts = service.NewAccountService(deps.Identities.WrapRepository(postgres.NewUserRepository(db)), notifier, deps.Jobs)
+	gateway := internal.NewGateway(deps, cfg)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Forgot@Passw0rd"), bcrypt.MinCost)
+	member := &entity.User{Username: "forgetful", Password: string(hash), Email: "forgetful@example.com", Role: "user"}
+	db.Create(member)
+	db.Create(&entity.RefreshToken{Token: "stolen-session", UserID: member.ID})
+
+	send := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		gateway.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Unknown and known emails get the same answer
+	unknown := send("/password/forgot", `{"email":"nobody@example.com"}`)
+	known := send("/password/forgot", `{"email":"forgetful@example.com"}`)
+	assert.Equal(t, http.StatusAccepted, unknown.Code)
+	assert.Equal(t, unknown.Code, known.Code)
+	assert.Equal(t, unknown.Body.String(), known.Body.String())
+
+	var notification *entity.Notification
+	assert.Eventually(t, func() bool {
+		notification = notifier.last("forgetful@example.com")
+		return notification != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	if notification == nil {
+		return
+	}
+	assert.Nil(t, notifier.last("nobody@example.com"))
+	resetToken := strings.TrimSuffix(strings.Fields(strings.SplitAfter(notification.Body, "the token ")[1])[0], ".")
+
+	// Only the token's hash is stored
+	var stored entity.PasswordReset
+	db.Where("user_id = ?", member.ID).Take(&stored)
+	assert.NotEqual(t, resetToken, stored.TokenHash)
+	assert.Len(t, stored.TokenHash, 64)
+
+	w := send("/password/reset", `{"token":"not-the-token","new_password":"Fresh@Passw0rd"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_token")
+	w = send("/password/reset", `{"token":"`+resetToken+`","new_password":"weak"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_password")
+	w = send("/password/reset", `{"token":"`+resetToken+`","new_password":"Forgot@Passw0rd"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "password_reused")
+
+	w = send("/password/reset", `{"token":"`+resetToken+`","new_password":"Fresh@Passw0rd"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var sessions int64
+	db.Model(&entity.RefreshToken{}).Where("user_id = ?", member.ID).Count(&sessions)
+	assert.Equal(t, int64(0), sessions)
+
+	// The token is single use and the new password works
+	w = send("/password/reset", `{"token":"`+resetToken+`","new_password":"Other@Passw0rd"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = send("/login", `{"username":"forgetful","password":"Fresh@Passw0rd"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Expired tokens are refused
+	send("/password/forgot", `{"email":"forgetful@example.com"}`)
+	assert.Eventually(t, func() bool {
+		latest := notifier.last("forgetful@example.com")
+		return latest != nil && latest.Body != notification.Body
+	}, 2*time.Second, 10*time.Millisecond)
+	db.Model(&entity.PasswordReset{}).Where("user_id = ?", member.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	expiredToken := strings.TrimSuffix(strings.Fields(strings.SplitAfter(notifier.last("forgetful@example.com").Body, "the token ")[1])[0], ".")
+	w = send("/password/reset", `{"token":"`+expiredToken+`","new_password":"Other@Passw0rd"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_token")
+
+	var events []entity.AuditEvent
+	db.Where("action = ?", "auth.password_reset").Order("id").Find(&events)
+	assert.Len(t, events, 6)
+	assert.Equal(t, entity.AuditOutcomeSuccess, events[3].Outcome)
+	assert.Equal(t, "forgetful", events[3].ActorUsername)
+}
+
+func TestAdminEmailEditIntegration(t *testing.T) {
+	logger.LogInfo("TestAdminEmailEditIntegration", "Test", "Starting integration test for email changes made by an admin", "")
+
+	cfg := TestConfig()
+	db := SetupTestDB()
+	notifier := &capturingNotifier{}
+	deps := app.New(db, cfg)
+	deps.Accounts = service.NewAccountService(deps.Identities.WrapRepository(postgres.NewUserRepository(db)), notifier, deps.Jobs)
+	gateway := internal.NewGateway(deps, cfg)
+
+	superadmin := &entity.User{Username: "emailroot", Password: "Root@Passw0rd", Email: "emailroot@example.com", Role: "superadmin"}
+	member := &entity.User{Username: "emailmember", Password: "Member@Passw0rd", Email: "member@example.com", Role: "user"}
+	db.Create(superadmin)
+	db.Create(member)
+	db.Create(&entity.PasswordReset{UserID: member.ID, TokenHash: strings.Repeat("a", 64), ExpiresAt: time.Now().Add(time.Hour)})
+
+	send := func(method, path, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		gateway.Router.ServeHTTP(w, req)
+		return w
+	}
+	memberPath := "/users/" + strconv.Itoa(int(member.ID))
+
+	// An edit cannot point the account at an address nobody confirmed
+	w := send("PATCH", memberPath, `{"username":"emailmember","email":"hijack@example.com"}`, GenerateTestTokenForUser(superadmin))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var stored entity.User
+	db.First(&stored, member.ID)
+	assert.Equal(t, "member@example.com", stored.Email)
+	warning := notifier.last("member@example.com")
+	if assert.NotNil(t, warning) {
+		assert.Equal(t, "Email change requested", warning.Subject)
+	}
+	confirmation := notifier.last("hijack@example.com")
+	if !assert.NotNil(t, confirmation) {
+		return
+	}
+	var resets int64
+	db.Model(&entity.PasswordReset{}).Where("user_id = ?", member.ID).Count(&resets)
+	assert.Equal(t, int64(1), resets)
+
+	// Once the user confirms it, reset tokens sent to the old address stop working
+	confirmToken := strings.TrimSuffix(strings.Fields(strings.SplitAfter(confirmation.Body, "the token ")[1])[0], ".")
+	w = send("POST", "/me/email/confirm", `{"token":"`+confirmToken+`"}`, GenerateTestTokenForUser(member))
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&stored, member.ID)
+	assert.Equal(t, "hijack@example.com", stored.Email)
+	db.Model(&entity.PasswordReset{}).Where("user_id = ?", member.ID).Count(&resets)
+	assert.Equal(t, int64(0), resets)
+
+	// A taken address is refused before anything changes
+	w = send("PATCH", memberPath, `{"username":"renamed","email":"emailroot@example.com"}`, GenerateTestTokenForUser(superadmin))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	db.First(&stored, member.ID)
+	assert.Equal(t, "emailmember", stored.Username)
+}
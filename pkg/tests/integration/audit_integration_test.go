@@ -0,0 +1,216 @@
+package integration
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/internal/adapter/repository/postgres"
+	"zeneye-gateway/internal/adapter/service"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func auditRequest(router *gin.Engine, method, path, body, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "203.0.113.7:41000"
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func listAuditEvents(t *testing.T, router *gin.Engine, token, query string) []entity.AuditEvent {
+	w := auditRequest(router, "GET", "/audit/events?"+query, "", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Events []entity.AuditEvent `json:"events"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return body.Events
+}
+
+func TestAuditTrailIntegration(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	db := SetupTestDB()
+	router := internal.SetupRouter(db, TestConfig())
+
+	logger.LogInfo("TestAuditTrailIntegration", "Test", "Starting integration test for the audit trail", "")
+
+	userService := service.NewUserService(postgres.NewUserRepository(db))
+	superadmin := &entity.User{Username: "rootadmin", Password: "Root@Passw0rd", Email: "root@example.com", Role: "superadmin"}
+	auditor := &entity.User{Username: "auditor1", Password: "Audit@Passw0rd", Email: "auditor@example.com", Role: "auditor"}
+	admin := &entity.User{Username: "admin1", Password: "Admin@Passw0rd", Email: "admin1@example.com", Role: "admin"}
+	for _, u := range []*entity.User{superadmin, auditor, admin} {
+		assert.Nil(t, userService.CreateUser(entity.SystemActorID, u))
+	}
+	superadminToken := GenerateTestTokenForUser(superadmin)
+	auditorToken := GenerateTestTokenForUser(auditor)
+
+	// Create, edit and delete a user as the superadmin.
+	w := auditRequest(router, "POST", "/users", `{"username":"target1","password":"Target@Passw0rd","email":"target@example.com","role":"admin"}`, superadminToken)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var target entity.User
+	db.Where("username = ?", "target1").First(&target)
+	targetID := strconv.FormatUint(uint64(target.ID), 10)
+
+	w = auditRequest(router, "PATCH", "/users/"+targetID, `{"username":"target2","email":"target@example.com"}`, superadminToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = auditRequest(router, "DELETE", "/users/"+targetID, "", superadminToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// One failed and one successful login.
+	w = auditRequest(router, "POST", "/login", `{"username":"admin1","password":"wrong"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = auditRequest(router, "POST", "/login", `{"username":"admin1","password":"Admin@Passw0rd"}`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Only auditors and superadmins may read the trail.
+	w = auditRequest(router, "GET", "/audit/events", "", GenerateTestTokenForUser(admin))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	events := listAuditEvents(t, router, auditorToken, "target_id="+targetID)
+	assert.Len(t, events, 3)
+	byAction := map[string]entity.AuditEvent{}
+	for _, event := range events {
+		byAction[event.Action] = event
+	}
+
+	created := byAction["user.create"]
+	assert.Equal(t, entity.AuditOutcomeSuccess, created.Outcome)
+	assert.Equal(t, superadmin.ID, *created.ActorID)
+	assert.Equal(t, "203.0.113.7", created.IP)
+	assert.NotEmpty(t, created.RequestID)
+	assert.Contains(t, created.Changes, "target@example.com")
+
+	var changes map[string]entity.AuditChange
+	json.Unmarshal([]byte(byAction["user.edit"].Changes), &changes)
+	assert.Equal(t, entity.AuditChange{From: "target1", To: "target2"}, changes["username"])
+	assert.NotContains(t, changes, "email")
+	assert.Contains(t, byAction["user.delete"].Changes, "target2")
+
+	failed := listAuditEvents(t, router, auditorToken, "action=auth.login&outcome=failure")
+	assert.Len(t, failed, 1)
+	assert.Equal(t, "admin1", failed[0].ActorUsername)
+	assert.Nil(t, failed[0].ActorID)
+	assert.NotEmpty(t, failed[0].Reason)
+
+	succeeded := listAuditEvents(t, router, auditorToken, "action=auth.login&outcome=success&actor_id="+strconv.FormatUint(uint64(admin.ID), 10))
+	assert.Len(t, succeeded, 1)
+
+	w = auditRequest(router, "GET", "/audit/events?from=yesterday", "", auditorToken)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Exports stream every event, oldest first.
+	w = auditRequest(router, "GET", "/audit/export", "", auditorToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, records, 6)
+	assert.Equal(t, "id", records[0][0])
+	assert.Equal(t, "user.create", records[1][5])
+
+	w = auditRequest(router, "GET", "/audit/export?format=jsonl&action=auth.login", "", auditorToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	lines := 0
+	scanner := bufio.NewScanner(strings.NewReader(w.Body.String()))
+	for scanner.Scan() {
+		var event entity.AuditEvent
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &event))
+		assert.Equal(t, "auth.login", event.Action)
+		lines++
+	}
+	assert.Equal(t, 2, lines)
+
+	// The hash chain holds until a row is modified.
+	w = auditRequest(router, "GET", "/audit/verify", "", superadminToken)
+	var verification entity.AuditVerification
+	json.Unmarshal(w.Body.Bytes(), &verification)
+	assert.True(t, verification.Valid)
+	assert.Equal(t, 5, verification.Checked)
+
+	db.Exec("UPDATE audit_events SET actor_username = ? WHERE id = ?", "someoneelse", created.ID)
+	w = auditRequest(router, "GET", "/audit/verify", "", superadminToken)
+	verification = entity.AuditVerification{}
+	json.Unmarshal(w.Body.Bytes(), &verification)
+	assert.False(t, verification.Valid)
+	assert.Equal(t, created.ID, verification.BrokenAt)
+}
+
+func TestAuditRecordsRequestID(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	db := SetupTestDB()
+	router := internal.SetupRouter(db, TestConfig())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"username":"nobody","password":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(requestid.Header, "audit-req-1")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var event entity.AuditEvent
+	assert.Nil(t, db.First(&event).Error)
+	assert.Equal(t, "audit-req-1", event.RequestID)
+	assert.Equal(t, entity.AuditOutcomeFailure, event.Outcome)
+	assert.Equal(t, event.ComputeHash(), event.Hash)
+	assert.Empty(t, event.PrevHash)
+}
+
+func TestAuditTruncatesOnRuneBoundary(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	db := SetupTestDB()
+	router := internal.SetupRouter(db, TestConfig())
+
+	// The username of a failed login is cut to 32 bytes, which falls inside
+	// the 16th é
+	username := "a" + strings.Repeat("é", 40)
+	w := auditRequest(router, "POST", "/login", `{"username":"`+username+`","password":"x"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var event entity.AuditEvent
+	assert.Nil(t, db.First(&event).Error)
+	assert.True(t, utf8.ValidString(event.ActorUsername))
+	assert.Equal(t, "a"+strings.Repeat("é", 15), event.ActorUsername)
+	assert.Equal(t, event.ComputeHash(), event.Hash)
+}
+
+func TestAuditTruncatesTargetID(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	db := SetupTestDB()
+	router := internal.SetupRouter(db, TestConfig())
+	superadmin := &entity.User{Username: "auditroot", Password: "x", Email: "auditroot@example.com", Role: "superadmin"}
+	db.Create(superadmin)
+
+	// The path is the client's to choose, but the column holds 64 bytes
+	w := auditRequest(router, "DELETE", "/users/"+strings.Repeat("9", 100), "", GenerateTestTokenForUser(superadmin))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var event entity.AuditEvent
+	assert.Nil(t, db.Where("action = ?", "user.delete").First(&event).Error)
+	assert.Equal(t, entity.AuditOutcomeFailure, event.Outcome)
+	assert.Equal(t, strings.Repeat("9", 64), event.TargetID)
+	assert.Equal(t, event.ComputeHash(), event.Hash)
+}
@@ -0,0 +1,116 @@
+package integration
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/internal/app"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const bootstrapBody = `{"username":"firstroot","password":"First@Passw0rd","email":"firstroot@example.com","role":"superadmin"}`
+
+func bootstrapRequest(gateway *internal.Gateway, method, path, token, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Setup-Token", token)
+	}
+	gateway.Router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSuperadminBootstrapIntegration(t *testing.T) {
+	logger.LogInfo("TestSuperadminBootstrapIntegration", "Test", "Starting integration test for the setup token", "")
+
+	cfg := TestConfig()
+	db := SetupTestDB()
+	deps := app.New(db, cfg)
+	var out bytes.Buffer
+	assert.Nil(t, deps.IssueSetupToken(cfg, &out))
+	gateway := internal.NewGateway(deps, cfg)
+	token := strings.TrimSpace(strings.SplitAfter(out.String(), "X-Setup-Token: ")[1])
+	token = strings.Fields(token)[0]
+
+	w := bootstrapRequest(gateway, "GET", "/superadmin/check", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"superadmin_exists":false`)
+
+	// Without the token nobody can claim the role
+	w = bootstrapRequest(gateway, "POST", "/superadmin/create", "", bootstrapBody)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_setup_token")
+	assert.Equal(t, http.StatusForbidden, bootstrapRequest(gateway, "POST", "/superadmin/create", "guess", bootstrapBody).Code)
+
+	// A rejected request does not use the token up
+	weak := strings.Replace(bootstrapBody, "First@Passw0rd", "weak", 1)
+	assert.Equal(t, http.StatusBadRequest, bootstrapRequest(gateway, "POST", "/superadmin/create", token, weak).Code)
+
+	w = bootstrapRequest(gateway, "POST", "/superadmin/create", token, bootstrapBody)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var count int64
+	db.Model(&entity.User{}).Where("role = ?", "superadmin").Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// The used token takes the bootstrap routes with it
+	second := strings.Replace(bootstrapBody, "firstroot", "secondroot", 2)
+	assert.Equal(t, http.StatusNotFound, bootstrapRequest(gateway, "POST", "/superadmin/create", token, second).Code)
+	assert.Equal(t, http.StatusNotFound, bootstrapRequest(gateway, "GET", "/superadmin/check", "", "").Code)
+
+	// Once a superadmin exists, a restart issues no token
+	out.Reset()
+	restarted := app.New(db, cfg)
+	assert.Nil(t, restarted.IssueSetupToken(cfg, &out))
+	assert.Nil(t, restarted.SetupToken)
+	assert.Empty(t, out.String())
+	gateway = internal.NewGateway(restarted, cfg)
+	assert.Equal(t, http.StatusNotFound, bootstrapRequest(gateway, "GET", "/superadmin/check", "", "").Code)
+}
+
+func TestSuperadminBootstrapTokenFile(t *testing.T) {
+	cfg := TestConfig()
+	cfg.Bootstrap.TokenFile = filepath.Join(t.TempDir(), "setup-token")
+	db := SetupTestDB()
+	deps := app.New(db, cfg)
+	var out bytes.Buffer
+	assert.Nil(t, deps.IssueSetupToken(cfg, &out))
+	assert.Empty(t, out.String())
+
+	info, err := os.Stat(cfg.Bootstrap.TokenFile)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	token, _ := os.ReadFile(cfg.Bootstrap.TokenFile)
+
+	gateway := internal.NewGateway(deps, cfg)
+	w := bootstrapRequest(gateway, "POST", "/superadmin/create", strings.TrimSpace(string(token)), bootstrapBody)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	_, err = os.Stat(cfg.Bootstrap.TokenFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSuperadminBootstrapDisabledInProduction(t *testing.T) {
+	cfg := TestConfig()
+	cfg.Env = "production"
+	db := SetupTestDB()
+	deps := app.New(db, cfg)
+	var out bytes.Buffer
+	assert.Nil(t, deps.IssueSetupToken(cfg, &out))
+	assert.Nil(t, deps.SetupToken)
+	assert.Empty(t, out.String())
+
+	gateway := internal.NewGateway(deps, cfg)
+	assert.Equal(t, http.StatusNotFound, bootstrapRequest(gateway, "POST", "/superadmin/create", "", bootstrapBody).Code)
+	assert.Equal(t, http.StatusNotFound, bootstrapRequest(gateway, "GET", "/superadmin/check", "", "").Code)
+}
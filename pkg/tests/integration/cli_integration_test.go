@@ -0,0 +1,112 @@
+package integration
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"zeneye-gateway/internal/cli"
+	"zeneye-gateway/internal/config"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// cliRunner runs commands over db with stdin as their input.
+func cliRunner(db *gorm.DB) func(stdin string, args ...string) (int, string, string) {
+	env := map[string]string{"DATABASE_URL": "file::memory:", "JWT_SECRET": testJWTSecret}
+	return func(stdin string, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := cli.Run(&cli.Env{
+			Stdin:  strings.NewReader(stdin),
+			Stdout: &stdout,
+			Stderr: &stderr,
+			Lookup: func(key string) (string, bool) { v, ok := env[key]; return v, ok },
+			OpenDB: func(*config.Config) (*gorm.DB, error) { return db, nil },
+		}, args)
+		return code, stdout.String(), stderr.String()
+	}
+}
+
+func TestCLIUserCommandsIntegration(t *testing.T) {
+	logger.LogInfo("TestCLIUserCommandsIntegration", "Test", "Starting integration test for the user commands", "")
+
+	db := SetupTestDB()
+	run := cliRunner(db)
+
+	// Users are created through the same validation as POST /users
+	code, _, errOut := run("weak\n", "user", "create", "-username", "cliuser", "-email", "cli@example.com", "-role", "admin")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "password")
+	code, out, _ := run("Cli@Passw0rd\n", "user", "create", "-username", "cliuser", "-email", "cli@example.com", "-role", "admin")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "User cliuser created")
+
+	code, out, _ = run("", "user", "list", "-role", "admin")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "cli@example.com")
+	assert.Contains(t, out, "1 of 1 users")
+
+	// An issued token is accepted until the user's tokens are revoked
+	code, token, _ := run("", "token", "issue", "cliuser")
+	assert.Equal(t, 0, code)
+	code, out, _ = run(token, "token", "inspect", "-")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "accepts it")
+	code, _, _ = run("", "token", "revoke", "cliuser")
+	assert.Equal(t, 0, code)
+	code, _, errOut = run("", "token", "inspect", strings.TrimSpace(token))
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "revoked")
+
+	// A reset password works at once and signs the user out
+	var created entity.User
+	db.Where("username = ?", "cliuser").First(&created)
+	db.Create(&entity.RefreshToken{Token: "cli-session", UserID: created.ID})
+	code, _, _ = run("Cli@Passw0rd2\n", "user", "reset-password", "cliuser")
+	assert.Equal(t, 0, code)
+	db.First(&created, created.ID)
+	assert.Nil(t, bcrypt.CompareHashAndPassword([]byte(created.Password), []byte("Cli@Passw0rd2")))
+	var sessions int64
+	db.Model(&entity.RefreshToken{}).Where("user_id = ?", created.ID).Count(&sessions)
+	assert.Equal(t, int64(0), sessions)
+
+	code, out, _ = run("", "user", "disable", "cliuser")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "disabled")
+	code, _, errOut = run("", "token", "issue", "cliuser")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "disabled")
+	code, _, errOut = run("", "user", "disable", "nobody")
+	assert.Equal(t, 1, code)
+	assert.NotEmpty(t, errOut)
+
+	var actions []string
+	db.Model(&entity.AuditEvent{}).Where("actor_username = ? AND outcome = ?", cli.AuditActor, entity.AuditOutcomeSuccess).
+		Order("id").Pluck("action", &actions)
+	assert.Equal(t, []string{"user.create", "user.token_issue", "user.token_revoke", "user.password_set", "user.disable"}, actions)
+}
+
+func TestCLIBootstrapIntegration(t *testing.T) {
+	logger.LogInfo("TestCLIBootstrapIntegration", "Test", "Starting integration test for the bootstrap command", "")
+
+	db := SetupTestDB()
+	run := cliRunner(db)
+
+	code, _, errOut := run("Root@Passw0rd\n", "bootstrap", "-username", "cliroot")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "-email is required")
+
+	code, out, _ := run("Root@Passw0rd\n", "bootstrap", "-username", "cliroot", "-email", "cliroot@example.com")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "Superadmin cliroot created")
+	code, _, _ = run("Root@Passw0rd\n", "bootstrap", "-username", "cliroot2", "-email", "cliroot2@example.com")
+	assert.Equal(t, 1, code)
+
+	var event entity.AuditEvent
+	db.Where("action = ?", "superadmin.create").First(&event)
+	assert.Equal(t, "cliroot", event.ActorUsername)
+}
@@ -0,0 +1,50 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSPreflightBeforeAuthIntegration(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://admin.example.com")
+	t.Setenv("CORS_ROUTES", `[{"prefix":"/audit","allowed_methods":["GET"]}]`)
+	router := internal.SetupRouter(SetupTestDB(), TestConfig())
+
+	logger.LogInfo("TestCORSPreflightBeforeAuthIntegration", "Test", "Starting integration test for CORS", "")
+
+	// The preflight carries no token and must not reach AuthMiddleware.
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("OPTIONS", "/users/1", nil)
+	req.Header.Set("Origin", "https://admin.example.com")
+	req.Header.Set("Access-Control-Request-Method", "PATCH")
+	req.Header.Set("Access-Control-Request-Headers", "Authorization, Content-Type")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://admin.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "PATCH")
+
+	// The audit override only allows reads.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("OPTIONS", "/audit/events", nil)
+	req.Header.Set("Origin", "https://admin.example.com")
+	req.Header.Set("Access-Control-Request-Method", "DELETE")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// The actual request still needs a token, and the error is readable by the browser.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users/1", nil)
+	req.Header.Set("Origin", "https://admin.example.com")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "https://admin.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Values("Vary"), "Origin")
+}
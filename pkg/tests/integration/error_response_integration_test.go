@@ -0,0 +1,107 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/internal/adapter/repository/postgres"
+	"zeneye-gateway/internal/adapter/service"
+	"zeneye-gateway/internal/domain/entity"
+	errorResponse "zeneye-gateway/pkg/error"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeProblem(t *testing.T, body []byte) errorResponse.Problem {
+	var problem errorResponse.Problem
+	assert.Nil(t, json.Unmarshal(body, &problem))
+	return problem
+}
+
+func fieldCodes(problem errorResponse.Problem) map[string]string {
+	codes := map[string]string{}
+	for _, f := range problem.Errors {
+		codes[f.Field] = f.Code
+	}
+	return codes
+}
+
+func TestProblemResponsesIntegration(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	db := SetupTestDB()
+	router := internal.SetupRouter(db, TestConfig())
+
+	logger.LogInfo("TestProblemResponsesIntegration", "Test", "Starting integration test for problem responses", "")
+
+	admin := &entity.User{Username: "problemadmin", Password: "Admin@Passw0rd", Email: "problem@example.com", Role: "superadmin"}
+	assert.Nil(t, service.NewUserService(postgres.NewUserRepository(db)).CreateUser(entity.SystemActorID, admin))
+	token := GenerateTestTokenForUser(admin)
+
+	// Every invalid field is reported at once, with a stable code per field.
+	w := auditRequest(router, "POST", "/users", `{"username":"x","password":"short","email":"new@example.com","role":"janitor"}`, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, errorResponse.ContentType, w.Header().Get("Content-Type"))
+	problem := decodeProblem(t, w.Body.Bytes())
+	assert.Equal(t, "validation_failed", problem.Code)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, "/users", problem.Instance)
+	assert.Equal(t, w.Header().Get("X-Request-ID"), problem.RequestID)
+	assert.Equal(t, map[string]string{
+		"username": "invalid_username",
+		"password": "invalid_password",
+		"role":     "invalid_role",
+	}, fieldCodes(problem))
+
+	// Binding errors use the JSON field names.
+	w = auditRequest(router, "POST", "/users", `{"username":"someone","email":"not-an-email"}`, token)
+	problem = decodeProblem(t, w.Body.Bytes())
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	codes := fieldCodes(problem)
+	assert.Equal(t, "required", codes["password"])
+	assert.Equal(t, "email", codes["email"])
+
+	w = auditRequest(router, "POST", "/users", `{"username":`, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, errorResponse.CodeMalformedBody, decodeProblem(t, w.Body.Bytes()).Code)
+
+	w = auditRequest(router, "POST", "/users", `{"username":"problemadmin2","password":"Admin@Passw0rd","email":"problem@example.com","role":"admin"}`, token)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "email_taken", decodeProblem(t, w.Body.Bytes()).Code)
+
+	w = auditRequest(router, "GET", "/users/9999", "", token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "user_not_found", decodeProblem(t, w.Body.Bytes()).Code)
+
+	w = auditRequest(router, "GET", "/users/abc", "", token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_id", fieldCodes(decodeProblem(t, w.Body.Bytes()))["id"])
+
+	w = auditRequest(router, "GET", "/users/1", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, errorResponse.CodeUnauthenticated, decodeProblem(t, w.Body.Bytes()).Code)
+
+	w = auditRequest(router, "POST", "/login", `{"username":"problemadmin","password":"wrong"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "invalid_credentials", decodeProblem(t, w.Body.Bytes()).Code)
+
+	w = auditRequest(router, "GET", "/does-not-exist", "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, errorResponse.CodeNotFound, decodeProblem(t, w.Body.Bytes()).Code)
+
+	w = auditRequest(router, "PUT", "/login", "", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, errorResponse.CodeMethodNotAllowed, decodeProblem(t, w.Body.Bytes()).Code)
+
+	// Database failures surface as a generic 500 without the driver message.
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	w = auditRequest(router, "GET", "/users/1", "", token)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	problem = decodeProblem(t, w.Body.Bytes())
+	assert.Equal(t, errorResponse.CodeInternal, problem.Code)
+	assert.NotContains(t, problem.Detail, "sql")
+}
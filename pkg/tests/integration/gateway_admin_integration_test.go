@@ -0,0 +1,166 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/internal/app"
+	"zeneye-gateway/internal/config"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/pkg/loadbalancer"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type policyResponse struct {
+	Version  uint            `json:"version"`
+	DryRun   bool            `json:"dry_run"`
+	Policies config.Policies `json:"policies"`
+	Code     string          `json:"code"`
+}
+
+func TestGatewayAdminPoliciesIntegration(t *testing.T) {
+	logger.LogInfo("TestGatewayAdminPoliciesIntegration", "Test", "Starting integration test for the gateway admin API", "")
+
+	blue, green := upstreamNamed("blue", nil, nil), upstreamNamed("green", nil, nil)
+	defer blue.Close()
+	defer green.Close()
+
+	cfg := TestConfig()
+	cfg.Routes = []loadbalancer.Route{{Prefix: "agent", URL: blue.URL}}
+	db := SetupTestDB()
+	gateway := internal.NewGateway(app.New(db, cfg), cfg)
+
+	superadmin := &entity.User{Username: "policyroot", Password: "Root@Passw0rd", Email: "policyroot@example.com", Role: "superadmin"}
+	admin := &entity.User{Username: "policyadmin", Password: "Admin@Passw0rd", Email: "policyadmin@example.com", Role: "admin"}
+	db.Create(superadmin)
+	db.Create(admin)
+	rootToken, adminToken := GenerateTestTokenForUser(superadmin), GenerateTestTokenForUser(admin)
+
+	send := func(method, path, body, token string) (*httptest.ResponseRecorder, policyResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		gateway.Router.ServeHTTP(w, req)
+		var resp policyResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	// Only superadmins may use the API
+	w, _ := send("GET", "/gateway-admin/policies", "", adminToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Before any change the policies come from the configuration
+	w, resp := send("GET", "/gateway-admin/policies", "", rootToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(0), resp.Version)
+	assert.Equal(t, blue.URL, resp.Policies.Routes[0].URL)
+
+	// A dry run validates without storing or applying anything
+	w, resp = send("POST", "/gateway-admin/pools?dry_run=true", `{"name":"search","targets":["`+green.URL+`"]}`, rootToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, resp.DryRun)
+	assert.Len(t, resp.Policies.Pools, 1)
+	_, current := gateway.Policies()
+	assert.Equal(t, uint(0), current)
+
+	// Invalid changes are rejected with every problem listed
+	w, resp = send("POST", "/gateway-admin/routes", `{"prefix":"search","pool":"missing"}`, rootToken)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "validation_failed", resp.Code)
+	assert.Contains(t, w.Body.String(), `unknown pool`)
+
+	// Each change is stored as a new version and applied live
+	w, resp = send("POST", "/gateway-admin/pools", `{"name":"search","targets":["`+green.URL+`","`+blue.URL+`"]}`, rootToken)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, uint(1), resp.Version)
+	w, resp = send("POST", "/gateway-admin/routes", `{"prefix":"search","pool":"search"}`, rootToken)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, uint(2), resp.Version)
+	w, _ = send("POST", "/gateway-admin/routes", `{"prefix":"search","url":"`+blue.URL+`"}`, rootToken)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w, _ = send("GET", "/search/q", "", adminToken)
+	assert.Equal(t, "green /q", w.Body.String())
+	w, _ = send("GET", "/search/q", "", adminToken)
+	assert.Equal(t, "blue /q", w.Body.String())
+
+	w, resp = send("PUT", "/gateway-admin/routes/agent", `{"url":"`+green.URL+`"}`, rootToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(3), resp.Version)
+	w, _ = send("GET", "/agent/status", "", adminToken)
+	assert.Equal(t, "green /status", w.Body.String())
+
+	// Rate limits and CORS rules are keyed by full path prefixes
+	w, _ = send("POST", "/gateway-admin/rate-limits", `{"prefix":"/agent","requests_per_second":1,"burst":1}`, rootToken)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w, _ = send("GET", "/agent/status", "", adminToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w, _ = send("GET", "/agent/status", "", adminToken)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	w, resp = send("DELETE", "/gateway-admin/rate-limits/agent", "", rootToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(5), resp.Version)
+	w, resp = send("DELETE", "/gateway-admin/rate-limits/agent", "", rootToken)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "policy_not_found", resp.Code)
+
+	w, resp = send("POST", "/gateway-admin/cors-rules", `{"prefix":"/public","allowed_origins":["*"]}`, rootToken)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/public", resp.Policies.CORS.Routes[0].Prefix)
+
+	// Versions are listed newest first and keep their documents
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/gateway-admin/versions?limit=2", nil)
+	req.Header.Set("Authorization", rootToken)
+	gateway.Router.ServeHTTP(w, req)
+	var list struct {
+		Versions []entity.GatewayPolicyVersion `json:"versions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Len(t, list.Versions, 2)
+	assert.Equal(t, uint(6), list.Versions[0].Version)
+	assert.Equal(t, "gateway_policy.create /public", list.Versions[0].Change)
+	assert.Equal(t, "policyroot", list.Versions[0].CreatedBy)
+
+	w, resp = send("GET", "/gateway-admin/versions/2", "", rootToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, blue.URL, resp.Policies.Routes[0].URL)
+	w, _ = send("GET", "/gateway-admin/versions/99", "", rootToken)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Rolling back stores the old document as a new version
+	w, resp = send("POST", "/gateway-admin/versions/2/rollback", "", rootToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(7), resp.Version)
+	w, _ = send("GET", "/agent/status", "", adminToken)
+	assert.Equal(t, "blue /status", w.Body.String())
+	var rolledBack entity.GatewayPolicyVersion
+	db.Where("version = ?", 7).Take(&rolledBack)
+	assert.Equal(t, uint(2), *rolledBack.RolledBackFrom)
+
+	// A file reload keeps the stored policies in force
+	assert.Nil(t, gateway.Reload(TestConfig()))
+	w, _ = send("GET", "/search/q", "", adminToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// So does a restart over the same database
+	restarted := internal.NewGateway(app.New(db, cfg), cfg)
+	policies, version := restarted.Policies()
+	assert.Equal(t, uint(7), version)
+	assert.Len(t, policies.Pools, 1)
+
+	// Every applied change is audited
+	var events []entity.AuditEvent
+	db.Where("action LIKE ?", "gateway_policy.%").Order("id").Find(&events)
+	assert.Len(t, events, 7)
+	assert.Equal(t, "gateway_policy.rollback", events[6].Action)
+	assert.Equal(t, "2", events[6].TargetID)
+}
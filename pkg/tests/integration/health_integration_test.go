@@ -0,0 +1,70 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/pkg/health"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func getHealth(t *testing.T, router *gin.Engine, path string) (int, health.Report) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, health.ContentType, w.Header().Get("Content-Type"))
+	var report health.Report
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return w.Code, report
+}
+
+func TestHealthEndpointsIntegration(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+	t.Setenv("HEALTH_CHECK_UPSTREAMS", "true")
+	t.Setenv("HEALTH_CACHE_TTL", "0s")
+	t.Setenv("AGENT_SERVICE_URL", upstream.URL)
+
+	db := SetupTestDB()
+	router := internal.SetupRouter(db, TestConfig())
+
+	logger.LogInfo("TestHealthEndpointsIntegration", "Test", "Starting integration test for health endpoints", "")
+
+	code, report := getHealth(t, router, "/livez")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusPass, report.Status)
+
+	// A broken upstream degrades the detailed report but not readiness.
+	code, report = getHealth(t, router, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusPass, report.Status)
+	assert.Contains(t, report.Checks, "postgres")
+	assert.NotContains(t, report.Checks, "upstream:agent")
+
+	code, report = getHealth(t, router, "/health")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusWarn, report.Status)
+	assert.Equal(t, "datastore", report.Checks["postgres"][0].ComponentType)
+	assert.Contains(t, report.Checks["upstream:agent"][0].Output, "503")
+
+	// Losing the database fails readiness, while liveness stays up.
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	code, report = getHealth(t, router, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.NotEmpty(t, report.Checks["postgres"][0].Output)
+
+	code, _ = getHealth(t, router, "/livez")
+	assert.Equal(t, http.StatusOK, code)
+}
@@ -0,0 +1,51 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogLevelEndpoint(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+	defer logger.SetLevel(logger.Level())
+
+	db := SetupTestDB()
+	router := internal.SetupRouter(db, TestConfig())
+
+	logger.LogInfo("TestLogLevelEndpoint", "Test", "Starting integration test for the log level endpoint", "")
+
+	superadmin := &entity.User{Username: "superadmin", Password: "x", Email: "superadmin@example.com", Role: "superadmin"}
+	admin := &entity.User{Username: "admin", Password: "x", Email: "admin@example.com", Role: "admin"}
+	db.Create(superadmin)
+	db.Create(admin)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/gateway-admin/log-level", strings.NewReader(`{"level":"debug"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", GenerateTestTokenForUser(admin))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/gateway-admin/log-level", strings.NewReader(`{"level":"debug"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", GenerateTestTokenForUser(superadmin))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "debug", logger.Level())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/gateway-admin/log-level", nil)
+	req.Header.Set("Authorization", GenerateTestTokenForUser(superadmin))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"level":"debug"`)
+}
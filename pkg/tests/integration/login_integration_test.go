@@ -21,7 +21,7 @@ func TestLogin(t *testing.T) {
 	logger.LogInfo("TestLogin", "TestLogin", "Starting TestLogin", "")
 
 	db := SetupTestDB()
-	router := internal.SetupRouter(db)
+	router := internal.SetupRouter(db, TestConfig())
 
 	// Create a user with hashed password
 	password := "password@123"
@@ -61,7 +61,7 @@ func TestLogin(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code, "Expected status code 200, got %d", w.Code)
 	assert.NotEmpty(t, w.Header().Get("Authorization"), "Authorization header should not be empty")
-	assert.NotEmpty(t, w.Header().Get("Refresh-Token"), "Refresh-Token header should not be empty")
+	assert.NotEmpty(t, w.Header().Get("X-Refresh-Token"), "X-Refresh-Token header should not be empty")
 
 	logger.LogInfo("TestLogin", "TestLogin", "TestLogin completed successfully")
 }
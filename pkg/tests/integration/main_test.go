@@ -0,0 +1,17 @@
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"zeneye-gateway/pkg/jwt"
+)
+
+func TestMain(m *testing.M) {
+	opts := jwt.DefaultOptions()
+	opts.Secret = testJWTSecret
+	if err := jwt.Configure(opts); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
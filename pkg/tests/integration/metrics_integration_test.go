@@ -0,0 +1,47 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	db := SetupTestDB()
+	router := internal.SetupRouter(db, TestConfig())
+
+	logger.LogInfo("TestMetricsEndpoint", "Test", "Starting integration test for the metrics endpoint", "")
+
+	for _, path := range []string{"/health", "/users/42", "/no/such/route/123", "/waf/rules/7"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	router.ServeHTTP(w, req)
+
+	logger.LogInfo("TestMetricsEndpoint", "Test", "Response", w.Code)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `gateway_http_requests_total{method="GET",route="/health",status="200"}`)
+	assert.Contains(t, body, `gateway_http_requests_total{method="GET",route="/users/:id",status="401"}`)
+	assert.Contains(t, body, `gateway_http_requests_total{method="GET",route="unmatched",status="404"}`)
+	assert.NotContains(t, body, "/no/such/route/123")
+	// Proxied paths are labelled with the route table prefix they matched
+	assert.Contains(t, body, `gateway_http_requests_total{method="GET",route="/waf",status="401"}`)
+	assert.NotContains(t, body, "/waf/rules/7")
+	assert.Contains(t, body, `gateway_auth_failures_total{reason="missing_header"}`)
+	assert.Contains(t, body, `gateway_cache_hits_total{cache="identity"}`)
+	assert.Contains(t, body, `go_goroutines`)
+	assert.Contains(t, body, `go_sql_open_connections{db_name="gateway"}`)
+}
@@ -0,0 +1,153 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/internal/app"
+	"zeneye-gateway/internal/domain/domainerr"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMigrator moves a version over three migrations the way golang-migrate
+// does, without a database.
+type fakeMigrator struct {
+	version uint
+	dirty   bool
+	closed  int
+}
+
+func (f *fakeMigrator) Up() error { return f.Goto(3) }
+
+func (f *fakeMigrator) Down(steps int) error {
+	if steps <= 0 || uint(steps) > f.version {
+		return domainerr.Validation(domainerr.FieldError{Field: "steps", Code: domainerr.CodeInvalidSteps, Message: "too many steps"})
+	}
+	return f.Goto(f.version - uint(steps))
+}
+
+func (f *fakeMigrator) Goto(version uint) error {
+	if version > 3 {
+		return domainerr.ErrMigrationNotFound
+	}
+	if f.dirty {
+		return domainerr.ErrSchemaDirty
+	}
+	f.version = version
+	return nil
+}
+
+func (f *fakeMigrator) Force(version uint) error {
+	if version > 3 {
+		return domainerr.ErrMigrationNotFound
+	}
+	f.version, f.dirty = version, false
+	return nil
+}
+
+func (f *fakeMigrator) Version() (uint, bool, error) { return f.version, f.dirty, nil }
+
+func (f *fakeMigrator) Status() ([]entity.MigrationStatus, error) {
+	var statuses []entity.MigrationStatus
+	for version, name := range []string{"create_users_table", "create_sessions_table", "add_role_to_users"} {
+		statuses = append(statuses, entity.MigrationStatus{Version: uint(version + 1), Name: name, Applied: uint(version+1) <= f.version})
+	}
+	return statuses, nil
+}
+
+func (f *fakeMigrator) Close() error { f.closed++; return nil }
+
+type migrationResponse struct {
+	Version    uint                     `json:"version"`
+	Dirty      bool                     `json:"dirty"`
+	Migrations []entity.MigrationStatus `json:"migrations"`
+	Code       string                   `json:"code"`
+}
+
+func TestGatewayAdminMigrationsIntegration(t *testing.T) {
+	logger.LogInfo("TestGatewayAdminMigrationsIntegration", "Test", "Starting integration test for the migration admin API", "")
+
+	cfg := TestConfig()
+	db := SetupTestDB()
+	migrator := &fakeMigrator{version: 3}
+	deps := app.New(db, cfg)
+	deps.Migrations = func() (port.Migrator, error) { return migrator, nil }
+	gateway := internal.NewGateway(deps, cfg)
+
+	superadmin := &entity.User{Username: "schemaroot", Password: "Root@Passw0rd", Email: "schemaroot@example.com", Role: "superadmin"}
+	admin := &entity.User{Username: "schemaadmin", Password: "Admin@Passw0rd", Email: "schemaadmin@example.com", Role: "admin"}
+	db.Create(superadmin)
+	db.Create(admin)
+	rootToken, adminToken := GenerateTestTokenForUser(superadmin), GenerateTestTokenForUser(admin)
+
+	send := func(method, path, body, token string) (*httptest.ResponseRecorder, migrationResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		gateway.Router.ServeHTTP(w, req)
+		var resp migrationResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	w, _ := send("GET", "/gateway-admin/migrations", "", adminToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w, resp := send("GET", "/gateway-admin/migrations", "", rootToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(3), resp.Version)
+	assert.Len(t, resp.Migrations, 3)
+	assert.True(t, resp.Migrations[2].Applied)
+
+	w, _ = send("POST", "/gateway-admin/migrations/down", `{"steps":0}`, rootToken)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, resp = send("POST", "/gateway-admin/migrations/down", `{"steps":2}`, rootToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(1), resp.Version)
+	assert.False(t, resp.Migrations[1].Applied)
+
+	w, resp = send("POST", "/gateway-admin/migrations/goto", `{"version":7}`, rootToken)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, domainerr.CodeMigrationNotFound, resp.Code)
+	w, resp = send("POST", "/gateway-admin/migrations/goto", `{"version":0}`, rootToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(0), resp.Version)
+
+	// A dirty schema refuses every move until its version is forced
+	migrator.version, migrator.dirty = 2, true
+	w, resp = send("POST", "/gateway-admin/migrations/up", "", rootToken)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, domainerr.CodeSchemaDirty, resp.Code)
+	w, resp = send("POST", "/gateway-admin/migrations/force", `{"version":1}`, rootToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, resp.Dirty)
+	w, resp = send("POST", "/gateway-admin/migrations/up", "", rootToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(3), resp.Version)
+	assert.Equal(t, 7, migrator.closed)
+
+	var events []entity.AuditEvent
+	db.Where("target_type = ?", "schema").Order("id").Find(&events)
+	var trail []string
+	for _, event := range events {
+		trail = append(trail, event.Action+" "+event.TargetID+" "+event.Outcome)
+	}
+	assert.Equal(t, []string{
+		"schema.migrate down 2 success",
+		"schema.migrate goto 7 failure",
+		"schema.migrate goto 0 success",
+		"schema.migrate up failure",
+		"schema.force 1 success",
+		"schema.migrate up success",
+	}, trail)
+	assert.Contains(t, events[0].Changes, `"version":{"from":3,"to":1}`)
+}
@@ -0,0 +1,98 @@
+//go:build postgres
+
+package integration
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"zeneye-gateway/internal/adapter/repository/postgres"
+	"zeneye-gateway/internal/domain/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormpostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// TestMigrationsOnPostgres applies the migrations to a real database and
+// compares the columns Postgres reports with the entities. Run it with
+//
+//	TEST_DATABASE_URL=postgres://... go test -tags postgres ./pkg/tests/integration/ -run TestMigrationsOnPostgres
+//
+// against a throwaway database: the test reverts every migration when done.
+func TestMigrationsOnPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	migrator, err := postgres.NewMigrator(dsn)
+	require.NoError(t, err)
+	defer migrator.Close()
+	require.NoError(t, migrator.Up())
+	t.Cleanup(func() { assert.NoError(t, migrator.Goto(0)) })
+
+	db, err := gorm.Open(gormpostgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+
+	type column struct {
+		Name      string `gorm:"column:column_name"`
+		Type      string `gorm:"column:data_type"`
+		Nullable  string `gorm:"column:is_nullable"`
+		MaxLength *int   `gorm:"column:character_maximum_length"`
+	}
+	models := []interface{}{
+		&entity.User{}, &entity.Session{}, &entity.RefreshToken{}, &entity.AuditEvent{}, &entity.GatewayPolicyVersion{},
+		&entity.PasswordHistory{}, &entity.EmailChange{}, &entity.PasswordReset{}, &entity.SuperadminTransfer{},
+	}
+	for _, model := range models {
+		s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+		require.NoError(t, err)
+
+		var rows []column
+		require.NoError(t, db.Raw(`SELECT column_name, data_type, is_nullable, character_maximum_length
+			FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ?`, s.Table).Scan(&rows).Error)
+		columns := map[string]column{}
+		var names, fields []string
+		for _, row := range rows {
+			columns[row.Name] = row
+			names = append(names, row.Name)
+		}
+		for _, field := range s.Fields {
+			if field.DBName != "" {
+				fields = append(fields, field.DBName)
+			}
+		}
+		sort.Strings(names)
+		sort.Strings(fields)
+		assert.Equal(t, fields, names, "columns of %s", s.Table)
+
+		for _, field := range s.Fields {
+			row, ok := columns[field.DBName]
+			if !ok {
+				continue
+			}
+			name := s.Table + "." + field.DBName
+			if !field.PrimaryKey {
+				assert.Equal(t, field.NotNull, row.Nullable == "NO", "NOT NULL of %s", name)
+			}
+			if field.DataType == schema.String && field.Size > 0 {
+				assert.Equal(t, "character varying", row.Type, "type of %s", name)
+				assert.Equal(t, field.Size, columnLength(row.MaxLength), "length of %s", name)
+			}
+		}
+	}
+}
+
+// columnLength is the length Postgres reports for a column, zero when it has
+// none.
+func columnLength(n *int) int {
+	if n == nil {
+		return 0
+	}
+	return *n
+}
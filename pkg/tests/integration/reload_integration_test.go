@@ -0,0 +1,99 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/internal/app"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/pkg/loadbalancer"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// upstreamNamed answers with its name and the path it received. Requests to
+// /slow wait for release after signalling started.
+func upstreamNamed(name string, started, release chan struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+		w.Write([]byte(name + " " + r.URL.Path))
+	}))
+}
+
+func TestReloadSwapsRoutesAndPolicies(t *testing.T) {
+	logger.LogInfo("TestReloadSwapsRoutesAndPolicies", "Test", "Starting integration test for configuration reload", "")
+
+	started, release := make(chan struct{}), make(chan struct{})
+	blue, green := upstreamNamed("blue", started, release), upstreamNamed("green", nil, nil)
+	defer blue.Close()
+	defer green.Close()
+
+	cfg := TestConfig()
+	cfg.Routes = []loadbalancer.Route{{Prefix: "agent", URL: blue.URL}}
+	db := SetupTestDB()
+	gateway := internal.NewGateway(app.New(db, cfg), cfg)
+
+	user := &entity.User{Username: "reloaduser", Password: "ReloadPassword@123", Email: "reload@example.com", Role: "admin"}
+	db.Create(user)
+	token := GenerateTestToken(user.ID)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", token)
+		gateway.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Paths under a configured prefix are proxied without the prefix
+	w := get("/agent/status")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "blue /status", w.Body.String())
+	assert.Equal(t, http.StatusNotFound, get("/agents").Code)
+
+	// A request in flight during the reload finishes on the old upstream
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- get("/agent/slow") }()
+	<-started
+
+	next := TestConfig()
+	next.Routes = []loadbalancer.Route{
+		{Prefix: "agent", URL: green.URL},
+		{Prefix: "waf", URL: blue.URL, Roles: []string{"superadmin"}},
+	}
+	next.RateLimit.RequestsPerSecond = 1
+	next.RateLimit.Burst = 2
+	assert.Nil(t, gateway.Reload(next))
+
+	close(release)
+	w = <-done
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "blue /slow", w.Body.String())
+	w = get("/agent/status")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "green /status", w.Body.String())
+
+	// Route roles are enforced after authentication
+	w = get("/waf/rules")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "insufficient role")
+
+	// The new rate limit applies: the burst of two was spent above
+	assert.Equal(t, http.StatusTooManyRequests, get("/agent/status").Code)
+
+	// An invalid configuration is rejected and the running one kept
+	broken := TestConfig()
+	broken.Routes = []loadbalancer.Route{{Prefix: "agent", URL: "not a url"}}
+	broken.CORS.AllowedOrigins = []string{"*"}
+	broken.CORS.AllowCredentials = true
+	err := gateway.Reload(broken)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "routes:")
+	assert.Contains(t, err.Error(), "cors:")
+	assert.Equal(t, green.URL, gateway.Snapshots.Current().Balancer.Upstreams()["agent"])
+}
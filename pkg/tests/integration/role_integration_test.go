@@ -0,0 +1,179 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// roleTestRouter serves the gateway over a fresh database and sends requests
+// with the given bearer token.
+func roleTestRouter(t *testing.T) (*gorm.DB, func(method, path, token, body string) *httptest.ResponseRecorder) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(upstream.Close)
+	t.Setenv("ADMIN_MANAGEMENT_SERVICE_URL", upstream.URL)
+
+	db := SetupTestDB()
+	router := internal.SetupRouter(db, TestConfig())
+	return db, func(method, path, token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+}
+
+func TestChangeUserRoleIntegration(t *testing.T) {
+	logger.LogInfo("TestChangeUserRoleIntegration", "Test", "Starting integration test for role changes", "")
+
+	db, send := roleTestRouter(t)
+	superadmin := &entity.User{Username: "roleroot", Password: "x", Email: "roleroot@example.com", Role: "superadmin"}
+	admin := &entity.User{Username: "roleadmin", Password: "x", Email: "roleadmin@example.com", Role: "admin"}
+	member := &entity.User{Username: "rolemember", Password: "x", Email: "rolemember@example.com", Role: "department_admin"}
+	for _, user := range []*entity.User{superadmin, admin, member} {
+		db.Create(user)
+	}
+	db.Create(&entity.RefreshToken{Token: "member-session", UserID: member.ID})
+	rootToken := GenerateTestTokenForUser(superadmin)
+	adminToken := GenerateTestTokenForUser(admin)
+	memberToken := GenerateTestTokenForUser(member)
+	path := func(user *entity.User) string { return "/users/" + strconv.Itoa(int(user.ID)) + "/role" }
+
+	// Roles are only granted below the caller's own, to users below it
+	w := send("PUT", path(member), adminToken, `{"role":"admin"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "role_escalation")
+	assert.Equal(t, http.StatusForbidden, send("PUT", path(admin), adminToken, `{"role":"auditor"}`).Code)
+	assert.Equal(t, http.StatusForbidden, send("PUT", path(superadmin), adminToken, `{"role":"auditor"}`).Code)
+	assert.Equal(t, http.StatusForbidden, send("PUT", path(admin), rootToken, `{"role":"superadmin"}`).Code)
+	assert.Equal(t, http.StatusForbidden, send("PUT", path(admin), memberToken, `{"role":"auditor"}`).Code)
+	w = send("PUT", path(admin), rootToken, `{"role":"janitor"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_role")
+
+	// A change revokes the user's tokens right away
+	assert.Equal(t, http.StatusOK, send("GET", "/me", memberToken, "").Code)
+	w = send("PUT", path(member), adminToken, `{"role":"auditor"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	assert.Equal(t, "auditor", body["role"])
+	w = send("GET", "/me", memberToken, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "token_revoked")
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/admin-management/get-all", memberToken, "").Code)
+	var sessions int64
+	db.Model(&entity.RefreshToken{}).Where("user_id = ?", member.ID).Count(&sessions)
+	assert.Equal(t, int64(0), sessions)
+
+	var refreshed entity.User
+	db.First(&refreshed, member.ID)
+	assert.Equal(t, http.StatusOK, send("GET", "/me", GenerateTestTokenForUser(&refreshed), "").Code)
+
+	var events []entity.AuditEvent
+	db.Where("action = ? AND outcome = ?", "user.role_change", entity.AuditOutcomeSuccess).Find(&events)
+	if assert.Len(t, events, 1) {
+		assert.Contains(t, events[0].Changes, "auditor")
+		assert.Equal(t, "roleadmin", events[0].ActorUsername)
+	}
+}
+
+func TestSuperadminTransferIntegration(t *testing.T) {
+	logger.LogInfo("TestSuperadminTransferIntegration", "Test", "Starting integration test for the superadmin transfer", "")
+
+	db, send := roleTestRouter(t)
+	superadmin := &entity.User{Username: "oldroot", Password: "x", Email: "oldroot@example.com", Role: "superadmin"}
+	heir := &entity.User{Username: "heir", Password: "x", Email: "heir@example.com", Role: "admin"}
+	bystander := &entity.User{Username: "bystander", Password: "x", Email: "bystander@example.com", Role: "auditor"}
+	for _, user := range []*entity.User{superadmin, heir, bystander} {
+		db.Create(user)
+	}
+	rootToken := GenerateTestTokenForUser(superadmin)
+	heirToken := GenerateTestTokenForUser(heir)
+	heirID := strconv.Itoa(int(heir.ID))
+
+	// Only the superadmin offers the role, and only to another user
+	assert.Equal(t, http.StatusForbidden, send("POST", "/superadmin/transfer", heirToken, `{"user_id":`+heirID+`}`).Code)
+	w := send("POST", "/superadmin/transfer", rootToken, `{"user_id":`+strconv.Itoa(int(superadmin.ID))+`}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_transfer")
+	assert.Equal(t, http.StatusAccepted, send("POST", "/superadmin/transfer", rootToken, `{"user_id":`+heirID+`}`).Code)
+
+	// Only the two parties see it, and only the recipient accepts it
+	assert.Equal(t, http.StatusOK, send("GET", "/superadmin/transfer", heirToken, "").Code)
+	w = send("GET", "/superadmin/transfer", GenerateTestTokenForUser(bystander), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "transfer_not_found")
+	assert.Equal(t, http.StatusNotFound, send("POST", "/superadmin/transfer/accept", rootToken, "").Code)
+
+	w = send("POST", "/superadmin/transfer/accept", heirToken, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	newToken := w.Header().Get("Authorization")
+	assert.NotEmpty(t, newToken)
+
+	var roles []string
+	db.Model(&entity.User{}).Where("id IN ?", []uint{superadmin.ID, heir.ID}).Order("id").Pluck("role", &roles)
+	assert.Equal(t, []string{"admin", "superadmin"}, roles)
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/me", rootToken, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/me", heirToken, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/superadmin/transfer", newToken, "").Code)
+
+	// The recipient may decline an offer
+	assert.Equal(t, http.StatusAccepted, send("POST", "/superadmin/transfer", newToken, `{"user_id":`+strconv.Itoa(int(bystander.ID))+`}`).Code)
+	assert.Equal(t, http.StatusOK, send("DELETE", "/superadmin/transfer", GenerateTestTokenForUser(bystander), "").Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/superadmin/transfer", newToken, "").Code)
+
+	var actions []string
+	db.Model(&entity.AuditEvent{}).Where("action LIKE ? AND outcome = ?", "superadmin.transfer%", entity.AuditOutcomeSuccess).
+		Order("id").Pluck("action", &actions)
+	assert.Equal(t, []string{"superadmin.transfer_request", "superadmin.transfer_accept", "superadmin.transfer_request", "superadmin.transfer_cancel"}, actions)
+}
+
+func TestUserManagementRankIntegration(t *testing.T) {
+	logger.LogInfo("TestUserManagementRankIntegration", "Test", "Starting integration test for user management by rank", "")
+
+	db, send := roleTestRouter(t)
+	superadmin := &entity.User{Username: "rankroot", Password: "x", Email: "rankroot@example.com", Role: "superadmin"}
+	auditor := &entity.User{Username: "rankauditor", Password: "x", Email: "rankauditor@example.com", Role: "auditor"}
+	plain := &entity.User{Username: "rankplain", Password: "x", Email: "rankplain@example.com", Role: "user"}
+	for _, user := range []*entity.User{superadmin, auditor, plain} {
+		db.Create(user)
+	}
+	auditorToken := GenerateTestTokenForUser(auditor)
+
+	// Users are only created, edited or deleted below the caller's own role
+	w := send("POST", "/users", auditorToken, `{"username":"rankadmin","password":"Test@Passw0rd","email":"rankadmin@example.com","role":"admin"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "role_escalation")
+	var created int64
+	db.Model(&entity.User{}).Where("username = ?", "rankadmin").Count(&created)
+	assert.Equal(t, int64(0), created)
+
+	w = send("PATCH", "/users/"+strconv.Itoa(int(superadmin.ID)), auditorToken, `{"username":"hijacked","email":"rankroot@example.com"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "role_escalation")
+	var unchanged entity.User
+	db.First(&unchanged, superadmin.ID)
+	assert.Equal(t, "rankroot", unchanged.Username)
+
+	// Plain accounts have no part in user management
+	w = send("GET", "/users", GenerateTestTokenForUser(plain), "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "insufficient role")
+}
@@ -1,6 +1,9 @@
 package integration
 
 import (
+	"os"
+
+	"zeneye-gateway/internal/config"
 	"zeneye-gateway/internal/domain/entity"
 	"zeneye-gateway/pkg/jwt"
 	"zeneye-gateway/pkg/logger"
@@ -9,6 +12,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// testJWTSecret signs every token issued by the integration tests.
+const testJWTSecret = "integration-test-secret"
+
 func SetupTestDB() *gorm.DB {
 	logger.LogInfo("SetupTestDB", "SetupTestDB", "Setting up the test database", "")
 
@@ -20,7 +26,8 @@ func SetupTestDB() *gorm.DB {
 
 	logger.LogInfo("SetupTestDB", "OpenDatabase", "Database connection established", "")
 
-	err = db.AutoMigrate(&entity.User{}, &entity.RefreshToken{})
+	err = db.AutoMigrate(&entity.User{}, &entity.RefreshToken{}, &entity.AuditEvent{}, &entity.GatewayPolicyVersion{},
+		&entity.PasswordHistory{}, &entity.EmailChange{}, &entity.PasswordReset{}, &entity.SuperadminTransfer{})
 	if err != nil {
 		logger.LogFatal("SetupTestDB", "AutoMigrate", "", err)
 		panic("failed to migrate database schema")
@@ -31,10 +38,23 @@ func SetupTestDB() *gorm.DB {
 	return db
 }
 
+// TestConfig returns the defaults with the test secret and the environment
+// applied, so tests can adjust the gateway with t.Setenv.
+func TestConfig() *config.Config {
+	cfg := config.Default()
+	cfg.Database.URL = "file::memory:"
+	cfg.Auth.JWTSecret = testJWTSecret
+	if err := cfg.LoadEnv(os.LookupEnv); err != nil {
+		logger.LogFatal("TestConfig", "LoadEnv", "", err)
+		panic("invalid test configuration")
+	}
+	return cfg
+}
+
 func GenerateTestToken(userID uint) string {
 	logger.LogInfo("GenerateTestToken", "GenerateTestToken", "Generating test token", userID)
 
-	token, err := jwt.GenerateToken(userID)
+	token, err := jwt.GenerateToken(userID, "", "", "")
 	if err != nil {
 		logger.LogFatal("GenerateTestToken", "GenerateToken", userID, err)
 		panic("failed to generate test token")
@@ -44,3 +64,13 @@ func GenerateTestToken(userID uint) string {
 
 	return "Bearer " + token
 }
+
+// GenerateTestTokenForUser issues a token carrying the user's role and identity.
+func GenerateTestTokenForUser(user *entity.User) string {
+	token, err := jwt.GenerateVersionedToken(user.ID, user.Username, user.Role, user.UserUUID, user.TokenVersion)
+	if err != nil {
+		logger.LogFatal("GenerateTestTokenForUser", "GenerateToken", user.ID, err)
+		panic("failed to generate test token")
+	}
+	return "Bearer " + token
+}
@@ -0,0 +1,73 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingPropagatesToUpstream(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	logger.LogInfo("TestTracingPropagatesToUpstream", "Test", "Starting integration test for trace propagation", "")
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer provider.Shutdown(context.Background())
+
+	var upstreamTraceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("ADMIN_MANAGEMENT_SERVICE_URL", upstream.URL)
+
+	db := SetupTestDB()
+	router := internal.SetupRouter(db, TestConfig())
+
+	user := &entity.User{
+		Username: "traceuser",
+		Password: "TracePassword@123",
+		Email:    "trace@example.com",
+		Role:     "admin",
+	}
+	db.Create(user)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin-management/get-all", nil)
+	req.Header.Set("Authorization", GenerateTestToken(user.ID))
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(w, req)
+
+	logger.LogInfo("TestTracingPropagatesToUpstream", "Test", "Response", w.Code)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(upstreamTraceparent, "00-"+traceID+"-"), "upstream traceparent %q", upstreamTraceparent)
+	assert.NotContains(t, upstreamTraceparent, "00f067aa0ba902b7", "upstream should see the gateway span as parent")
+
+	names := map[string]bool{}
+	for _, span := range exporter.GetSpans() {
+		assert.Equal(t, traceID, span.SpanContext.TraceID().String())
+		names[span.Name] = true
+	}
+	for _, name := range []string{"GET /admin-management/get-all", "ratelimit.check", "auth.validate_token", "identity.resolve", "db.get_user", "proxy.upstream"} {
+		assert.True(t, names[name], "missing span %s", name)
+	}
+}
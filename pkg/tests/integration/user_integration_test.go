@@ -11,6 +11,7 @@ import (
 	"testing"
 	internal "zeneye-gateway/internal/adapter/http"
 	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/dto"
 	"zeneye-gateway/pkg/logger"
 
 	"github.com/stretchr/testify/assert"
@@ -22,7 +23,7 @@ func TestCreateUserIntegration(t *testing.T) {
 	defer logger.SyncLogger()
 
 	db := SetupTestDB()
-	router := internal.SetupRouter(db)
+	router := internal.SetupRouter(db, TestConfig())
 
 	logger.LogInfo("TestCreateUserIntegration", "Test", "Starting integration test for creating a user", "")
 
@@ -34,11 +35,11 @@ func TestCreateUserIntegration(t *testing.T) {
 		Role:     "superadmin",
 	}
 	db.Create(&superadmin)
-	token := GenerateTestToken(superadmin.ID)
+	token := GenerateTestTokenForUser(superadmin)
 
 	user := map[string]string{
 		"username": "testuser",
-		"password": "password",
+		"password": "Test@Passw0rd",
 		"email":    "test@example.com",
 		"role":     "admin",
 	}
@@ -57,7 +58,7 @@ func TestCreateUserIntegration(t *testing.T) {
 	var createdUser entity.User
 	db.Where("username = ?", "testuser").First(&createdUser)
 	assert.NotEmpty(t, createdUser.UserUUID)
-	assert.Equal(t, 8, len(createdUser.UserUUID))
+	assert.Len(t, createdUser.UserUUID, 36)
 }
 
 func TestEditUserIntegration(t *testing.T) {
@@ -65,7 +66,7 @@ func TestEditUserIntegration(t *testing.T) {
 	defer logger.SyncLogger()
 
 	db := SetupTestDB()
-	router := internal.SetupRouter(db)
+	router := internal.SetupRouter(db, TestConfig())
 
 	logger.LogInfo("TestEditUserIntegration", "Test", "Starting integration test for editing a user", "")
 
@@ -77,7 +78,7 @@ func TestEditUserIntegration(t *testing.T) {
 		Role:     "superadmin",
 	}
 	db.Create(&superadmin)
-	token := GenerateTestToken(superadmin.ID)
+	token := GenerateTestTokenForUser(superadmin)
 
 	user := entity.User{
 		Username: "testuser",
@@ -101,12 +102,16 @@ func TestEditUserIntegration(t *testing.T) {
 
 	logger.LogInfo("TestEditUserIntegration", "Test", "Response", w)
 
-	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusAccepted, w.Code)
 
+	// The new email waits for the user to confirm it
 	var updatedUserFromDB entity.User
 	db.First(&updatedUserFromDB, user.ID)
 	assert.Equal(t, "updateduser", updatedUserFromDB.Username)
-	assert.Equal(t, "newemail@example.com", updatedUserFromDB.Email)
+	assert.Equal(t, "test@example.com", updatedUserFromDB.Email)
+	var change entity.EmailChange
+	db.Where("user_id = ?", user.ID).First(&change)
+	assert.Equal(t, "newemail@example.com", change.NewEmail)
 	assert.Equal(t, user.UserUUID, updatedUserFromDB.UserUUID) // Ensure user_uuid is not changed
 }
 
@@ -115,7 +120,7 @@ func TestDeleteUserIntegration(t *testing.T) {
 	defer logger.SyncLogger()
 
 	db := SetupTestDB()
-	router := internal.SetupRouter(db)
+	router := internal.SetupRouter(db, TestConfig())
 
 	logger.LogInfo("TestDeleteUserIntegration", "Test", "Starting integration test for deleting a user", "")
 
@@ -127,7 +132,7 @@ func TestDeleteUserIntegration(t *testing.T) {
 		Role:     "superadmin",
 	}
 	db.Create(&superadmin)
-	token := GenerateTestToken(superadmin.ID)
+	token := GenerateTestTokenForUser(superadmin)
 
 	user := entity.User{
 		Username: "testuser",
@@ -157,7 +162,7 @@ func TestGetUserIntegration(t *testing.T) {
 	defer logger.SyncLogger()
 
 	db := SetupTestDB()
-	router := internal.SetupRouter(db)
+	router := internal.SetupRouter(db, TestConfig())
 
 	logger.LogInfo("TestGetUserIntegration", "Test", "Starting integration test for getting a user", "")
 
@@ -169,7 +174,7 @@ func TestGetUserIntegration(t *testing.T) {
 		Role:     "superadmin",
 	}
 	db.Create(&superadmin)
-	token := GenerateTestToken(superadmin.ID)
+	token := GenerateTestTokenForUser(superadmin)
 
 	user := entity.User{
 		Username: "testuser",
@@ -188,7 +193,7 @@ func TestGetUserIntegration(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	var result entity.User
+	var result dto.UserResponse
 	json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, user.Username, result.Username)
 	assert.Equal(t, user.Email, result.Email)
@@ -200,7 +205,7 @@ func TestListUsersIntegration(t *testing.T) {
 	defer logger.SyncLogger()
 
 	db := SetupTestDB()
-	router := internal.SetupRouter(db)
+	router := internal.SetupRouter(db, TestConfig())
 
 	logger.LogInfo("TestListUsersIntegration", "Test", "Starting integration test for listing users", "")
 
@@ -212,7 +217,7 @@ func TestListUsersIntegration(t *testing.T) {
 		Role:     "superadmin",
 	}
 	db.Create(&superadmin)
-	token := GenerateTestToken(superadmin.ID)
+	token := GenerateTestTokenForUser(superadmin)
 
 	user1 := &entity.User{
 		Username: "testuser1",
@@ -229,18 +234,40 @@ func TestListUsersIntegration(t *testing.T) {
 	db.Create(&user1)
 	db.Create(&user2)
 
-	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/users", nil)
-	req.Header.Set("Authorization", token)
-	router.ServeHTTP(w, req)
+	list := func(query string) (*httptest.ResponseRecorder, dto.UserListPage) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/users?"+query, nil)
+		req.Header.Set("Authorization", token)
+		router.ServeHTTP(w, req)
+		var page dto.UserListPage
+		json.Unmarshal(w.Body.Bytes(), &page)
+		return w, page
+	}
 
+	w, page := list("role=admin")
 	logger.LogInfo("TestListUsersIntegration", "Test", "Response", w)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, page.Users, 2)
+	assert.Equal(t, int64(2), page.Total)
 
+	// Pages are linked by an opaque cursor
+	w, page = list("sort=username&order=desc&limit=2")
 	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(3), page.Total)
+	assert.Equal(t, []string{"testuser2", "testuser1"}, []string{page.Users[0].Username, page.Users[1].Username})
+	assert.NotEmpty(t, page.NextCursor)
+	w, page = list("sort=username&order=desc&limit=2&cursor=" + page.NextCursor)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, page.Users, 1)
+	assert.Equal(t, "superadmin", page.Users[0].Username)
+	assert.Empty(t, page.NextCursor)
 
-	var users []entity.User
-	json.Unmarshal(w.Body.Bytes(), &users)
-	assert.Len(t, users, 2)
+	// Every invalid parameter is reported at once
+	w, _ = list("sort=password&limit=-1&created_from=yesterday&cursor=bogus")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	for _, field := range []string{`"sort"`, `"limit"`, `"created_from"`, `"cursor"`} {
+		assert.Contains(t, w.Body.String(), field)
+	}
 }
 
 func TestCheckSuperadmin(t *testing.T) {
@@ -248,7 +275,7 @@ func TestCheckSuperadmin(t *testing.T) {
 	defer logger.SyncLogger()
 
 	db := SetupTestDB()
-	router := internal.SetupRouter(db)
+	router := internal.SetupRouter(db, TestConfig())
 
 	logger.LogInfo("TestCheckSuperadmin", "Test", "Starting integration test for checking superadmin", "")
 
@@ -260,7 +287,7 @@ func TestCheckSuperadmin(t *testing.T) {
 		Role:     "superadmin",
 	}
 	db.Create(&superadmin)
-	token := GenerateTestToken(superadmin.ID)
+	token := GenerateTestTokenForUser(superadmin)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/superadmin/check", nil)
@@ -269,8 +296,8 @@ func TestCheckSuperadmin(t *testing.T) {
 
 	logger.LogInfo("TestCheckSuperadmin", "Test", "Response", w)
 
-	assert.Equal(t, http.StatusOK, w.Code)
-	assert.Contains(t, w.Body.String(), `"superadmin_exists":true`)
+	// Once a superadmin exists, the bootstrap is not served
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
 func TestCreateSuperadmin(t *testing.T) {
@@ -278,7 +305,7 @@ func TestCreateSuperadmin(t *testing.T) {
 	defer logger.SyncLogger()
 
 	db := SetupTestDB()
-	router := internal.SetupRouter(db)
+	router := internal.SetupRouter(db, TestConfig())
 
 	logger.LogInfo("TestCreateSuperadmin", "Test", "Starting integration test for creating superadmin", "")
 
@@ -290,7 +317,7 @@ func TestCreateSuperadmin(t *testing.T) {
 		Role:     "superadmin",
 	}
 	db.Create(&superadmin)
-	token := GenerateTestToken(superadmin.ID)
+	token := GenerateTestTokenForUser(superadmin)
 
 	// Attempt to create another superadmin
 	w := httptest.NewRecorder()
@@ -308,6 +335,6 @@ func TestCreateSuperadmin(t *testing.T) {
 
 	logger.LogInfo("TestCreateSuperadmin", "Test", "Response", w)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "Superadmin already exists")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"not_found"`)
 }
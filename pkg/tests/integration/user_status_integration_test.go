@@ -0,0 +1,137 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	internal "zeneye-gateway/internal/adapter/http"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserStatusIntegration(t *testing.T) {
+	logger.LogInfo("TestUserStatusIntegration", "Test", "Starting integration test for disable, enable, delete and restore", "")
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	t.Setenv("ADMIN_MANAGEMENT_SERVICE_URL", upstream.URL)
+
+	db := SetupTestDB()
+	router := internal.SetupRouter(db, TestConfig())
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Status@Passw0rd"), bcrypt.MinCost)
+	superadmin := &entity.User{Username: "statusroot", Password: string(hash), Email: "root@example.com", Role: "superadmin"}
+	member := &entity.User{Username: "statususer", Password: string(hash), Email: "member@example.com", Role: "admin"}
+	db.Create(superadmin)
+	db.Create(member)
+	adminToken := GenerateTestTokenForUser(superadmin)
+	memberToken := GenerateTestTokenForUser(member)
+	memberPath := "/users/" + strconv.Itoa(int(member.ID))
+	rootPath := "/users/" + strconv.Itoa(int(superadmin.ID))
+
+	send := func(method, path, token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	login := func() *httptest.ResponseRecorder {
+		return send("POST", "/login", "", `{"username":"statususer","password":"Status@Passw0rd"}`)
+	}
+
+	assert.Equal(t, http.StatusOK, send("GET", "/admin-management/get-all", memberToken, "").Code)
+
+	// A disabled user cannot sign in, be routed or use the gateway's own APIs
+	w := send("POST", memberPath+"/disable", adminToken, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = login()
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "account_disabled")
+	w = send("GET", "/admin-management/get-all", memberToken, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "account_disabled")
+	assert.Equal(t, http.StatusForbidden, send("GET", "/me", memberToken, "").Code)
+
+	w = send("GET", "/users?status=disabled", adminToken, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "statususer")
+	assert.Equal(t, http.StatusBadRequest, send("GET", "/users?status=asleep", adminToken, "").Code)
+
+	w = send("POST", memberPath+"/enable", adminToken, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusOK, login().Code)
+	assert.Equal(t, http.StatusOK, send("GET", "/admin-management/get-all", memberToken, "").Code)
+
+	// Only admins manage statuses, and only of users ranked below them
+	peer := &entity.User{Username: "statuspeer", Password: string(hash), Email: "peer@example.com", Role: "admin"}
+	auditor := &entity.User{Username: "statusauditor", Password: string(hash), Email: "auditor@example.com", Role: "auditor"}
+	db.Create(peer)
+	db.Create(auditor)
+	peerPath := "/users/" + strconv.Itoa(int(peer.ID))
+	for _, path := range []string{peerPath + "/disable", peerPath + "/enable", rootPath + "/disable", rootPath + "/enable"} {
+		w = send("POST", path, memberToken, "")
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+		assert.Contains(t, w.Body.String(), "insufficient_rank", path)
+	}
+	for _, action := range []string{"/disable", "/enable", "/restore"} {
+		w = send("POST", memberPath+action, GenerateTestTokenForUser(auditor), "")
+		assert.Equal(t, http.StatusForbidden, w.Code, action)
+		assert.Contains(t, w.Body.String(), "insufficient role", action)
+	}
+	w = send("POST", rootPath+"/disable", adminToken, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "insufficient_rank")
+	var stored entity.User
+	db.First(&stored, peer.ID)
+	assert.Equal(t, entity.UserStatusActive, stored.Status)
+
+	// Nobody ranks above the superadmin, so it cannot be deleted over HTTP
+	w = send("DELETE", rootPath, adminToken, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "role_escalation")
+
+	// Deleting hides the user and signs it out; restoring brings it back
+	assert.Equal(t, http.StatusOK, send("DELETE", memberPath, adminToken, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", memberPath, adminToken, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, login().Code)
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/admin-management/get-all", memberToken, "").Code)
+
+	var page struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+	w = send("GET", "/users?status=deleted", adminToken, "")
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if assert.Len(t, page.Users, 1) {
+		assert.Equal(t, "statususer", page.Users[0]["username"])
+		assert.Equal(t, "deleted", page.Users[0]["status"])
+	}
+	w = send("GET", "/users", adminToken, "")
+	assert.NotContains(t, w.Body.String(), "statususer")
+
+	w = send("POST", rootPath+"/restore", adminToken, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "user_not_deleted")
+	w = send("POST", memberPath+"/restore", GenerateTestTokenForUser(peer), "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "insufficient_rank")
+	assert.Equal(t, http.StatusOK, send("POST", memberPath+"/restore", adminToken, "").Code)
+	assert.Equal(t, http.StatusOK, login().Code)
+
+	var actions []string
+	db.Model(&entity.AuditEvent{}).Where("action LIKE ? AND target_id = ? AND outcome = ?", "user.%", strconv.Itoa(int(member.ID)), entity.AuditOutcomeSuccess).
+		Order("id").Pluck("action", &actions)
+	assert.Equal(t, []string{"user.disable", "user.enable", "user.delete", "user.restore"}, actions)
+}
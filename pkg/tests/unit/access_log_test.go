@@ -0,0 +1,157 @@
+package unit
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"zeneye-gateway/internal/adapter/http/middlewares"
+	"zeneye-gateway/pkg/accesslog"
+	"zeneye-gateway/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func testAccessEntry() *accesslog.Entry {
+	return &accesslog.Entry{
+		Time:       time.Date(2024, 3, 5, 14, 7, 9, 0, time.FixedZone("", 3600)),
+		RemoteAddr: "203.0.113.7",
+		User:       "alice",
+		UserUUID:   "ab12cd34",
+		Method:     "GET",
+		URI:        "/admin-management/get-all?page=2",
+		Proto:      "HTTP/1.1",
+		Status:     200,
+		BytesIn:    12,
+		BytesOut:   512,
+		DurationMS: 3.5,
+		Referer:    "https://example.com/",
+		UserAgent:  `curl/8.0 "quoted"`,
+		RequestID:  "req-1",
+	}
+}
+
+func configureTestAccessLog(t *testing.T, mutate func(*accesslog.Options)) string {
+	path := filepath.Join(t.TempDir(), "access.log")
+	opts := accesslog.DefaultOptions()
+	opts.Output = accesslog.OutputFile
+	opts.File = path
+	if mutate != nil {
+		mutate(&opts)
+	}
+	if err := accesslog.Configure(opts); err != nil {
+		t.Fatalf("Failed to configure access log: %v", err)
+	}
+	t.Cleanup(func() { accesslog.Close() })
+	return path
+}
+
+func readTestAccessLog(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read access log: %v", err)
+	}
+	return string(data)
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	for name, expected := range map[string]string{
+		accesslog.FormatCommon:   `203.0.113.7 - ab12cd34 [05/Mar/2024:14:07:09 +0100] "GET /admin-management/get-all?page=2 HTTP/1.1" 200 512` + "\n",
+		accesslog.FormatCombined: `203.0.113.7 - ab12cd34 [05/Mar/2024:14:07:09 +0100] "GET /admin-management/get-all?page=2 HTTP/1.1" 200 512 "https://example.com/" "curl/8.0 \"quoted\""` + "\n",
+	} {
+		path := configureTestAccessLog(t, func(o *accesslog.Options) { o.Format = name })
+		accesslog.Log(testAccessEntry())
+		assert.Equal(t, expected, readTestAccessLog(t, path), name)
+	}
+
+	path := configureTestAccessLog(t, func(o *accesslog.Options) { o.Format = accesslog.FormatJSON })
+	accesslog.Log(testAccessEntry())
+	var decoded map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(readTestAccessLog(t, path)), &decoded))
+	assert.Equal(t, "ab12cd34", decoded["user_uuid"])
+	assert.Equal(t, float64(12), decoded["bytes_in"])
+	assert.Equal(t, "req-1", decoded["request_id"])
+
+	path = configureTestAccessLog(t, func(o *accesslog.Options) {
+		o.Format = accesslog.FormatTemplate
+		o.Template = `${remote_addr} ${status} rid=${request_id} up=${upstream_service}`
+	})
+	accesslog.Log(testAccessEntry())
+	assert.Equal(t, "203.0.113.7 200 rid=req-1 up=-\n", readTestAccessLog(t, path))
+}
+
+func TestAccessLogOptionsValidation(t *testing.T) {
+	opts := accesslog.DefaultOptions()
+	opts.Format = accesslog.FormatTemplate
+	opts.Template = "${status} ${password}"
+	opts.Output = "printer"
+
+	err := opts.Validate()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "password")
+	assert.Contains(t, err.Error(), "printer")
+}
+
+func TestAccessLogMiddlewareOneLinePerRequest(t *testing.T) {
+	path := configureTestAccessLog(t, func(o *accesslog.Options) { o.Format = accesslog.FormatJSON })
+
+	router := gin.New()
+	router.Use(middlewares.RequestIDMiddleware(), middlewares.LoggingMiddleware())
+	router.POST("/echo", func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		c.String(http.StatusOK, string(body))
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/echo", strings.NewReader(`{"hello":"world"}`))
+	req.Header.Set(requestid.Header, "req-access-1")
+	router.ServeHTTP(w, req)
+
+	content := strings.TrimSpace(readTestAccessLog(t, path))
+	assert.Equal(t, 1, strings.Count(content, "\n")+1)
+
+	var entry accesslog.Entry
+	assert.Nil(t, json.Unmarshal([]byte(content), &entry))
+	assert.Equal(t, "req-access-1", entry.RequestID)
+	assert.Equal(t, "/echo", entry.Route)
+	assert.Equal(t, http.StatusOK, entry.Status)
+	assert.Equal(t, int64(17), entry.BytesIn)
+	assert.Equal(t, int64(17), entry.BytesOut)
+}
+
+func TestAccessLogSyslogSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("syslog is not available on windows")
+	}
+
+	socket := filepath.Join(t.TempDir(), "syslog.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	configureTestAccessLog(t, func(o *accesslog.Options) {
+		o.Output = accesslog.OutputSyslog
+		o.SyslogNetwork = "unixgram"
+		o.SyslogAddress = socket
+		o.Format = accesslog.FormatCommon
+	})
+	accesslog.Log(testAccessEntry())
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFromUnix(buf)
+	assert.Nil(t, err)
+	message := string(buf[:n])
+	assert.Contains(t, message, "zeneye-gateway")
+	assert.Contains(t, message, `"GET /admin-management/get-all?page=2 HTTP/1.1" 200 512`)
+}
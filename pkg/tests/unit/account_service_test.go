@@ -0,0 +1,145 @@
+package unit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"zeneye-gateway/internal/adapter/notify"
+	"zeneye-gateway/internal/adapter/repository/postgres"
+	"zeneye-gateway/internal/adapter/service"
+	"zeneye-gateway/internal/domain/domainerr"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/worker"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestChangePasswordKeepsBoundedHistory(t *testing.T) {
+	db := setupTestDB()
+	repo := postgres.NewUserRepository(db)
+	accounts := service.NewAccountService(repo, notify.NewLogNotifier(), worker.New(1, 1))
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Pass@word0"), bcrypt.MinCost)
+	user := &entity.User{Username: "historian", Password: string(hash), Email: "historian@example.com", Role: "user"}
+	assert.Nil(t, db.Create(user).Error)
+
+	for i := 1; i <= service.PasswordHistoryDepth+2; i++ {
+		assert.Nil(t, accounts.ChangePassword(user.ID, fmt.Sprintf("Pass@word%d", i-1), fmt.Sprintf("Pass@word%d", i)))
+	}
+
+	var kept int64
+	db.Model(&entity.PasswordHistory{}).Where("user_id = ?", user.ID).Count(&kept)
+	assert.Equal(t, int64(service.PasswordHistoryDepth), kept)
+
+	// The oldest passwords dropped out of the history and may be used again
+	current := fmt.Sprintf("Pass@word%d", service.PasswordHistoryDepth+2)
+	var fieldErr *domainerr.Error
+	err := accounts.ChangePassword(user.ID, current, "Pass@word2")
+	if assert.True(t, errors.As(err, &fieldErr)) {
+		assert.Equal(t, domainerr.CodePasswordReused, fieldErr.Fields[0].Code)
+	}
+	assert.Nil(t, accounts.ChangePassword(user.ID, current, "Pass@word0"))
+}
+
+func TestConfirmEmailChangeRejectsExpiredToken(t *testing.T) {
+	db := setupTestDB()
+	repo := postgres.NewUserRepository(db)
+	accounts := service.NewAccountService(repo, notify.NewLogNotifier(), worker.New(1, 1))
+
+	user := &entity.User{Username: "latecomer", Password: "x", Email: "late@example.com", Role: "user"}
+	assert.Nil(t, db.Create(user).Error)
+	sum := sha256.Sum256([]byte("expired"))
+	change := &entity.EmailChange{UserID: user.ID, NewEmail: "later@example.com",
+		TokenHash: hex.EncodeToString(sum[:]), ExpiresAt: time.Now().Add(-time.Minute)}
+	assert.Nil(t, repo.CreateEmailChange(change))
+
+	_, err := accounts.ConfirmEmailChange(user.ID, "expired")
+	var fieldErr *domainerr.Error
+	if assert.True(t, errors.As(err, &fieldErr)) {
+		assert.Equal(t, domainerr.CodeInvalidToken, fieldErr.Fields[0].Code)
+	}
+	reloaded, _ := repo.GetUser(user.ID)
+	assert.Equal(t, "late@example.com", reloaded.Email)
+}
+
+// blockingResetRepository holds every CreatePasswordReset until release is
+// closed.
+type blockingResetRepository struct {
+	port.UserRepository
+	started chan struct{}
+	release chan struct{}
+	stored  chan struct{}
+}
+
+func newBlockingResetRepository(repo port.UserRepository) *blockingResetRepository {
+	return &blockingResetRepository{UserRepository: repo, started: make(chan struct{}), release: make(chan struct{}), stored: make(chan struct{})}
+}
+
+func (r *blockingResetRepository) CreatePasswordReset(reset *entity.PasswordReset) error {
+	close(r.started)
+	<-r.release
+	err := r.UserRepository.CreatePasswordReset(reset)
+	close(r.stored)
+	return err
+}
+
+func TestRequestPasswordResetDoesNotWaitForTheToken(t *testing.T) {
+	db := setupTestDB()
+	repo := newBlockingResetRepository(postgres.NewUserRepository(db))
+	accounts := service.NewAccountService(repo, notify.NewLogNotifier(), worker.New(1, 1))
+
+	user := &entity.User{Username: "resetter", Password: "x", Email: "resetter@example.com", Role: "user"}
+	assert.Nil(t, db.Create(user).Error)
+
+	// Answering before the token is stored keeps a known email as fast as an
+	// unknown one
+	done := make(chan error, 1)
+	go func() { done <- accounts.RequestPasswordReset("resetter@example.com") }()
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("RequestPasswordReset waited for the token to be stored")
+	}
+
+	close(repo.release)
+	<-repo.stored
+	var resets int64
+	db.Model(&entity.PasswordReset{}).Where("user_id = ?", user.ID).Count(&resets)
+	assert.Equal(t, int64(1), resets)
+}
+
+func TestShutdownWaitsForPasswordReset(t *testing.T) {
+	db := setupTestDB()
+	repo := newBlockingResetRepository(postgres.NewUserRepository(db))
+	jobs := worker.New(1, 1)
+	accounts := service.NewAccountService(repo, notify.NewLogNotifier(), jobs)
+
+	user := &entity.User{Username: "latereset", Password: "x", Email: "latereset@example.com", Role: "user"}
+	assert.Nil(t, db.Create(user).Error)
+	assert.Nil(t, accounts.RequestPasswordReset("latereset@example.com"))
+	<-repo.started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- jobs.Stop(context.Background()) }()
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned while a password reset was still running")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(repo.release)
+	assert.Nil(t, <-stopped)
+	var resets int64
+	db.Model(&entity.PasswordReset{}).Where("user_id = ?", user.ID).Count(&resets)
+	assert.Equal(t, int64(1), resets)
+
+	// Requests after shutdown are refused without telling the caller
+	assert.Nil(t, accounts.RequestPasswordReset("latereset@example.com"))
+}
@@ -11,6 +11,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestAuthMiddleware(t *testing.T) {
@@ -27,7 +29,7 @@ func TestAuthMiddleware(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/test", nil)
-	token, _ := jwt.GenerateToken(1)                 // Pass a uint ID
+	token, _ := jwt.GenerateToken(1, "", "", "")     // Pass a uint ID
 	req.Header.Set("Authorization", "Bearer "+token) // Add "Bearer" prefix
 
 	logger.LogInfo("TestAuthMiddleware", "Test", "Sending request", map[string]interface{}{
@@ -46,3 +48,24 @@ func TestAuthMiddleware(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "success")
 }
+
+func TestAuthMiddlewareKeepsSuccessOutOfInfoLog(t *testing.T) {
+	token, _ := jwt.GenerateToken(1, "", "", "")
+	core, logs := observer.New(zap.InfoLevel)
+	previous := logger.Logging
+	logger.Logging = zap.New(core)
+	defer func() { logger.Logging = previous }()
+
+	router := gin.New()
+	router.Use(middlewares.AuthMiddleware())
+	router.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.Empty(t, logs.All())
+}
@@ -0,0 +1,83 @@
+package unit
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"zeneye-gateway/internal/adapter/bootstrap"
+	"zeneye-gateway/internal/config"
+	"zeneye-gateway/internal/domain/domainerr"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetupTokenRedeemsOnce(t *testing.T) {
+	token, value, err := bootstrap.NewSetupToken()
+	assert.Nil(t, err)
+	assert.Len(t, value, 43)
+	assert.True(t, token.Outstanding())
+
+	var created atomic.Int32
+	create := func() error { created.Add(1); return nil }
+	assert.True(t, errors.Is(token.Redeem("wrong", create), domainerr.ErrInvalidSetupToken))
+
+	// A failed creation leaves the token usable
+	failure := errors.New("validation failed")
+	assert.Equal(t, failure, token.Redeem(value, func() error { return failure }))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token.Redeem(value, create)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), created.Load())
+	assert.False(t, token.Outstanding())
+
+	var none *bootstrap.SetupToken
+	assert.True(t, errors.Is(none.Redeem(value, create), domainerr.ErrInvalidSetupToken))
+	assert.False(t, none.Outstanding())
+}
+
+func TestBootstrapHTTPIsOffInProduction(t *testing.T) {
+	cfg := config.Default()
+	assert.True(t, cfg.BootstrapHTTP())
+
+	assert.Nil(t, cfg.Bootstrap.LoadEnv(mapLookup(map[string]string{"BOOTSTRAP_HTTP": "false"})))
+	assert.False(t, cfg.BootstrapHTTP())
+	assert.NotNil(t, cfg.Bootstrap.LoadEnv(mapLookup(map[string]string{"BOOTSTRAP_HTTP": "maybe"})))
+
+	cfg = config.Default()
+	cfg.Env = "production"
+	assert.False(t, cfg.BootstrapHTTP())
+
+	opts := bootstrap.DefaultOptions()
+	opts.TokenFile = t.TempDir()
+	assert.ErrorContains(t, opts.Validate(), "is a directory")
+}
+
+func TestSetupTokenFileReplacesLooseLeftover(t *testing.T) {
+	opts := bootstrap.DefaultOptions()
+	opts.TokenFile = filepath.Join(t.TempDir(), "setup-token")
+	assert.Nil(t, os.WriteFile(opts.TokenFile, []byte("stale\n"), 0o644))
+
+	token, err := bootstrap.Issue(opts, io.Discard)
+	if !assert.Nil(t, err) {
+		return
+	}
+	info, err := os.Stat(opts.TokenFile)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	value, _ := os.ReadFile(opts.TokenFile)
+	assert.NotContains(t, string(value), "stale")
+	assert.Nil(t, token.Redeem(strings.TrimSpace(string(value)), func() error { return nil }))
+}
@@ -0,0 +1,103 @@
+package unit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"zeneye-gateway/internal/cli"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runCLI runs a command with env as the environment and returns its exit code
+// and output.
+func runCLI(env map[string]string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := cli.Run(&cli.Env{
+		Stdin:  strings.NewReader(""),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Lookup: mapLookup(env),
+	}, args)
+	return code, stdout.String(), stderr.String()
+}
+
+func cliEnv(extra map[string]string) map[string]string {
+	env := map[string]string{
+		"DATABASE_URL": "postgres://gateway:db-password@db:5432/authdb",
+		"JWT_SECRET":   "unit-test-secret",
+	}
+	for k, v := range extra {
+		env[k] = v
+	}
+	return env
+}
+
+func TestCLIConfigPrintMasksSecrets(t *testing.T) {
+	code, out, _ := runCLI(cliEnv(map[string]string{"SMTP_PASSWORD": "smtp-pass", "NOTIFY_WEBHOOK_SECRET": "hook-secret"}), "config", "print")
+	assert.Equal(t, 0, code)
+	for _, secret := range []string{"db-password", "unit-test-secret", "smtp-pass", "hook-secret"} {
+		assert.NotContains(t, out, secret)
+	}
+	assert.Contains(t, out, "jwt_secret: '[REDACTED]'")
+	assert.Contains(t, out, "requests_per_second: 100")
+
+	code, _, errOut := runCLI(map[string]string{}, "config", "validate")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "database: url must be set")
+
+	code, _, errOut = runCLI(cliEnv(nil), "config", "explain")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "unknown command")
+}
+
+func TestCLIRoutesTest(t *testing.T) {
+	file := writeFile(t, "gateway.yaml", `
+routes:
+  - prefix: reports
+    pool: reporting
+    roles: [auditor, superadmin]
+  - prefix: agent
+    url: http://agent:8080
+pools:
+  - name: reporting
+    targets: ["http://reports-a:8080", "http://reports-b:8080"]
+rate_limit:
+  requests_per_second: 50
+  routes:
+    - prefix: /reports
+      requests_per_second: 5
+cors:
+  allowed_origins: ["https://admin.example.com"]
+  routes:
+    - prefix: /reports
+      allowed_origins: ["https://audit.example.com"]
+`)
+	routes := func(args ...string) (int, string, string) {
+		return runCLI(cliEnv(nil), append(append([]string{"routes", "test", "-config-only"}, args...), "--", "-config", file)...)
+	}
+
+	code, out, _ := routes("-role", "auditor", "/reports/daily?day=1")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "pool reporting, in turn: http://reports-a:8080, http://reports-b:8080")
+	assert.Contains(t, out, "upstream path  /daily")
+	assert.Contains(t, out, "role auditor   allowed")
+	assert.Contains(t, out, "5 requests/s per client, burst 5 (route /reports)")
+	assert.Contains(t, out, "origins https://audit.example.com")
+
+	code, out, errOut := routes("-role", "admin", "/reports")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out, "denied")
+	assert.Contains(t, errOut, "403")
+
+	code, out, _ = routes("agent/tasks")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "http://agent:8080")
+	assert.Contains(t, out, "50 requests/s per client, burst 50 (gateway-wide)")
+	assert.Contains(t, out, "origins https://admin.example.com")
+
+	code, _, errOut = routes("/agents")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "404")
+}
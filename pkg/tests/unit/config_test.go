@@ -0,0 +1,210 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"zeneye-gateway/internal/config"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func mapLookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestConfigLayering(t *testing.T) {
+	file := writeFile(t, "gateway.yaml", `
+env: staging
+database:
+  url: postgres://gateway:file@db:5432/authdb
+auth:
+  jwt_secret: file-secret
+server:
+  addr: ":7000"
+  write_timeout: 90s
+rate_limit:
+  requests_per_second: 10
+log:
+  level: warn
+routes:
+  - prefix: agent
+    url: http://agent-from-file:8080
+    env: AGENT_SERVICE_URL
+`)
+	dotenv := writeFile(t, ".env.test", "RATE_LIMIT=20\nJWT_EXPIRATION=3\nLOG_LEVEL=error\nAGENT_SERVICE_URL=http://agent-from-dotenv:8080\n")
+	env := map[string]string{"RATE_LIMIT": "30", "LOG_LEVEL": "debug"}
+
+	cfg, err := config.Load([]string{"-config", file, "-env-file", dotenv, "-rate-limit", "40"}, mapLookup(env))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "staging", cfg.Env)                                 // file over defaults
+	assert.Equal(t, ":7000", cfg.Server.Addr)                           // file
+	assert.Equal(t, 90*time.Second, cfg.Server.WriteTimeout)            // file duration
+	assert.Equal(t, 5*time.Second, cfg.Server.ReadHeaderTimeout)        // default
+	assert.Equal(t, 3*time.Hour, cfg.Auth.AccessTokenTTL)               // .env over file
+	assert.Equal(t, "debug", cfg.Log.Level)                             // env over .env
+	assert.Equal(t, 40, cfg.RateLimit.RequestsPerSecond)                // flag over env
+	assert.Equal(t, "http://agent-from-dotenv:8080", cfg.Routes[0].URL) // .env over file
+	assert.Len(t, cfg.Routes, 1)
+	assert.Equal(t, "file-secret", cfg.JWT().Secret)
+}
+
+func TestConfigTOML(t *testing.T) {
+	file := writeFile(t, "gateway.toml", `
+gin_mode = "release"
+
+[database]
+url = "postgres://gateway:secret@db:5432/authdb"
+
+[auth]
+jwt_secret = "toml-secret"
+refresh_token_ttl = "168h"
+
+[health]
+check_upstreams = true
+`)
+	cfg, err := config.Load([]string{"-config", file}, mapLookup(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "release", cfg.Mode())
+	assert.Equal(t, 168*time.Hour, cfg.Auth.RefreshTokenTTL)
+	assert.True(t, cfg.Health.CheckUpstreams)
+}
+
+func TestConfigEnvOnly(t *testing.T) {
+	// The variables the gateway has always read keep working, including
+	// token lifetimes given in hours.
+	cfg, err := config.Load(nil, mapLookup(map[string]string{
+		"DATABASE_URL":             "postgres://gateway:secret@db:5432/authdb",
+		"JWT_SECRET":               "env-secret",
+		"JWT_EXPIRATION":           "2",
+		"REFRESH_TOKEN_EXPIRATION": "720",
+		"GIN_MODE":                 "development",
+		"WAF_SERVICE_URL":          "http://waf-service:8080",
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, 720*time.Hour, cfg.Auth.RefreshTokenTTL)
+	assert.Equal(t, "debug", cfg.Mode())
+	for _, r := range cfg.Routes {
+		if r.Prefix == "waf" {
+			assert.Equal(t, "http://waf-service:8080", r.URL)
+		}
+	}
+}
+
+func TestConfigAggregatesErrors(t *testing.T) {
+	_, err := config.Load(nil, mapLookup(map[string]string{
+		"SERVER_READ_TIMEOUT": "forever",
+		"IDENTITY_MODE":       "ldap",
+		"AGENT_SERVICE_URL":   "agent-service:8080",
+		"RATE_LIMIT":          "0",
+	}))
+	assert.NotNil(t, err)
+	for _, want := range []string{
+		"SERVER_READ_TIMEOUT",
+		"database: url must be set",
+		"auth: JWT secret must be set",
+		"identity: identity mode \"ldap\"",
+		"routes: route \"agent\"",
+		"rate limit: rate limit must be at least",
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestConfigRejectsUnknownKeys(t *testing.T) {
+	file := writeFile(t, "gateway.yaml", "server:\n  adress: \":7000\"\n")
+	_, err := config.Load([]string{"-config", file}, mapLookup(nil))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "adress")
+
+	_, err = config.Load([]string{"-env-file", filepath.Join(t.TempDir(), "missing.env")}, mapLookup(nil))
+	assert.NotNil(t, err)
+}
+
+func TestConfigMasksSecrets(t *testing.T) {
+	cfg, err := config.Load(nil, mapLookup(map[string]string{
+		"DATABASE_URL": "postgres://gateway:db-password@db:5432/authdb",
+		"JWT_SECRET":   "WareWaPain",
+	}))
+	assert.Nil(t, err)
+
+	encoded, _ := json.Marshal(cfg)
+	logged, _ := json.Marshal(logger.Redact(cfg))
+	for _, out := range []string{string(encoded), string(logged), fmt.Sprintf("%v", cfg), fmt.Sprintf("%+v", *cfg), fmt.Sprintf("%#v", cfg.Auth)} {
+		assert.NotContains(t, out, "WareWaPain")
+		assert.NotContains(t, out, "db-password")
+	}
+	assert.Contains(t, string(encoded), "[REDACTED]")
+	assert.Equal(t, "WareWaPain", cfg.Auth.JWTSecret.Value())
+}
+
+func TestConfigReloader(t *testing.T) {
+	const base = "database:\n  url: postgres://gateway:secret@db:5432/authdb\nauth:\n  jwt_secret: s\n"
+	file := writeFile(t, "gateway.yaml", base+"rate_limit:\n  requests_per_second: 10\n")
+	args := []string{"-config", file}
+	cfg, err := config.Load(args, mapLookup(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{file}, cfg.Files)
+
+	applied := make(chan *config.Config, 1)
+	reloader := config.NewReloader(args, mapLookup(nil), func(next *config.Config) error {
+		applied <- next
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hup := make(chan os.Signal, 1)
+	go reloader.Watch(ctx, cfg.Files, 10*time.Millisecond, hup)
+
+	next := func() *config.Config {
+		select {
+		case c := <-applied:
+			return c
+		case <-time.After(2 * time.Second):
+			t.Fatal("configuration was not reloaded")
+			return nil
+		}
+	}
+
+	// SIGHUP reloads even when nothing changed
+	hup <- syscall.SIGHUP
+	assert.Equal(t, 10, next().RateLimit.RequestsPerSecond)
+
+	// A changed file is picked up
+	assert.Nil(t, os.WriteFile(file, []byte(base+"rate_limit:\n  requests_per_second: 20\n"), 0o600))
+	reloaded := next()
+	assert.Equal(t, 20, reloaded.RateLimit.RequestsPerSecond)
+	assert.Equal(t, []string{"rate_limit"}, cfg.Changed(reloaded))
+
+	// An invalid file is rejected without applying anything
+	assert.Nil(t, os.WriteFile(file, []byte(base+"rate_limit:\n  requests_per_second: 0\n"), 0o600))
+	assert.NotNil(t, reloader.Reload())
+	select {
+	case <-applied:
+		t.Fatal("invalid configuration was applied")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Fixing the file applies it again
+	assert.Nil(t, os.WriteFile(file, []byte(base+"rate_limit:\n  requests_per_second: 30\n"), 0o600))
+	assert.Equal(t, 30, next().RateLimit.RequestsPerSecond)
+}
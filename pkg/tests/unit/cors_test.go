@@ -0,0 +1,174 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"zeneye-gateway/internal/adapter/http/middlewares"
+	"zeneye-gateway/pkg/cors"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSOriginMatching(t *testing.T) {
+	opts := cors.DefaultOptions()
+	opts.AllowedOrigins = []string{
+		"https://admin.example.com",
+		"https://*.tenants.example.com",
+		`regex:^http://localhost:\d+$`,
+	}
+	policy, err := cors.New(opts)
+	assert.Nil(t, err)
+	rule := policy.For("/users/1")
+
+	for origin, allowed := range map[string]bool{
+		"https://admin.example.com":        true,
+		"https://ADMIN.example.com":        true,
+		"http://admin.example.com":         false,
+		"https://acme.tenants.example.com": true,
+		"https://a.b.tenants.example.com":  true,
+		"https://tenants.example.com":      false,
+		"http://acme.tenants.example.com":  false,
+		"https://eviltenants.example.com":  false,
+		"http://localhost:3000":            true,
+		"http://localhost:3000.evil.com":   false,
+		"null":                             false,
+	} {
+		_, ok := rule.AllowOrigin(origin)
+		assert.Equal(t, allowed, ok, origin)
+	}
+	assert.True(t, rule.VaryOrigin())
+}
+
+func TestCORSRouteOverrides(t *testing.T) {
+	credentials := true
+	opts := cors.DefaultOptions()
+	opts.AllowedOrigins = []string{"https://admin.example.com"}
+	opts.Routes = []cors.RouteOptions{
+		{Prefix: "/public", AllowedOrigins: []string{"*"}},
+		{Prefix: "/public/session", AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: &credentials},
+	}
+	policy, err := cors.New(opts)
+	assert.Nil(t, err)
+
+	value, ok := policy.For("/public/docs").AllowOrigin("https://anything.example.org")
+	assert.True(t, ok)
+	assert.Equal(t, "*", value)
+	assert.False(t, policy.For("/public/docs").VaryOrigin())
+
+	session := policy.For("/public/session/new")
+	assert.True(t, session.Credentials())
+	_, ok = session.AllowOrigin("https://admin.example.com")
+	assert.False(t, ok)
+
+	_, ok = policy.For("/publicity").AllowOrigin("https://anything.example.org")
+	assert.False(t, ok)
+	assert.Equal(t, opts.AllowedMethods, []string{"GET", "POST", "PUT", "PATCH", "DELETE"})
+}
+
+func TestCORSOptionsValidation(t *testing.T) {
+	opts := cors.DefaultOptions()
+	opts.AllowedOrigins = []string{"*", "regex:(", "https://*", "admin.example.com"}
+	opts.AllowCredentials = true
+	opts.MaxAge = -1
+	opts.Routes = []cors.RouteOptions{{Prefix: "users"}}
+
+	err := opts.Validate()
+	assert.NotNil(t, err)
+	for _, want := range []string{"credentials", "regex:(", "https://*", "admin.example.com", "max age", "users"} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestCORSOptionsFromEnv(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+	t.Setenv("ADMIN_PANEL_SERVICE_URL", "http://admin-panel-service:8080/app")
+	t.Setenv("CORS_ROUTES", `[{"prefix":"/audit","allowed_methods":["GET"],"max_age":60}]`)
+
+	opts, err := cors.OptionsFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"http://admin-panel-service:8080"}, opts.AllowedOrigins)
+	assert.Equal(t, "/audit", opts.Routes[0].Prefix)
+	assert.Equal(t, 60, *opts.Routes[0].MaxAge)
+
+	t.Setenv("CORS_MAX_AGE", "soon")
+	_, err = cors.OptionsFromEnv()
+	assert.NotNil(t, err)
+}
+
+func setupCORSRouter(t *testing.T, opts cors.Options) *gin.Engine {
+	policy, err := cors.New(opts)
+	if err != nil {
+		t.Fatalf("Failed to build CORS policy: %v", err)
+	}
+	router := gin.New()
+	router.Use(middlewares.SnapshotMiddleware(middlewares.NewSnapshots(&middlewares.Snapshot{CORS: policy})))
+	router.Use(middlewares.CORSMiddleware())
+	router.GET("/resource", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	return router
+}
+
+func corsRequest(router *gin.Engine, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, "/resource", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	opts := cors.DefaultOptions()
+	opts.AllowedOrigins = []string{"https://admin.example.com"}
+	opts.AllowCredentials = true
+	router := setupCORSRouter(t, opts)
+
+	// Same-origin and non-browser requests get no CORS headers but still vary.
+	w := corsRequest(router, "GET", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+
+	w = corsRequest(router, "GET", "https://admin.example.com", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://admin.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "X-Request-ID")
+
+	w = corsRequest(router, "GET", "https://evil.example.com", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = corsRequest(router, "OPTIONS", "https://admin.example.com", map[string]string{
+		"Access-Control-Request-Method":  "DELETE",
+		"Access-Control-Request-Headers": "authorization, content-type",
+	})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://admin.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "DELETE")
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	assert.ElementsMatch(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, w.Header().Values("Vary"))
+
+	w = corsRequest(router, "OPTIONS", "https://admin.example.com", map[string]string{
+		"Access-Control-Request-Method":  "GET",
+		"Access-Control-Request-Headers": "X-Secret-Header",
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = corsRequest(router, "OPTIONS", "https://evil.example.com", map[string]string{
+		"Access-Control-Request-Method": "GET",
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "origin_not_allowed")
+}
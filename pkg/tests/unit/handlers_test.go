@@ -0,0 +1,126 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zeneye-gateway/internal/adapter/http/handlers"
+	"zeneye-gateway/internal/adapter/http/middlewares"
+	"zeneye-gateway/internal/adapter/identity"
+	"zeneye-gateway/internal/domain/domainerr"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/jwt"
+	"zeneye-gateway/pkg/loadbalancer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUserService keeps users in memory. Methods a test does not need are
+// left to the embedded nil interface.
+type fakeUserService struct {
+	port.UserService
+	users map[uint]*entity.User
+}
+
+func (f *fakeUserService) CreateUser(_ uint, user *entity.User) error {
+	user.ID = uint(len(f.users) + 1)
+	f.users[user.ID] = user
+	return nil
+}
+
+func (f *fakeUserService) GetUser(id uint) (*entity.User, error) {
+	if user, ok := f.users[id]; ok {
+		return user, nil
+	}
+	return nil, domainerr.ErrUserNotFound
+}
+
+func (f *fakeUserService) GetUserByUsername(username string) (*entity.User, error) {
+	for _, user := range f.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, domainerr.ErrUserNotFound
+}
+
+type fakeAuditService struct {
+	port.AuditService
+	events []*entity.AuditEvent
+}
+
+func (f *fakeAuditService) Record(event *entity.AuditEvent) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+func TestUserHandlerWithFakes(t *testing.T) {
+	users := &fakeUserService{users: map[uint]*entity.User{}}
+	audit := &fakeAuditService{}
+	h := handlers.NewUserHandler(users, nil, audit, nil)
+
+	router := gin.New()
+	router.POST("/users", h.CreateUser)
+	router.GET("/users/:id", h.GetUser)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/users", strings.NewReader(`{"username":"fakeuser","password":"FakePassword@123","email":"fake@example.com","role":"admin"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Len(t, audit.events, 1)
+	assert.Equal(t, handlers.AuditActionUserCreate, audit.events[0].Action)
+	assert.Equal(t, "1", audit.events[0].TargetID)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users/1", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":"fakeuser"`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users/2", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), string(domainerr.CodeUserNotFound))
+}
+
+type fakeResolver struct {
+	role string
+}
+
+func (f fakeResolver) Resolve(_ context.Context, claims *jwt.Claims) (identity.Identity, error) {
+	return identity.Identity{UserID: claims.UserID, Username: "fake", Role: f.role}, nil
+}
+
+func TestRoutingMiddlewareWithFakeResolver(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-User-Role") + " " + r.URL.Path))
+	}))
+	defer upstream.Close()
+
+	snapshots := middlewares.NewSnapshots(&middlewares.Snapshot{
+		Balancer: loadbalancer.New([]loadbalancer.Route{{Prefix: "waf", URL: upstream.URL, Roles: []string{"superadmin"}}}, nil),
+	})
+	token, _ := jwt.GenerateToken(7, "fake", "", "")
+
+	for role, want := range map[string]int{"superadmin": http.StatusOK, "auditor": http.StatusForbidden} {
+		router := gin.New()
+		router.Use(middlewares.SnapshotMiddleware(snapshots))
+		router.GET("/waf/rules", middlewares.MicroserviceRoutingMiddleware(fakeResolver{role: role}))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/waf/rules", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, role)
+		if want == http.StatusOK {
+			assert.Equal(t, "superadmin /rules", w.Body.String())
+		}
+	}
+}
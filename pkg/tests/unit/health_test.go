@@ -0,0 +1,135 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zeneye-gateway/pkg/health"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func countingCheck(name string, critical bool, runs *int32, down *atomic.Bool) health.Check {
+	return health.Check{
+		Name:     name,
+		Critical: critical,
+		Run: func(ctx context.Context) error {
+			atomic.AddInt32(runs, 1)
+			if down.Load() {
+				return errors.New(name + " is down")
+			}
+			return nil
+		},
+	}
+}
+
+func TestHealthAggregatesAndCaches(t *testing.T) {
+	var dbRuns, agentRuns int32
+	var dbDown, agentDown atomic.Bool
+	agentDown.Store(true)
+
+	opts := health.DefaultOptions()
+	opts.CacheTTL = time.Hour
+	checker := health.New(opts,
+		countingCheck("postgres", true, &dbRuns, &dbDown),
+		countingCheck("upstream:agent", false, &agentRuns, &agentDown),
+	)
+
+	// A failing non-critical check only degrades the report.
+	report := checker.Report(context.Background())
+	assert.Equal(t, health.StatusWarn, report.Status)
+	assert.Equal(t, http.StatusOK, report.HTTPStatus())
+	agent := report.Checks["upstream:agent"][0]
+	assert.Equal(t, health.StatusFail, agent.Status)
+	assert.Equal(t, "upstream:agent is down", agent.Output)
+	assert.False(t, agent.Critical)
+	assert.Equal(t, "ms", agent.ObservedUnit)
+
+	ready := checker.Ready(context.Background())
+	assert.Equal(t, health.StatusPass, ready.Status)
+	assert.NotContains(t, ready.Checks, "upstream:agent")
+
+	// Cached results are reused until the TTL expires.
+	checker.Report(context.Background())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dbRuns))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&agentRuns))
+}
+
+func TestHealthCriticalFailureAndLastError(t *testing.T) {
+	var runs int32
+	var dbDown atomic.Bool
+	dbDown.Store(true)
+
+	opts := health.DefaultOptions()
+	opts.CacheTTL = 0
+	checker := health.New(opts, countingCheck("postgres", true, &runs, &dbDown))
+
+	report := checker.Ready(context.Background())
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.Equal(t, http.StatusServiceUnavailable, report.HTTPStatus())
+	assert.Equal(t, "postgres is down", report.Checks["postgres"][0].Output)
+
+	// After recovery the component passes but remembers its last error.
+	dbDown.Store(false)
+	report = checker.Report(context.Background())
+	assert.Equal(t, health.StatusPass, report.Status)
+	component := report.Checks["postgres"][0]
+	assert.Empty(t, component.Output)
+	assert.Equal(t, "postgres is down", component.LastError)
+	assert.NotEmpty(t, component.LastErrorTime)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+}
+
+func TestHealthCheckTimeout(t *testing.T) {
+	opts := health.DefaultOptions()
+	opts.Timeout = 20 * time.Millisecond
+	checker := health.New(opts, health.Check{Name: "hung", Critical: true, Run: func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}})
+
+	start := time.Now()
+	report := checker.Report(context.Background())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.Contains(t, report.Checks["hung"][0].Output, "timed out")
+}
+
+func TestHealthHTTPCheck(t *testing.T) {
+	status := int32(http.StatusOK)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/health", r.URL.Path)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer upstream.Close()
+
+	check := health.HTTPCheck("upstream:agent", upstream.URL+"/health", false, nil)
+	assert.Nil(t, check.Run(context.Background()))
+
+	atomic.StoreInt32(&status, http.StatusBadGateway)
+	assert.NotNil(t, check.Run(context.Background()))
+
+	upstream.Close()
+	assert.NotNil(t, check.Run(context.Background()))
+}
+
+func TestHealthOptionsFromEnv(t *testing.T) {
+	t.Setenv("HEALTH_CHECK_TIMEOUT", "500ms")
+	t.Setenv("HEALTH_CHECK_UPSTREAMS", "true")
+	opts, err := health.OptionsFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, 500*time.Millisecond, opts.Timeout)
+	assert.True(t, opts.CheckUpstreams)
+
+	t.Setenv("HEALTH_UPSTREAM_PATH", "health")
+	t.Setenv("HEALTH_CACHE_TTL", "-1s")
+	_, err = health.OptionsFromEnv()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "upstream path")
+	assert.Contains(t, err.Error(), "cache TTL")
+}
@@ -0,0 +1,175 @@
+package unit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	"zeneye-gateway/internal/adapter/identity"
+	"zeneye-gateway/internal/adapter/repository/postgres"
+	"zeneye-gateway/internal/adapter/service"
+	"zeneye-gateway/internal/domain/entity"
+	"zeneye-gateway/internal/domain/port"
+	"zeneye-gateway/pkg/jwt"
+	"zeneye-gateway/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupIdentityResolver(t testing.TB, mode identity.Mode) (*identity.Resolver, port.UserService, *jwt.Claims) {
+	db := setupTestDB()
+	repo := postgres.NewUserRepository(db)
+	resolver := identity.NewResolver(repo, identity.Options{Mode: mode, CacheSize: 16, CacheTTL: time.Minute})
+	userService := service.NewUserService(resolver.WrapRepository(repo))
+
+	user := &entity.User{
+		Username: "identityuser",
+		Password: "Identity@123",
+		Email:    "identity@example.com",
+		Role:     "admin",
+	}
+	if err := userService.CreateUser(entity.SystemActorID, user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	claims := &jwt.Claims{UserID: user.ID, Username: "claimsuser", Role: "auditor", UserUUID: "claims-uuid"}
+	return resolver, userService, claims
+}
+
+func TestIdentityResolverCachesLookups(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	resolver, _, claims := setupIdentityResolver(t, identity.ModeCache)
+
+	first, err := resolver.Resolve(context.Background(), claims)
+	assert.Nil(t, err)
+	assert.Equal(t, "identityuser", first.Username)
+
+	second, err := resolver.Resolve(context.Background(), claims)
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+
+	stats := resolver.Stats()
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Hits)
+}
+
+func TestIdentityResolverInvalidatesOnEdit(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	resolver, userService, claims := setupIdentityResolver(t, identity.ModeCache)
+
+	_, err := resolver.Resolve(context.Background(), claims)
+	assert.Nil(t, err)
+
+	err = userService.EditUser(entity.SystemActorID, &entity.User{ID: claims.UserID, Username: "renameduser", Email: "identity@example.com"})
+	assert.Nil(t, err)
+
+	id, err := resolver.Resolve(context.Background(), claims)
+	assert.Nil(t, err)
+	assert.Equal(t, "renameduser", id.Username)
+	assert.Equal(t, uint64(2), resolver.Stats().Misses)
+}
+
+func TestIdentityResolverInvalidatesOnDelete(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	resolver, userService, claims := setupIdentityResolver(t, identity.ModeCache)
+
+	_, err := resolver.Resolve(context.Background(), claims)
+	assert.Nil(t, err)
+
+	assert.Nil(t, userService.DeleteUser(entity.SystemActorID, claims.UserID))
+
+	_, err = resolver.Resolve(context.Background(), claims)
+	assert.NotNil(t, err)
+}
+
+// pausingRepository holds the first GetUser after reading the user until
+// release is closed.
+type pausingRepository struct {
+	port.UserRepository
+	read, release chan struct{}
+	once          sync.Once
+}
+
+func (r *pausingRepository) GetUser(id uint) (*entity.User, error) {
+	user, err := r.UserRepository.GetUser(id)
+	r.once.Do(func() {
+		close(r.read)
+		<-r.release
+	})
+	return user, err
+}
+
+func TestIdentityResolverDoesNotCacheRacingLookup(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	repo := &pausingRepository{UserRepository: postgres.NewUserRepository(setupTestDB()), read: make(chan struct{}), release: make(chan struct{})}
+	resolver := identity.NewResolver(repo, identity.Options{Mode: identity.ModeCache, CacheSize: 16, CacheTTL: time.Minute})
+	user := &entity.User{Username: "racinguser", Password: "Racing@123", Email: "racing@example.com", Role: "admin"}
+	assert.Nil(t, service.NewUserService(repo).CreateUser(entity.SystemActorID, user))
+	claims := &jwt.Claims{UserID: user.ID}
+
+	// The user is disabled after the lookup read it but before it was cached
+	done := make(chan identity.Identity, 1)
+	go func() {
+		id, _ := resolver.Resolve(context.Background(), claims)
+		done <- id
+	}()
+	<-repo.read
+	assert.Nil(t, repo.UserRepository.SetUserStatus(user.ID, entity.UserStatusDisabled))
+	resolver.Invalidate(user.ID)
+	close(repo.release)
+	assert.True(t, (<-done).Active())
+
+	id, err := resolver.Resolve(context.Background(), claims)
+	assert.Nil(t, err)
+	assert.False(t, id.Active())
+	assert.Equal(t, uint64(2), resolver.Stats().Misses)
+}
+
+func TestIdentityResolverTrustsClaims(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	resolver, _, claims := setupIdentityResolver(t, identity.ModeClaims)
+
+	id, err := resolver.Resolve(context.Background(), claims)
+	assert.Nil(t, err)
+	assert.Equal(t, "claimsuser", id.Username)
+	assert.Equal(t, "auditor", id.Role)
+	assert.Equal(t, "claims-uuid", id.UserUUID)
+	assert.Equal(t, uint64(0), resolver.Stats().Misses)
+}
+
+func benchmarkIdentityResolver(b *testing.B, mode identity.Mode) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	resolver, _, claims := setupIdentityResolver(b, mode)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := resolver.Resolve(context.Background(), claims); err != nil {
+			b.Fatalf("Resolve failed: %v", err)
+		}
+	}
+}
+
+// Compare with: go test ./pkg/tests/unit -run '^$' -bench IdentityResolver
+func BenchmarkIdentityResolverDatabase(b *testing.B) {
+	benchmarkIdentityResolver(b, identity.ModeDatabase)
+}
+
+func BenchmarkIdentityResolverCache(b *testing.B) {
+	benchmarkIdentityResolver(b, identity.ModeCache)
+}
+
+func BenchmarkIdentityResolverClaims(b *testing.B) {
+	benchmarkIdentityResolver(b, identity.ModeClaims)
+}
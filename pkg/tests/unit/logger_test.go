@@ -0,0 +1,113 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/requestid"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func configureTestLogger(t *testing.T, mutate func(*logger.Options)) string {
+	path := filepath.Join(t.TempDir(), "test.log")
+	opts := logger.DefaultOptions()
+	opts.File = path
+	if mutate != nil {
+		mutate(&opts)
+	}
+	if err := logger.Configure(opts); err != nil {
+		t.Fatalf("Failed to configure logger: %v", err)
+	}
+	t.Cleanup(logger.InitLogger)
+	return path
+}
+
+func readTestLog(t *testing.T, path string) string {
+	logger.SyncLogger()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	return string(data)
+}
+
+func TestLoggerFromContextAddsRequestID(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	previous := logger.Logging
+	logger.Logging = zap.New(core)
+	defer func() { logger.Logging = previous }()
+
+	ctx := requestid.NewContext(context.Background(), "req-typed-1")
+	logger.FromContext(ctx).WithSource("TestLogger").Info("typed entry", logger.Int("attempt", 3))
+
+	entries := logs.All()
+	assert.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "req-typed-1", fields["RequestID"])
+	assert.Equal(t, "TestLogger", fields["Source"])
+	assert.Equal(t, int64(3), fields["attempt"])
+}
+
+func TestLoggerRuntimeLevelChange(t *testing.T) {
+	path := configureTestLogger(t, nil)
+
+	logger.FromContext(context.Background()).Debug("hidden debug line")
+	assert.NotContains(t, readTestLog(t, path), "hidden debug line")
+
+	assert.Nil(t, logger.SetLevel("debug"))
+	assert.Equal(t, "debug", logger.Level())
+
+	logger.FromContext(context.Background()).Debug("visible debug line")
+	assert.Contains(t, readTestLog(t, path), "visible debug line")
+
+	assert.NotNil(t, logger.SetLevel("verbose"))
+	assert.Equal(t, "debug", logger.Level())
+}
+
+func TestLoggerSamplesHotPath(t *testing.T) {
+	path := configureTestLogger(t, func(o *logger.Options) {
+		o.SampleInitial = 2
+		o.SampleThereafter = 0
+	})
+
+	for i := 0; i < 10; i++ {
+		logger.FromContext(context.Background()).Sampled().Info("hot path line")
+	}
+	logger.FromContext(context.Background()).Info("unsampled line")
+
+	content := readTestLog(t, path)
+	assert.Equal(t, 2, strings.Count(content, "hot path line"))
+	assert.Equal(t, 1, strings.Count(content, "unsampled line"))
+}
+
+func TestLoggerConsoleFormat(t *testing.T) {
+	path := configureTestLogger(t, func(o *logger.Options) {
+		o.Format = logger.FormatConsole
+	})
+
+	logger.LogInfo("TestLogger", "Test", "console entry", "")
+	content := readTestLog(t, path)
+	assert.Contains(t, content, "INFO")
+	assert.Contains(t, content, "console entry")
+	assert.False(t, strings.HasPrefix(content, "{"))
+}
+
+func TestLoggerOptionsValidation(t *testing.T) {
+	opts := logger.DefaultOptions()
+	opts.Level = "loud"
+	opts.Output = "printer"
+	opts.Format = "xml"
+
+	err := opts.Validate()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "log level")
+	assert.Contains(t, err.Error(), "printer")
+	assert.Contains(t, err.Error(), "xml")
+}
@@ -0,0 +1,265 @@
+package unit
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"zeneye-gateway/db"
+	"zeneye-gateway/internal/domain/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+)
+
+// sqlTable is a table as the up migrations leave it.
+type sqlTable struct {
+	columns map[string]*sqlColumn
+	// indexes maps the leading column of every index to whether it is unique.
+	indexes map[string]bool
+}
+
+type sqlColumn struct {
+	typ      string
+	notNull  bool
+	defaults string
+}
+
+var (
+	createTableStmt = regexp.MustCompile(`(?is)^CREATE TABLE (?:IF NOT EXISTS )?(\w+) \((.*)\)$`)
+	addColumnStmt   = regexp.MustCompile(`(?is)^ALTER TABLE (\w+) ADD COLUMN (?:IF NOT EXISTS )?(\w+) (.*)$`)
+	dropColumnStmt  = regexp.MustCompile(`(?is)^ALTER TABLE (\w+) DROP COLUMN (?:IF EXISTS )?(\w+)$`)
+	setDefaultStmt  = regexp.MustCompile(`(?is)^ALTER TABLE (\w+) ALTER COLUMN (\w+) (?:SET DEFAULT (.*)|DROP DEFAULT)$`)
+	createIndexStmt = regexp.MustCompile(`(?is)^CREATE (UNIQUE )?INDEX (?:IF NOT EXISTS )?\w+ ON (\w+) \((\w+)`)
+	dropTableStmt   = regexp.MustCompile(`(?is)^DROP TABLE (?:IF EXISTS )?(\w+)$`)
+	// dataStmt matches the statements that leave tables, columns and indexes
+	// as they are: backfills and the append-only trigger of audit_events.
+	dataStmt        = regexp.MustCompile(`(?is)^(?:UPDATE \w+ SET |CREATE (?:OR REPLACE )?FUNCTION |CREATE TRIGGER )`)
+	tableConstraint = regexp.MustCompile(`(?i)^(?:CONSTRAINT|PRIMARY KEY|UNIQUE|FOREIGN KEY|CHECK|EXCLUDE)\b`)
+	columnDefault   = regexp.MustCompile(`(?i)DEFAULT (\S+)`)
+	literalDefault  = regexp.MustCompile(`^'.*'$|^\d+$`)
+)
+
+// replayMigrations applies the table and column statements of every up
+// migration, in order, to an empty schema. Any statement it cannot account
+// for fails the test, so new DDL is never silently left out of the check.
+func replayMigrations(t *testing.T) map[string]*sqlTable {
+	files, err := fs.Glob(db.Migrations, "*.up.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	sort.Strings(files)
+
+	tables := map[string]*sqlTable{}
+	for _, file := range files {
+		content, err := fs.ReadFile(db.Migrations, file)
+		require.NoError(t, err)
+		for _, stmt := range sqlStatements(string(content)) {
+			require.NoError(t, replayStatement(tables, stmt), "%s: %s", file, stmt)
+		}
+	}
+	return tables
+}
+
+// replayStatement applies stmt to tables.
+func replayStatement(tables map[string]*sqlTable, stmt string) error {
+	table := func(name string) (*sqlTable, error) {
+		if tables[name] == nil {
+			return nil, fmt.Errorf("table %s does not exist", name)
+		}
+		return tables[name], nil
+	}
+
+	if m := createTableStmt.FindStringSubmatch(stmt); m != nil {
+		created := &sqlTable{columns: map[string]*sqlColumn{}, indexes: map[string]bool{}}
+		for _, def := range splitTopLevel(m[2]) {
+			if tableConstraint.MatchString(def) {
+				return fmt.Errorf("cannot replay the table constraint %q", def)
+			}
+			name, rest, _ := strings.Cut(def, " ")
+			created.columns[name] = parseColumn(rest)
+			if strings.Contains(strings.ToUpper(rest), "UNIQUE") {
+				created.indexes[name] = true
+			}
+		}
+		tables[m[1]] = created
+	} else if m := addColumnStmt.FindStringSubmatch(stmt); m != nil {
+		if len(splitTopLevel(m[3])) > 1 {
+			return fmt.Errorf("cannot replay more than one action per ALTER TABLE")
+		}
+		altered, err := table(m[1])
+		if err != nil {
+			return err
+		}
+		altered.columns[m[2]] = parseColumn(m[3])
+	} else if m := dropColumnStmt.FindStringSubmatch(stmt); m != nil {
+		altered, err := table(m[1])
+		if err != nil {
+			return err
+		}
+		delete(altered.columns, m[2])
+	} else if m := setDefaultStmt.FindStringSubmatch(stmt); m != nil {
+		altered, err := table(m[1])
+		if err != nil {
+			return err
+		}
+		if altered.columns[m[2]] == nil {
+			return fmt.Errorf("column %s.%s does not exist", m[1], m[2])
+		}
+		altered.columns[m[2]].defaults = m[3]
+	} else if m := createIndexStmt.FindStringSubmatch(stmt); m != nil {
+		indexed, err := table(m[2])
+		if err != nil {
+			return err
+		}
+		indexed.indexes[m[3]] = indexed.indexes[m[3]] || m[1] != ""
+	} else if m := dropTableStmt.FindStringSubmatch(stmt); m != nil {
+		delete(tables, m[1])
+	} else if !dataStmt.MatchString(stmt) {
+		return fmt.Errorf("cannot replay this statement")
+	}
+	return nil
+}
+
+// sqlStatements splits a migration into statements, dropping comments and
+// keeping $$-quoted function bodies whole.
+func sqlStatements(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+	var stmts []string
+	for i, chunk := range strings.Split(strings.Join(lines, " "), "$$") {
+		if i%2 == 1 {
+			// Inside a function body
+			stmts[len(stmts)-1] += "$$" + chunk + "$$"
+			continue
+		}
+		parts := strings.Split(chunk, ";")
+		if i > 0 {
+			stmts[len(stmts)-1] += parts[0]
+			parts = parts[1:]
+		}
+		stmts = append(stmts, parts...)
+	}
+	var cleaned []string
+	for _, stmt := range stmts {
+		if stmt = strings.Join(strings.Fields(stmt), " "); stmt != "" {
+			cleaned = append(cleaned, stmt)
+		}
+	}
+	return cleaned
+}
+
+// splitTopLevel splits a column list at the commas outside parentheses.
+func splitTopLevel(list string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range list {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(list[start:]))
+}
+
+func parseColumn(def string) *sqlColumn {
+	upper := strings.ToUpper(def)
+	column := &sqlColumn{
+		typ:     strings.Fields(upper)[0],
+		notNull: strings.Contains(upper, "NOT NULL") || strings.Contains(upper, "PRIMARY KEY"),
+	}
+	if m := columnDefault.FindStringSubmatch(def); m != nil {
+		column.defaults = m[1]
+	}
+	return column
+}
+
+// TestMigrationsMatchEntities checks that the SQL migrations, the only source
+// of the schema, create every table, column and index the entities map to.
+func TestMigrationsMatchEntities(t *testing.T) {
+	tables := replayMigrations(t)
+
+	models := []interface{}{
+		&entity.User{}, &entity.Session{}, &entity.RefreshToken{}, &entity.AuditEvent{}, &entity.GatewayPolicyVersion{},
+		&entity.PasswordHistory{}, &entity.EmailChange{}, &entity.PasswordReset{}, &entity.SuperadminTransfer{},
+	}
+	for _, model := range models {
+		s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+		require.NoError(t, err)
+		table, ok := tables[s.Table]
+		if !assert.True(t, ok, "no migration creates table %s", s.Table) {
+			continue
+		}
+
+		var fields, columns []string
+		for _, field := range s.Fields {
+			if field.DBName != "" {
+				fields = append(fields, field.DBName)
+			}
+		}
+		for name := range table.columns {
+			columns = append(columns, name)
+		}
+		sort.Strings(fields)
+		sort.Strings(columns)
+		assert.Equal(t, fields, columns, "columns of %s", s.Table)
+
+		for _, field := range s.Fields {
+			column, ok := table.columns[field.DBName]
+			if !ok {
+				continue
+			}
+			name := s.Table + "." + field.DBName
+			if !field.PrimaryKey {
+				assert.Equal(t, field.NotNull, column.notNull, "NOT NULL of %s", name)
+			}
+			if field.DataType == schema.String && field.Size > 0 {
+				assert.Equal(t, fmt.Sprintf("VARCHAR(%d)", field.Size), column.typ, "type of %s", name)
+			}
+			// Constant defaults must be the entity's, so rows the gateway does
+			// not write itself still pass its validation
+			if literalDefault.MatchString(column.defaults) || field.DefaultValue != "" {
+				assert.Equal(t, field.DefaultValue, strings.Trim(column.defaults, "'"), "default of %s", name)
+			}
+		}
+
+		for _, index := range s.ParseIndexes() {
+			leading := index.Fields[0].DBName
+			unique, ok := table.indexes[leading]
+			if assert.True(t, ok, "no index on %s.%s", s.Table, leading) && index.Class == "UNIQUE" {
+				assert.True(t, unique, "index on %s.%s is not unique", s.Table, leading)
+			}
+		}
+	}
+}
+
+// TestReplayRejectsUnknownStatements keeps the replay from skipping DDL that
+// would change the schema behind TestMigrationsMatchEntities' back.
+func TestReplayRejectsUnknownStatements(t *testing.T) {
+	for _, stmt := range []string{
+		"ALTER TABLE users ALTER COLUMN role TYPE VARCHAR(64)",
+		"ALTER TABLE users RENAME COLUMN email TO mail",
+		"ALTER TABLE users ADD COLUMN a INTEGER, ADD COLUMN b INTEGER",
+		"CREATE TABLE pairs (a INTEGER, b INTEGER, PRIMARY KEY (a, b))",
+		"DROP INDEX idx_users_role",
+		"ALTER TABLE missing ADD COLUMN a INTEGER",
+	} {
+		tables := map[string]*sqlTable{"users": {columns: map[string]*sqlColumn{"role": {}}, indexes: map[string]bool{}}}
+		assert.Error(t, replayStatement(tables, stmt), stmt)
+	}
+}
@@ -0,0 +1,186 @@
+package unit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"zeneye-gateway/internal/adapter/notify"
+	"zeneye-gateway/internal/domain/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPServer accepts one session and records what the client sent.
+type fakeSMTPServer struct {
+	listener net.Listener
+	done     chan struct{}
+	auth     string
+	from     string
+	to       string
+	data     string
+}
+
+func startFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTPServer{listener: listener, done: make(chan struct{})}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250-fake")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			fields := strings.Fields(line)
+			decoded, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			s.auth = string(decoded)
+			reply("235 authenticated")
+		case "MAIL":
+			s.from = line
+			reply("250 ok")
+		case "RCPT":
+			s.to = line
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil || dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.data = data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown command")
+		}
+	}
+}
+
+func TestSMTPNotifierDeliversToFakeServer(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	notifier := notify.New(notify.Options{
+		Backend: notify.BackendSMTP,
+		Timeout: 5 * time.Second,
+		SMTP: notify.SMTPOptions{Host: "127.0.0.1", Port: server.port(), Username: "gateway", Password: "mailpass",
+			From: "gateway@example.com"},
+	})
+
+	err := notifier.Notify(&entity.Notification{To: "user@example.com", Subject: "Reset your password", Body: "line one\nline two"})
+	assert.Nil(t, err)
+	<-server.done
+
+	assert.Equal(t, "\x00gateway\x00mailpass", server.auth)
+	assert.Equal(t, "MAIL FROM:<gateway@example.com>", server.from)
+	assert.Equal(t, "RCPT TO:<user@example.com>", server.to)
+	assert.Contains(t, server.data, "Subject: Reset your password\r\n")
+	assert.Contains(t, server.data, "To: user@example.com\r\n")
+	assert.Contains(t, server.data, "\r\n\r\nline one\r\nline two\r\n")
+}
+
+func TestSMTPNotifierRejectsHeaderInjection(t *testing.T) {
+	notifier := notify.NewSMTPNotifier(notify.SMTPOptions{Host: "127.0.0.1", Port: 1, From: "gateway@example.com"}, time.Second)
+	err := notifier.Notify(&entity.Notification{To: "user@example.com\r\nBcc: everyone@example.com", Subject: "hi"})
+	assert.NotNil(t, err)
+}
+
+func TestWebhookNotifierSignsPayload(t *testing.T) {
+	var body []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Signature-256")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := notify.New(notify.Options{Backend: notify.BackendWebhook, Timeout: time.Second,
+		Webhook: notify.WebhookOptions{URL: server.URL, Secret: "hook-secret"}})
+	assert.Nil(t, notifier.Notify(&entity.Notification{To: "user@example.com", Subject: "s", Body: "b"}))
+
+	var received map[string]string
+	assert.Nil(t, json.Unmarshal(body, &received))
+	assert.Equal(t, map[string]string{"to": "user@example.com", "subject": "s", "body": "b"}, received)
+	mac := hmac.New(sha256.New, []byte("hook-secret"))
+	mac.Write(body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), signature)
+}
+
+func TestServiceNotifierPostsToNotificationService(t *testing.T) {
+	var path string
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	opts := notify.DefaultOptions()
+	opts.Backend = notify.BackendService
+	opts.Service.URL = server.URL + "/"
+	assert.Nil(t, opts.Validate())
+	notifier := notify.New(opts)
+	assert.Nil(t, notifier.Notify(&entity.Notification{To: "user@example.com"}))
+	assert.Equal(t, "/notifications", path)
+
+	// Failures are reported so they can be logged
+	status = http.StatusBadGateway
+	err := notifier.Notify(&entity.Notification{To: "user@example.com"})
+	assert.ErrorContains(t, err, strconv.Itoa(http.StatusBadGateway))
+}
+
+func TestNotifyOptionsValidate(t *testing.T) {
+	opts := notify.DefaultOptions()
+	assert.Nil(t, opts.Validate())
+
+	opts.Backend = notify.BackendSMTP
+	err := opts.Validate()
+	assert.ErrorContains(t, err, "smtp host must be set")
+	assert.ErrorContains(t, err, "smtp from must be set")
+
+	opts.Backend = "pigeon"
+	assert.ErrorContains(t, opts.Validate(), `notify backend "pigeon"`)
+}
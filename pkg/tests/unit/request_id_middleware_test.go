@@ -0,0 +1,98 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"zeneye-gateway/internal/adapter/http/middlewares"
+	errorResponse "zeneye-gateway/pkg/error"
+	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func setupRequestIDRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(middlewares.RequestIDMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		logger.FromContext(c.Request.Context()).WithSource("TestRequestID").Info("Accessed /test endpoint", logger.String("Activity", "Handler"))
+		c.JSON(http.StatusOK, gin.H{
+			"request_id": requestid.FromContext(c.Request.Context()),
+			"forwarded":  c.Request.Header.Get(requestid.Header),
+		})
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		errorResponse.NewProblem(c, http.StatusBadRequest, errorResponse.CodeMalformedBody, "request body is not valid JSON")
+	})
+	return router
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	setupRequestIDRouter().ServeHTTP(w, req)
+
+	var body map[string]string
+	json.Unmarshal(w.Body.Bytes(), &body)
+
+	id := w.Header().Get(requestid.Header)
+	assert.True(t, requestid.Valid(id))
+	assert.Equal(t, id, body["request_id"])
+	assert.Equal(t, id, body["forwarded"])
+}
+
+func TestRequestIDPreservedOrReplaced(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	router := setupRequestIDRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set(requestid.Header, "client-abc.123")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "client-abc.123", w.Header().Get(requestid.Header))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/test", nil)
+	req.Header.Set(requestid.Header, "bad id\r\nX-Injected: 1")
+	router.ServeHTTP(w, req)
+	assert.NotEqual(t, "bad id\r\nX-Injected: 1", w.Header().Get(requestid.Header))
+	assert.True(t, requestid.Valid(w.Header().Get(requestid.Header)))
+}
+
+func TestRequestIDInErrorResponseAndLogs(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	previous := logger.Logging
+	logger.Logging = zap.New(core)
+	defer func() { logger.Logging = previous }()
+
+	router := setupRequestIDRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/fail", nil)
+	req.Header.Set(requestid.Header, "req-error-1")
+	router.ServeHTTP(w, req)
+
+	var body errorResponse.Problem
+	json.Unmarshal(w.Body.Bytes(), &body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "req-error-1", body.RequestID)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/test", nil)
+	req.Header.Set(requestid.Header, "req-log-1")
+	router.ServeHTTP(w, req)
+
+	entries := logs.FilterField(zap.String("RequestID", "req-log-1")).All()
+	assert.Len(t, entries, 1)
+}
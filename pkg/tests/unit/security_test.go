@@ -0,0 +1,152 @@
+package unit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zeneye-gateway/internal/adapter/http/middlewares"
+	errorResponse "zeneye-gateway/pkg/error"
+	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/security"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePath(t *testing.T) {
+	for path, want := range map[string]error{
+		"/users/42":                 nil,
+		"/files/report%20final.pdf": nil,
+		"/users/../gateway-admin":   security.ErrPathTraversal,
+		"/users/%2e%2e/admin":       security.ErrPathTraversal,
+		"/users/%2E/admin":          security.ErrPathTraversal,
+		"/agent/a%2fb":              security.ErrEncodedSeparator,
+		"/agent/a%5Cb":              security.ErrEncodedSeparator,
+		"/agent/a\\b":               security.ErrInvalidCharacter,
+		"/agent/%zz":                security.ErrMalformedEncoding,
+		"/agent/%252e%252e":         security.ErrDoubleEncoding,
+		"/agent/%00":                security.ErrInvalidCharacter,
+		"/agent/%ff":                security.ErrInvalidCharacter,
+	} {
+		assert.Equal(t, want, security.ValidatePath(path), path)
+	}
+
+	assert.Nil(t, security.ValidateQuery("page=2&q=caf%C3%A9"))
+	assert.Equal(t, security.ErrMalformedQuery, security.ValidateQuery("q=%zz"))
+	assert.Equal(t, security.ErrMalformedQuery, security.ValidateQuery("q=%ff"))
+}
+
+func TestSecurityOptionsValidation(t *testing.T) {
+	opts := security.DefaultOptions()
+	opts.FrameOptions = "DENY\r\nX-Injected: 1"
+	opts.AllowedContentTypes = []string{"application/json", "not a type"}
+	opts.MaxHeaderBytes = -1
+	opts.Routes = []security.RouteOptions{{Prefix: "uploads"}}
+
+	err := opts.Validate()
+	assert.NotNil(t, err)
+	for _, want := range []string{"X-Frame-Options", "not a type", "max header bytes", "uploads"} {
+		assert.Contains(t, err.Error(), want)
+	}
+
+	t.Setenv("SECURITY_CSP", "")
+	t.Setenv("SECURITY_ROUTES", `[{"prefix":"/uploads","max_body_bytes":10485760,"allowed_content_types":["multipart/form-data"]}]`)
+	fromEnv, err := security.OptionsFromEnv()
+	assert.Nil(t, err)
+	assert.Empty(t, fromEnv.ContentSecurityPolicy)
+	assert.Equal(t, int64(10<<20), *fromEnv.Routes[0].MaxBodyBytes)
+}
+
+func setupSecurityRouter(t *testing.T, opts security.Options) *gin.Engine {
+	policy, err := security.New(opts)
+	if err != nil {
+		t.Fatalf("Failed to build security policy: %v", err)
+	}
+	router := gin.New()
+	router.Use(middlewares.SnapshotMiddleware(middlewares.NewSnapshots(&middlewares.Snapshot{Security: policy})))
+	router.Use(middlewares.SecurityMiddleware())
+	echo := func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			errorResponse.FromError(c, err)
+			return
+		}
+		c.String(http.StatusOK, "%d", len(body))
+	}
+	router.POST("/echo", echo)
+	router.POST("/uploads/file", echo)
+	router.GET("/echo", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	return router
+}
+
+func TestSecurityMiddleware(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	opts := security.DefaultOptions()
+	opts.MaxBodyBytes = 16
+	opts.MaxHeaderBytes = 512
+	opts.Routes = []security.RouteOptions{{Prefix: "/uploads", AllowedContentTypes: []string{"application/octet-stream"}}}
+	router := setupSecurityRouter(t, opts)
+
+	send := func(method, target, contentType string, body io.Reader, mutate func(*http.Request)) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, body)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if mutate != nil {
+			mutate(req)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("GET", "/echo", "", nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+	assert.Contains(t, w.Header().Get("Strict-Transport-Security"), "max-age=")
+	assert.Contains(t, w.Header().Get("Content-Security-Policy"), "default-src 'none'")
+
+	w = send("POST", "/echo", "application/json; charset=utf-8", strings.NewReader(`{"a":1}`), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send("POST", "/echo", "application/json", strings.NewReader(strings.Repeat("x", 17)), nil)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), errorResponse.CodePayloadTooLarge)
+
+	// Without a declared length the body is cut off while it is read.
+	w = send("POST", "/echo", "application/json", strings.NewReader(strings.Repeat("x", 64)), func(r *http.Request) {
+		r.ContentLength = -1
+	})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = send("POST", "/echo", "text/xml", strings.NewReader("<a/>"), nil)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Body.String(), errorResponse.CodeUnsupportedMedia)
+
+	w = send("POST", "/uploads/file", "application/octet-stream", strings.NewReader("data"), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send("POST", "/uploads/file", "application/json", strings.NewReader("{}"), nil)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	w = send("GET", "/echo", "", nil, func(r *http.Request) {
+		r.Header.Set("X-Padding", strings.Repeat("p", 600))
+	})
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, w.Code)
+
+	w = send("GET", "/echo/%2e%2e/gateway-admin", "", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), errorResponse.CodeInvalidPath)
+	// Security headers are sent on rejections too.
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	w = send("GET", "/echo?q=%ff", "", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), errorResponse.CodeInvalidQuery)
+}
@@ -0,0 +1,142 @@
+package unit
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zeneye-gateway/internal/adapter/http/handlers"
+	"zeneye-gateway/pkg/health"
+	"zeneye-gateway/pkg/logger"
+	"zeneye-gateway/pkg/server"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func startTestServer(t *testing.T, handler http.Handler, opts server.Options) (string, context.CancelFunc, chan error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx, server.New(handler, opts), ln, opts)
+	}()
+	return "http://" + ln.Addr().String(), cancel, done
+}
+
+func TestServerDrainsInFlightRequests(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := gin.New()
+	router.GET("/readyz", handlers.Readyz(health.New(health.DefaultOptions())))
+	router.GET("/slow", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.String(http.StatusOK, "done")
+	})
+
+	opts := server.DefaultOptions()
+	opts.DrainDelay = 50 * time.Millisecond
+	opts.ShutdownTimeout = 5 * time.Second
+	baseURL, cancel, done := startTestServer(t, router, opts)
+
+	result := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(baseURL + "/slow")
+		if err != nil {
+			result <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		result <- string(body)
+	}()
+	<-started
+
+	cancel()
+	assert.Eventually(t, server.Draining, time.Second, 5*time.Millisecond)
+
+	// Readiness fails while the listener is still open.
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	select {
+	case err := <-done:
+		t.Fatalf("Server stopped before the in-flight request finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Equal(t, "done", <-result)
+	assert.Nil(t, <-done)
+
+	_, err := http.Get(baseURL + "/slow")
+	assert.NotNil(t, err)
+}
+
+func TestServerShutdownDeadline(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	started := make(chan struct{})
+	stuck := make(chan struct{})
+	defer close(stuck)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-stuck
+	})
+
+	opts := server.DefaultOptions()
+	opts.DrainDelay = 0
+	opts.ShutdownTimeout = 50 * time.Millisecond
+	baseURL, cancel, done := startTestServer(t, handler, opts)
+
+	go http.Get(baseURL)
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Shutdown did not respect its deadline")
+	}
+}
+
+func TestServerOptions(t *testing.T) {
+	t.Setenv("SERVER_ADDR", "127.0.0.1:9090")
+	t.Setenv("SERVER_WRITE_TIMEOUT", "2m")
+	opts, err := server.OptionsFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1:9090", opts.Addr)
+	assert.Equal(t, 2*time.Minute, opts.WriteTimeout)
+
+	srv := server.New(http.NotFoundHandler(), opts)
+	assert.Equal(t, opts.ReadHeaderTimeout, srv.ReadHeaderTimeout)
+	assert.Equal(t, opts.IdleTimeout, srv.IdleTimeout)
+
+	t.Setenv("SERVER_READ_TIMEOUT", "forever")
+	_, err = server.OptionsFromEnv()
+	assert.NotNil(t, err)
+
+	bad := server.DefaultOptions()
+	bad.Addr = "8080"
+	bad.IdleTimeout = 0
+	bad.DrainDelay = -time.Second
+	err = bad.Validate()
+	assert.NotNil(t, err)
+	for _, want := range []string{"8080", "idle timeout", "drain delay"} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
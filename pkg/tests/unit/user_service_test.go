@@ -1,9 +1,12 @@
 package unit
 
 import (
+	"errors"
 	"testing"
+	"time"
 	"zeneye-gateway/internal/adapter/repository/postgres"
 	"zeneye-gateway/internal/adapter/service"
+	"zeneye-gateway/internal/domain/domainerr"
 	"zeneye-gateway/internal/domain/entity"
 	"zeneye-gateway/pkg/logger"
 
@@ -14,7 +17,7 @@ import (
 
 func setupTestDB() *gorm.DB {
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	db.AutoMigrate(&entity.User{}, &entity.RefreshToken{})
+	db.AutoMigrate(&entity.User{}, &entity.RefreshToken{}, &entity.AuditEvent{}, &entity.PasswordHistory{}, &entity.EmailChange{}, &entity.PasswordReset{}, &entity.SuperadminTransfer{})
 	return db
 }
 
@@ -34,7 +37,7 @@ func TestCreateUser(t *testing.T) {
 
 	logger.LogInfo("TestCreateUser", "Test", "Creating user", user)
 
-	err := userService.CreateUser(user)
+	err := userService.CreateUser(entity.SystemActorID, user)
 	assert.Nil(t, err)
 	assert.NotEmpty(t, user.UserUUID) // Ensure UserUUID is not empty
 }
@@ -55,7 +58,7 @@ func TestGetUser(t *testing.T) {
 
 	logger.LogInfo("TestGetUser", "Test", "Creating user for retrieval", user)
 
-	userService.CreateUser(user)
+	userService.CreateUser(entity.SystemActorID, user)
 	result, err := userService.GetUser(user.ID)
 
 	logger.LogInfo("TestGetUser", "Test", "Retrieving user", result)
@@ -78,18 +81,19 @@ func TestEditUser(t *testing.T) {
 	}
 
 	logger.LogInfo("TestEditUser", "Test", "Creating user for editing", user)
-	userService.CreateUser(user)
+	userService.CreateUser(entity.SystemActorID, user)
 	user.Username = "updateduser"
 	user.Email = "newemail@example.com"
 
 	logger.LogInfo("TestEditUser", "Test", "Editing user", user)
-	err := userService.EditUser(user)
+	err := userService.EditUser(entity.SystemActorID, user)
 	assert.Nil(t, err)
 
 	result, _ := userService.GetUser(user.ID)
 	logger.LogInfo("TestEditUser", "Test", "Retrieving edited user", result)
 	assert.Equal(t, "updateduser", result.Username)
-	assert.Equal(t, "newemail@example.com", result.Email)
+	// The email only changes through a confirmed email change
+	assert.Equal(t, "unit_test@example.com", result.Email)
 }
 
 func TestDeleteUser(t *testing.T) {
@@ -107,8 +111,8 @@ func TestDeleteUser(t *testing.T) {
 	}
 
 	logger.LogInfo("TestDeleteUser", "Test", "Creating user for deletion", user)
-	userService.CreateUser(user)
-	err := userService.DeleteUser(user.ID)
+	userService.CreateUser(entity.SystemActorID, user)
+	err := userService.DeleteUser(entity.SystemActorID, user.ID)
 
 	logger.LogInfo("TestDeleteUser", "Test", "Deleting user", user.ID)
 	assert.Nil(t, err)
@@ -141,13 +145,85 @@ func TestListUsers(t *testing.T) {
 	}
 
 	logger.LogInfo("TestListUsers", "Test", "Creating multiple users for listing", user1, user2)
-	userService.CreateUser(user1)
-	userService.CreateUser(user2)
+	userService.CreateUser(entity.SystemActorID, user1)
+	userService.CreateUser(entity.SystemActorID, user2)
 
-	users, err := userService.ListUsers()
-	logger.LogInfo("TestListUsers", "Test", "Listing all users", users)
+	page, err := userService.ListUsers(entity.UserFilter{})
+	logger.LogInfo("TestListUsers", "Test", "Listing all users", page)
 	assert.Nil(t, err)
-	assert.Len(t, users, 2)
+	assert.Len(t, page.Users, 2)
+	assert.Equal(t, int64(2), page.Total)
+	assert.Nil(t, page.Next)
+}
+
+func TestListUsersFiltersAndPages(t *testing.T) {
+	logger.InitLogger()
+	defer logger.SyncLogger()
+
+	db := setupTestDB()
+	userService := service.NewUserService(postgres.NewUserRepository(db))
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, u := range []struct{ username, email, role string }{
+		{"carol", "carol@corp.example", "admin"},
+		{"alice", "alice@corp.example", "auditor"},
+		{"bob", "bob@other.example", "admin"},
+		{"dave", "dave_1@corp.example", "admin"},
+		{"erin", "erin@CORP.example", "department_admin"},
+	} {
+		user := &entity.User{Username: u.username, Password: "x", Email: u.email, Role: u.role}
+		assert.Nil(t, userService.CreateUser(entity.SystemActorID, user))
+		db.Model(user).UpdateColumn("created_at", base.Add(time.Duration(i)*time.Hour))
+	}
+
+	// Filters combine, and the total ignores the page size
+	page, err := userService.ListUsers(entity.UserFilter{EmailDomain: "corp.example", Role: "admin"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	assert.Equal(t, "carol", page.Users[0].Username)
+
+	page, _ = userService.ListUsers(entity.UserFilter{CreatedFrom: base.Add(time.Hour), CreatedTo: base.Add(3 * time.Hour)})
+	assert.Equal(t, int64(2), page.Total)
+
+	// Search matches usernames and emails, and wildcards match literally
+	page, _ = userService.ListUsers(entity.UserFilter{Search: "OTHER"})
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, "bob", page.Users[0].Username)
+	page, _ = userService.ListUsers(entity.UserFilter{Search: "_1"})
+	assert.Equal(t, int64(1), page.Total)
+	page, _ = userService.ListUsers(entity.UserFilter{Search: "corp", Role: "auditor"})
+	assert.Equal(t, int64(1), page.Total)
+
+	// A cursor walks a sorted listing without gaps or repeats
+	var walked []string
+	filter := entity.UserFilter{Sort: entity.UserSortUsername, Desc: true, Limit: 2}
+	for {
+		page, err = userService.ListUsers(filter)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(5), page.Total)
+		for _, user := range page.Users {
+			walked = append(walked, user.Username)
+		}
+		if page.Next == nil {
+			break
+		}
+		cursor, err := entity.ParseUserCursor(page.Next.Encode())
+		assert.Nil(t, err)
+		filter.After = cursor
+	}
+	assert.Equal(t, []string{"erin", "dave", "carol", "bob", "alice"}, walked)
+
+	filter = entity.UserFilter{Sort: entity.UserSortCreatedAt, Limit: 3}
+	page, _ = userService.ListUsers(filter)
+	filter.After = page.Next
+	page, _ = userService.ListUsers(filter)
+	assert.Len(t, page.Users, 2)
+	assert.Equal(t, "dave", page.Users[0].Username)
+
+	// Offsets page too, and the limit is clamped
+	page, _ = userService.ListUsers(entity.UserFilter{Offset: 4, Limit: 10_000})
+	assert.Len(t, page.Users, 1)
+	assert.Equal(t, service.MaxUserLimit, page.Limit)
 }
 
 func TestCreateSuperadmin(t *testing.T) {
@@ -166,7 +242,7 @@ func TestCreateSuperadmin(t *testing.T) {
 	}
 
 	logger.LogInfo("TestCreateSuperadmin", "Test", "Creating superadmin", superadmin)
-	err := userService.CreateUser(superadmin)
+	err := userService.CreateUser(entity.SystemActorID, superadmin)
 	assert.Nil(t, err)
 	assert.NotEmpty(t, superadmin.UserUUID) // Ensure UserUUID is not empty
 
@@ -179,7 +255,7 @@ func TestCreateSuperadmin(t *testing.T) {
 	}
 
 	logger.LogInfo("TestCreateSuperadmin", "Test", "Attempting to create another superadmin", anotherSuperadmin)
-	err = userService.CreateUser(anotherSuperadmin)
+	err = userService.CreateUser(entity.SystemActorID, anotherSuperadmin)
 	assert.NotNil(t, err)
 	assert.Equal(t, "superadmin already exists", err.Error())
 }
@@ -205,7 +281,7 @@ func TestIsSuperadminPresent(t *testing.T) {
 	}
 
 	logger.LogInfo("TestIsSuperadminPresent", "Test", "Creating superadmin", superadmin)
-	err = userService.CreateUser(superadmin)
+	err = userService.CreateUser(entity.SystemActorID, superadmin)
 	assert.Nil(t, err)
 
 	exists, err = userService.IsSuperadminPresent()
@@ -230,7 +306,7 @@ func TestGenerateRefreshToken(t *testing.T) {
 	}
 
 	logger.LogInfo("TestGenerateRefreshToken", "Test", "Creating user for refresh token generation", user)
-	userService.CreateUser(user)
+	userService.CreateUser(entity.SystemActorID, user)
 
 	refreshToken, err := userService.GenerateRefreshToken(user.ID)
 	logger.LogInfo("TestGenerateRefreshToken", "Test", "Generating refresh token", refreshToken)
@@ -254,7 +330,7 @@ func TestRefreshAccessToken(t *testing.T) {
 	}
 
 	logger.LogInfo("TestRefreshAccessToken", "Test", "Creating user for access token refresh", user)
-	userService.CreateUser(user)
+	userService.CreateUser(entity.SystemActorID, user)
 
 	refreshToken, _ := userService.GenerateRefreshToken(user.ID)
 	newToken, err := userService.RefreshAccessToken(refreshToken)
@@ -262,3 +338,68 @@ func TestRefreshAccessToken(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotEmpty(t, newToken)
 }
+
+func TestDisableUserRevokesRefreshTokens(t *testing.T) {
+	db := setupTestDB()
+	userService := service.NewUserService(postgres.NewUserRepository(db))
+
+	user := &entity.User{Username: "disableuser", Password: "password@123", Email: "disable@example.com", Role: "admin"}
+	assert.Nil(t, userService.CreateUser(entity.SystemActorID, user))
+	refreshToken, _ := userService.GenerateRefreshToken(user.ID)
+
+	assert.Nil(t, userService.DisableUser(entity.SystemActorID, user.ID))
+	_, err := userService.RefreshAccessToken(refreshToken)
+	assert.NotNil(t, err)
+	_, err = userService.AuthenticateUser("disableuser", "password@123")
+	assert.True(t, errors.Is(err, domainerr.ErrAccountDisabled))
+
+	assert.Nil(t, userService.EnableUser(entity.SystemActorID, user.ID))
+	_, err = userService.AuthenticateUser("disableuser", "password@123")
+	assert.Nil(t, err)
+}
+
+func TestRestoreUserRejectsReusedEmail(t *testing.T) {
+	db := setupTestDB()
+	userService := service.NewUserService(postgres.NewUserRepository(db))
+
+	user := &entity.User{Username: "gone", Password: "password@123", Email: "reused@example.com", Role: "admin"}
+	assert.Nil(t, userService.CreateUser(entity.SystemActorID, user))
+	assert.Nil(t, userService.DeleteUser(entity.SystemActorID, user.ID))
+	assert.Nil(t, userService.CreateUser(entity.SystemActorID, &entity.User{Username: "newcomer", Password: "password@123", Email: "reused@example.com", Role: "admin"}))
+
+	err := userService.RestoreUser(entity.SystemActorID, user.ID)
+	assert.True(t, errors.Is(err, domainerr.ErrEmailTaken))
+	assert.True(t, errors.Is(userService.RestoreUser(entity.SystemActorID, 9999), domainerr.ErrUserNotFound))
+}
+
+func TestLastSuperadminStays(t *testing.T) {
+	db := setupTestDB()
+	userService := service.NewUserService(postgres.NewUserRepository(db))
+
+	root := &entity.User{Username: "lastroot", Password: "password@123", Email: "lastroot@example.com", Role: "superadmin"}
+	assert.Nil(t, userService.CreateUser(entity.SystemActorID, root))
+
+	// Not even the command line, which ranks are no obstacle to, removes it
+	assert.True(t, errors.Is(userService.DeleteUser(entity.SystemActorID, root.ID), domainerr.ErrLastSuperadmin))
+	assert.True(t, errors.Is(userService.DisableUser(entity.SystemActorID, root.ID), domainerr.ErrLastSuperadmin))
+}
+
+func TestAcceptSuperadminTransferRejectsExpiredOffer(t *testing.T) {
+	db := setupTestDB()
+	userService := service.NewUserService(postgres.NewUserRepository(db))
+
+	root := &entity.User{Username: "root", Password: "password@123", Email: "root@example.com", Role: "superadmin"}
+	heir := &entity.User{Username: "heir", Password: "password@123", Email: "heir@example.com", Role: "admin"}
+	assert.Nil(t, userService.CreateUser(entity.SystemActorID, root))
+	assert.Nil(t, userService.CreateUser(entity.SystemActorID, heir))
+
+	_, err := userService.RequestSuperadminTransfer(root.ID, heir.ID)
+	assert.Nil(t, err)
+	db.Model(&entity.SuperadminTransfer{}).Where("1 = 1").Update("expires_at", time.Now().Add(-time.Minute))
+
+	_, err = userService.AcceptSuperadminTransfer(heir.ID)
+	assert.True(t, errors.Is(err, domainerr.ErrTransferNotFound))
+	current, _ := userService.GetUser(root.ID)
+	assert.Equal(t, "superadmin", current.Role)
+	assert.Equal(t, uint(0), current.TokenVersion)
+}
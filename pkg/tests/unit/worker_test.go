@@ -0,0 +1,44 @@
+package unit
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zeneye-gateway/pkg/worker"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPoolBoundsAndDrainsJobs(t *testing.T) {
+	pool := worker.New(1, 1)
+	release := make(chan struct{})
+	var ran atomic.Int32
+
+	started := make(chan struct{})
+	assert.Nil(t, pool.Submit(func() { close(started); <-release; ran.Add(1) }))
+	<-started
+	assert.Nil(t, pool.Submit(func() { ran.Add(1) }))
+	// One job runs and one waits; there is no room for a third
+	assert.Equal(t, worker.ErrQueueFull, pool.Submit(func() { ran.Add(1) }))
+
+	// Stop gives up when its context ends, but the jobs keep going
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, pool.Stop(ctx))
+	assert.Equal(t, worker.ErrStopped, pool.Submit(func() {}))
+
+	close(release)
+	assert.Nil(t, pool.Stop(context.Background()))
+	assert.Equal(t, int32(2), ran.Load())
+}
+
+func TestWorkerPoolSurvivesPanics(t *testing.T) {
+	pool := worker.New(1, 2)
+	var ran atomic.Bool
+	assert.Nil(t, pool.Submit(func() { panic("boom") }))
+	assert.Nil(t, pool.Submit(func() { ran.Store(true) }))
+	assert.Nil(t, pool.Stop(context.Background()))
+	assert.True(t, ran.Load())
+}
@@ -0,0 +1,212 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"zeneye-gateway/pkg/logger"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "zeneye-gateway"
+
+// Exporter names accepted in TRACING_EXPORTER.
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// Options configures the tracer provider.
+type Options struct {
+	ServiceName string `yaml:"service_name" toml:"service_name"`
+	Exporter    string `yaml:"exporter" toml:"exporter"`
+	// Endpoint is the OTLP/HTTP collector URL, e.g. http://otel-collector:4318.
+	// When empty the standard OTEL_EXPORTER_OTLP_* variables apply.
+	Endpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint"`
+	// FilePath is where the file exporter writes one JSON span per line.
+	FilePath string `yaml:"file" toml:"file"`
+	// SampleRatio is the fraction of new traces recorded; incoming sampled
+	// parents are always honored.
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// DefaultOptions records every trace but exports none.
+func DefaultOptions() Options {
+	return Options{
+		ServiceName: tracerName,
+		Exporter:    ExporterNone,
+		FilePath:    "traces.jsonl",
+		SampleRatio: 1,
+	}
+}
+
+// OptionsFromEnv applies the environment over the defaults; see LoadEnv.
+func OptionsFromEnv() (Options, error) {
+	opts := DefaultOptions()
+	if err := opts.LoadEnv(os.LookupEnv); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// LoadEnv overrides o with TRACING_EXPORTER, TRACING_OTLP_ENDPOINT,
+// TRACING_FILE, TRACING_SAMPLE_RATIO and OTEL_SERVICE_NAME as found by lookup,
+// usually os.LookupEnv.
+func (o *Options) LoadEnv(lookup func(string) (string, bool)) error {
+	getenv := func(key string) string { v, _ := lookup(key); return v }
+	for key, target := range map[string]*string{
+		"OTEL_SERVICE_NAME":     &o.ServiceName,
+		"TRACING_EXPORTER":      &o.Exporter,
+		"TRACING_OTLP_ENDPOINT": &o.Endpoint,
+		"TRACING_FILE":          &o.FilePath,
+	} {
+		if v := getenv(key); v != "" {
+			*target = v
+		}
+	}
+	if v := getenv("TRACING_SAMPLE_RATIO"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("TRACING_SAMPLE_RATIO: %w", err)
+		}
+		o.SampleRatio = ratio
+	}
+	return nil
+}
+
+// Validate reports every invalid option at once.
+func (o Options) Validate() error {
+	var errs []error
+	if o.ServiceName == "" {
+		errs = append(errs, errors.New("tracing service name must not be empty"))
+	}
+	switch o.Exporter {
+	case ExporterNone, ExporterOTLP, ExporterStdout:
+	case ExporterFile:
+		if o.FilePath == "" {
+			errs = append(errs, errors.New("tracing file must be set for the file exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing exporter %q must be none, otlp, stdout or file", o.Exporter))
+	}
+	if o.SampleRatio < 0 || o.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing sample ratio %v must be between 0 and 1", o.SampleRatio))
+	}
+	return errors.Join(errs...)
+}
+
+// Init installs the global tracer provider and the W3C trace-context propagator.
+// Spans are always created so context propagates upstream; they are only exported
+// when an exporter other than "none" is configured. The returned func flushes and
+// stops the provider.
+func Init(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	exporter, closer, err := newExporter(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", opts.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	}
+	if exporter != nil {
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	}
+	provider := sdktrace.NewTracerProvider(providerOpts...)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	logger.LogInfo("Tracing", "Init", "Tracing initialized", map[string]interface{}{
+		"exporter":     opts.Exporter,
+		"sample_ratio": opts.SampleRatio,
+		"service_name": opts.ServiceName,
+	})
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}
+
+func newExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, io.Closer, error) {
+	switch opts.Exporter {
+	case ExporterNone:
+		return nil, nil, nil
+	case ExporterOTLP:
+		var clientOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exporter, err := otlptracehttp.New(ctx, clientOpts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, nil, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil, nil
+	case ExporterFile:
+		file, err := os.OpenFile(opts.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		return exporter, file, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown tracing exporter %q", opts.Exporter)
+	}
+}
+
+// Tracer returns the gateway tracer from the current global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Start opens a span named name as a child of any span already in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// RecordError marks span as failed with err.
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,102 @@
+// Package worker runs background jobs on a fixed number of goroutines fed by a
+// bounded queue, so a burst of requests cannot start unbounded work, and
+// drains them on shutdown.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"zeneye-gateway/pkg/logger"
+)
+
+var (
+	// ErrQueueFull is returned by Submit when every worker is busy and the
+	// queue holds as many jobs as it can.
+	ErrQueueFull = errors.New("worker queue is full")
+	// ErrStopped is returned by Submit once Stop was called.
+	ErrStopped = errors.New("worker pool is stopped")
+)
+
+// Pool runs the jobs handed to Submit in the background.
+type Pool struct {
+	jobs    chan func()
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// New starts workers goroutines that run the submitted jobs, with up to queue
+// jobs waiting for a free worker.
+func New(workers, queue int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queue < 0 {
+		queue = 0
+	}
+	p := &Pool{jobs: make(chan func(), queue)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues job without waiting for it to run. It fails with ErrQueueFull
+// when the queue is full and with ErrStopped after Stop.
+func (p *Pool) Submit(job func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return ErrStopped
+	}
+	select {
+	case p.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Stop refuses new jobs and waits until the queued and running ones have
+// finished, or until ctx is done.
+func (p *Pool) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		run(job)
+	}
+}
+
+// run keeps a panicking job from taking the gateway down with it.
+func run(job func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.LogError("Worker", "Run", "", fmt.Errorf("job panicked: %v", r))
+		}
+	}()
+	job()
+}